@@ -0,0 +1,106 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decrypt_value
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	pgTransformers "github.com/greenmaskio/greenmask/internal/db/postgres/transformers"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/generators/transformers"
+	"github.com/greenmaskio/greenmask/internal/utils"
+	"github.com/greenmaskio/greenmask/internal/utils/logger"
+)
+
+var (
+	Cmd = &cobra.Command{
+		Use:   "decrypt-value [flags] values...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "decrypt values encrypted by FPE transformer",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := logger.SetDefaultContextLogger(Config.Log.Level, Config.Log.Format); err != nil {
+				log.Fatal().Err(err).Msg("")
+			}
+
+			if err := run(os.Stdout, args); err != nil {
+				log.Fatal().Err(err).Msg("")
+			}
+		},
+	}
+	Config         = domains.NewConfig()
+	keyRef         string
+	alphabet       string
+	tweak          string
+	preservePrefix int
+	preserveSuffix int
+)
+
+func run(w io.Writer, values []string) error {
+	salt, err := utils.GetGlobalSalt()
+	if err != nil {
+		return err
+	}
+	key, err := transformers.ResolveFpeKey(keyRef, salt)
+	if err != nil {
+		return fmt.Errorf("unable to get encryption key: %w", err)
+	}
+	t, err := transformers.NewFpeTransformer(key, []rune(alphabet), preservePrefix, preserveSuffix)
+	if err != nil {
+		return fmt.Errorf("unable to create FPE transformer: %w", err)
+	}
+
+	for _, v := range values {
+		res, err := t.Decrypt([]rune(v), []byte(tweak))
+		if errors.Is(err, transformers.ErrFpeValueTooShort) {
+			// The values that are too short to be encrypted are kept as is by the transformer
+			res = []rune(v)
+		} else if err != nil {
+			return fmt.Errorf("unable to decrypt value \"%s\": %w", v, err)
+		}
+		if _, err = fmt.Fprintln(w, string(res)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	Cmd.Flags().StringVar(
+		&keyRef, "key", "",
+		fmt.Sprintf(
+			"reference to hex encoded AES key: %sVAR_NAME or %s/path/to/key. If not set the key is derived from "+
+				"the global salt provided via %s",
+			transformers.FpeKeyEnvPrefix, transformers.FpeKeyFilePrefix, utils.GlobalSaltEnvVariable,
+		),
+	)
+	Cmd.Flags().StringVar(
+		&alphabet, "alphabet", pgTransformers.FpeDefaultAlphabet, "the characters that were encrypted",
+	)
+	Cmd.Flags().StringVar(
+		&tweak, "tweak", "", "value of the tweak column of the encrypted row",
+	)
+	Cmd.Flags().IntVar(
+		&preservePrefix, "preserve-prefix", 0, "number of characters at the beginning of the value kept as is",
+	)
+	Cmd.Flags().IntVar(
+		&preserveSuffix, "preserve-suffix", 0, "number of characters at the end of the value kept as is",
+	)
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -123,6 +124,16 @@ func deleteDump(ctx context.Context, st storages.Storager, dumpId string) error
 		return fmt.Errorf("dump with id %s was not found", dumpId)
 	}
 
+	sr, err := getSortedBackupWithStatuses(ctx, st)
+	if err != nil {
+		return fmt.Errorf("could not get sorted dumps: %s", err)
+	}
+	if referencedBy := sr.References.ReferencedBy(dumpId); len(referencedBy) > 0 {
+		return fmt.Errorf(
+			"dump %s is referenced by incremental dumps %s: delete them first", dumpId, strings.Join(referencedBy, ", "),
+		)
+	}
+
 	log.Info().
 		Str("DumpId", dumpId).
 		Msg("deleting dump")
@@ -140,13 +151,13 @@ func pruneFailedDumps(ctx context.Context, st storages.Storager, pruneUnsafe boo
 		return fmt.Errorf("could not get sorted dumps: %s", err)
 	}
 	for _, d := range sr.Failed {
-		if err = deleteDumpById(ctx, st, d, dryRun); err != nil {
+		if err = deleteDumpById(ctx, st, d, sr.References, dryRun); err != nil {
 			return fmt.Errorf("could not delete dump %s: %s", d.DumpId, err)
 		}
 	}
 	if pruneUnsafe {
 		for _, d := range sr.UnknownOrFailed {
-			if err = deleteDumpById(ctx, st, d, dryRun); err != nil {
+			if err = deleteDumpById(ctx, st, d, sr.References, dryRun); err != nil {
 				return fmt.Errorf("could not delete dump %s: %s", d.DumpId, err)
 			}
 		}
@@ -173,7 +184,7 @@ func deleteBeforeDate(ctx context.Context, st storages.Storager, dateStr string)
 	}
 	for _, d := range sr.Valid {
 		if d.Date.Before(dt) {
-			if err = deleteDumpById(ctx, st, d, dryRun); err != nil {
+			if err = deleteDumpById(ctx, st, d, sr.References, dryRun); err != nil {
 				return fmt.Errorf("could not delete dump %s: %s", d.DumpId, err)
 			}
 		}
@@ -202,7 +213,7 @@ func retainForDumps(ctx context.Context, st storages.Storager, retainFor string)
 		if time.Since(d.Date) < dur {
 			continue
 		}
-		if err = deleteDumpById(ctx, st, d, dryRun); err != nil {
+		if err = deleteDumpById(ctx, st, d, sr.References, dryRun); err != nil {
 			return fmt.Errorf("could not delete dump %s: %s", d.DumpId, err)
 		}
 	}
@@ -224,7 +235,7 @@ func retainRecentNDumps(ctx context.Context, st storages.Storager) error {
 		if idx < retainRecent {
 			continue
 		}
-		if err = deleteDumpById(ctx, st, d, dryRun); err != nil {
+		if err = deleteDumpById(ctx, st, d, sr.References, dryRun); err != nil {
 			return fmt.Errorf("could not delete dump %s: %s", d.DumpId, err)
 		}
 	}
@@ -252,6 +263,7 @@ func getSortedBackupWithStatuses(ctx context.Context, st storages.Storager) (*St
 		if status == dumpstatus.DoneStatusName {
 			d.Date = md.StartedAt
 			d.Database = md.Header.DbName
+			d.References = md.ReferencedDumpIds()
 		}
 		switch status {
 		case dumpstatus.DoneStatusName:
@@ -279,13 +291,21 @@ func getSortedBackupWithStatuses(ctx context.Context, st storages.Storager) (*St
 		Valid:           valid,
 		Failed:          failed,
 		UnknownOrFailed: unknownOrFailed,
+		References:      NewDumpReferences(valid),
 	}, nil
 }
 
-func deleteDumpById(ctx context.Context, st storages.Storager, d *Dump, dryRun bool) error {
+func deleteDumpById(ctx context.Context, st storages.Storager, d *Dump, refs DumpReferences, dryRun bool) error {
 	if d.DumpId == "" {
 		panic("empty dump id")
 	}
+	if referencedBy := refs.ReferencedBy(d.DumpId); len(referencedBy) > 0 {
+		log.Warn().
+			Str("DumpId", d.DumpId).
+			Strs("ReferencedBy", referencedBy).
+			Msg("dump is referenced by incremental dumps: skipping")
+		return nil
+	}
 	e := log.Info().
 		Str("DumpId", d.DumpId)
 	if !d.Date.IsZero() {
@@ -303,6 +323,8 @@ func deleteDumpById(ctx context.Context, st storages.Storager, d *Dump, dryRun b
 	}
 	e.Msg(msg)
 
+	// The dumps referenced by the deleted dump may be deleted now
+	refs.Release(d.DumpId)
 	if dryRun {
 		return nil
 	}
@@ -6,6 +6,8 @@ type StorageResponse struct {
 	Valid           []*Dump
 	Failed          []*Dump
 	UnknownOrFailed []*Dump
+	// References - index of the dumps that are referenced by incremental dumps
+	References DumpReferences
 }
 
 type Dump struct {
@@ -13,4 +15,6 @@ type Dump struct {
 	Date     time.Time
 	Status   string
 	Database string
+	// References - ids of the dumps which objects are referenced by this dump
+	References []string
 }
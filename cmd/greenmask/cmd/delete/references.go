@@ -0,0 +1,56 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delete
+
+import (
+	"slices"
+)
+
+// DumpReferences - maps dump id to the set of incremental dumps that reference its objects. The dump that is
+// referenced by at least one existing dump must not be deleted
+type DumpReferences map[string]map[string]struct{}
+
+func NewDumpReferences(dumps []*Dump) DumpReferences {
+	res := make(DumpReferences)
+	for _, d := range dumps {
+		for _, ref := range d.References {
+			if _, ok := res[ref]; !ok {
+				res[ref] = make(map[string]struct{})
+			}
+			res[ref][d.DumpId] = struct{}{}
+		}
+	}
+	return res
+}
+
+// ReferencedBy - returns sorted ids of the dumps that reference the dump objects
+func (dr DumpReferences) ReferencedBy(dumpId string) []string {
+	var res []string
+	for id := range dr[dumpId] {
+		res = append(res, id)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Release - removes references of the deleted dump so the dumps it referenced can be deleted
+func (dr DumpReferences) Release(dumpId string) {
+	for ref, referencedBy := range dr {
+		delete(referencedBy, dumpId)
+		if len(referencedBy) == 0 {
+			delete(dr, ref)
+		}
+	}
+}
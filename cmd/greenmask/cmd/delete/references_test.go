@@ -0,0 +1,40 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delete
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpReferences(t *testing.T) {
+	refs := NewDumpReferences([]*Dump{
+		{DumpId: "3", References: []string{"1", "2"}},
+		{DumpId: "2", References: []string{"1"}},
+		{DumpId: "1"},
+	})
+
+	assert.Equal(t, []string{"2", "3"}, refs.ReferencedBy("1"))
+	assert.Equal(t, []string{"3"}, refs.ReferencedBy("2"))
+	assert.Empty(t, refs.ReferencedBy("3"))
+
+	refs.Release("3")
+	assert.Equal(t, []string{"2"}, refs.ReferencedBy("1"))
+	assert.Empty(t, refs.ReferencedBy("2"))
+
+	refs.Release("2")
+	assert.Empty(t, refs.ReferencedBy("1"))
+}
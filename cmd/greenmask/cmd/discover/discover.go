@@ -0,0 +1,101 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discover
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	cmdInternals "github.com/greenmaskio/greenmask/internal/db/postgres/cmd"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/utils/logger"
+)
+
+var (
+	Cmd = &cobra.Command{
+		Use:   "discover",
+		Short: "find columns that might contain PII and propose the transformation config for them",
+		Run:   run,
+	}
+	Config = domains.NewConfig()
+)
+
+func run(cmd *cobra.Command, args []string) {
+	if err := logger.SetDefaultContextLogger(Config.Log.Level, Config.Log.Format); err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	discoverCmd, err := cmdInternals.NewDiscover(Config, utils.DefaultTransformerRegistry)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	exitCode, err := discoverCmd.Run(ctx, os.Stdout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+func init() {
+	formatFlagName := "format"
+	Cmd.Flags().String(
+		formatFlagName, cmdInternals.FormatYaml, "Format of output. possible values [yaml|json]",
+	)
+	flag := Cmd.Flags().Lookup(formatFlagName)
+	if err := viper.BindPFlag("discover.format", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
+	sampleSizeFlagName := "sample-size"
+	Cmd.Flags().Int(
+		sampleSizeFlagName, cmdInternals.DefaultDiscoverSampleSize,
+		"Number of rows sampled from each table for values classification",
+	)
+	flag = Cmd.Flags().Lookup(sampleSizeFlagName)
+	if err := viper.BindPFlag("discover.sample_size", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
+	minConfidenceFlagName := "min-confidence"
+	Cmd.Flags().Float64(
+		minConfidenceFlagName, cmdInternals.DefaultDiscoverMinConfidence,
+		"Minimal confidence of the column classification in range (0, 1]",
+	)
+	flag = Cmd.Flags().Lookup(minConfidenceFlagName)
+	if err := viper.BindPFlag("discover.min_confidence", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
+	failOnUnmaskedFlagName := "fail-on-unmasked"
+	Cmd.Flags().Bool(
+		failOnUnmaskedFlagName, false,
+		"Exit with non-zero code if there are PII columns that are not transformed by the current config",
+	)
+	flag = Cmd.Flags().Lookup(failOnUnmaskedFlagName)
+	if err := viper.BindPFlag("discover.fail_on_unmasked", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+}
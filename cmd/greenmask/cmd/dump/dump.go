@@ -27,6 +27,7 @@ import (
 	cmdInternals "github.com/greenmaskio/greenmask/internal/db/postgres/cmd"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
 	pgDomains "github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/hooks"
 	"github.com/greenmaskio/greenmask/internal/storages/builder"
 	"github.com/greenmaskio/greenmask/internal/utils/logger"
 )
@@ -51,15 +52,30 @@ var (
 					log.Warn().Err(err).Msg("error closing storage")
 				}
 			}()
-			st = st.SubStorage(strconv.FormatInt(time.Now().UnixMilli(), 10), true)
+			rootSt := st
+			dumpId := strconv.FormatInt(time.Now().UnixMilli(), 10)
+			if Config.Dump.PgDumpOptions.Resume != "" {
+				dumpId = Config.Dump.PgDumpOptions.Resume
+			}
+			st = st.SubStorage(dumpId, true)
 
 			if Config.Common.TempDirectory == "" {
 				log.Fatal().Msg("common.tmp_dir cannot be empty")
 			}
 
+			h, err := hooks.New(Config.Hooks)
+			if err != nil {
+				log.Fatal().Err(err).Msg("cannot initialize hooks")
+			}
+
 			dump := cmdInternals.NewDump(Config, st, utils.DefaultTransformerRegistry)
+			dump.SetRootStorage(rootSt)
+			dump.SetHooks(h)
 
-			if err := dump.Run(ctx); err != nil {
+			err = dump.Run(ctx)
+			// Deliver the events before exit
+			h.Close()
+			if err != nil {
 				log.Fatal().Err(err).Msg("cannot make a backup")
 			}
 
@@ -91,6 +107,9 @@ func init() {
 
 	// Description options
 	Cmd.Flags().StringVarP(&Config.Dump.PgDumpOptions.Description, "description", "", "", "add a description for this dump")
+	Cmd.Flags().StringSliceP(
+		"tag", "", []string{}, "add a tag for this dump. The tag can be used instead of dump id as the schema baseline",
+	)
 
 	// Options controlling the output content:
 	Cmd.Flags().BoolP("data-only", "a", false, "dump only the data, not the schema")
@@ -156,8 +175,26 @@ func init() {
 		"pgzip", "", false,
 		"use pgzip compression instead of gzip",
 	)
+	Cmd.Flags().StringP(
+		"data-compression", "", "gzip",
+		"compression codec of the table data and large objects [gzip|zstd|lz4|none]",
+	)
+	Cmd.Flags().IntP(
+		"data-compression-level", "", 0,
+		"compression level of the data compression codec (0 means the codec default)",
+	)
+	Cmd.Flags().BoolP("zstd-long", "", false, "enable zstd long distance matching mode")
 
 	// Connection options:
+	Cmd.Flags().StringP(
+		"parent", "", "",
+		"dump id of the parent dump: unchanged tables are referenced from the parent instead of being dumped",
+	)
+	Cmd.Flags().StringP(
+		"resume", "", "",
+		"dump id of the failed dump that must be continued: the objects completed by the failed run are not dumped again",
+	)
+
 	Cmd.Flags().StringP("dbname", "d", "postgres", "database to dump")
 	Cmd.Flags().StringP("host", "h", "/var/run/postgres", "database server host or socket directory")
 	Cmd.Flags().IntP("port", "p", 5432, "database server port number")
@@ -173,7 +210,8 @@ func init() {
 		"include-foreign-data", "load-via-partition-root", "no-comments", "no-publications", "no-security-labels",
 		"no-subscriptions", "no-synchronized-snapshots", "no-tablespaces", "no-toast-compression",
 		"no-unlogged-table-data", "quote-all-identifiers", "section",
-		"serializable-deferrable", "snapshot", "strict-names", "use-set-session-authorization", "pgzip",
+		"serializable-deferrable", "snapshot", "strict-names", "use-set-session-authorization", "pgzip", "parent",
+		"tag", "resume", "data-compression", "data-compression-level", "zstd-long",
 
 		"dbname", "host", "port", "username",
 	} {
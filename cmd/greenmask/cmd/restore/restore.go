@@ -27,6 +27,7 @@ import (
 
 	cmdInternals "github.com/greenmaskio/greenmask/internal/db/postgres/cmd"
 	pgDomains "github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/hooks"
 	"github.com/greenmaskio/greenmask/internal/storages"
 	"github.com/greenmaskio/greenmask/internal/storages/builder"
 	"github.com/greenmaskio/greenmask/internal/utils/logger"
@@ -66,17 +67,28 @@ var (
 				log.Fatal().Err(err).Msg("")
 			}
 
+			rootSt := st
 			st = st.SubStorage(dumpId, true)
 
 			restore := cmdInternals.NewRestore(
 				Config.Common.PgBinPath, st, &Config.Restore, Config.Restore.Scripts,
 				Config.Common.TempDirectory,
 			)
+			restore.SetRootStorage(rootSt)
+
+			h, err := hooks.New(Config.Hooks)
+			if err != nil {
+				log.Fatal().Err(err).Msg("cannot initialize hooks")
+			}
+			restore.SetHooks(h)
 
 			log.Info().
 				Str("dumpId", dumpId).
 				Msgf("restoring dump")
-			if err := restore.Run(ctx); err != nil {
+			err = restore.Run(ctx)
+			// Deliver the events before exit
+			h.Close()
+			if err != nil {
 				log.Fatal().Err(err).Msg("fatal")
 			}
 		},
@@ -200,6 +212,14 @@ func init() {
 		"use OVERRIDING SYSTEM VALUE clause for INSERTs",
 	)
 	Cmd.Flags().BoolP("no-blobs", "B", false, "exclude large objects from restoration (large objects will be created as empty placeholders)")
+	Cmd.Flags().BoolP(
+		"resume", "", false,
+		"continue the failed restoration: skip the restored entries and reload the partially restored tables",
+	)
+	Cmd.Flags().StringP(
+		"checkpoint-file", "", "",
+		"path to the restoration checkpoint file (default \"<common.tmp_dir>/restore_<dumpId>.checkpoint.json\")",
+	)
 
 	// Connection options:
 	Cmd.Flags().StringP("host", "h", "/var/run/postgres", "database server host or socket directory")
@@ -216,7 +236,7 @@ func init() {
 		"strict-names", "use-set-session-authorization", "inserts", "on-conflict-do-nothing", "restore-in-order",
 		"pgzip", "batch-size", "overriding-system-value", "superuser", "use-session-replication-role-replica",
 
-		"host", "port", "username", "no-blobs",
+		"host", "port", "username", "no-blobs", "resume", "checkpoint-file",
 	} {
 		flag := Cmd.Flags().Lookup(flagName)
 		if err := viper.BindPFlag(fmt.Sprintf("%s.%s", "restore.pg_restore_options", flagName), flag); err != nil {
@@ -26,15 +26,21 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/decrypt_value"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/delete"
+	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/discover"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/dump"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/list_dumps"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/list_transformers"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/restore"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/show_dump"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/show_transformer"
+	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/sync"
+	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/transform_dump"
 	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/validate"
+	"github.com/greenmaskio/greenmask/cmd/greenmask/cmd/verify"
 	pgDomains "github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 	configUtils "github.com/greenmaskio/greenmask/internal/utils/config"
 )
 
@@ -100,6 +106,11 @@ func init() {
 	RootCmd.AddCommand(list_transformers.Cmd)
 	RootCmd.AddCommand(validate.Cmd)
 	RootCmd.AddCommand(show_transformer.Cmd)
+	RootCmd.AddCommand(sync.Cmd)
+	RootCmd.AddCommand(decrypt_value.Cmd)
+	RootCmd.AddCommand(discover.Cmd)
+	RootCmd.AddCommand(verify.Cmd)
+	RootCmd.AddCommand(transform_dump.Cmd)
 
 	if err := viper.BindPFlag("log.format", RootCmd.PersistentFlags().Lookup("log-format")); err != nil {
 		log.Fatal().Err(err).Msg("")
@@ -160,4 +171,8 @@ func initConfig() {
 			log.Fatal().Err(err).Msg("error parsing transformer parameters")
 		}
 	}
+
+	if err := locales.LoadPacks(Config.LocalePacks); err != nil {
+		log.Fatal().Err(err).Msg("error loading locale packs")
+	}
 }
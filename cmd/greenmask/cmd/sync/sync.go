@@ -0,0 +1,90 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	cmdInternals "github.com/greenmaskio/greenmask/internal/db/postgres/cmd"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	pgDomains "github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages/builder"
+	"github.com/greenmaskio/greenmask/internal/utils/logger"
+)
+
+var (
+	restoreTo    string
+	writeThrough bool
+)
+
+var (
+	Cmd = &cobra.Command{
+		Use:   "sync",
+		Short: "dump, transform and restore data directly into the target database without intermediate storage",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := logger.SetDefaultContextLogger(Config.Log.Level, Config.Log.Format); err != nil {
+				log.Fatal().Err(err).Msg("")
+			}
+
+			if Config.Common.TempDirectory == "" {
+				log.Fatal().Msg("common.tmp_dir cannot be empty")
+			}
+			if restoreTo != "" {
+				Config.Restore.PgRestoreOptions.DbName = restoreTo
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			st, err := builder.GetStorage(ctx, &Config.Storage, &Config.Log)
+			if err != nil {
+				log.Fatal().Err(err).Msg("fatal")
+			}
+			defer func() {
+				if err := st.Close(); err != nil {
+					log.Warn().Err(err).Msg("error closing storage")
+				}
+			}()
+			dumpId := strconv.FormatInt(time.Now().UnixMilli(), 10)
+			st = st.SubStorage(dumpId, true)
+
+			sync := cmdInternals.NewSync(Config, st, utils.DefaultTransformerRegistry, writeThrough)
+			if err := sync.Run(ctx); err != nil {
+				log.Fatal().Err(err).Msg("cannot sync database")
+			}
+			if writeThrough {
+				log.Info().
+					Str("DumpId", dumpId).
+					Msg("dump is stored in the storage")
+			}
+		},
+	}
+	Config = pgDomains.NewConfig()
+)
+
+func init() {
+	Cmd.Flags().StringVar(
+		&restoreTo, "restore-to", "",
+		"target database connection string (overrides restore.pg_restore_options.dbname)",
+	)
+	Cmd.Flags().BoolVar(
+		&writeThrough, "write-through", false,
+		"store the dump in the storage as well, so it can be inspected or restored later",
+	)
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform_dump
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	cmdInternals "github.com/greenmaskio/greenmask/internal/db/postgres/cmd"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	pgDomains "github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages/builder"
+	"github.com/greenmaskio/greenmask/internal/utils/logger"
+)
+
+const (
+	latestDumpName = "latest"
+)
+
+var (
+	Config      = pgDomains.NewConfig()
+	jobs        int
+	usePgzip    bool
+	description string
+	tags        []string
+	compression string
+	level       int
+	zstdLong    bool
+)
+
+var (
+	Cmd = &cobra.Command{
+		Use:   "transform-dump [flags] dumpId|tag|latest",
+		Args:  cobra.ExactArgs(1),
+		Short: "transform the existing dump using the current config and store it as a new dump without database connection",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := logger.SetDefaultContextLogger(Config.Log.Level, Config.Log.Format); err != nil {
+				log.Fatal().Err(err).Msg("error setting up logger")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			st, err := builder.GetStorage(ctx, &Config.Storage, &Config.Log)
+			if err != nil {
+				log.Fatal().Err(err).Msg("error building storage")
+			}
+			defer func() {
+				if err := st.Close(); err != nil {
+					log.Warn().Err(err).Msg("error closing storage")
+				}
+			}()
+
+			srcDumpId := args[0]
+			if srcDumpId == latestDumpName {
+				srcDumpId = ""
+			}
+			srcDumpId, err = cmdInternals.ResolveDumpId(ctx, st, srcDumpId)
+			if err != nil {
+				log.Fatal().Err(err).Msg("cannot find dump")
+			}
+
+			Config.Dump.PgDumpOptions.Jobs = jobs
+			Config.Dump.PgDumpOptions.Pgzip = usePgzip
+			Config.Dump.PgDumpOptions.Description = description
+			Config.Dump.PgDumpOptions.Tags = tags
+			Config.Dump.PgDumpOptions.DataCompression = compression
+			Config.Dump.PgDumpOptions.DataCompressionLevel = level
+			Config.Dump.PgDumpOptions.ZstdLong = zstdLong
+
+			dumpId := strconv.FormatInt(time.Now().UnixMilli(), 10)
+			td := cmdInternals.NewTransformDump(Config, st, utils.DefaultTransformerRegistry, srcDumpId, dumpId)
+			if err := td.Run(ctx); err != nil {
+				log.Fatal().Err(err).Msg("cannot transform dump")
+			}
+			log.Info().
+				Str("SourceDumpId", srcDumpId).
+				Str("DumpId", dumpId).
+				Msg("dump has been transformed")
+		},
+	}
+)
+
+func init() {
+	Cmd.Flags().IntVarP(&jobs, "jobs", "j", 1, "use this many parallel jobs to transform")
+	Cmd.Flags().BoolVar(&usePgzip, "pgzip", false, "use pgzip compression instead of gzip")
+	Cmd.Flags().StringVar(
+		&compression, "data-compression", "gzip",
+		"compression codec of the table data and large objects of the new dump [gzip|zstd|lz4|none]",
+	)
+	Cmd.Flags().IntVar(
+		&level, "data-compression-level", 0,
+		"compression level of the data compression codec (0 means the codec default)",
+	)
+	Cmd.Flags().BoolVar(&zstdLong, "zstd-long", false, "enable zstd long distance matching mode")
+	Cmd.Flags().StringVar(&description, "description", "", "add a description for the new dump")
+	Cmd.Flags().StringSliceVar(&tags, "tag", []string{}, "add a tag for the new dump")
+}
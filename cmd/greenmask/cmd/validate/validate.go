@@ -17,12 +17,14 @@ package validate
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	cmdInternals "github.com/greenmaskio/greenmask/internal/db/postgres/cmd"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/subset"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/greenmaskio/greenmask/internal/domains"
 	"github.com/greenmaskio/greenmask/internal/storages"
@@ -68,6 +70,14 @@ func run(cmd *cobra.Command, args []string) {
 			Msg("unknown --table-format value")
 	}
 
+	switch Config.Validate.SubsetGraphFormat {
+	case "", subset.GraphFormatDot, subset.GraphFormatMermaid:
+	default:
+		log.Fatal().
+			Str("RequestedGraphFormat", Config.Validate.SubsetGraphFormat).
+			Msg("unknown --subset-graph value")
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -181,6 +191,24 @@ func init() {
 		log.Fatal().Err(err).Msg("fatal")
 	}
 
+	schemaBaselineFlagName := "schema-baseline"
+	Cmd.Flags().String(
+		schemaBaselineFlagName, "", "Dump id or tag of the dump to compare the schema with (default the latest dump)",
+	)
+	flag = Cmd.Flags().Lookup(schemaBaselineFlagName)
+	if err := viper.BindPFlag("validate.schema_baseline", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
+	schemaAllowlistFlagName := "schema-allowlist"
+	Cmd.Flags().String(
+		schemaAllowlistFlagName, "", "Path to YAML file with the schema changes that must not fail the validation",
+	)
+	flag = Cmd.Flags().Lookup(schemaAllowlistFlagName)
+	if err := viper.BindPFlag("validate.schema_allowlist", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
 	strictFlagName := "strict"
 	Cmd.Flags().Bool(
 		strictFlagName, false, "Exit with non-zero code if there are any validation warnings (warnings-as-errors)",
@@ -190,4 +218,39 @@ func init() {
 		log.Fatal().Err(err).Msg("fatal")
 	}
 
+	subsetFlagName := "subset"
+	Cmd.Flags().Bool(
+		subsetFlagName, false, "Print subset queries with row estimates and references without indexes",
+	)
+	flag = Cmd.Flags().Lookup(subsetFlagName)
+	if err := viper.BindPFlag("validate.subset", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
+	subsetAnalyzeFlagName := "subset-analyze"
+	Cmd.Flags().Bool(
+		subsetAnalyzeFlagName, false, "Execute subset queries using EXPLAIN ANALYZE to get the actual row counts",
+	)
+	flag = Cmd.Flags().Lookup(subsetAnalyzeFlagName)
+	if err := viper.BindPFlag("validate.subset_analyze", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
+	subsetTimeoutFlagName := "subset-timeout"
+	Cmd.Flags().Duration(
+		subsetTimeoutFlagName, time.Minute, "Statement timeout of each subset query EXPLAIN (0 means no timeout)",
+	)
+	flag = Cmd.Flags().Lookup(subsetTimeoutFlagName)
+	if err := viper.BindPFlag("validate.subset_timeout", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
+	subsetGraphFlagName := "subset-graph"
+	Cmd.Flags().String(
+		subsetGraphFlagName, "", "Render tables graph with cycles. Possible values [dot|mermaid]",
+	)
+	flag = Cmd.Flags().Lookup(subsetGraphFlagName)
+	if err := viper.BindPFlag("validate.subset_graph_format", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
 }
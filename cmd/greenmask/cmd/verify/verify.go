@@ -0,0 +1,97 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	cmdInternals "github.com/greenmaskio/greenmask/internal/db/postgres/cmd"
+	pgDomains "github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages/builder"
+	"github.com/greenmaskio/greenmask/internal/utils/logger"
+)
+
+const (
+	latestDumpName = "latest"
+)
+
+var (
+	Config    = pgDomains.NewConfig()
+	format    string
+	parseRows bool
+)
+
+var (
+	Cmd = &cobra.Command{
+		Use:   "verify [flags] dumpId|tag|latest",
+		Args:  cobra.ExactArgs(1),
+		Short: "verify the dump objects integrity against the dump metadata",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := logger.SetDefaultContextLogger(Config.Log.Level, Config.Log.Format); err != nil {
+				log.Fatal().Err(err).Msg("error setting up logger")
+			}
+
+			if format != cmdInternals.TextFormat && format != cmdInternals.JsonFormat {
+				log.Fatal().Msgf("unknown format %s", format)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			st, err := builder.GetStorage(ctx, &Config.Storage, &Config.Log)
+			if err != nil {
+				log.Fatal().Err(err).Msg("error building storage")
+			}
+			defer func() {
+				if err := st.Close(); err != nil {
+					log.Warn().Err(err).Msg("error closing storage")
+				}
+			}()
+
+			dumpId := args[0]
+			if dumpId == latestDumpName {
+				dumpId = ""
+			}
+			dumpId, err = cmdInternals.ResolveDumpId(ctx, st, dumpId)
+			if err != nil {
+				log.Fatal().Err(err).Msg("cannot find dump")
+			}
+
+			report, err := cmdInternals.NewVerify(st, dumpId, parseRows).Run(ctx)
+			if err != nil {
+				log.Fatal().Err(err).Msg("")
+			}
+			if format == cmdInternals.JsonFormat {
+				err = report.WriteJson(os.Stdout)
+			} else {
+				err = report.WriteText(os.Stdout)
+			}
+			if err != nil {
+				log.Fatal().Err(err).Msg("cannot write report")
+			}
+			if !report.Passed {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	Cmd.Flags().StringVarP(&format, "format", "f", "text", "output format [text|json]")
+	Cmd.Flags().BoolVar(&parseRows, "parse-rows", false, "decode every row of the table data and check the number of columns")
+}
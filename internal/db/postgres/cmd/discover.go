@@ -0,0 +1,217 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+
+	runtimeContext "github.com/greenmaskio/greenmask/internal/db/postgres/context"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/discovery"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/custom"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const (
+	DefaultDiscoverSampleSize    = 100
+	DefaultDiscoverMinConfidence = 0.5
+)
+
+// Discover - finds the columns that might contain PII and proposes the transformation config for them
+type Discover struct {
+	*Dump
+	classifiers []*discovery.Classifier
+	exitCode    int
+}
+
+func NewDiscover(cfg *domains.Config, registry *utils.TransformerRegistry) (*Discover, error) {
+	if cfg.Discover.SampleSize <= 0 {
+		cfg.Discover.SampleSize = DefaultDiscoverSampleSize
+	}
+	if cfg.Discover.MinConfidence <= 0 {
+		cfg.Discover.MinConfidence = DefaultDiscoverMinConfidence
+	}
+	if cfg.Discover.Format == "" {
+		cfg.Discover.Format = FormatYaml
+	}
+	if cfg.Discover.Format != FormatYaml && cfg.Discover.Format != JsonFormat {
+		return nil, fmt.Errorf("unknown format \"%s\": expected %s or %s", cfg.Discover.Format, FormatYaml, JsonFormat)
+	}
+
+	classifiers, err := discovery.NewClassifiers(cfg.Discover.Classifiers)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build classifiers: %w", err)
+	}
+
+	return &Discover{
+		Dump:        NewDump(cfg, nil, registry),
+		classifiers: classifiers,
+		exitCode:    zeroExitCode,
+	}, nil
+}
+
+// Run - discover PII columns and write the report to w
+func (d *Discover) Run(ctx context.Context, w io.Writer) (int, error) {
+	if err := custom.BootstrapCustomTransformers(ctx, d.registry, d.config.CustomTransformers); err != nil {
+		return nonZeroExitCode, fmt.Errorf("error bootstraping custom transformers: %w", err)
+	}
+
+	ctx, mappingRegistry, err := openMappingStores(ctx, d.config.MappingStores, true)
+	if err != nil {
+		return nonZeroExitCode, err
+	}
+	defer closeMappingStores(ctx, mappingRegistry)
+
+	dsn, err := d.pgDumpOptions.GetPgDSN()
+	if err != nil {
+		return nonZeroExitCode, fmt.Errorf("cannot build connection string: %w", err)
+	}
+
+	conn, err := d.connect(ctx, dsn)
+	if err != nil {
+		return nonZeroExitCode, err
+	}
+	defer func() {
+		if err := conn.Close(ctx); err != nil {
+			log.Warn().Err(err)
+		}
+	}()
+
+	tx, err := d.startMainTx(ctx, conn)
+	if err != nil {
+		return nonZeroExitCode, fmt.Errorf("cannot prepare transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			log.Warn().Err(err)
+		}
+	}()
+
+	if err = d.gatherPgFacts(ctx, tx); err != nil {
+		return nonZeroExitCode, fmt.Errorf("error gathering facts: %w", err)
+	}
+
+	d.context, err = runtimeContext.NewRuntimeContext(
+		ctx, tx, &d.config.Dump, d.registry,
+		d.config.Dump.VirtualReferences, d.version,
+	)
+	if err != nil {
+		return nonZeroExitCode, fmt.Errorf("unable to build runtime context: %w", err)
+	}
+	if err = toolkit.PrintValidationWarnings(d.context.Warnings, nil, false); err != nil {
+		return nonZeroExitCode, err
+	}
+	if d.context.IsFatal() {
+		return nonZeroExitCode, fmt.Errorf("fatal validation error")
+	}
+
+	report, err := d.discover(ctx, tx)
+	if err != nil {
+		return nonZeroExitCode, err
+	}
+
+	switch d.config.Discover.Format {
+	case JsonFormat:
+		err = report.WriteJson(w)
+	default:
+		err = report.WriteYaml(w)
+	}
+	if err != nil {
+		return nonZeroExitCode, fmt.Errorf("cannot write report: %w", err)
+	}
+
+	if unmasked := report.Unmasked(); d.config.Discover.FailOnUnmasked && len(unmasked) > 0 {
+		log.Warn().
+			Int("UnmaskedColumns", len(unmasked)).
+			Msg("found PII columns that are not transformed")
+		d.exitCode = nonZeroExitCode
+	}
+	return d.exitCode, nil
+}
+
+func (d *Discover) discover(ctx context.Context, tx pgx.Tx) (*discovery.Report, error) {
+	masked := discovery.TransformedColumns(d.context.DataSectionObjects)
+	report := discovery.NewReport()
+	for _, table := range d.context.DatabaseSchema {
+		// Partitions are sampled via the partitioned table
+		if table.RootPtOid != 0 || (table.Kind != "r" && table.Kind != "p") {
+			continue
+		}
+		samples, err := sampleTable(ctx, tx, table, d.config.Discover.SampleSize)
+		if err != nil {
+			return nil, err
+		}
+		columns := discovery.ClassifyTable(table, samples, d.classifiers, d.config.Discover.MinConfidence)
+		for _, c := range columns {
+			_, c.Masked = masked[discovery.ColumnKey{Schema: table.Schema, Table: table.Name, Column: c.Column}]
+			report.Add(c)
+		}
+	}
+	return report, nil
+}
+
+// sampleTable - get up to limit not NULL values of the textual columns of the table
+func sampleTable(ctx context.Context, tx pgx.Tx, table *toolkit.Table, limit int) (map[string][]string, error) {
+	var columns []string
+	var exprs []string
+	for _, c := range table.Columns {
+		if !discovery.IsSampleable(c) {
+			continue
+		}
+		columns = append(columns, c.Name)
+		exprs = append(exprs, fmt.Sprintf("%s::TEXT", pgx.Identifier{c.Name}.Sanitize()))
+	}
+	res := make(map[string][]string, len(columns))
+	if len(columns) == 0 {
+		return res, nil
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s LIMIT %d",
+		strings.Join(exprs, ", "), pgx.Identifier{table.Schema, table.Name}.Sanitize(), limit,
+	)
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sample table %s.%s: %w", table.Schema, table.Name, err)
+	}
+	defer rows.Close()
+
+	values := make([]*string, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("cannot scan sampled values of %s.%s: %w", table.Schema, table.Name, err)
+		}
+		for i, v := range values {
+			if v != nil {
+				res[columns[i]] = append(res[columns[i]], *v)
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("cannot sample table %s.%s: %w", table.Schema, table.Name, err)
+	}
+	return res, nil
+}
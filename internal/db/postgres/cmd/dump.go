@@ -39,7 +39,11 @@ import (
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/custom"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/hooks"
 	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/storages/encryption"
+	commonutils "github.com/greenmaskio/greenmask/internal/utils"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
@@ -83,6 +87,24 @@ type Dump struct {
 	// validate shows that dump worker must be in validation mode
 	validate          bool
 	validateRowsLimit uint64
+	// rootSt - storage that contains all the dumps. It is required for incremental dump for reading the parent
+	rootSt storages.Storager
+	// parent - the parent dump of the incremental dump
+	parent *parentDump
+	// tableSignatures - change indicators of the tables by table oid
+	tableSignatures map[toolkit.Oid]string
+	// references - objects that were not dumped because they are unchanged since the parent dump
+	references map[int32]*storageDto.ObjectReference
+	// progress - the completed objects of the dump. It is stored by the heartbeat worker and used for resuming
+	progress *dumpProgress
+	// progressUpdated - notifies the heartbeat worker that the progress must be written
+	progressUpdated chan struct{}
+	// codec - compression codec of the table data and large objects
+	codec ioutils.Codec
+	// chunks - table chunk entries by dump id
+	chunks map[int32]*entries.Table
+	// hooks - receive the dump lifecycle events
+	hooks *hooks.Hooks
 }
 
 func NewDump(cfg *domains.Config, st storages.Storager, registry *utils.TransformerRegistry) *Dump {
@@ -96,9 +118,23 @@ func NewDump(cfg *domains.Config, st storages.Storager, registry *utils.Transfor
 		dumpedObjectSizes: map[int32]storageDto.ObjectSizeStat{},
 		registry:          registry,
 		tableOidToDumpId:  make(map[toolkit.Oid]int32),
+		tableSignatures:   make(map[toolkit.Oid]string),
+		references:        make(map[int32]*storageDto.ObjectReference),
+		progressUpdated:   make(chan struct{}, 1),
+		chunks:            make(map[int32]*entries.Table),
 	}
 }
 
+// SetRootStorage - sets the storage that contains all the dumps. It is used for reading the parent dump
+func (d *Dump) SetRootStorage(st storages.Storager) {
+	d.rootSt = st
+}
+
+// SetHooks - sets the hooks that receive the dump lifecycle events
+func (d *Dump) SetHooks(h *hooks.Hooks) {
+	d.hooks = h
+}
+
 func (d *Dump) prune() {
 	d.schemaToc = nil
 	d.context = nil
@@ -196,7 +232,9 @@ func (d *Dump) buildContextAndValidate(ctx context.Context, tx pgx.Tx) (err erro
 	if err != nil {
 		return fmt.Errorf("unable to build runtime context: %w", err)
 	}
-	// TODO: Implement warnings hook, such as logging and HTTP sender
+	if len(d.context.Warnings) > 0 {
+		d.hooks.Emit(hooks.NewEvent(hooks.EventValidationWarnings, d.st.Dirname()).SetWarnings(d.context.Warnings))
+	}
 	for _, w := range d.context.Warnings {
 		if w.Severity == "error" {
 			log.Error().Any("ValidationWarning", w).Msg("")
@@ -209,6 +247,88 @@ func (d *Dump) buildContextAndValidate(ctx context.Context, tx pgx.Tx) (err erro
 	return nil
 }
 
+// readParent - reads metadata of the parent dump if the dump is incremental
+func (d *Dump) readParent(ctx context.Context) error {
+	parentId := d.pgDumpOptions.Parent
+	if parentId == "" {
+		return nil
+	}
+	if d.rootSt == nil {
+		return ErrRootStorageIsNotSet
+	}
+	md, err := readDumpMetadata(ctx, d.rootSt.SubStorage(parentId, true))
+	if err != nil {
+		return fmt.Errorf("cannot read parent dump %s: %w", parentId, err)
+	}
+	d.parent = &parentDump{
+		id:       parentId,
+		metadata: md,
+	}
+	log.Info().
+		Str("ParentDumpId", parentId).
+		Msg("performing incremental dump")
+	return nil
+}
+
+// collectTableSignatures - collects change indicators of the tables in the snapshot of the main transaction. They are
+// stored in the metadata even if the dump is not incremental, so any dump can be used as the parent
+func (d *Dump) collectTableSignatures(ctx context.Context, tx pgx.Tx) error {
+	salt, err := commonutils.GetGlobalSalt()
+	if err != nil {
+		return fmt.Errorf("cannot get global salt: %w", err)
+	}
+	for _, obj := range d.context.DataSectionObjects {
+		t, ok := obj.(*entries.Table)
+		if !ok || t.RelKind == 'p' {
+			continue
+		}
+		signature, err := getTableSignature(ctx, tx, t, d.config.Dump.Transformation, salt)
+		if err != nil {
+			return fmt.Errorf("table %s.%s: %w", t.Schema, t.Name, err)
+		}
+		d.tableSignatures[t.Oid] = signature
+	}
+	return nil
+}
+
+// reuseParentObject - checks that the table is unchanged since the parent dump and if so, references the parent
+// object instead of dumping the table again
+func (d *Dump) reuseParentObject(t *entries.Table) bool {
+	if d.parent == nil || d.validate || t.Chunk != nil {
+		return false
+	}
+	parentEntry, ref := d.parent.reference(t.Oid, d.tableSignatures[t.Oid])
+	if ref == nil {
+		return false
+	}
+	t.OriginalSize = parentEntry.OriginalSize
+	t.CompressedSize = parentEntry.CompressedSize
+	t.Checksum = parentEntry.Checksum
+	d.references[t.DumpId] = ref
+	log.Debug().
+		Str("SchemaName", t.Schema).
+		Str("TableName", t.Name).
+		Str("ReferencedDumpId", ref.DumpId).
+		Str("FileName", ref.FileName).
+		Msg("table is unchanged: referencing parent dump object")
+	return true
+}
+
+// checkReferencedObjects - checks that the referenced objects still exist. The parent dump might be deleted
+// while the incremental dump was running
+func (d *Dump) checkReferencedObjects(ctx context.Context) error {
+	for _, ref := range d.references {
+		exists, err := d.rootSt.SubStorage(ref.DumpId, true).Exists(ctx, ref.FileName)
+		if err != nil {
+			return fmt.Errorf("cannot check referenced object %s/%s: %w", ref.DumpId, ref.FileName, err)
+		}
+		if !exists {
+			return fmt.Errorf("referenced object %s/%s does not exist", ref.DumpId, ref.FileName)
+		}
+	}
+	return nil
+}
+
 func (d *Dump) schemaOnlyDump(ctx context.Context, tx pgx.Tx) error {
 	// Dump schema
 	options := *d.pgDumpOptions
@@ -298,11 +418,23 @@ func (d *Dump) taskProducer(ctx context.Context, tasks chan<- dumpers.DumpTask)
 				if v.RelKind == 'p' {
 					continue
 				}
-				task = dumpers.NewTableDumper(v, d.validate, d.validateRowsLimit, d.pgDumpOptions.Pgzip)
+				v.Codec = d.codec
+				if d.reuseParentObject(v) {
+					continue
+				}
+				resumed, err := d.resumeObject(v)
+				if err != nil {
+					return err
+				}
+				if resumed {
+					continue
+				}
+				task = dumpers.NewTableDumper(v, d.validate, d.validateRowsLimit)
 			case *entries.Sequence:
 				task = dumpers.NewSequenceDumper(v)
 			case *entries.Blobs:
 				d.blobs = v
+				v.Codec = d.codec
 				if d.validate {
 					// Skip blobs in validation mode
 					continue
@@ -313,7 +445,14 @@ func (d *Dump) taskProducer(ctx context.Context, tasks chan<- dumpers.DumpTask)
 					log.Debug().Msg("skipping blobs")
 					continue
 				}
-				task = dumpers.NewLargeObjectDumper(v, d.pgDumpOptions.Pgzip)
+				resumed, err := d.resumeObject(v)
+				if err != nil {
+					return err
+				}
+				if resumed {
+					continue
+				}
+				task = dumpers.NewLargeObjectDumper(v)
 			default:
 				return fmt.Errorf("unknow dumper type")
 			}
@@ -340,10 +479,17 @@ func (d *Dump) createTocEntries() error {
 		}
 		switch v := obj.(type) {
 		case *entries.Table:
-			d.tableOidToDumpId[v.Oid] = entry.DumpId
+			if v.Chunk != nil {
+				d.chunks[entry.DumpId] = v
+			}
+			if v.Chunk == nil || v.Chunk.Idx == 0 {
+				// The rest of the chunks are mapped to the table oid in writeMetaData
+				d.tableOidToDumpId[v.Oid] = entry.DumpId
+			}
 			d.dumpedObjectSizes[entry.DumpId] = storageDto.ObjectSizeStat{
 				Original:   v.OriginalSize,
 				Compressed: v.CompressedSize,
+				Checksum:   v.Checksum,
 			}
 			if v.RelKind != 'p' {
 				// Do not create TOC entry for partitioned tables because they are not dumped. Only their partitions are
@@ -379,7 +525,7 @@ func (d *Dump) createTocEntries() error {
 }
 
 // setDumpDependenciesGraph - sets dumpDependenciesGraph of entries using their dumpId and build topological order of
-// dump ids
+// dump ids. The chunks of the table have the same dependencies and the dependant tables depend on all the chunks
 func (d *Dump) setDumpDependenciesGraph(tables []*entries.Table) {
 	sortedOids, graph := d.context.Graph.GetSortedTablesAndDependenciesGraph()
 	d.dumpDependenciesGraph = make(map[int32][]int32)
@@ -390,26 +536,38 @@ func (d *Dump) setDumpDependenciesGraph(tables []*entries.Table) {
 		if idx == -1 {
 			panic(fmt.Sprintf("table not found: oid=%d", oid))
 		}
-		t := tables[idx]
 		// Create dependencies graph with DumpId sequence for easier restoration coordination
-		d.dumpDependenciesGraph[t.DumpId] = []int32{}
+		var deps []int32
 		for _, depOid := range graph[oid] {
 			// If dependency table is not in the tables slice, it is likely excluded
 			if !slices.Contains(sortedOids, depOid) {
 				continue
 			}
-			// Find dependency table in the tables slice by OID
-			depIdx := slices.IndexFunc(tables, func(depTable *entries.Table) bool {
-				return depTable.Oid == depOid
-			})
-			if depIdx == -1 {
+			// Find dependency table chunks in the tables slice by OID
+			depDumpIds := getTableDumpIds(tables, depOid)
+			if len(depDumpIds) == 0 {
 				panic("table not found")
 			}
-			// Append dependency table DumpId to the current table dependencies
-			d.dumpDependenciesGraph[t.DumpId] = append(d.dumpDependenciesGraph[t.DumpId], tables[depIdx].DumpId)
+			// Append dependency table DumpIds to the current table dependencies
+			deps = append(deps, depDumpIds...)
+		}
+		for _, dumpId := range getTableDumpIds(tables, tables[idx].Oid) {
+			d.dumpDependenciesGraph[dumpId] = append([]int32{}, deps...)
+			d.sortedTablesDumpIds = append(d.sortedTablesDumpIds, dumpId)
+		}
+	}
+}
+
+// getTableDumpIds - returns dump ids of the table entries with the oid. There are several entries if the table is
+// split into chunks
+func getTableDumpIds(tables []*entries.Table, oid toolkit.Oid) []int32 {
+	var res []int32
+	for _, t := range tables {
+		if t.Oid == oid {
+			res = append(res, t.DumpId)
 		}
-		d.sortedTablesDumpIds = append(d.sortedTablesDumpIds, t.DumpId)
 	}
+	return res
 }
 
 func (d *Dump) dataDump(ctx context.Context) error {
@@ -476,6 +634,30 @@ func (d *Dump) writeMetaData(ctx context.Context, startedAt, completedAt time.Ti
 	if err != nil {
 		return fmt.Errorf("unable build metadata: %w", err)
 	}
+	if encSt, ok := d.st.(*encryption.Storage); ok {
+		metadata.Encryption = encSt.Info()
+	}
+	if d.parent != nil {
+		metadata.Parent = d.parent.id
+	}
+	metadata.Tags = d.pgDumpOptions.Tags
+	metadata.DataCompression = d.getCompressionInfo()
+	for _, e := range metadata.Entries {
+		if t, ok := d.chunks[e.DumpId]; ok {
+			e.Chunk = &storageDto.TableChunk{
+				Idx:   t.Chunk.Idx,
+				Count: t.Chunk.Count,
+				Cond:  t.Chunk.Cond,
+				By:    t.Chunk.By,
+			}
+			metadata.DumpIdsToTableOid[e.DumpId] = t.Oid
+		}
+		if oid, ok := metadata.DumpIdsToTableOid[e.DumpId]; ok && e.Chunk == nil {
+			// The chunked tables are never referenced by the incremental dumps, so they have no signature
+			e.Signature = d.tableSignatures[oid]
+		}
+		e.Reference = d.references[e.DumpId]
+	}
 
 	buf := bytes.NewBuffer(make([]byte, 0, 1024))
 	if err = json.NewEncoder(buf).Encode(metadata); err != nil {
@@ -488,9 +670,25 @@ func (d *Dump) writeMetaData(ctx context.Context, startedAt, completedAt time.Ti
 	return nil
 }
 
+// getCompressionInfo - compression settings of the dump objects that are stored in the metadata
+func (d *Dump) getCompressionInfo() *storageDto.Compression {
+	return &storageDto.Compression{
+		Codec:    d.codec.Name(),
+		Level:    d.pgDumpOptions.DataCompressionLevel,
+		ZstdLong: d.pgDumpOptions.ZstdLong,
+	}
+}
+
 func (d *Dump) Run(ctx context.Context) (err error) {
 	defer d.prune()
 	startedAt := time.Now()
+	dumpId := d.st.Dirname()
+	d.hooks.Emit(hooks.NewEvent(hooks.EventDumpStarted, dumpId))
+	defer func() {
+		if err != nil {
+			d.hooks.Emit(hooks.NewEvent(hooks.EventDumpFailed, dumpId).SetDuration(startedAt).SetError(err))
+		}
+	}()
 
 	if err := custom.BootstrapCustomTransformers(ctx, d.registry, d.config.CustomTransformers); err != nil {
 		return fmt.Errorf("error bootstraping custom transformers: %w", err)
@@ -501,6 +699,20 @@ func (d *Dump) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("cannot build connection string: %w", err)
 	}
 
+	if d.codec, err = d.pgDumpOptions.GetCodec(); err != nil {
+		return fmt.Errorf("compression codec error: %w", err)
+	}
+
+	ctx, mappingRegistry, err := openMappingStores(ctx, d.config.MappingStores, false)
+	if err != nil {
+		return err
+	}
+	defer closeMappingStores(ctx, mappingRegistry)
+
+	if err = d.readResumedProgress(ctx); err != nil {
+		return err
+	}
+
 	conn, err := d.connect(ctx, dsn)
 	if err != nil {
 		return err
@@ -513,6 +725,12 @@ func (d *Dump) Run(ctx context.Context) (err error) {
 
 	tx, err := d.startMainTx(ctx, conn)
 	if err != nil {
+		if d.progress != nil {
+			return fmt.Errorf(
+				"%w: snapshot %s of the dump %s is not valid anymore: %w",
+				ErrDumpCannotBeResumed, d.pgDumpOptions.Snapshot, d.pgDumpOptions.Resume, err,
+			)
+		}
 		return fmt.Errorf("cannot prepare backup transaction: %w", err)
 	}
 	defer func() {
@@ -529,6 +747,22 @@ func (d *Dump) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("context error: %w", err)
 	}
 
+	if err = d.initProgress(); err != nil {
+		return err
+	}
+
+	if err = d.readParent(ctx); err != nil {
+		return fmt.Errorf("parent dump error: %w", err)
+	}
+
+	if err = d.collectTableSignatures(ctx, tx); err != nil {
+		return fmt.Errorf("cannot collect table signatures: %w", err)
+	}
+
+	if err = d.context.SplitTablesIntoChunks(ctx, tx, d.config.Dump.Transformation); err != nil {
+		return fmt.Errorf("cannot split tables into chunks: %w", err)
+	}
+
 	if err = d.schemaOnlyDump(ctx, tx); err != nil {
 		return fmt.Errorf("schema only stage dumping error: %w", err)
 	}
@@ -537,13 +771,33 @@ func (d *Dump) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("data stage dumping error: %w", err)
 	}
 
+	if err = mappingRegistry.Close(ctx); err != nil {
+		return fmt.Errorf("cannot save mappings: %w", err)
+	}
+
 	if err = d.mergeAndWriteToc(ctx); err != nil {
 		return fmt.Errorf("mergeAndWriteToc stage dumping error: %w", err)
 	}
 
+	if err = d.checkReferencedObjects(ctx); err != nil {
+		return fmt.Errorf("parent dump error: %w", err)
+	}
+
 	if err = d.writeMetaData(ctx, startedAt, time.Now()); err != nil {
 		return fmt.Errorf("writeMetaData stage dumping error: %w", err)
 	}
+	d.deleteProgress(ctx)
+
+	var originalSize, compressedSize int64
+	for _, s := range d.dumpedObjectSizes {
+		originalSize += s.Original
+		compressedSize += s.Compressed
+	}
+	d.hooks.Emit(
+		hooks.NewEvent(hooks.EventDumpCompleted, dumpId).
+			SetDuration(startedAt).
+			SetSize(originalSize, compressedSize),
+	)
 
 	return nil
 }
@@ -674,9 +928,12 @@ func (d *Dump) dumpWorker(
 			Str("ObjectName", task.DebugInfo()).
 			Msgf("dumping started")
 
+		taskStartedAt := time.Now()
 		if err = task.Execute(ctx, tx, d.st); err != nil {
 			return err
 		}
+		d.taskCompleted(task)
+		d.emitTableCompleted(task, taskStartedAt)
 
 		log.Debug().
 			Int("WorkerId", id).
@@ -685,6 +942,21 @@ func (d *Dump) dumpWorker(
 	}
 }
 
+// emitTableCompleted - sends the table completion event to the hooks
+func (d *Dump) emitTableCompleted(task dumpers.DumpTask, startedAt time.Time) {
+	td, ok := task.(*dumpers.TableDumper)
+	if !ok {
+		return
+	}
+	t := td.Table()
+	d.hooks.Emit(
+		hooks.NewEvent(hooks.EventDumpTableCompleted, d.st.Dirname()).
+			SetTable(t.Schema, t.Name).
+			SetSize(t.OriginalSize, t.CompressedSize).
+			SetDuration(startedAt),
+	)
+}
+
 func (d *Dump) validateDumpWorker(
 	ctx context.Context, tasks <-chan dumpers.DumpTask, id int,
 ) error {
@@ -746,23 +1018,34 @@ func (d *Dump) validateDumpExecuteTask(ctx context.Context, id int, task dumpers
 	return nil
 }
 
-// writeHeartBeatWorker - writes heart beat file each HeartBeatWriteInterval and on the jobs completion
+// writeHeartBeatWorker - writes heart beat file each HeartBeatWriteInterval and on the jobs completion. It also writes
+// the progress file each time the object is completed, so the failed dump can be resumed
 func (d *Dump) writeHeartBeatWorker(ctx context.Context, done chan struct{}) func() error {
 	return func() error {
 		// Initial write
 		if err := d.writeHeartBeat(ctx, HeartBeatInProgressContent); err != nil {
 			return fmt.Errorf("error writing heartbeat: %w", err)
 		}
+		if err := d.writeProgress(ctx); err != nil {
+			return err
+		}
 		t := time.NewTicker(HeartBeatWriteInterval)
 		for {
 			select {
 			case <-ctx.Done():
 				return nil
 			case <-done:
+				if err := d.writeProgress(ctx); err != nil {
+					return err
+				}
 				if err := d.writeHeartBeat(ctx, HeartBeatDoneContent); err != nil {
 					return fmt.Errorf("error writing heartbeat: %w", err)
 				}
 				return nil
+			case <-d.progressUpdated:
+				if err := d.writeProgress(ctx); err != nil {
+					return err
+				}
 			case <-t.C:
 				if err := d.writeHeartBeat(ctx, HeartBeatInProgressContent); err != nil {
 					return fmt.Errorf("error writing heartbeat: %w", err)
@@ -0,0 +1,171 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	storageDto "github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+var ErrRootStorageIsNotSet = errors.New("root storage is not set")
+
+const tableUpdatedAtChangeIndicatorQuery = `
+	SELECT count(*), coalesce(max(%s)::TEXT, '') FROM %s
+`
+
+// tableSignature - the data that identifies the state of the table data at the dump time. If the signatures of
+// the table are equal in two dumps, the data of the table is considered as unchanged
+type tableSignature struct {
+	Database        string           `json:"database"`
+	Oid             toolkit.Oid      `json:"oid"`
+	Schema          string           `json:"schema"`
+	Name            string           `json:"name"`
+	Columns         []string         `json:"columns"`
+	Transformations []*domains.Table `json:"transformations"`
+	Indicator       []string         `json:"indicator"`
+}
+
+// parentDump - the dump that is used as the base for the incremental dump
+type parentDump struct {
+	id       string
+	metadata *storageDto.Metadata
+}
+
+// reference - returns the reference to the parent dump object of the table that has the same signature or nil
+// if the table must be dumped
+func (pd *parentDump) reference(oid toolkit.Oid, signature string) (*storageDto.Entry, *storageDto.ObjectReference) {
+	if signature == "" {
+		return nil, nil
+	}
+	dumpId, ok := pd.metadata.TableOidToDumpId[oid]
+	if !ok {
+		return nil, nil
+	}
+	entry := pd.metadata.GetEntryByDumpId(dumpId)
+	if entry == nil || entry.Chunk != nil || entry.Signature != signature {
+		return nil, nil
+	}
+	if entry.Reference != nil {
+		// The parent itself does not store the object - reference the dump that stores it
+		return entry, entry.Reference
+	}
+	return entry, &storageDto.ObjectReference{
+		DumpId:   pd.id,
+		FileName: entry.FileName,
+	}
+}
+
+// readDumpMetadata - reads and decodes metadata.json of the dump
+func readDumpMetadata(ctx context.Context, st storages.Storager) (*storageDto.Metadata, error) {
+	f, err := st.GetObject(ctx, MetadataJsonFileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open metadata file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing metadata file")
+		}
+	}()
+	md := &storageDto.Metadata{}
+	if err := json.NewDecoder(f).Decode(md); err != nil {
+		return nil, fmt.Errorf("cannot decode metadata: %w", err)
+	}
+	return md, nil
+}
+
+// getTableSignature - calculates the signature of the table data. The empty signature means that the table cannot
+// be reused from the parent and must always be dumped. This is the case for the tables with custom query or
+// subset conditions because their content depends on the other tables. The tables without updated_at column are
+// always dumped as well, because the change indicator must be calculated in the snapshot of the dump and there is
+// no cheap way to do it for them
+func getTableSignature(
+	ctx context.Context, tx pgx.Tx, t *entries.Table, transformations []*domains.Table, salt []byte,
+) (string, error) {
+	if t.Query != "" || len(t.SubsetConds) > 0 || t.UpdatedAtColumn == "" {
+		return "", nil
+	}
+
+	sig := tableSignature{
+		Oid:    t.Oid,
+		Schema: t.Schema,
+		Name:   t.Name,
+	}
+	if err := tx.QueryRow(ctx, "SELECT current_database()").Scan(&sig.Database); err != nil {
+		return "", fmt.Errorf("cannot get current database: %w", err)
+	}
+	for _, c := range t.Columns {
+		sig.Columns = append(sig.Columns, fmt.Sprintf("%s %s", c.Name, c.TypeName))
+	}
+	for _, cfg := range transformations {
+		// The configs with apply_for_references or apply_for_inherited may affect other tables, so they are
+		// included into the signature of each table
+		if isTableConfigFor(cfg, t) || cfg.ApplyForInherited || hasApplyForReferences(cfg) {
+			sig.Transformations = append(sig.Transformations, cfg)
+		}
+	}
+
+	var count int64
+	var maxValue string
+	query := fmt.Sprintf(
+		tableUpdatedAtChangeIndicatorQuery,
+		pgx.Identifier{t.UpdatedAtColumn}.Sanitize(), pgx.Identifier{t.Schema, t.Name}.Sanitize(),
+	)
+	if err := tx.QueryRow(ctx, query).Scan(&count, &maxValue); err != nil {
+		return "", fmt.Errorf("cannot get updated_at column change indicator: %w", err)
+	}
+	sig.Indicator = []string{"updated_at", fmt.Sprintf("%d", count), maxValue}
+
+	return sig.encode(salt)
+}
+
+// encode - returns the hex encoded HMAC-SHA256 of the signature keyed by the global salt. The objects transformed
+// with another salt contain different values, so the signature changes with the salt
+func (ts *tableSignature) encode(salt []byte) (string, error) {
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode table signature: %w", err)
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func isTableConfigFor(cfg *domains.Table, t *entries.Table) bool {
+	return (cfg.Name == t.Name || fmt.Sprintf(`"%s"`, cfg.Name) == t.Name) &&
+		(cfg.Schema == t.Schema || fmt.Sprintf(`"%s"`, cfg.Schema) == t.Schema)
+}
+
+func hasApplyForReferences(cfg *domains.Table) bool {
+	for _, tr := range cfg.Transformers {
+		if tr.ApplyForReferences {
+			return true
+		}
+	}
+	return false
+}
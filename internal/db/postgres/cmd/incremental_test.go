@@ -0,0 +1,176 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgdump"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/storages/directory"
+	"github.com/greenmaskio/greenmask/internal/storages/validate"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func newParentMetadata() *storage.Metadata {
+	return &storage.Metadata{
+		TableOidToDumpId: map[toolkit.Oid]int32{
+			1000: 10,
+			1001: 11,
+		},
+		Entries: []*storage.Entry{
+			{
+				DumpId: 10, FileName: "10.dat.gz", Signature: "sig-users", OriginalSize: 100, CompressedSize: 10,
+				Checksum: "checksum-users",
+			},
+			{
+				DumpId: 11, FileName: "11.dat.gz", Signature: "sig-posts",
+				Reference: &storage.ObjectReference{DumpId: "100", FileName: "7.dat.gz"},
+			},
+		},
+	}
+}
+
+func TestParentDump_reference(t *testing.T) {
+	pd := &parentDump{id: "200", metadata: newParentMetadata()}
+
+	entry, ref := pd.reference(1000, "sig-users")
+	require.NotNil(t, ref)
+	assert.Equal(t, int32(10), entry.DumpId)
+	assert.Equal(t, &storage.ObjectReference{DumpId: "200", FileName: "10.dat.gz"}, ref)
+
+	// The reference of the parent is flattened to the dump that stores the object
+	_, ref = pd.reference(1001, "sig-posts")
+	assert.Equal(t, &storage.ObjectReference{DumpId: "100", FileName: "7.dat.gz"}, ref)
+
+	_, ref = pd.reference(1000, "changed")
+	assert.Nil(t, ref)
+
+	_, ref = pd.reference(1000, "")
+	assert.Nil(t, ref)
+
+	_, ref = pd.reference(2000, "sig-users")
+	assert.Nil(t, ref)
+
+	// The chunked tables of the parent are not referenced
+	pd.metadata.GetEntryByDumpId(10).Chunk = &storage.TableChunk{Idx: 0, Count: 2, Cond: `"id" < 10`}
+	_, ref = pd.reference(1000, "sig-users")
+	assert.Nil(t, ref)
+}
+
+func TestDump_reuseParentObject(t *testing.T) {
+	d := &Dump{
+		parent:          &parentDump{id: "200", metadata: newParentMetadata()},
+		tableSignatures: map[toolkit.Oid]string{1000: "sig-users", 1001: "changed"},
+		references:      map[int32]*storage.ObjectReference{},
+	}
+
+	users := &entries.Table{Table: &toolkit.Table{Oid: 1000, Schema: "public", Name: "users"}, DumpId: 20}
+	require.True(t, d.reuseParentObject(users))
+	assert.Equal(t, int64(100), users.OriginalSize)
+	assert.Equal(t, int64(10), users.CompressedSize)
+	assert.Equal(t, "checksum-users", users.Checksum)
+	assert.Equal(t, &storage.ObjectReference{DumpId: "200", FileName: "10.dat.gz"}, d.references[20])
+
+	posts := &entries.Table{Table: &toolkit.Table{Oid: 1001, Schema: "public", Name: "posts"}, DumpId: 21}
+	assert.False(t, d.reuseParentObject(posts))
+	assert.NotContains(t, d.references, int32(21))
+
+	// The chunks are always dumped
+	chunk := &entries.Table{
+		Table: &toolkit.Table{Oid: 1000, Schema: "public", Name: "users"}, DumpId: 22,
+		Chunk: &entries.TableChunk{Idx: 0, Count: 2, Cond: `"id" < 10`},
+	}
+	assert.False(t, d.reuseParentObject(chunk))
+	assert.NotContains(t, d.references, int32(22))
+
+	d.validate = true
+	assert.False(t, d.reuseParentObject(users))
+}
+
+func TestDump_readParent(t *testing.T) {
+	root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, json.NewEncoder(buf).Encode(newParentMetadata()))
+	require.NoError(t, root.SubStorage("200", true).PutObject(context.Background(), MetadataJsonFileName, buf))
+
+	d := &Dump{pgDumpOptions: &pgdump.Options{Parent: "200"}}
+	require.ErrorIs(t, d.readParent(context.Background()), ErrRootStorageIsNotSet)
+
+	d.SetRootStorage(root)
+	require.NoError(t, d.readParent(context.Background()))
+	require.NotNil(t, d.parent)
+	assert.Equal(t, "200", d.parent.id)
+	assert.Len(t, d.parent.metadata.Entries, 2)
+
+	d = &Dump{pgDumpOptions: &pgdump.Options{Parent: "404"}, rootSt: root}
+	require.Error(t, d.readParent(context.Background()))
+}
+
+func TestRestore_resolveObject(t *testing.T) {
+	root := validate.New("")
+	r := &Restore{
+		st:       root.SubStorage("300", true),
+		rootSt:   root,
+		metadata: newParentMetadata(),
+	}
+
+	fileName := "10.dat.gz"
+	entry := &toc.Entry{DumpId: 10, Desc: strPtr(toc.TableDataDesc), FileName: &fileName}
+	resolved, st := r.resolveObject(entry)
+	assert.Same(t, entry, resolved)
+	assert.Equal(t, "300", st.GetCwd())
+
+	fileName = "11.dat.gz"
+	entry = &toc.Entry{DumpId: 11, Desc: strPtr(toc.TableDataDesc), FileName: &fileName}
+	resolved, st = r.resolveObject(entry)
+	assert.Equal(t, "7.dat.gz", *resolved.FileName)
+	assert.Equal(t, "11.dat.gz", *entry.FileName)
+	assert.Equal(t, int32(11), resolved.DumpId)
+	assert.Equal(t, "100", st.GetCwd())
+}
+
+func TestTableSignature_encode(t *testing.T) {
+	sig := &tableSignature{
+		Database:  "test",
+		Oid:       1000,
+		Schema:    "public",
+		Name:      "users",
+		Columns:   []string{"id int4", "updated_at timestamptz"},
+		Indicator: []string{"updated_at", "10", "2024-01-01 00:00:00+00"},
+	}
+	first, err := sig.encode([]byte("salt-1"))
+	require.NoError(t, err)
+	second, err := sig.encode([]byte("salt-1"))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// The objects transformed with another salt must not be reused
+	changed, err := sig.encode([]byte("salt-2"))
+	require.NoError(t, err)
+	assert.NotEqual(t, first, changed)
+	noSalt, err := sig.encode(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, noSalt)
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/mapping"
+)
+
+// openMappingStores - opens the mapping stores and sets them to the context, so the transformers with the mapping
+// option can use them. The stores opened in read only mode do not save the new mappings
+func openMappingStores(
+	ctx context.Context, cfgs []*mapping.Config, readOnly bool,
+) (context.Context, *mapping.Registry, error) {
+	r, err := mapping.NewRegistry(ctx, cfgs, readOnly)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open mapping stores: %w", err)
+	}
+	return mapping.WithRegistry(ctx, r), r, nil
+}
+
+// closeMappingStores - saves the pending mappings and closes the stores. It is used in defer, so the error is logged
+func closeMappingStores(ctx context.Context, r *mapping.Registry) {
+	if err := r.Close(ctx); err != nil {
+		log.Warn().Err(err).Msg("cannot close mapping stores")
+	}
+}
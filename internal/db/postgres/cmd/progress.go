@@ -0,0 +1,302 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/dumpers"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const ProgressFileName = "progress.json"
+
+var ErrDumpCannotBeResumed = errors.New("dump cannot be resumed")
+
+// progressObject - the object that was completely written into the storage
+type progressObject struct {
+	ObjectType     string      `json:"objectType"`
+	Oid            toolkit.Oid `json:"oid,omitempty"`
+	OriginalSize   int64       `json:"originalSize"`
+	CompressedSize int64       `json:"compressedSize"`
+	Checksum       string      `json:"checksum,omitempty"`
+}
+
+// dumpProgress - the state of the running dump. It is written by the heartbeat worker and used for resuming the
+// failed dump
+type dumpProgress struct {
+	Snapshot string `json:"snapshot"`
+	// TransformationHash - hash of the transformation config. The dump cannot be resumed with the different config
+	TransformationHash string `json:"transformationHash"`
+	// Codec - compression codec of the dump objects. The dump cannot be resumed with the different codec because
+	// the file names of the objects depend on it
+	Codec string `json:"codec,omitempty"`
+	// Objects - the completed objects by DumpId
+	Objects map[int32]*progressObject `json:"objects"`
+	mx      sync.Mutex
+}
+
+func newDumpProgress(snapshot string, transformation []*domains.Table, codec string) (*dumpProgress, error) {
+	hash, err := getTransformationHash(transformation)
+	if err != nil {
+		return nil, err
+	}
+	return &dumpProgress{
+		Snapshot:           snapshot,
+		TransformationHash: hash,
+		Codec:              codec,
+		Objects:            make(map[int32]*progressObject),
+	}, nil
+}
+
+func (dp *dumpProgress) add(dumpId int32, obj *progressObject) {
+	dp.mx.Lock()
+	defer dp.mx.Unlock()
+	dp.Objects[dumpId] = obj
+}
+
+func (dp *dumpProgress) get(dumpId int32) *progressObject {
+	dp.mx.Lock()
+	defer dp.mx.Unlock()
+	return dp.Objects[dumpId]
+}
+
+func (dp *dumpProgress) encode() (*bytes.Buffer, error) {
+	dp.mx.Lock()
+	defer dp.mx.Unlock()
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(dp); err != nil {
+		return nil, fmt.Errorf("error encoding %s: %w", ProgressFileName, err)
+	}
+	return buf, nil
+}
+
+func getTransformationHash(transformation []*domains.Table) (string, error) {
+	data, err := json.Marshal(transformation)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode transformation config: %w", err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// newProgressObject - get the progress object of the completed task. It returns nil if the task does not write any
+// object into the storage
+func newProgressObject(task dumpers.DumpTask) (int32, *progressObject) {
+	switch v := task.(type) {
+	case *dumpers.TableDumper:
+		t := v.Table()
+		return t.DumpId, &progressObject{
+			ObjectType:     toc.TableDataDesc,
+			Oid:            t.Oid,
+			OriginalSize:   t.OriginalSize,
+			CompressedSize: t.CompressedSize,
+			Checksum:       t.Checksum,
+		}
+	case *dumpers.BlobsDumper:
+		return v.Blobs.DumpId, &progressObject{
+			ObjectType:     toc.BlobsDesc,
+			OriginalSize:   v.Blobs.OriginalSize,
+			CompressedSize: v.Blobs.CompressedSize,
+		}
+	}
+	return 0, nil
+}
+
+// readResumedProgress - reads the progress of the dump that must be resumed. The snapshot of the resumed dump is
+// used for the current run
+func (d *Dump) readResumedProgress(ctx context.Context) error {
+	dumpId := d.pgDumpOptions.Resume
+	if dumpId == "" {
+		return nil
+	}
+	exists, err := d.st.Exists(ctx, MetadataJsonFileName)
+	if err != nil {
+		return fmt.Errorf("cannot check file existence: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("%w: dump %s is already completed", ErrDumpCannotBeResumed, dumpId)
+	}
+	exists, err = d.st.Exists(ctx, ProgressFileName)
+	if err != nil {
+		return fmt.Errorf("cannot check file existence: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: dump %s does not have %s file", ErrDumpCannotBeResumed, dumpId, ProgressFileName)
+	}
+
+	f, err := d.st.GetObject(ctx, ProgressFileName)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", ProgressFileName, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing progress file")
+		}
+	}()
+	progress := &dumpProgress{}
+	if err = json.NewDecoder(f).Decode(progress); err != nil {
+		return fmt.Errorf("cannot decode %s: %w", ProgressFileName, err)
+	}
+	if progress.Objects == nil {
+		progress.Objects = make(map[int32]*progressObject)
+	}
+	if progress.Snapshot == "" {
+		return fmt.Errorf("%w: snapshot of the dump %s is unknown", ErrDumpCannotBeResumed, dumpId)
+	}
+	if d.pgDumpOptions.Snapshot != "" && d.pgDumpOptions.Snapshot != progress.Snapshot {
+		return fmt.Errorf(
+			"%w: snapshot %s differs from the snapshot %s of the dump %s",
+			ErrDumpCannotBeResumed, d.pgDumpOptions.Snapshot, progress.Snapshot, dumpId,
+		)
+	}
+	d.pgDumpOptions.Snapshot = progress.Snapshot
+	d.progress = progress
+	log.Info().
+		Str("DumpId", dumpId).
+		Str("Snapshot", progress.Snapshot).
+		Int("CompletedObjects", len(progress.Objects)).
+		Msg("resuming dump")
+	return nil
+}
+
+// initProgress - initializes the progress of the dump. If the dump is resumed it checks that the transformation
+// config has not been changed
+func (d *Dump) initProgress() error {
+	progress, err := newDumpProgress(d.pgDumpOptions.Snapshot, d.config.Dump.Transformation, d.codec.Name())
+	if err != nil {
+		return err
+	}
+	if d.progress != nil {
+		if d.progress.TransformationHash != progress.TransformationHash {
+			return fmt.Errorf(
+				"%w: transformation config has been changed since the dump %s was started",
+				ErrDumpCannotBeResumed, d.pgDumpOptions.Resume,
+			)
+		}
+		codec := d.progress.Codec
+		if codec == "" {
+			codec = ioutils.GzipCodecName
+		}
+		if codec != progress.Codec {
+			return fmt.Errorf(
+				"%w: compression codec %s differs from the codec %s of the dump %s",
+				ErrDumpCannotBeResumed, progress.Codec, codec, d.pgDumpOptions.Resume,
+			)
+		}
+		return nil
+	}
+	d.progress = progress
+	return nil
+}
+
+// resumeObject - checks that the object was completed by the resumed dump and if so, sets its sizes from the
+// progress instead of dumping it again
+func (d *Dump) resumeObject(obj entries.Entry) (bool, error) {
+	if d.pgDumpOptions.Resume == "" || d.progress == nil || d.validate {
+		return false, nil
+	}
+	switch v := obj.(type) {
+	case *entries.Table:
+		completed := d.progress.get(v.DumpId)
+		if completed == nil {
+			return false, nil
+		}
+		if completed.ObjectType != toc.TableDataDesc || completed.Oid != v.Oid {
+			return false, fmt.Errorf(
+				"%w: object with dump id %d is not the table %s.%s", ErrDumpCannotBeResumed, v.DumpId, v.Schema, v.Name,
+			)
+		}
+		v.OriginalSize = completed.OriginalSize
+		v.CompressedSize = completed.CompressedSize
+		v.Checksum = completed.Checksum
+		log.Debug().
+			Str("SchemaName", v.Schema).
+			Str("TableName", v.Name).
+			Int32("DumpId", v.DumpId).
+			Msg("table has been dumped by the resumed dump")
+		return true, nil
+	case *entries.Blobs:
+		completed := d.progress.get(v.DumpId)
+		if completed == nil {
+			return false, nil
+		}
+		if completed.ObjectType != toc.BlobsDesc {
+			return false, fmt.Errorf("%w: object with dump id %d is not the blobs", ErrDumpCannotBeResumed, v.DumpId)
+		}
+		v.OriginalSize = completed.OriginalSize
+		v.CompressedSize = completed.CompressedSize
+		log.Debug().
+			Int32("DumpId", v.DumpId).
+			Msg("large objects have been dumped by the resumed dump")
+		return true, nil
+	}
+	return false, nil
+}
+
+// taskCompleted - adds the completed task to the progress and notifies the heartbeat worker that records it
+func (d *Dump) taskCompleted(task dumpers.DumpTask) {
+	if d.progress == nil || d.validate {
+		return
+	}
+	dumpId, obj := newProgressObject(task)
+	if obj == nil {
+		return
+	}
+	d.progress.add(dumpId, obj)
+	select {
+	case d.progressUpdated <- struct{}{}:
+	default:
+		// The update is already pending - the heartbeat worker writes the latest state
+	}
+}
+
+// writeProgress - write progress file into the storage
+func (d *Dump) writeProgress(ctx context.Context) error {
+	if d.progress == nil {
+		return nil
+	}
+	buf, err := d.progress.encode()
+	if err != nil {
+		return err
+	}
+	if err = d.st.PutObject(ctx, ProgressFileName, buf); err != nil {
+		return fmt.Errorf("error writing %s: %w", ProgressFileName, err)
+	}
+	return nil
+}
+
+// deleteProgress - delete the progress file of the completed dump
+func (d *Dump) deleteProgress(ctx context.Context) {
+	if d.progress == nil {
+		return
+	}
+	if err := d.st.Delete(ctx, ProgressFileName); err != nil {
+		log.Warn().Err(err).Msg("error deleting progress file")
+	}
+}
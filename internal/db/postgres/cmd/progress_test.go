@@ -0,0 +1,138 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgdump"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages/directory"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func newResumedProgress() *dumpProgress {
+	return &dumpProgress{
+		Snapshot:           "00000003-00000002-1",
+		TransformationHash: "hash",
+		Objects: map[int32]*progressObject{
+			10: {ObjectType: toc.TableDataDesc, Oid: 1000, OriginalSize: 100, CompressedSize: 10, Checksum: "sum"},
+			12: {ObjectType: toc.BlobsDesc, OriginalSize: 50, CompressedSize: 5},
+		},
+	}
+}
+
+func TestDump_readResumedProgress(t *testing.T) {
+	ctx := context.Background()
+	root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+	buf, err := newResumedProgress().encode()
+	require.NoError(t, err)
+	require.NoError(t, root.SubStorage("100", true).PutObject(ctx, ProgressFileName, buf))
+
+	d := &Dump{pgDumpOptions: &pgdump.Options{Resume: "100"}, st: root.SubStorage("100", true)}
+	require.NoError(t, d.readResumedProgress(ctx))
+	assert.Equal(t, "00000003-00000002-1", d.pgDumpOptions.Snapshot)
+	require.NotNil(t, d.progress)
+	assert.Len(t, d.progress.Objects, 2)
+
+	d = &Dump{
+		pgDumpOptions: &pgdump.Options{Resume: "100", Snapshot: "00000003-00000005-1"},
+		st:            root.SubStorage("100", true),
+	}
+	require.ErrorIs(t, d.readResumedProgress(ctx), ErrDumpCannotBeResumed)
+
+	d = &Dump{pgDumpOptions: &pgdump.Options{Resume: "200"}, st: root.SubStorage("200", true)}
+	require.ErrorIs(t, d.readResumedProgress(ctx), ErrDumpCannotBeResumed)
+
+	md := bytes.NewBuffer(nil)
+	require.NoError(t, json.NewEncoder(md).Encode(newParentMetadata()))
+	require.NoError(t, root.SubStorage("100", true).PutObject(ctx, MetadataJsonFileName, md))
+	d = &Dump{pgDumpOptions: &pgdump.Options{Resume: "100"}, st: root.SubStorage("100", true)}
+	require.ErrorContains(t, d.readResumedProgress(ctx), "already completed")
+}
+
+func TestDump_initProgress(t *testing.T) {
+	transformation := []*domains.Table{{Schema: "public", Name: "users"}}
+	hash, err := getTransformationHash(transformation)
+	require.NoError(t, err)
+
+	cfg := &domains.Config{}
+	cfg.Dump.Transformation = transformation
+	d := &Dump{pgDumpOptions: &pgdump.Options{Snapshot: "snapshot"}, config: cfg, codec: ioutils.DefaultCodec()}
+	require.NoError(t, d.initProgress())
+	assert.Equal(t, "snapshot", d.progress.Snapshot)
+	assert.Equal(t, hash, d.progress.TransformationHash)
+	assert.Equal(t, ioutils.GzipCodecName, d.progress.Codec)
+
+	progress := newResumedProgress()
+	progress.TransformationHash = hash
+	d = &Dump{
+		pgDumpOptions: &pgdump.Options{Resume: "100"}, config: cfg, progress: progress, codec: ioutils.DefaultCodec(),
+	}
+	require.NoError(t, d.initProgress())
+	assert.Same(t, progress, d.progress)
+
+	zstdCodec, err := ioutils.NewCodec(ioutils.ZstdCodecName, 0, false, false)
+	require.NoError(t, err)
+	d.codec = zstdCodec
+	require.ErrorContains(t, d.initProgress(), "compression codec zstd differs from the codec gzip")
+
+	d.codec = ioutils.DefaultCodec()
+	cfg.Dump.Transformation = nil
+	require.ErrorIs(t, d.initProgress(), ErrDumpCannotBeResumed)
+}
+
+func TestDump_resumeObject(t *testing.T) {
+	d := &Dump{pgDumpOptions: &pgdump.Options{Resume: "100"}, progress: newResumedProgress()}
+
+	users := &entries.Table{Table: &toolkit.Table{Oid: 1000, Schema: "public", Name: "users"}, DumpId: 10}
+	resumed, err := d.resumeObject(users)
+	require.NoError(t, err)
+	assert.True(t, resumed)
+	assert.Equal(t, int64(100), users.OriginalSize)
+	assert.Equal(t, int64(10), users.CompressedSize)
+	assert.Equal(t, "sum", users.Checksum)
+
+	posts := &entries.Table{Table: &toolkit.Table{Oid: 1001, Schema: "public", Name: "posts"}, DumpId: 11}
+	resumed, err = d.resumeObject(posts)
+	require.NoError(t, err)
+	assert.False(t, resumed)
+
+	blobs := &entries.Blobs{DumpId: 12}
+	resumed, err = d.resumeObject(blobs)
+	require.NoError(t, err)
+	assert.True(t, resumed)
+	assert.Equal(t, int64(50), blobs.OriginalSize)
+
+	// The dump ids are assigned differently - the dump cannot be resumed
+	orders := &entries.Table{Table: &toolkit.Table{Oid: 1002, Schema: "public", Name: "orders"}, DumpId: 12}
+	_, err = d.resumeObject(orders)
+	require.ErrorIs(t, err, ErrDumpCannotBeResumed)
+
+	d.pgDumpOptions.Resume = ""
+	resumed, err = d.resumeObject(users)
+	require.NoError(t, err)
+	assert.False(t, resumed)
+}
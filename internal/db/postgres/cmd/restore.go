@@ -42,7 +42,9 @@ import (
 	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/utils"
 	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/hooks"
 	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
@@ -100,6 +102,13 @@ type Restore struct {
 	postDataClenUpToc string
 	restoredDumpIds   map[int32]bool
 	maintenanceDbName string
+	// rootSt - storage that contains all the dumps. It is used for reading objects referenced by incremental dump
+	rootSt storages.Storager
+	// checkpoint - the restoration progress that is used for resuming the failed restoration
+	checkpoint    *restoreCheckpoint
+	checkpointDir string
+	// hooks - receive the restoration lifecycle events
+	hooks *hooks.Hooks
 }
 
 func NewRestore(
@@ -116,11 +125,32 @@ func NewRestore(
 		metadata:        &storage.Metadata{},
 		restoredDumpIds: make(map[int32]bool),
 		mx:              &sync.RWMutex{},
+		checkpointDir:   tmpDir,
 	}
 }
 
-func (r *Restore) Run(ctx context.Context) error {
+// SetRootStorage - sets the storage that contains all the dumps. It is required for restoration of incremental dumps
+func (r *Restore) SetRootStorage(st storages.Storager) {
+	r.rootSt = st
+}
+
+// SetHooks - sets the hooks that receive the restoration lifecycle events
+func (r *Restore) SetHooks(h *hooks.Hooks) {
+	r.hooks = h
+}
+
+func (r *Restore) Run(ctx context.Context) (err error) {
 	defer r.prune()
+	startedAt := time.Now()
+	dumpId := r.st.Dirname()
+	r.hooks.Emit(hooks.NewEvent(hooks.EventRestoreStarted, dumpId))
+	defer func() {
+		if err != nil {
+			r.hooks.Emit(hooks.NewEvent(hooks.EventRestoreFailed, dumpId).SetDuration(startedAt).SetError(err))
+			return
+		}
+		r.hooks.Emit(hooks.NewEvent(hooks.EventRestoreCompleted, dumpId).SetDuration(startedAt))
+	}()
 
 	if err := r.readMetadata(ctx); err != nil {
 		return fmt.Errorf("cannot read metadata: %w", err)
@@ -134,24 +164,54 @@ func (r *Restore) Run(ctx context.Context) error {
 		return fmt.Errorf("pre-flight stage restoration error: %w", err)
 	}
 
-	if err := r.preDataRestore(ctx); err != nil {
-		return fmt.Errorf("pre-data stage restoration error: %w", err)
+	if err := r.initCheckpoint(); err != nil {
+		return fmt.Errorf("checkpoint error: %w", err)
+	}
+
+	if r.isPreDataRestored() {
+		log.Info().Msg("pre-data section has been restored by the resumed restoration: skipping")
+	} else {
+		if err := r.preDataRestore(ctx); err != nil {
+			return fmt.Errorf("pre-data stage restoration error: %w", err)
+		}
+		if err := r.checkpoint.setPreDataCompleted(); err != nil {
+			return fmt.Errorf("checkpoint error: %w", err)
+		}
+	}
+
+	var cleanupQueries []string
+	if r.restoreOpt.Resume {
+		var restartDumpIds []int32
+		cleanupQueries, restartDumpIds, err = r.getCleanupQueries()
+		if err != nil {
+			return fmt.Errorf("checkpoint error: %w", err)
+		}
+		// The chunks are marked as started before the data is deleted, so the table is cleaned again if the
+		// restoration fails before they are restored
+		if err = r.checkpoint.restart(restartDumpIds...); err != nil {
+			return fmt.Errorf("checkpoint error: %w", err)
+		}
 	}
 
-	if err := r.dataRestore(ctx); err != nil {
+	if err := r.dataRestore(ctx, cleanupQueries); err != nil {
 		return fmt.Errorf("data stage restoration error: %w", err)
 	}
 
 	if err := r.postDataRestore(ctx); err != nil {
 		return fmt.Errorf("post-data stage restoration error: %w", err)
 	}
+	r.checkpoint.remove()
 
 	return nil
 }
 
 func (r *Restore) putDumpId(task restorationTask) {
+	r.markDumpIdRestored(task.GetEntry().DumpId)
+}
+
+func (r *Restore) markDumpIdRestored(dumpId int32) {
 	r.mx.Lock()
-	r.restoredDumpIds[task.GetEntry().DumpId] = true
+	r.restoredDumpIds[dumpId] = true
 	r.mx.Unlock()
 }
 
@@ -174,9 +234,30 @@ func (r *Restore) readMetadata(ctx context.Context) error {
 	if err := json.NewDecoder(f).Decode(r.metadata); err != nil {
 		return fmt.Errorf("cannot decode metadata: %w", err)
 	}
+	if len(r.metadata.ReferencedDumpIds()) > 0 && r.rootSt == nil {
+		return fmt.Errorf("dump references objects of parent dumps: %w", ErrRootStorageIsNotSet)
+	}
 	return nil
 }
 
+// resolveObject - returns the toc entry and the storage that must be used for reading the entry object. If the
+// object is referenced from the parent dump, then a copy of the entry with the referenced file name is returned
+func (r *Restore) resolveObject(entry *toc.Entry) (*toc.Entry, storages.Storager) {
+	md := r.metadata.GetEntryByDumpId(entry.DumpId)
+	if md == nil || md.Reference == nil {
+		return entry, r.st
+	}
+	resolved := *entry
+	fileName := md.Reference.FileName
+	resolved.FileName = &fileName
+	log.Debug().
+		Int32("DumpId", entry.DumpId).
+		Str("ReferencedDumpId", md.Reference.DumpId).
+		Str("FileName", fileName).
+		Msg("restoring object from referenced dump")
+	return &resolved, r.rootSt.SubStorage(md.Reference.DumpId, true)
+}
+
 func (r *Restore) RunScripts(ctx context.Context, conn *pgx.Conn, section, when string) error {
 	if section != scriptPreDataSection &&
 		section != scriptDataSection && section != scriptPostDataSection {
@@ -534,7 +615,7 @@ func (r *Restore) prepareCleanupToc() (string, string, error) {
 	return preDatadirName, postDatadirName, nil
 }
 
-func (r *Restore) dataRestore(ctx context.Context) error {
+func (r *Restore) dataRestore(ctx context.Context, cleanupQueries []string) error {
 	// Execute Data Before scripts
 
 	// Do not restore this section if implicitly provided
@@ -557,6 +638,10 @@ func (r *Restore) dataRestore(ctx context.Context) error {
 		return err
 	}
 
+	if err = r.cleanupPartiallyRestored(ctx, conn, cleanupQueries); err != nil {
+		return err
+	}
+
 	tasks := make(chan restorationTask, r.restoreOpt.Jobs)
 	eg, gtx := errgroup.WithContext(ctx)
 
@@ -640,11 +725,8 @@ func (r *Restore) postDataRestore(ctx context.Context) error {
 		options.DirPath = r.postDataClenUpToc
 	}
 
-	if err = r.pgRestore.Run(ctx, &options); err != nil {
-		var exitErr *exec.ExitError
-		if r.restoreOpt.ExitOnError || (errors.As(err, &exitErr) && exitErr.ExitCode() != 1) {
-			return fmt.Errorf("cannot restore post-data section using pg_restore: %w", err)
-		}
+	if err = r.restorePostDataEntries(ctx, options); err != nil {
+		return err
 	}
 
 	if err = r.RunScripts(ctx, conn, scriptPostDataSection, scriptExecuteAfter); err != nil {
@@ -654,6 +736,99 @@ func (r *Restore) postDataRestore(ctx context.Context) error {
 	return nil
 }
 
+// restorePostDataEntries - restores the post-data entries one by one, so each restored entry is recorded in the
+// checkpoint and skipped by the resumed restoration. The entries of the same dependency level are restored
+// concurrently
+func (r *Restore) restorePostDataEntries(ctx context.Context, options pgrestore.Options) error {
+	for _, level := range getPostDataLevels(r.tocObj.Entries, r.isEntryRestored) {
+		eg, gtx := errgroup.WithContext(ctx)
+		eg.SetLimit(max(r.restoreOpt.Jobs, 1))
+		for _, entry := range level {
+			eg.Go(func() error {
+				return r.restorePostDataEntry(gtx, options, entry)
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restorePostDataEntry - restores the single post-data entry using pg_restore with the list file that contains only
+// this entry
+func (r *Restore) restorePostDataEntry(ctx context.Context, options pgrestore.Options, entry *toc.Entry) error {
+	listFileName := path.Join(r.tmpDir, fmt.Sprintf("post_data_%d.list", entry.DumpId))
+	if err := os.WriteFile(listFileName, []byte(fmt.Sprintf("%d; \n", entry.DumpId)), 0600); err != nil {
+		return fmt.Errorf("cannot write post-data list file: %w", err)
+	}
+	options.UseList = listFileName
+	options.Jobs = 1
+
+	if r.checkpoint != nil {
+		if err := r.checkpoint.start(entry.DumpId); err != nil {
+			return fmt.Errorf("checkpoint error: %w", err)
+		}
+	}
+	if err := r.pgRestore.Run(ctx, &options); err != nil {
+		var exitErr *exec.ExitError
+		if r.restoreOpt.ExitOnError || (errors.As(err, &exitErr) && exitErr.ExitCode() != 1) {
+			return fmt.Errorf("cannot restore post-data entry %d using pg_restore: %w", entry.DumpId, err)
+		}
+	}
+	if r.checkpoint != nil {
+		if err := r.checkpoint.complete(entry.DumpId); err != nil {
+			return fmt.Errorf("checkpoint error: %w", err)
+		}
+	}
+	return nil
+}
+
+// getPostDataLevels - groups the post-data entries that are not restored yet into the levels. The entry is placed
+// into the level after all the entries it depends on, so the entries of the same level can be restored concurrently
+func getPostDataLevels(entries []*toc.Entry, isRestored func(dumpId int32) bool) [][]*toc.Entry {
+	pending := make(map[int32]*toc.Entry)
+	var order []int32
+	for _, e := range entries {
+		if e.Section != toc.SectionPostData || isRestored(e.DumpId) {
+			continue
+		}
+		pending[e.DumpId] = e
+		order = append(order, e.DumpId)
+	}
+
+	var res [][]*toc.Entry
+	for len(pending) > 0 {
+		var level []*toc.Entry
+		for _, dumpId := range order {
+			e, ok := pending[dumpId]
+			if !ok {
+				continue
+			}
+			if !slices.ContainsFunc(e.Dependencies, func(dep int32) bool {
+				_, ok := pending[dep]
+				return ok
+			}) {
+				level = append(level, e)
+			}
+		}
+		if len(level) == 0 {
+			// The dependencies are cyclic: the rest of the entries are restored one by one in the toc order
+			for _, dumpId := range order {
+				if e, ok := pending[dumpId]; ok {
+					res = append(res, []*toc.Entry{e})
+				}
+			}
+			break
+		}
+		for _, e := range level {
+			delete(pending, e.DumpId)
+		}
+		res = append(res, level)
+	}
+	return res
+}
+
 func (r *Restore) prune() {
 	if err := os.RemoveAll(r.tmpDir); err != nil {
 		log.Debug().Err(err).Msg("error deleting temp dir")
@@ -770,6 +945,15 @@ func (r *Restore) taskPusher(ctx context.Context, tasks chan restorationTask) fu
 				continue
 			}
 
+			if r.isEntryRestored(entry.DumpId) {
+				// Mark as restored so the dependant tables are not blocked when --restore-in-order is used
+				r.markDumpIdRestored(entry.DumpId)
+				log.Debug().
+					Int32("DumpId", entry.DumpId).
+					Msg("entry has been restored by the resumed restoration: skipping")
+				continue
+			}
+
 			if r.restoreOpt.RestoreInOrder && r.restoreOpt.Jobs > 1 {
 				deps := r.metadata.DependenciesGraph[entry.DumpId]
 				if err := r.waitDependenciesAreRestore(ctx, deps); err != nil {
@@ -783,16 +967,17 @@ func (r *Restore) taskPusher(ctx context.Context, tasks chan restorationTask) fu
 			}
 			switch *entry.Desc {
 			case toc.TableDataDesc:
+				entry, st := r.resolveObject(entry)
 				if r.restoreOpt.Inserts || r.restoreOpt.OnConflictDoNothing {
 					t, err := r.getTableDefinitionFromMeta(entry.DumpId)
 					if err != nil {
 						return fmt.Errorf("cannot get table definition from meta: %w", err)
 					}
 					task = restorers.NewTableRestorerInsertFormat(
-						entry, t, r.st, r.restoreOpt.ToDataSectionSettings(), r.cfg.ErrorExclusions,
+						entry, t, st, r.restoreOpt.ToDataSectionSettings(), r.cfg.ErrorExclusions,
 					)
 				} else {
-					task = restorers.NewTableRestorer(entry, r.st, r.restoreOpt.ToDataSectionSettings())
+					task = restorers.NewTableRestorer(entry, st, r.restoreOpt.ToDataSectionSettings())
 				}
 
 			case toc.SequenceSetDesc:
@@ -805,7 +990,11 @@ func (r *Restore) taskPusher(ctx context.Context, tasks chan restorationTask) fu
 						Msg("blobs restoration is skipped")
 					continue
 				}
-				task = restorers.NewBlobsRestorer(entry, r.st, r.restoreOpt.Pgzip)
+				codec, err := ioutils.GetCodecByName(r.metadata.GetCodecName(), r.restoreOpt.Pgzip)
+				if err != nil {
+					return fmt.Errorf("cannot get large objects codec: %w", err)
+				}
+				task = restorers.NewBlobsRestorer(entry, r.st, codec)
 			case toc.AclDesc:
 				// Skip ACL restoration if --no-privileges is set
 				if r.restoreOpt.NoPrivileges {
@@ -875,11 +1064,23 @@ func (r *Restore) restoreWorker(ctx context.Context, tasks <-chan restorationTas
 			Str("objectName", task.DebugInfo()).
 			Msg("restoring")
 
+		if r.checkpoint != nil {
+			if err = r.checkpoint.start(task.GetEntry().DumpId); err != nil {
+				return fmt.Errorf("checkpoint error: %w", err)
+			}
+		}
+		taskStartedAt := time.Now()
 		// Open new transaction for each task
 		if err = task.Execute(ctx, utils.NewPGConn(conn)); err != nil {
 			return fmt.Errorf("unable to perform restoration task (worker %d restoring %s): %w", id, task.DebugInfo(), err)
 		}
+		if r.checkpoint != nil {
+			if err = r.checkpoint.complete(task.GetEntry().DumpId); err != nil {
+				return fmt.Errorf("checkpoint error: %w", err)
+			}
+		}
 		r.putDumpId(task)
+		r.emitTableCompleted(task, taskStartedAt)
 		log.Debug().
 			Int("workerId", id).
 			Str("objectName", task.DebugInfo()).
@@ -887,6 +1088,19 @@ func (r *Restore) restoreWorker(ctx context.Context, tasks <-chan restorationTas
 	}
 }
 
+// emitTableCompleted - sends the table completion event to the hooks
+func (r *Restore) emitTableCompleted(task restorationTask, startedAt time.Time) {
+	e := task.GetEntry()
+	if e.Desc == nil || *e.Desc != toc.TableDataDesc || e.Namespace == nil || e.Tag == nil {
+		return
+	}
+	r.hooks.Emit(
+		hooks.NewEvent(hooks.EventRestoreTableCompleted, r.st.Dirname()).
+			SetTable(removeEscapeQuotes(*e.Namespace), removeEscapeQuotes(*e.Tag)).
+			SetDuration(startedAt),
+	)
+}
+
 func (r *Restore) setRestoreList(fileName string, format string) (err error) {
 	f, err := os.Open(fileName)
 	if err != nil {
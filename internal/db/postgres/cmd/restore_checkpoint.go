@@ -0,0 +1,283 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+
+	runtimeContext "github.com/greenmaskio/greenmask/internal/db/postgres/context"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+var ErrRestoreCannotBeResumed = errors.New("restoration cannot be resumed")
+
+// restoreCheckpoint - the state of the restoration. It is written into the local file each time the data entry is
+// started or completed and used for resuming the failed restoration
+type restoreCheckpoint struct {
+	DumpId           string `json:"dumpId"`
+	PreDataCompleted bool   `json:"preDataCompleted"`
+	// InProgress - the data entries that were started but not completed. They might be partially restored
+	InProgress map[int32]bool `json:"inProgress"`
+	Completed  map[int32]bool `json:"completed"`
+	fileName   string
+	mx         sync.Mutex
+}
+
+func newRestoreCheckpoint(fileName, dumpId string) *restoreCheckpoint {
+	return &restoreCheckpoint{
+		DumpId:     dumpId,
+		InProgress: make(map[int32]bool),
+		Completed:  make(map[int32]bool),
+		fileName:   fileName,
+	}
+}
+
+// loadRestoreCheckpoint - read the checkpoint of the failed restoration of the dump
+func loadRestoreCheckpoint(fileName, dumpId string) (*restoreCheckpoint, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: checkpoint file %s is not found", ErrRestoreCannotBeResumed, fileName)
+		}
+		return nil, fmt.Errorf("cannot read checkpoint file: %w", err)
+	}
+	cp := newRestoreCheckpoint(fileName, dumpId)
+	if err = json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("cannot decode checkpoint file: %w", err)
+	}
+	if cp.DumpId != dumpId {
+		return nil, fmt.Errorf(
+			"%w: checkpoint file %s belongs to the dump %s", ErrRestoreCannotBeResumed, fileName, cp.DumpId,
+		)
+	}
+	if cp.InProgress == nil {
+		cp.InProgress = make(map[int32]bool)
+	}
+	if cp.Completed == nil {
+		cp.Completed = make(map[int32]bool)
+	}
+	return cp, nil
+}
+
+// save - write the checkpoint into the file. The file is replaced atomically, so the checkpoint is not corrupted
+// if the process is killed
+func (cp *restoreCheckpoint) save() error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("cannot encode checkpoint: %w", err)
+	}
+	tmpFileName := cp.fileName + ".tmp"
+	if err = os.WriteFile(tmpFileName, data, 0600); err != nil {
+		return fmt.Errorf("cannot write checkpoint file: %w", err)
+	}
+	if err = os.Rename(tmpFileName, cp.fileName); err != nil {
+		return fmt.Errorf("cannot write checkpoint file: %w", err)
+	}
+	return nil
+}
+
+func (cp *restoreCheckpoint) setPreDataCompleted() error {
+	cp.mx.Lock()
+	defer cp.mx.Unlock()
+	cp.PreDataCompleted = true
+	return cp.save()
+}
+
+func (cp *restoreCheckpoint) start(dumpId int32) error {
+	cp.mx.Lock()
+	defer cp.mx.Unlock()
+	cp.InProgress[dumpId] = true
+	return cp.save()
+}
+
+func (cp *restoreCheckpoint) complete(dumpId int32) error {
+	cp.mx.Lock()
+	defer cp.mx.Unlock()
+	delete(cp.InProgress, dumpId)
+	cp.Completed[dumpId] = true
+	return cp.save()
+}
+
+// restart - marks the entries as started again, so they are restored even if they were completed
+func (cp *restoreCheckpoint) restart(dumpIds ...int32) error {
+	cp.mx.Lock()
+	defer cp.mx.Unlock()
+	for _, dumpId := range dumpIds {
+		delete(cp.Completed, dumpId)
+		cp.InProgress[dumpId] = true
+	}
+	return cp.save()
+}
+
+func (cp *restoreCheckpoint) isCompleted(dumpId int32) bool {
+	cp.mx.Lock()
+	defer cp.mx.Unlock()
+	return cp.Completed[dumpId]
+}
+
+// partiallyRestored - the sorted dump ids of the entries that were started but not completed
+func (cp *restoreCheckpoint) partiallyRestored() []int32 {
+	cp.mx.Lock()
+	defer cp.mx.Unlock()
+	res := make([]int32, 0, len(cp.InProgress))
+	for dumpId := range cp.InProgress {
+		res = append(res, dumpId)
+	}
+	slices.Sort(res)
+	return res
+}
+
+func (cp *restoreCheckpoint) remove() {
+	if err := os.Remove(cp.fileName); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Msg("error deleting checkpoint file")
+	}
+}
+
+// initCheckpoint - creates the checkpoint of the restoration or reads the checkpoint of the failed restoration if
+// the restoration is resumed
+func (r *Restore) initCheckpoint() error {
+	dumpId := r.st.Dirname()
+	fileName := r.restoreOpt.CheckpointFile
+	if fileName == "" {
+		fileName = path.Join(r.checkpointDir, fmt.Sprintf("restore_%s.checkpoint.json", dumpId))
+	}
+
+	if !r.restoreOpt.Resume {
+		r.checkpoint = newRestoreCheckpoint(fileName, dumpId)
+		return r.checkpoint.save()
+	}
+
+	cp, err := loadRestoreCheckpoint(fileName, dumpId)
+	if err != nil {
+		return err
+	}
+	r.checkpoint = cp
+	log.Info().
+		Str("CheckpointFile", fileName).
+		Bool("PreDataCompleted", cp.PreDataCompleted).
+		Int("CompletedEntries", len(cp.Completed)).
+		Int("PartiallyRestoredEntries", len(cp.InProgress)).
+		Msg("resuming restoration")
+	if cp.PreDataCompleted {
+		// The database is already created and cleaned by the failed restoration
+		r.restoreOpt.Create = false
+		r.restoreOpt.Clean = false
+	}
+	return nil
+}
+
+// isPreDataRestored - the pre-data section was restored by the resumed restoration
+func (r *Restore) isPreDataRestored() bool {
+	return r.restoreOpt.Resume && r.checkpoint != nil && r.checkpoint.PreDataCompleted
+}
+
+// isEntryRestored - the entry was restored by the resumed restoration
+func (r *Restore) isEntryRestored(dumpId int32) bool {
+	return r.restoreOpt.Resume && r.checkpoint != nil && r.checkpoint.isCompleted(dumpId)
+}
+
+// cleanupPartiallyRestored - deletes the data of the tables that were partially restored by the failed restoration,
+// so they can be restored again
+func (r *Restore) cleanupPartiallyRestored(ctx context.Context, conn *pgx.Conn, queries []string) error {
+	for _, query := range queries {
+		if _, err := conn.Exec(ctx, query); err != nil {
+			return fmt.Errorf("cannot delete data of partially restored table: %w", err)
+		}
+	}
+	return nil
+}
+
+// getCleanupQueries - returns the queries that delete the partially restored data. The chunk of the table split by
+// the primary key is deleted by its range condition, so the completed chunks are kept. The ctid ranges do not match
+// the rows of the restored table, so the whole table is deleted and the dump ids of all its chunks are returned to be
+// restored again
+func (r *Restore) getCleanupQueries() ([]string, []int32, error) {
+	var queries []string
+	var restartDumpIds []int32
+	cleanedTables := make(map[toolkit.Oid]bool)
+	for _, dumpId := range r.checkpoint.partiallyRestored() {
+		idx := slices.IndexFunc(r.tocObj.Entries, func(e *toc.Entry) bool {
+			return e.DumpId == dumpId
+		})
+		if idx == -1 {
+			return nil, nil, fmt.Errorf("%w: entry with dump id %d is not found in toc", ErrRestoreCannotBeResumed, dumpId)
+		}
+		entry := r.tocObj.Entries[idx]
+		if entry.Desc == nil || *entry.Desc != toc.TableDataDesc {
+			// Sequences, ACLs and large objects are restored idempotently
+			continue
+		}
+		t, err := r.getTableDefinitionFromMeta(dumpId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: cannot find table with dump id %d: %w", ErrRestoreCannotBeResumed, dumpId, err)
+		}
+		if cleanedTables[t.Oid] {
+			continue
+		}
+		// TRUNCATE cannot be used for the tables referenced by foreign keys, even if the referencing tables are empty
+		query := fmt.Sprintf("DELETE FROM ONLY %s", pgx.Identifier{t.Schema, t.Name}.Sanitize())
+		logEvent := log.Info().
+			Str("SchemaName", t.Schema).
+			Str("TableName", t.Name)
+		md := r.metadata.GetEntryByDumpId(dumpId)
+		switch {
+		case md == nil || md.Chunk == nil:
+			cleanedTables[t.Oid] = true
+			logEvent.Msg("deleting data of partially restored table")
+		case isPkChunk(md.Chunk):
+			query = fmt.Sprintf("%s WHERE %s", query, md.Chunk.Cond)
+			logEvent.Int("ChunkIdx", md.Chunk.Idx).Msg("deleting data of partially restored table chunk")
+		default:
+			cleanedTables[t.Oid] = true
+			restartDumpIds = append(restartDumpIds, r.getTableChunkDumpIds(t.Oid)...)
+			logEvent.Msg("deleting data of partially restored table: all the chunks of the table will be restored again")
+		}
+		queries = append(queries, query)
+	}
+	return queries, restartDumpIds, nil
+}
+
+// getTableChunkDumpIds - returns the dump ids of all the chunks of the table
+func (r *Restore) getTableChunkDumpIds(tableOid toolkit.Oid) []int32 {
+	var res []int32
+	for _, e := range r.metadata.Entries {
+		if e.Chunk != nil && r.metadata.DumpIdsToTableOid[e.DumpId] == tableOid {
+			res = append(res, e.DumpId)
+		}
+	}
+	return res
+}
+
+// isPkChunk - the chunk is selected by the primary key range. The chunking method is not stored in the dumps made
+// before it was introduced, so it is detected by the condition
+func isPkChunk(c *storage.TableChunk) bool {
+	if c.By != "" {
+		return c.By == runtimeContext.ChunkByPk
+	}
+	return !strings.HasPrefix(c.Cond, "ctid ")
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	runtimeContext "github.com/greenmaskio/greenmask/internal/db/postgres/context"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgrestore"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func TestRestoreCheckpoint_saveAndLoad(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "checkpoint.json")
+	cp := newRestoreCheckpoint(fileName, "100")
+	require.NoError(t, cp.setPreDataCompleted())
+	require.NoError(t, cp.start(10))
+	require.NoError(t, cp.start(11))
+	require.NoError(t, cp.start(12))
+	require.NoError(t, cp.complete(11))
+
+	loaded, err := loadRestoreCheckpoint(fileName, "100")
+	require.NoError(t, err)
+	assert.True(t, loaded.PreDataCompleted)
+	assert.True(t, loaded.isCompleted(11))
+	assert.False(t, loaded.isCompleted(10))
+	assert.Equal(t, []int32{10, 12}, loaded.partiallyRestored())
+
+	_, err = loadRestoreCheckpoint(fileName, "200")
+	require.ErrorIs(t, err, ErrRestoreCannotBeResumed)
+
+	cp.remove()
+	_, err = loadRestoreCheckpoint(fileName, "100")
+	require.ErrorIs(t, err, ErrRestoreCannotBeResumed)
+}
+
+func TestRestore_taskPusher_resume(t *testing.T) {
+	newEntry := func(dumpId int32, name string) *toc.Entry {
+		return &toc.Entry{
+			DumpId:    dumpId,
+			Section:   toc.SectionData,
+			Desc:      strPtr(toc.SequenceSetDesc),
+			Namespace: strPtr("public"),
+			Tag:       strPtr(name),
+		}
+	}
+	cp := newRestoreCheckpoint(path.Join(t.TempDir(), "checkpoint.json"), "100")
+	require.NoError(t, cp.complete(200))
+
+	r := &Restore{
+		restoreOpt:      &pgrestore.Options{Resume: true, Jobs: 1},
+		tocObj:          &toc.Toc{Entries: []*toc.Entry{newEntry(200, "users_id_seq"), newEntry(201, "posts_id_seq")}},
+		checkpoint:      cp,
+		restoredDumpIds: make(map[int32]bool),
+		mx:              &sync.RWMutex{},
+	}
+	tasks := make(chan restorationTask, 10)
+	require.NoError(t, r.taskPusher(context.Background(), tasks)())
+
+	var pushed []int32
+	for task := range tasks {
+		pushed = append(pushed, task.GetEntry().DumpId)
+	}
+	assert.Equal(t, []int32{201}, pushed)
+	assert.True(t, r.dependenciesAreRestored([]int32{200}))
+
+	// Without --resume the checkpoint is only written
+	r.restoreOpt.Resume = false
+	tasks = make(chan restorationTask, 10)
+	require.NoError(t, r.taskPusher(context.Background(), tasks)())
+	assert.Len(t, tasks, 2)
+}
+
+func TestRestore_getCleanupQueries_chunks(t *testing.T) {
+	newRestore := func(t *testing.T, chunks ...*storage.TableChunk) *Restore {
+		md := &storage.Metadata{
+			DatabaseSchema:    toolkit.DatabaseSchema{{Oid: 1000, Schema: "public", Name: "users"}},
+			DumpIdsToTableOid: map[int32]toolkit.Oid{},
+		}
+		tocObj := &toc.Toc{}
+		for i, c := range chunks {
+			dumpId := int32(10 + i)
+			md.Entries = append(md.Entries, &storage.Entry{DumpId: dumpId, Chunk: c})
+			md.DumpIdsToTableOid[dumpId] = 1000
+			tocObj.Entries = append(tocObj.Entries, &toc.Entry{
+				DumpId:  dumpId,
+				Section: toc.SectionData,
+				Desc:    strPtr(toc.TableDataDesc),
+			})
+		}
+		cp := newRestoreCheckpoint(path.Join(t.TempDir(), "checkpoint.json"), "100")
+		// The first chunk is completed and the second one is in progress
+		require.NoError(t, cp.complete(10))
+		require.NoError(t, cp.start(11))
+		return &Restore{
+			restoreOpt: &pgrestore.Options{Resume: true},
+			tocObj:     tocObj,
+			metadata:   md,
+			checkpoint: cp,
+		}
+	}
+
+	t.Run("pk chunks", func(t *testing.T) {
+		r := newRestore(t,
+			&storage.TableChunk{Idx: 0, Count: 2, Cond: `"id" < 10`, By: runtimeContext.ChunkByPk},
+			&storage.TableChunk{Idx: 1, Count: 2, Cond: `"id" >= 10`, By: runtimeContext.ChunkByPk},
+		)
+		queries, restartDumpIds, err := r.getCleanupQueries()
+		require.NoError(t, err)
+		assert.Equal(t, []string{`DELETE FROM ONLY "public"."users" WHERE "id" >= 10`}, queries)
+		assert.Empty(t, restartDumpIds)
+		assert.True(t, r.isEntryRestored(10))
+		assert.Equal(t, []int32{11}, r.checkpoint.partiallyRestored())
+	})
+
+	t.Run("pk chunks without chunking method", func(t *testing.T) {
+		r := newRestore(t,
+			&storage.TableChunk{Idx: 0, Count: 2, Cond: `"id" < 10`},
+			&storage.TableChunk{Idx: 1, Count: 2, Cond: `"id" >= 10`},
+		)
+		queries, restartDumpIds, err := r.getCleanupQueries()
+		require.NoError(t, err)
+		assert.Equal(t, []string{`DELETE FROM ONLY "public"."users" WHERE "id" >= 10`}, queries)
+		assert.Empty(t, restartDumpIds)
+		assert.True(t, r.isEntryRestored(10))
+	})
+
+	t.Run("ctid chunks", func(t *testing.T) {
+		r := newRestore(t,
+			&storage.TableChunk{Idx: 0, Count: 3, Cond: `ctid < '(10,0)'::TID`, By: runtimeContext.ChunkByCtid},
+			&storage.TableChunk{
+				Idx: 1, Count: 3, Cond: `ctid >= '(10,0)'::TID AND ctid < '(20,0)'::TID`, By: runtimeContext.ChunkByCtid,
+			},
+			&storage.TableChunk{Idx: 2, Count: 3, Cond: `ctid >= '(20,0)'::TID`, By: runtimeContext.ChunkByCtid},
+		)
+		queries, restartDumpIds, err := r.getCleanupQueries()
+		require.NoError(t, err)
+		assert.Equal(t, []string{`DELETE FROM ONLY "public"."users"`}, queries)
+		// All the chunks of the table are restored again
+		assert.Equal(t, []int32{10, 11, 12}, restartDumpIds)
+		// The checkpoint is not changed by the queries builder
+		assert.True(t, r.isEntryRestored(10))
+		assert.Equal(t, []int32{11}, r.checkpoint.partiallyRestored())
+	})
+}
+
+func TestGetPostDataLevels(t *testing.T) {
+	newEntry := func(dumpId int32, section int32, deps ...int32) *toc.Entry {
+		return &toc.Entry{
+			DumpId:       dumpId,
+			Section:      section,
+			Dependencies: deps,
+		}
+	}
+	entries := []*toc.Entry{
+		newEntry(1, toc.SectionPreData),
+		newEntry(2, toc.SectionData, 1),
+		// Primary key, index and foreign key referencing the primary key
+		newEntry(10, toc.SectionPostData, 1),
+		newEntry(11, toc.SectionPostData, 1),
+		newEntry(12, toc.SectionPostData, 10),
+		newEntry(13, toc.SectionPostData, 12),
+	}
+	dumpIds := func(levels [][]*toc.Entry) [][]int32 {
+		var res [][]int32
+		for _, level := range levels {
+			var ids []int32
+			for _, e := range level {
+				ids = append(ids, e.DumpId)
+			}
+			res = append(res, ids)
+		}
+		return res
+	}
+
+	t.Run("all entries", func(t *testing.T) {
+		levels := getPostDataLevels(entries, func(int32) bool { return false })
+		assert.Equal(t, [][]int32{{10, 11}, {12}, {13}}, dumpIds(levels))
+	})
+
+	t.Run("restored entries are skipped", func(t *testing.T) {
+		levels := getPostDataLevels(entries, func(dumpId int32) bool { return dumpId == 10 || dumpId == 11 })
+		assert.Equal(t, [][]int32{{12}, {13}}, dumpIds(levels))
+	})
+
+	t.Run("cyclic dependencies", func(t *testing.T) {
+		levels := getPostDataLevels([]*toc.Entry{
+			newEntry(10, toc.SectionPostData, 11),
+			newEntry(11, toc.SectionPostData, 10),
+		}, func(int32) bool { return false })
+		assert.Equal(t, [][]int32{{10}, {11}}, dumpIds(levels))
+	})
+}
@@ -42,12 +42,21 @@ var templateString = `;
 ;     dbname: {{ .Header.DbName }}
 ;     TOC Entries: {{ .Header.TocEntriesCount }}
 ;     Compression: {{ .Header.Compression }}
+;     Data compression: {{ .GetCodecName }}
+{{- if and .DataCompression .DataCompression.Level }} (level {{ .DataCompression.Level }}){{ end }}
+{{- if and .DataCompression .DataCompression.ZstdLong }} with long window{{ end }}
 ;     Dump Version: {{ .Header.DumpVersion }}
 ;     TableFormat: DIRECTORY
 ;     Integer: {{ .Header.Integer }} bytes
 ;     Offset: {{ .Header.Offset }} bytes
 ;     Dumped from database version: {{ .Header.DumpedFrom }}
 ;     Dumped by pg_dump version: {{ .Header.DumpedBy }}
+{{- if .Encryption }}
+;     Encryption: {{ .Encryption.Cipher }} (key id {{ .Encryption.KeyId }})
+{{- end }}
+{{- if .Parent }}
+;     Parent dump: {{ .Parent }}
+{{- end }}
 ;
 ;
 ; Selected TOC Entries:
@@ -0,0 +1,508 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/dumpers"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/restorers"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/custom"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	pgUtils "github.com/greenmaskio/greenmask/internal/db/postgres/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/storages/validate"
+)
+
+// syncTask - dumps the table from the source database and restores it into the target database at the same time
+type syncTask struct {
+	table *entries.Table
+	entry *toc.Entry
+	// restore - the table is restored into the target database. Otherwise, it is only dumped into the storage
+	restore bool
+}
+
+// Sync - dumps the data from the source database, transforms it and restores it into the target database without
+// the round trip through the storage. The transformed COPY stream of each table is passed from the TableDumper
+// directly into the TableRestorer of the target database. The pre-data and post-data sections are restored using
+// pg_restore as usual.
+//
+// If writeThrough is set, the dump objects are stored in the storage as well, so the result is a regular dump that
+// can be inspected or restored later. Otherwise, only the schema and large objects are kept in memory
+type Sync struct {
+	*Dump
+	restore      *Restore
+	writeThrough bool
+}
+
+func NewSync(
+	cfg *domains.Config, st storages.Storager, registry *utils.TransformerRegistry, writeThrough bool,
+) *Sync {
+	if !writeThrough {
+		st = validate.New("")
+	}
+	// The restoration reads the schema toc from the in-memory storage. The data is passed directly from the dumper
+	restore := NewRestore(
+		cfg.Common.PgBinPath, validate.New(""), &cfg.Restore, cfg.Restore.Scripts, cfg.Common.TempDirectory,
+	)
+	return &Sync{
+		Dump:         NewDump(cfg, st, registry),
+		restore:      restore,
+		writeThrough: writeThrough,
+	}
+}
+
+func (s *Sync) Run(ctx context.Context) (err error) {
+	defer s.prune()
+	defer s.restore.prune()
+	startedAt := time.Now()
+
+	if err := custom.BootstrapCustomTransformers(ctx, s.registry, s.config.CustomTransformers); err != nil {
+		return fmt.Errorf("error bootstraping custom transformers: %w", err)
+	}
+
+	dsn, err := s.pgDumpOptions.GetPgDSN()
+	if err != nil {
+		return fmt.Errorf("cannot build connection string: %w", err)
+	}
+
+	if s.codec, err = s.pgDumpOptions.GetCodec(); err != nil {
+		return fmt.Errorf("compression codec error: %w", err)
+	}
+
+	ctx, mappingRegistry, err := openMappingStores(ctx, s.config.MappingStores, false)
+	if err != nil {
+		return err
+	}
+	defer closeMappingStores(ctx, mappingRegistry)
+
+	conn, err := s.connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := conn.Close(ctx); err != nil {
+			log.Warn().Err(err)
+		}
+	}()
+
+	tx, err := s.startMainTx(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("cannot prepare backup transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			log.Warn().Err(err)
+		}
+	}()
+
+	if err = s.gatherPgFacts(ctx, tx); err != nil {
+		return fmt.Errorf("error gathering facts: %w", err)
+	}
+
+	if err := s.buildContextAndValidate(ctx, tx); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	if err = s.collectTableSignatures(ctx, tx); err != nil {
+		return fmt.Errorf("cannot collect table signatures: %w", err)
+	}
+
+	if err = s.schemaOnlyDump(ctx, tx); err != nil {
+		return fmt.Errorf("schema only stage dumping error: %w", err)
+	}
+
+	if err = s.preDataRestore(ctx); err != nil {
+		return fmt.Errorf("pre-data stage restoration error: %w", err)
+	}
+
+	if s.restore.restoreOpt.SchemaOnly {
+		log.Info().Msg("data section is skipped due to schema-only restoration")
+	} else if err = s.dataSync(ctx); err != nil {
+		return fmt.Errorf("data stage sync error: %w", err)
+	}
+
+	if err = mappingRegistry.Close(ctx); err != nil {
+		return fmt.Errorf("cannot save mappings: %w", err)
+	}
+
+	if err = s.restore.postDataRestore(ctx); err != nil {
+		return fmt.Errorf("post-data stage restoration error: %w", err)
+	}
+
+	if !s.writeThrough {
+		return nil
+	}
+
+	if err = s.mergeAndWriteToc(ctx); err != nil {
+		return fmt.Errorf("mergeAndWriteToc stage dumping error: %w", err)
+	}
+
+	if err = s.writeMetaData(ctx, startedAt, time.Now()); err != nil {
+		return fmt.Errorf("writeMetaData stage dumping error: %w", err)
+	}
+
+	return nil
+}
+
+// preDataRestore - restores pre-data section into the target database using the schema toc of the source
+func (s *Sync) preDataRestore(ctx context.Context) error {
+	buf := bytes.NewBuffer(nil)
+	if err := toc.NewWriter(buf).Write(s.schemaToc); err != nil {
+		return fmt.Errorf("error writing schema toc: %w", err)
+	}
+	if err := s.restore.st.PutObject(ctx, "toc.dat", buf); err != nil {
+		return fmt.Errorf("error writing schema toc: %w", err)
+	}
+
+	if err := s.restore.prepare(ctx); err != nil {
+		return fmt.Errorf("preparation error: %w", err)
+	}
+
+	if err := s.restore.preFlightRestore(); err != nil {
+		return fmt.Errorf("pre-flight stage restoration error: %w", err)
+	}
+
+	return s.restore.preDataRestore(ctx)
+}
+
+// dataSync - dumps and restores the tables in parallel and then restores sequences and large objects
+func (s *Sync) dataSync(ctx context.Context) error {
+	var tables []*entries.Table
+	for _, obj := range s.context.DataSectionObjects {
+		obj.SetDumpId(s.dumpIdSequence)
+		if t, ok := obj.(*entries.Table); ok {
+			tables = append(tables, t)
+		}
+	}
+	s.setDumpDependenciesGraph(tables)
+
+	conn, err := pgx.Connect(ctx, s.restore.dsn)
+	if err != nil {
+		return fmt.Errorf("cannot connect to target server: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(ctx); err != nil {
+			log.Warn().Err(err).Msg("cannot close target connection")
+		}
+	}()
+
+	if err = s.restore.RunScripts(ctx, conn, scriptDataSection, scriptExecuteBefore); err != nil {
+		return err
+	}
+
+	tasks := make(chan *syncTask, s.pgDumpOptions.Jobs)
+	done := make(chan struct{})
+	eg, gtx := errgroup.WithContext(ctx)
+	eg.Go(s.writeHeartBeatWorker(gtx, done))
+	eg.Go(func() error {
+		defer close(done)
+		workerEg, wtx := errgroup.WithContext(gtx)
+		for j := 0; j < s.pgDumpOptions.Jobs; j++ {
+			workerEg.Go(func() error {
+				return s.syncWorker(wtx, tasks, j)
+			})
+		}
+		if err := workerEg.Wait(); err != nil {
+			return err
+		}
+		return s.syncSequencesAndBlobs(gtx, conn)
+	})
+	eg.Go(s.syncTaskProducer(gtx, tables, tasks))
+
+	if err := eg.Wait(); err != nil {
+		return fmt.Errorf("at least one worker exited with error: %w", err)
+	}
+
+	if err = s.restore.RunScripts(ctx, conn, scriptDataSection, scriptExecuteAfter); err != nil {
+		return err
+	}
+
+	if err := s.createTocEntries(); err != nil {
+		return fmt.Errorf("error creating toc entries: %w", err)
+	}
+	log.Debug().Msg("all the data have been synced")
+	return nil
+}
+
+// syncTaskProducer - produces the table tasks. If restore-in-order is set, the tables are produced in topological
+// order and each table is produced only after its dependencies are restored
+func (s *Sync) syncTaskProducer(
+	ctx context.Context, tables []*entries.Table, tasks chan<- *syncTask,
+) func() error {
+	return func() error {
+		defer close(tasks)
+		if s.restore.restoreOpt.RestoreInOrder {
+			tables = sortTablesByDumpIds(tables, s.sortedTablesDumpIds)
+		}
+		for _, t := range tables {
+			if t.RelKind == 'p' {
+				continue
+			}
+			t.Codec = s.codec
+			entry, err := t.Entry()
+			if err != nil {
+				return fmt.Errorf("error producing toc entry: %w", err)
+			}
+			task := &syncTask{
+				table:   t,
+				entry:   entry,
+				restore: s.restore.isNeedRestore(entry),
+			}
+			if !task.restore {
+				// The table is excluded from the restoration - do not wait for it
+				s.restore.markDumpIdRestored(t.DumpId)
+				if !s.writeThrough {
+					continue
+				}
+			}
+			if task.restore && s.restore.restoreOpt.RestoreInOrder && s.pgDumpOptions.Jobs > 1 {
+				deps := s.dumpDependenciesGraph[t.DumpId]
+				if err := s.restore.waitDependenciesAreRestore(ctx, deps); err != nil {
+					return fmt.Errorf("cannot wait for dependencies are restored: %w", err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case tasks <- task:
+			}
+		}
+		return nil
+	}
+}
+
+// syncWorker - executes the sync tasks using the source transaction with the exported snapshot and the target
+// database connection
+func (s *Sync) syncWorker(ctx context.Context, tasks <-chan *syncTask, id int) error {
+	srcConn, tx, err := s.getWorkerTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("error preparing worker (id=%d) transaction: %w", id, err)
+	}
+	defer func() {
+		if err := srcConn.Close(ctx); err != nil {
+			log.Debug().Err(err).Int("WorkerId", id).Msg("error closing connection")
+		}
+	}()
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			log.Debug().Err(err).Int("WorkerId", id).Msg("unable to rollback transaction")
+		}
+	}()
+
+	dstConn, err := pgx.Connect(ctx, s.restore.dsn)
+	if err != nil {
+		return fmt.Errorf("cannot connect to target server (worker %d): %w", id, err)
+	}
+	defer func() {
+		if err := dstConn.Close(ctx); err != nil {
+			log.Warn().Err(err).Int("WorkerId", id).Msg("cannot close target connection")
+		}
+	}()
+
+	for {
+		var task *syncTask
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case task, ok = <-tasks:
+			if !ok {
+				return nil
+			}
+		}
+		log.Debug().
+			Int("WorkerId", id).
+			Str("ObjectName", fmt.Sprintf("table %s.%s", task.table.Schema, task.table.Name)).
+			Msg("sync started")
+
+		dumper := dumpers.NewTableDumper(task.table, false, 0)
+		var st storages.Storager = s.st
+		if task.restore {
+			st = &streamStorage{
+				Storager:     s.st,
+				writeThrough: s.writeThrough,
+				restore: func(ctx context.Context, st storages.Storager) error {
+					return s.newTableRestorer(task, st).Execute(ctx, pgUtils.NewPGConn(dstConn))
+				},
+			}
+		}
+		if err = dumper.Execute(ctx, tx, st); err != nil {
+			return fmt.Errorf(
+				"unable to sync table %s.%s (worker %d): %w", task.table.Schema, task.table.Name, id, err,
+			)
+		}
+		if task.restore {
+			s.restore.markDumpIdRestored(task.entry.DumpId)
+		}
+
+		log.Debug().
+			Int("WorkerId", id).
+			Str("ObjectName", fmt.Sprintf("table %s.%s", task.table.Schema, task.table.Name)).
+			Msg("sync is done")
+	}
+}
+
+func (s *Sync) newTableRestorer(task *syncTask, st storages.Storager) restorationTask {
+	opt := s.restore.restoreOpt
+	if opt.Inserts || opt.OnConflictDoNothing {
+		return restorers.NewTableRestorerInsertFormat(
+			task.entry, task.table.Table, st, opt.ToDataSectionSettings(), s.restore.cfg.ErrorExclusions,
+		)
+	}
+	return restorers.NewTableRestorer(task.entry, st, opt.ToDataSectionSettings())
+}
+
+// syncSequencesAndBlobs - dumps the large objects into the storage and restores sequences and large objects into
+// the target database. Large objects cannot be streamed because their restoration requires the blobs toc
+func (s *Sync) syncSequencesAndBlobs(ctx context.Context, conn *pgx.Conn) error {
+	srcConn, tx, err := s.getWorkerTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("error preparing transaction: %w", err)
+	}
+	defer func() {
+		if err := srcConn.Close(ctx); err != nil {
+			log.Debug().Err(err).Msg("error closing connection")
+		}
+	}()
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			log.Debug().Err(err).Msg("unable to rollback transaction")
+		}
+	}()
+
+	for _, obj := range s.context.DataSectionObjects {
+		var task restorationTask
+		switch v := obj.(type) {
+		case *entries.Sequence:
+			entry, err := v.Entry()
+			if err != nil {
+				return fmt.Errorf("error producing toc entry: %w", err)
+			}
+			if !s.restore.isNeedRestore(entry) {
+				continue
+			}
+			task = restorers.NewSequenceRestorer(entry)
+		case *entries.Blobs:
+			s.blobs = v
+			v.Codec = s.codec
+			if s.pgDumpOptions.NoBlobs {
+				continue
+			}
+			if err := dumpers.NewLargeObjectDumper(v).Execute(ctx, tx, s.st); err != nil {
+				return fmt.Errorf("cannot dump large objects: %w", err)
+			}
+			if s.restore.restoreOpt.NoBlobs {
+				continue
+			}
+			entry, err := v.Entry()
+			if err != nil {
+				return fmt.Errorf("error producing toc entry: %w", err)
+			}
+			task = restorers.NewBlobsRestorer(entry, s.st, s.codec)
+		default:
+			continue
+		}
+		if err := task.Execute(ctx, pgUtils.NewPGConn(conn)); err != nil {
+			return fmt.Errorf("unable to restore %s: %w", task.DebugInfo(), err)
+		}
+	}
+	return nil
+}
+
+// sortTablesByDumpIds - returns tables in the order of dump ids. The tables that are not in the list are appended
+// to the end
+func sortTablesByDumpIds(tables []*entries.Table, dumpIds []int32) []*entries.Table {
+	res := make([]*entries.Table, 0, len(tables))
+	for _, dumpId := range dumpIds {
+		idx := slices.IndexFunc(tables, func(t *entries.Table) bool {
+			return t.DumpId == dumpId
+		})
+		if idx != -1 {
+			res = append(res, tables[idx])
+		}
+	}
+	for _, t := range tables {
+		if !slices.Contains(res, t) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// streamStorage - passes the object written by the dumper to the restore function instead of storing it. If
+// writeThrough is set, the object is stored in the underlying storage as well
+type streamStorage struct {
+	storages.Storager
+	writeThrough bool
+	restore      func(ctx context.Context, st storages.Storager) error
+}
+
+func (s *streamStorage) PutObject(ctx context.Context, filePath string, body io.Reader) error {
+	eg, gtx := errgroup.WithContext(ctx)
+	src := body
+	var pw *io.PipeWriter
+	if s.writeThrough {
+		var pr *io.PipeReader
+		pr, pw = io.Pipe()
+		src = io.TeeReader(body, pw)
+		eg.Go(func() error {
+			err := s.Storager.PutObject(gtx, filePath, pr)
+			if err != nil {
+				// Unblock the restoration that writes into the pipe
+				pr.CloseWithError(err)
+			}
+			return err
+		})
+	}
+	eg.Go(func() error {
+		err := s.restore(gtx, &objectStreamStorage{Storager: s.Storager, fileName: filePath, r: src})
+		if err == nil {
+			// The restorer might stop reading before EOF, the rest of the object must be passed to the storage
+			_, err = io.Copy(io.Discard, src)
+		}
+		if pw != nil {
+			pw.CloseWithError(err)
+		}
+		return err
+	})
+	return eg.Wait()
+}
+
+// objectStreamStorage - returns the stream instead of the storage object with the same name
+type objectStreamStorage struct {
+	storages.Storager
+	fileName string
+	r        io.Reader
+}
+
+func (s *objectStreamStorage) GetObject(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	if filePath != s.fileName {
+		return s.Storager.GetObject(ctx, filePath)
+	}
+	return io.NopCloser(s.r), nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/storages/validate"
+)
+
+func readStreamObject(ctx context.Context, st storages.Storager, name string, limit int64) ([]byte, error) {
+	r, err := st.GetObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, limit))
+}
+
+func TestStreamStorage_PutObject(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("1\tdata\n"), 10000)
+
+	t.Run("without write-through", func(t *testing.T) {
+		underlying := validate.New("")
+		var restored []byte
+		st := &streamStorage{
+			Storager: underlying,
+			restore: func(ctx context.Context, st storages.Storager) (err error) {
+				restored, err = readStreamObject(ctx, st, "1.dat.gz", int64(len(data)))
+				return err
+			},
+		}
+		require.NoError(t, st.PutObject(ctx, "1.dat.gz", bytes.NewReader(data)))
+		assert.Equal(t, data, restored)
+		exists, err := underlying.Exists(ctx, "1.dat.gz")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("write-through stores the whole object", func(t *testing.T) {
+		underlying := validate.New("")
+		var restored []byte
+		st := &streamStorage{
+			Storager:     underlying,
+			writeThrough: true,
+			restore: func(ctx context.Context, st storages.Storager) (err error) {
+				// Read only a part of the object - the rest must still be written into the storage
+				restored, err = readStreamObject(ctx, st, "1.dat.gz", 10)
+				return err
+			},
+		}
+		require.NoError(t, st.PutObject(ctx, "1.dat.gz", bytes.NewReader(data)))
+		assert.Equal(t, data[:10], restored)
+
+		stored, err := readStreamObject(ctx, underlying, "1.dat.gz", int64(len(data)+1))
+		require.NoError(t, err)
+		assert.Equal(t, data, stored)
+	})
+
+	t.Run("restoration error", func(t *testing.T) {
+		expectedErr := errors.New("restoration error")
+		st := &streamStorage{
+			Storager:     validate.New(""),
+			writeThrough: true,
+			restore: func(ctx context.Context, st storages.Storager) error {
+				return expectedErr
+			},
+		}
+		require.ErrorIs(t, st.PutObject(ctx, "1.dat.gz", bytes.NewReader(data)), expectedErr)
+	})
+}
+
+func TestSortTablesByDumpIds(t *testing.T) {
+	a := &entries.Table{DumpId: 1}
+	b := &entries.Table{DumpId: 2}
+	c := &entries.Table{DumpId: 3}
+	res := sortTablesByDumpIds([]*entries.Table{a, b, c}, []int32{3, 1})
+	assert.Equal(t, []*entries.Table{c, a, b}, res)
+}
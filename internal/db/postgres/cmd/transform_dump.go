@@ -0,0 +1,409 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+
+	runtimeContext "github.com/greenmaskio/greenmask/internal/db/postgres/context"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/dumpers"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	storageDto "github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/custom"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/storages/encryption"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+// TransformDump - transforms the existing dump using the transformation config without the database connection.
+// The table data of the source dump is passed through the transformation pipeline and stored as a new dump. The
+// schema of the tables is taken from the source dump metadata
+type TransformDump struct {
+	config   *domains.Config
+	registry *utils.TransformerRegistry
+	rootSt   storages.Storager
+	// srcSt - the storage of the source dump
+	srcSt storages.Storager
+	// st - the storage of the new dump
+	st        storages.Storager
+	srcDumpId string
+	context   *runtimeContext.RuntimeContext
+	// codec - compression codec of the new dump objects
+	codec ioutils.Codec
+}
+
+func NewTransformDump(
+	cfg *domains.Config, rootSt storages.Storager, registry *utils.TransformerRegistry, srcDumpId, dumpId string,
+) *TransformDump {
+	return &TransformDump{
+		config:    cfg,
+		registry:  registry,
+		rootSt:    rootSt,
+		srcSt:     rootSt.SubStorage(srcDumpId, true),
+		st:        rootSt.SubStorage(dumpId, true),
+		srcDumpId: srcDumpId,
+	}
+}
+
+func (td *TransformDump) Run(ctx context.Context) error {
+	startedAt := time.Now()
+
+	err := custom.BootstrapCustomTransformers(ctx, td.registry, td.config.CustomTransformers)
+	if err != nil {
+		return fmt.Errorf("error bootstraping custom transformers: %w", err)
+	}
+
+	td.codec, err = td.config.Dump.PgDumpOptions.GetCodec()
+	if err != nil {
+		return fmt.Errorf("compression codec error: %w", err)
+	}
+
+	ctx, mappingRegistry, err := openMappingStores(ctx, td.config.MappingStores, false)
+	if err != nil {
+		return err
+	}
+	defer closeMappingStores(ctx, mappingRegistry)
+
+	md, err := readDumpMetadata(ctx, td.srcSt)
+	if err != nil {
+		return fmt.Errorf("cannot read source dump: %w", err)
+	}
+	if len(md.DatabaseSchema) == 0 {
+		return fmt.Errorf("source dump %s does not contain the database schema", td.srcDumpId)
+	}
+
+	td.context, err = runtimeContext.NewOfflineRuntimeContext(
+		ctx, md.DatabaseSchema, md.DumpIdsToTableOid, &td.config.Dump, td.registry,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to build runtime context: %w", err)
+	}
+	if err = toolkit.PrintValidationWarnings(td.context.Warnings, nil, false); err != nil {
+		return err
+	}
+	if td.context.IsFatal() {
+		return fmt.Errorf("fatal validation error")
+	}
+
+	tocObj, err := td.readToc(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = td.writeHeartBeat(ctx, HeartBeatInProgressContent); err != nil {
+		return fmt.Errorf("error writing heartbeat: %w", err)
+	}
+
+	stats, err := td.dataDump(ctx, md)
+	if err != nil {
+		return fmt.Errorf("data stage dumping error: %w", err)
+	}
+
+	if err = mappingRegistry.Close(ctx); err != nil {
+		return fmt.Errorf("cannot save mappings: %w", err)
+	}
+	td.setTableDataFileNames(tocObj)
+
+	tocFileSize, err := td.writeToc(ctx, tocObj)
+	if err != nil {
+		return fmt.Errorf("cannot write toc: %w", err)
+	}
+
+	if err = td.writeMetaData(ctx, md, tocObj, tocFileSize, stats, startedAt); err != nil {
+		return fmt.Errorf("writeMetaData stage dumping error: %w", err)
+	}
+
+	if err = td.writeHeartBeat(ctx, HeartBeatDoneContent); err != nil {
+		return fmt.Errorf("error writing heartbeat: %w", err)
+	}
+	return nil
+}
+
+func (td *TransformDump) readToc(ctx context.Context) (*toc.Toc, error) {
+	f, err := td.srcSt.GetObject(ctx, tocFileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open source toc: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing toc file")
+		}
+	}()
+	tocObj, err := toc.NewReader(f).Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read source toc: %w", err)
+	}
+	return tocObj, nil
+}
+
+// dataDump - transform the table data and copy the large objects of the source dump. It returns the sizes of the
+// written table data objects by dump id
+func (td *TransformDump) dataDump(
+	ctx context.Context, md *storageDto.Metadata,
+) (map[int32]storageDto.ObjectSizeStat, error) {
+	var tasks []*dumpers.OfflineTableDumper
+	for _, e := range md.Entries {
+		switch e.ObjectType {
+		case toc.TableDataDesc:
+			src, fileName := td.srcSt, e.FileName
+			if e.Reference != nil {
+				src, fileName = td.rootSt.SubStorage(e.Reference.DumpId, true), e.Reference.FileName
+			}
+			table := td.getTable(e)
+			table.Codec = td.codec
+			tasks = append(tasks, dumpers.NewOfflineTableDumper(
+				table, src, fileName, td.config.Dump.PgDumpOptions.Pgzip,
+			))
+		case toc.BlobsDesc:
+			if err := td.copyBlobs(ctx, e, md.GetCodecName()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	jobs := td.config.Dump.PgDumpOptions.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	taskCh := make(chan *dumpers.OfflineTableDumper)
+	eg, gtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		defer close(taskCh)
+		for _, task := range tasks {
+			select {
+			case <-gtx.Done():
+				return gtx.Err()
+			case taskCh <- task:
+			}
+		}
+		return nil
+	})
+	for j := 0; j < jobs; j++ {
+		eg.Go(func() error {
+			for task := range taskCh {
+				log.Debug().
+					Int("WorkerId", j).
+					Str("ObjectName", task.DebugInfo()).
+					Msgf("dumping started")
+				if err := task.Execute(gtx, td.st); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	stats := make(map[int32]storageDto.ObjectSizeStat, len(tasks))
+	for _, task := range tasks {
+		t := task.Table()
+		stats[t.DumpId] = storageDto.ObjectSizeStat{
+			Original:   t.OriginalSize,
+			Compressed: t.CompressedSize,
+			Checksum:   t.Checksum,
+		}
+	}
+	return stats, nil
+}
+
+// getTable - find the table of the table data entry in the runtime context. If the table is not found in the dump
+// schema, the table without transformers is returned, so the data is copied as is
+func (td *TransformDump) getTable(e *storageDto.Entry) *entries.Table {
+	for _, obj := range td.context.DataSectionObjects {
+		if t, ok := obj.(*entries.Table); ok && t.DumpId == e.DumpId {
+			return t
+		}
+	}
+	return &entries.Table{
+		Table:  &toolkit.Table{Schema: e.Schema, Name: e.Name},
+		DumpId: e.DumpId,
+	}
+}
+
+// copyBlobs - copy blobs.toc and the large objects. The large objects are recompressed if the codec of the source
+// dump differs from the codec of the new dump
+func (td *TransformDump) copyBlobs(ctx context.Context, e *storageDto.Entry, srcCodecName string) error {
+	srcCodec, err := ioutils.GetCodecByName(srcCodecName, td.config.Dump.PgDumpOptions.Pgzip)
+	if err != nil {
+		return fmt.Errorf("cannot get source dump codec: %w", err)
+	}
+	f, err := td.srcSt.GetObject(ctx, e.FileName)
+	if err != nil {
+		return fmt.Errorf("cannot open source object %s: %w", e.FileName, err)
+	}
+	data, err := io.ReadAll(f)
+	if closeErr := f.Close(); closeErr != nil {
+		log.Warn().Err(closeErr).Msg("error closing blobs toc")
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read source object %s: %w", e.FileName, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		_, fileName, ok := strings.Cut(line, " ")
+		if !ok {
+			return fmt.Errorf("wrong blobs toc line \"%s\"", line)
+		}
+		if err = td.copyObject(ctx, fileName, srcCodec); err != nil {
+			return err
+		}
+	}
+	if err = td.st.PutObject(ctx, e.FileName, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("cannot write object %s: %w", e.FileName, err)
+	}
+	return nil
+}
+
+// copyObject - copy the object from the source dump. The object is copied as is if the codecs are the same
+func (td *TransformDump) copyObject(ctx context.Context, fileName string, srcCodec ioutils.Codec) error {
+	srcFileName := fileName + srcCodec.Extension()
+	dstFileName := fileName + td.codec.Extension()
+	f, err := td.srcSt.GetObject(ctx, srcFileName)
+	if err != nil {
+		return fmt.Errorf("cannot open source object %s: %w", srcFileName, err)
+	}
+	if srcCodec.Name() == td.codec.Name() {
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Warn().Err(err).Str("FileName", srcFileName).Msg("error closing source object")
+			}
+		}()
+		if err = td.st.PutObject(ctx, dstFileName, f); err != nil {
+			return fmt.Errorf("cannot write object %s: %w", dstFileName, err)
+		}
+		return nil
+	}
+
+	r, err := srcCodec.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("cannot decompress source object %s: %w", srcFileName, err)
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Warn().Err(err).Str("FileName", srcFileName).Msg("error closing source object")
+		}
+	}()
+	w, cr := ioutils.NewCompressionPipe(td.codec)
+	eg, gtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		defer func() {
+			if err := cr.Close(); err != nil {
+				log.Warn().Err(err).Msg("error closing compression reader")
+			}
+		}()
+		if err := td.st.PutObject(gtx, dstFileName, cr); err != nil {
+			return fmt.Errorf("cannot write object %s: %w", dstFileName, err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		if _, err := io.Copy(w, r); err != nil {
+			if closeErr := w.Close(); closeErr != nil {
+				log.Warn().Err(closeErr).Msg("error closing compression writer")
+			}
+			return fmt.Errorf("cannot recompress object %s: %w", srcFileName, err)
+		}
+		return w.Close()
+	})
+	return eg.Wait()
+}
+
+// setTableDataFileNames - set the names of the table data objects of the new dump into the toc. The extension of
+// the objects depends on the codec of the new dump
+func (td *TransformDump) setTableDataFileNames(tocObj *toc.Toc) {
+	for _, e := range tocObj.Entries {
+		if e.Desc == nil || *e.Desc != toc.TableDataDesc || e.FileName == nil {
+			continue
+		}
+		fileName := fmt.Sprintf("%d.dat%s", e.DumpId, td.codec.Extension())
+		e.FileName = &fileName
+	}
+}
+
+func (td *TransformDump) writeToc(ctx context.Context, tocObj *toc.Toc) (int64, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	if err := toc.NewWriter(buf).Write(tocObj); err != nil {
+		return 0, fmt.Errorf("error encoding toc: %w", err)
+	}
+	size := int64(buf.Len())
+	if err := td.st.PutObject(ctx, tocFileName, buf); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func (td *TransformDump) writeMetaData(
+	ctx context.Context, srcMd *storageDto.Metadata, tocObj *toc.Toc, tocFileSize int64,
+	stats map[int32]storageDto.ObjectSizeStat, startedAt time.Time,
+) error {
+	description := td.config.Dump.PgDumpOptions.Description
+	if description == "" {
+		description = fmt.Sprintf("transformed dump %s", td.srcDumpId)
+	}
+	metadata, err := storageDto.NewMetadata(
+		tocObj, tocFileSize, startedAt, time.Now(), td.config.Dump.Transformation, stats,
+		srcMd.DatabaseSchema, srcMd.DependenciesGraph, srcMd.DumpIdsOrder, srcMd.Cycles, srcMd.TableOidToDumpId,
+		description,
+	)
+	if err != nil {
+		return fmt.Errorf("unable build metadata: %w", err)
+	}
+	if encSt, ok := td.st.(*encryption.Storage); ok {
+		metadata.Encryption = encSt.Info()
+	}
+	metadata.Tags = td.config.Dump.PgDumpOptions.Tags
+	metadata.DataCompression = &storageDto.Compression{
+		Codec:    td.codec.Name(),
+		Level:    td.config.Dump.PgDumpOptions.DataCompressionLevel,
+		ZstdLong: td.config.Dump.PgDumpOptions.ZstdLong,
+	}
+	// The table chunks are not present in TableOidToDumpId, so they are copied from the source dump
+	for _, e := range metadata.Entries {
+		if srcEntry := srcMd.GetEntryByDumpId(e.DumpId); srcEntry != nil && srcEntry.Chunk != nil {
+			e.Chunk = srcEntry.Chunk
+			metadata.DumpIdsToTableOid[e.DumpId] = srcMd.DumpIdsToTableOid[e.DumpId]
+		}
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	if err = json.NewEncoder(buf).Encode(metadata); err != nil {
+		return fmt.Errorf("error encoding metadata.json: %w", err)
+	}
+	if err = td.st.PutObject(ctx, MetadataJsonFileName, buf); err != nil {
+		return fmt.Errorf("error writing metadata to the storage: %w", err)
+	}
+	return nil
+}
+
+func (td *TransformDump) writeHeartBeat(ctx context.Context, data string) error {
+	return td.st.PutObject(ctx, HeartBeatFileName, bytes.NewBufferString(data))
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/storages/directory"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func newTransformDumpTestToc(t *testing.T, tables ...*entries.Table) []byte {
+	var tocEntries []*toc.Entry
+	for _, table := range tables {
+		entry, err := table.Entry()
+		require.NoError(t, err)
+		tocEntries = append(tocEntries, entry)
+	}
+	dbName, version := "testdb", "17.0"
+	tocObj := &toc.Toc{
+		Header: &toc.Header{
+			VersionMajor:         1,
+			VersionMinor:         14,
+			Version:              toc.BackupVersions["1.14"],
+			IntSize:              4,
+			OffSize:              8,
+			Format:               toc.ArchTar,
+			ArchDbName:           &dbName,
+			ArchiveRemoteVersion: &version,
+			ArchiveDumpVersion:   &version,
+			TocCount:             int32(len(tocEntries)),
+			MaxDumpId:            tables[len(tables)-1].DumpId,
+		},
+		Entries: tocEntries,
+	}
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, toc.NewWriter(buf).Write(tocObj))
+	return buf.Bytes()
+}
+
+func readTransformedObject(t *testing.T, st storages.Storager, fileName string) string {
+	f, err := st.GetObject(context.Background(), fileName)
+	require.NoError(t, err)
+	gz, err := ioutils.NewGzipReader(f, false)
+	require.NoError(t, err)
+	defer gz.Close()
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestTransformDump_Run(t *testing.T) {
+	schema := toolkit.DatabaseSchema{
+		{
+			Oid: 1000, Schema: "public", Name: "users", Kind: "r",
+			Columns: []*toolkit.Column{
+				{Idx: 0, Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID, Num: 1},
+				{Idx: 1, Name: "name", TypeName: "text", TypeOid: pgtype.TextOID, Num: 2},
+			},
+		},
+	}
+	table := &entries.Table{Table: schema[0], DumpId: 10}
+	data := "1\tjohn\n2\tjane\n\\.\n\n"
+
+	root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+	putVerifyDump(t, root.SubStorage("100", true), map[string][]byte{
+		"10.dat.gz": compressTableData(t, data),
+		tocFileName: newTransformDumpTestToc(t, table),
+	}, &storage.Metadata{
+		DatabaseSchema:    schema,
+		TableOidToDumpId:  map[toolkit.Oid]int32{1000: 10},
+		DumpIdsToTableOid: map[int32]toolkit.Oid{10: 1000},
+		Entries: []*storage.Entry{
+			{
+				DumpId: 10, ObjectType: toc.TableDataDesc, Schema: "public", Name: "users",
+				FileName: "10.dat.gz", OriginalSize: int64(len(data)),
+			},
+		},
+	})
+
+	t.Run("transformed", func(t *testing.T) {
+		cfg := &domains.Config{}
+		cfg.Dump.Transformation = []*domains.Table{
+			{
+				Schema: "public",
+				Name:   "users",
+				Transformers: []*domains.TransformerConfig{
+					{
+						Name: transformers.ReplaceTransformerName,
+						Params: toolkit.StaticParameters{
+							"column": toolkit.ParamsValue("name"),
+							"value":  toolkit.ParamsValue("masked"),
+						},
+					},
+				},
+			},
+		}
+		err := NewTransformDump(cfg, root, utils.DefaultTransformerRegistry, "100", "200").Run(context.Background())
+		require.NoError(t, err)
+
+		st := root.SubStorage("200", true)
+		assert.Equal(t, "1\tmasked\n2\tmasked\n\\.\n\n", readTransformedObject(t, st, "10.dat.gz"))
+
+		report, err := NewVerify(root, "200", true).Run(context.Background())
+		require.NoError(t, err)
+		assert.True(t, report.Passed, report.Entries)
+
+		md, err := readDumpMetadata(context.Background(), st)
+		require.NoError(t, err)
+		require.Len(t, md.Transformers, 1)
+		assert.Equal(t, "users", md.Transformers[0].Name)
+		assert.Equal(t, "transformed dump 100", md.Description)
+
+		f, err := st.GetObject(context.Background(), HeartBeatFileName)
+		require.NoError(t, err)
+		heartbeat, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		assert.Equal(t, HeartBeatDoneContent, string(heartbeat))
+	})
+
+	t.Run("zstd codec", func(t *testing.T) {
+		cfg := &domains.Config{}
+		cfg.Dump.PgDumpOptions.DataCompression = ioutils.ZstdCodecName
+		err := NewTransformDump(cfg, root, utils.DefaultTransformerRegistry, "100", "400").Run(context.Background())
+		require.NoError(t, err)
+
+		report, err := NewVerify(root, "400", true).Run(context.Background())
+		require.NoError(t, err)
+		assert.True(t, report.Passed, report.Entries)
+
+		md, err := readDumpMetadata(context.Background(), root.SubStorage("400", true))
+		require.NoError(t, err)
+		assert.Equal(t, ioutils.ZstdCodecName, md.GetCodecName())
+		require.Len(t, md.Entries, 1)
+		assert.Equal(t, "10.dat.zst", md.Entries[0].FileName)
+	})
+
+	t.Run("fatal validation error", func(t *testing.T) {
+		cfg := &domains.Config{}
+		cfg.Dump.Transformation = []*domains.Table{
+			{Schema: "public", Name: "users", Query: "select * from public.users"},
+		}
+		err := NewTransformDump(cfg, root, utils.DefaultTransformerRegistry, "100", "300").Run(context.Background())
+		require.ErrorContains(t, err, "fatal validation error")
+		exists, err := root.SubStorage("300", true).Exists(context.Background(), MetadataJsonFileName)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestTransformDump_Run_chunks(t *testing.T) {
+	schema := toolkit.DatabaseSchema{
+		{
+			Oid: 1000, Schema: "public", Name: "users", Kind: "r",
+			Columns: []*toolkit.Column{
+				{Idx: 0, Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID, Num: 1},
+				{Idx: 1, Name: "name", TypeName: "text", TypeOid: pgtype.TextOID, Num: 2},
+			},
+		},
+	}
+	first := &entries.Table{Table: schema[0], DumpId: 10}
+	second := &entries.Table{Table: schema[0], DumpId: 11}
+	firstData, secondData := "1\tjohn\n\\.\n\n", "2\tjane\n\\.\n\n"
+
+	root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+	putVerifyDump(t, root.SubStorage("100", true), map[string][]byte{
+		"10.dat.gz": compressTableData(t, firstData),
+		"11.dat.gz": compressTableData(t, secondData),
+		tocFileName: newTransformDumpTestToc(t, first, second),
+	}, &storage.Metadata{
+		DatabaseSchema:    schema,
+		TableOidToDumpId:  map[toolkit.Oid]int32{1000: 10},
+		DumpIdsToTableOid: map[int32]toolkit.Oid{10: 1000, 11: 1000},
+		Entries: []*storage.Entry{
+			{
+				DumpId: 10, ObjectType: toc.TableDataDesc, Schema: "public", Name: "users",
+				FileName: "10.dat.gz", OriginalSize: int64(len(firstData)),
+				Chunk: &storage.TableChunk{Idx: 0, Count: 2, Cond: `"id" < 2`},
+			},
+			{
+				DumpId: 11, ObjectType: toc.TableDataDesc, Schema: "public", Name: "users",
+				FileName: "11.dat.gz", OriginalSize: int64(len(secondData)),
+				Chunk: &storage.TableChunk{Idx: 1, Count: 2, Cond: `"id" >= 2`},
+			},
+		},
+	})
+
+	cfg := &domains.Config{}
+	cfg.Dump.Transformation = []*domains.Table{
+		{
+			Schema: "public",
+			Name:   "users",
+			Transformers: []*domains.TransformerConfig{
+				{
+					Name: transformers.ReplaceTransformerName,
+					Params: toolkit.StaticParameters{
+						"column": toolkit.ParamsValue("name"),
+						"value":  toolkit.ParamsValue("masked"),
+					},
+				},
+			},
+		},
+	}
+	err = NewTransformDump(cfg, root, utils.DefaultTransformerRegistry, "100", "200").Run(context.Background())
+	require.NoError(t, err)
+
+	st := root.SubStorage("200", true)
+	assert.Equal(t, "1\tmasked\n\\.\n\n", readTransformedObject(t, st, "10.dat.gz"))
+	assert.Equal(t, "2\tmasked\n\\.\n\n", readTransformedObject(t, st, "11.dat.gz"))
+
+	md, err := readDumpMetadata(context.Background(), st)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]toolkit.Oid{10: 1000, 11: 1000}, md.DumpIdsToTableOid)
+	require.Len(t, md.Entries, 2)
+	assert.Equal(t, &storage.TableChunk{Idx: 1, Count: 2, Cond: `"id" >= 2`}, md.GetEntryByDumpId(11).Chunk)
+}
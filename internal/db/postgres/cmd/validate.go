@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bufio"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
@@ -14,10 +13,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/cmd/validate_utils"
 	runtimeContext "github.com/greenmaskio/greenmask/internal/db/postgres/context"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/discovery"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/pgcopy"
 	storageDto "github.com/greenmaskio/greenmask/internal/db/postgres/storage"
@@ -88,6 +89,13 @@ func (v *Validate) Run(ctx context.Context) (int, error) {
 		return nonZeroExitCode, fmt.Errorf("error bootstraping custom transformers: %w", err)
 	}
 
+	// The validated data is not dumped, so the generated values are not saved to the mapping stores
+	ctx, mappingRegistry, err := openMappingStores(ctx, v.config.MappingStores, true)
+	if err != nil {
+		return nonZeroExitCode, err
+	}
+	defer closeMappingStores(ctx, mappingRegistry)
+
 	dsn, err := v.pgDumpOptions.GetPgDSN()
 	if err != nil {
 		return nonZeroExitCode, fmt.Errorf("cannot build connection string: %w", err)
@@ -146,6 +154,12 @@ func (v *Validate) Run(ctx context.Context) (int, error) {
 		v.exitCode = nonZeroExitCode
 	}
 
+	if v.config.Validate.Subset {
+		if err = v.printSubsetPlan(ctx, tx); err != nil {
+			return nonZeroExitCode, err
+		}
+	}
+
 	if err = v.diffWithPreviousSchema(ctx); err != nil {
 		return nonZeroExitCode, err
 	}
@@ -165,6 +179,26 @@ func (v *Validate) Run(ctx context.Context) (int, error) {
 	return v.exitCode, nil
 }
 
+// printSubsetPlan - prints the generated subset queries with the row estimates, the cycles and the tables graph
+func (v *Validate) printSubsetPlan(ctx context.Context, tx pgx.Tx) error {
+	plan, err := buildSubsetPlan(
+		ctx, tx, v.context.Graph, v.config.Validate.SubsetAnalyze, v.config.Validate.SubsetTimeout,
+		v.config.Validate.SubsetGraphFormat,
+	)
+	if err != nil {
+		return fmt.Errorf("cannot build subset plan: %w", err)
+	}
+	if v.config.Validate.Format == JsonFormat {
+		err = plan.WriteJson(os.Stdout)
+	} else {
+		err = plan.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot print subset plan: %w", err)
+	}
+	return nil
+}
+
 func (v *Validate) print(ctx context.Context) error {
 	for _, e := range v.dataEntries {
 		idx := slices.IndexFunc(v.context.DataSectionObjectsToValidate, func(entry entries.Entry) bool {
@@ -210,29 +244,23 @@ func (v *Validate) getDocument(table *entries.Table) validate_utils.Documenter {
 }
 
 func (v *Validate) getReader(ctx context.Context, table *entries.Table) (closeFunc, *bufio.Reader, error) {
-	tableData, err := v.st.GetObject(ctx, fmt.Sprintf("%d.dat.gz", table.DumpId))
+	tableData, err := v.st.GetObject(ctx, table.FileName())
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to get object from storage: %w", err)
 	}
 
-	gz, err := gzip.NewReader(tableData)
+	r, err := table.GetCodec().NewReader(tableData)
 	if err != nil {
-		if err := tableData.Close(); err != nil {
-			log.Warn().Err(err).Msg("error closing table data")
-		}
-		return nil, nil, fmt.Errorf("cannot create gzip reader: %w", err)
+		return nil, nil, fmt.Errorf("cannot create decompression reader: %w", err)
 	}
 
 	f := func() {
-		if err := tableData.Close(); err != nil {
+		if err := r.Close(); err != nil {
 			log.Warn().Err(err).Msg("caused error when closing reader object")
 		}
-		if err := gz.Close(); err != nil {
-			log.Warn().Err(err).Msg("caused error when closing gzip reader")
-		}
 	}
 
-	return f, bufio.NewReader(gz), nil
+	return f, bufio.NewReader(r), nil
 }
 
 func (v *Validate) readRecords(r *bufio.Reader, t *entries.Table) (original, transformed *pgcopy.Row, err error) {
@@ -328,9 +356,9 @@ func (v *Validate) diffWithPreviousSchema(ctx context.Context) error {
 		return nil
 	}
 
-	dumpId, err := v.getPreviousDumpId(ctx)
+	dumpId, err := ResolveDumpId(ctx, v.remoteSt, v.config.Validate.SchemaBaseline)
 	if err != nil {
-		return fmt.Errorf("cannot get previous dump id: %w", err)
+		return fmt.Errorf("cannot get baseline dump id: %w", err)
 	}
 	if dumpId == "" {
 		return nil
@@ -342,22 +370,54 @@ func (v *Validate) diffWithPreviousSchema(ctx context.Context) error {
 	}
 
 	diff := md.DatabaseSchema.Diff(v.context.DatabaseSchema)
-	if len(diff) > 0 {
+	if len(diff) == 0 {
+		return nil
+	}
+
+	dc, err := v.getDriftClassifier()
+	if err != nil {
+		return err
+	}
+	drift := dc.Classify(diff, v.context.DatabaseSchema)
+	if slices.ContainsFunc(drift, func(n *validate_utils.DriftNode) bool {
+		return n.IsActionable()
+	}) {
 		v.exitCode = nonZeroExitCode
+	}
 
-		err = v.printSchemaDiff(diff, dumpId)
-		if err != nil {
-			return fmt.Errorf("cannot print schema diff: %w", err)
-		}
+	if err = v.printSchemaDiff(drift, dumpId); err != nil {
+		return fmt.Errorf("cannot print schema diff: %w", err)
 	}
 
 	return nil
 }
 
-func (v *Validate) printSchemaDiff(diff []*toolkit.DiffNode, previousDumpId string) error {
+func (v *Validate) getDriftClassifier() (*validate_utils.DriftClassifier, error) {
+	var allowlist []*validate_utils.DriftAllowlistEntry
+	if v.config.Validate.SchemaAllowlist != "" {
+		var err error
+		allowlist, err = validate_utils.LoadDriftAllowlist(v.config.Validate.SchemaAllowlist)
+		if err != nil {
+			return nil, err
+		}
+	}
+	classifiers, err := discovery.NewClassifiers(v.config.Discover.Classifiers)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build classifiers: %w", err)
+	}
+	minConfidence := v.config.Discover.MinConfidence
+	if minConfidence <= 0 {
+		minConfidence = DefaultDiscoverMinConfidence
+	}
+	return validate_utils.NewDriftClassifier(
+		discovery.TransformedColumns(v.context.DataSectionObjects), classifiers, minConfidence, allowlist,
+	), nil
+}
+
+func (v *Validate) printSchemaDiff(drift []*validate_utils.DriftNode, previousDumpId string) error {
 
 	if v.config.Validate.Format == JsonFormat {
-		data, err := json.Marshal(diff)
+		data, err := json.Marshal(drift)
 		if err != nil {
 			return fmt.Errorf("cannot encode diff node: %w", err)
 		}
@@ -373,9 +433,16 @@ func (v *Validate) printSchemaDiff(diff []*toolkit.DiffNode, previousDumpId stri
 		Str("Hint", "Check schema changes before making new dump").
 		Msg("Database schema has been changed")
 
-	for _, node := range diff {
-		log.Warn().
+	for _, node := range drift {
+		event := log.Info()
+		if node.IsActionable() {
+			event = log.Warn()
+		}
+		event.
 			Str("Event", node.Event).
+			Str("Coverage", node.Coverage).
+			Str("Classifier", node.Classifier).
+			Bool("Allowed", node.Allowed).
 			Any("Signature", node.Signature).
 			Msg(toolkit.DiffEventMsgs[node.Event])
 	}
@@ -383,10 +450,13 @@ func (v *Validate) printSchemaDiff(diff []*toolkit.DiffNode, previousDumpId stri
 	return nil
 }
 
-func (v *Validate) getPreviousDumpId(ctx context.Context) (string, error) {
+// ResolveDumpId - find the dump by id or tag. If baseline is empty the latest dump is returned. The dumps
+// without metadata (in progress or failed) are skipped
+func ResolveDumpId(ctx context.Context, st storages.Storager, baseline string) (string, error) {
 	var backupNames []string
+	var tagged []string
 
-	_, dirs, err := v.remoteSt.ListDir(ctx)
+	_, dirs, err := st.ListDir(ctx)
 	if err != nil {
 		return "", fmt.Errorf("cannot walk through directory: %w", err)
 	}
@@ -395,9 +465,30 @@ func (v *Validate) getPreviousDumpId(ctx context.Context) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("cannot check file existence: %w", err)
 		}
-		if exists {
+		if !exists {
+			continue
+		}
+		if baseline == "" {
 			backupNames = append(backupNames, dir.Dirname())
+			continue
+		}
+		if dir.Dirname() == baseline {
+			return baseline, nil
+		}
+		md, err := readDumpMetadata(ctx, dir)
+		if err != nil {
+			return "", err
 		}
+		if slices.Contains(md.Tags, baseline) {
+			tagged = append(tagged, dir.Dirname())
+		}
+	}
+
+	if baseline != "" {
+		if len(tagged) == 0 {
+			return "", fmt.Errorf("dump with id or tag \"%s\" is not found", baseline)
+		}
+		backupNames = tagged
 	}
 
 	slices.SortFunc(
@@ -415,25 +506,7 @@ func (v *Validate) getPreviousDumpId(ctx context.Context) (string, error) {
 }
 
 func (v *Validate) getPreviousMetadata(ctx context.Context, dumpId string) (*storageDto.Metadata, error) {
-
-	st := v.remoteSt.SubStorage(dumpId, true)
-
-	f, err := st.GetObject(ctx, MetadataJsonFileName)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open metadata file: %w", err)
-	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			log.Warn().Err(err).Msg("error closing metadata file")
-		}
-	}()
-
-	previousMetadata := &storageDto.Metadata{}
-
-	if err = json.NewDecoder(f).Decode(&previousMetadata); err != nil {
-		return nil, fmt.Errorf("cannot decode metadata file: %w", err)
-	}
-	return previousMetadata, nil
+	return readDumpMetadata(ctx, v.remoteSt.SubStorage(dumpId, true))
 }
 
 func findTableBySchemaAndName(Transformations []*domains.Table, schemaName, tableName string) (*domains.Table, error) {
@@ -0,0 +1,269 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/subset"
+)
+
+const (
+	explainSubsetQuery        = `EXPLAIN (FORMAT JSON) %s`
+	explainAnalyzeSubsetQuery = `EXPLAIN (ANALYZE, FORMAT JSON) %s`
+	setStatementTimeoutQuery  = `SET LOCAL statement_timeout = %d`
+)
+
+// referenceIndexExistsQuery - checks there is a non-partial index which leading columns are the reference columns
+const referenceIndexExistsQuery = `
+	SELECT EXISTS(
+		SELECT 1
+		FROM pg_catalog.pg_index i
+		WHERE i.indrelid = $1
+		  AND i.indpred IS NULL
+		  AND (
+			SELECT array_agg(a.attname::TEXT)
+			FROM unnest(i.indkey::INT2[]) WITH ORDINALITY AS k(attnum, ord)
+					 JOIN pg_catalog.pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+			WHERE k.ord <= cardinality($2::TEXT[])
+		  ) @> $2::TEXT[]
+	)
+`
+
+// SubsetPlanReference - the reference of the subset table that does not have an index on the referencing columns
+type SubsetPlanReference struct {
+	Columns          []string `json:"columns"`
+	ReferencedSchema string   `json:"referencedSchema"`
+	ReferencedName   string   `json:"referencedName"`
+}
+
+// SubsetPlanTable - the subset query of the table and its estimates
+type SubsetPlanTable struct {
+	Schema        string   `json:"schema"`
+	Name          string   `json:"name"`
+	Query         string   `json:"query"`
+	EstimatedRows int64    `json:"estimatedRows"`
+	ActualRows    *int64   `json:"actualRows,omitempty"`
+	ExecutionTime *float64 `json:"executionTimeMs,omitempty"`
+	// UnindexedReferences - the references used for the joins that do not have an index on the referencing columns
+	UnindexedReferences []*SubsetPlanReference `json:"unindexedReferences,omitempty"`
+	// Error - the error of the query EXPLAIN, for instance statement timeout
+	Error string `json:"error,omitempty"`
+}
+
+// SubsetPlan - the subset queries of the tables, the cycles and the rendered tables graph
+type SubsetPlan struct {
+	Tables []*SubsetPlanTable `json:"tables"`
+	Cycles [][]string         `json:"cycles,omitempty"`
+	Graph  string             `json:"graph,omitempty"`
+}
+
+// WriteJson - write the plan as JSON document
+func (sp *SubsetPlan) WriteJson(w io.Writer) error {
+	return json.NewEncoder(w).Encode(sp)
+}
+
+// WriteText - write the plan as the summary table followed by the queries, the cycles and the graph
+func (sp *SubsetPlan) WriteText(w io.Writer) error {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"schema", "name", "estimated rows", "actual rows", "time ms", "unindexed references", "error"})
+	for _, t := range sp.Tables {
+		var actualRows, executionTime string
+		if t.ActualRows != nil {
+			actualRows = strconv.FormatInt(*t.ActualRows, 10)
+		}
+		if t.ExecutionTime != nil {
+			executionTime = strconv.FormatFloat(*t.ExecutionTime, 'f', 3, 64)
+		}
+		var refs []string
+		for _, r := range t.UnindexedReferences {
+			refs = append(refs, fmt.Sprintf("(%s) -> %s.%s", strings.Join(r.Columns, ", "), r.ReferencedSchema, r.ReferencedName))
+		}
+		table.Append([]string{
+			t.Schema, t.Name, strconv.FormatInt(t.EstimatedRows, 10), actualRows, executionTime,
+			strings.Join(refs, "; "), t.Error,
+		})
+	}
+	table.Render()
+
+	for _, t := range sp.Tables {
+		if _, err := fmt.Fprintf(w, "\n-- %s.%s\n%s;\n", t.Schema, t.Name, t.Query); err != nil {
+			return err
+		}
+	}
+	if len(sp.Cycles) > 0 {
+		if _, err := fmt.Fprint(w, "\nCycles:\n"); err != nil {
+			return err
+		}
+		for _, c := range sp.Cycles {
+			if _, err := fmt.Fprintf(w, "  %s\n", strings.Join(c, " -> ")); err != nil {
+				return err
+			}
+		}
+	}
+	if sp.Graph != "" {
+		if _, err := fmt.Fprintf(w, "\n%s", sp.Graph); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// explainResult - the part of EXPLAIN (FORMAT JSON) output that is used in the subset plan
+type explainResult struct {
+	Plan struct {
+		PlanRows    float64  `json:"Plan Rows"`
+		ActualRows  *float64 `json:"Actual Rows"`
+		ActualLoops *float64 `json:"Actual Loops"`
+	} `json:"Plan"`
+	ExecutionTime *float64 `json:"Execution Time"`
+}
+
+// buildSubsetPlan - collects the subset queries of the tables and explains them. The EXPLAIN errors are stored in the
+// plan, so the slow query does not prevent checking the rest
+func buildSubsetPlan(
+	ctx context.Context, tx pgx.Tx, graph *subset.Graph, analyze bool, timeout time.Duration, graphFormat string,
+) (*SubsetPlan, error) {
+	plan := &SubsetPlan{
+		Tables: make([]*SubsetPlanTable, 0),
+		Cycles: graph.GetCycledTables(),
+	}
+	for _, t := range graph.GetTables() {
+		if t.Query == "" {
+			continue
+		}
+		pt := &SubsetPlanTable{
+			Schema: t.Schema,
+			Name:   t.Name,
+			Query:  t.Query,
+		}
+		res, err := explainQuery(ctx, tx, t.Query, analyze, timeout)
+		if err != nil {
+			pt.Error = err.Error()
+		} else {
+			setExplainResult(pt, res)
+		}
+		pt.UnindexedReferences, err = getUnindexedReferences(ctx, tx, graph, t)
+		if err != nil {
+			return nil, err
+		}
+		plan.Tables = append(plan.Tables, pt)
+	}
+	if graphFormat != "" {
+		var err error
+		plan.Graph, err = graph.Render(graphFormat)
+		if err != nil {
+			return nil, fmt.Errorf("cannot render graph: %w", err)
+		}
+	}
+	return plan, nil
+}
+
+// explainQuery - explains the query in the savepoint, so the statement timeout is reset and the main transaction
+// is usable after the error
+func explainQuery(
+	ctx context.Context, tx pgx.Tx, query string, analyze bool, timeout time.Duration,
+) (*explainResult, error) {
+	stx, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create savepoint: %w", err)
+	}
+	defer stx.Rollback(ctx) // nolint: errcheck
+
+	if timeout > 0 {
+		if _, err = stx.Exec(ctx, fmt.Sprintf(setStatementTimeoutQuery, timeout.Milliseconds())); err != nil {
+			return nil, fmt.Errorf("cannot set statement timeout: %w", err)
+		}
+	}
+	explain := explainSubsetQuery
+	if analyze {
+		explain = explainAnalyzeSubsetQuery
+	}
+	var data []byte
+	if err = stx.QueryRow(ctx, fmt.Sprintf(explain, query)).Scan(&data); err != nil {
+		return nil, fmt.Errorf("cannot explain query: %w", err)
+	}
+	return parseExplainOutput(data)
+}
+
+func parseExplainOutput(data []byte) (*explainResult, error) {
+	var res []*explainResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("cannot parse explain output: %w", err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("empty explain output")
+	}
+	return res[0], nil
+}
+
+func setExplainResult(pt *SubsetPlanTable, res *explainResult) {
+	pt.EstimatedRows = int64(res.Plan.PlanRows)
+	if res.Plan.ActualRows != nil {
+		loops := 1.0
+		if res.Plan.ActualLoops != nil {
+			loops = *res.Plan.ActualLoops
+		}
+		actualRows := int64(*res.Plan.ActualRows * loops)
+		pt.ActualRows = &actualRows
+	}
+	pt.ExecutionTime = res.ExecutionTime
+}
+
+// getUnindexedReferences - finds the references of the table that do not have an index on the referencing
+// columns. The references with expressions are skipped
+func getUnindexedReferences(
+	ctx context.Context, tx pgx.Tx, graph *subset.Graph, t *entries.Table,
+) ([]*SubsetPlanReference, error) {
+	var res []*SubsetPlanReference
+	for _, e := range graph.Edges() {
+		if e.From().Table().Oid != t.Oid {
+			continue
+		}
+		var columns []string
+		for _, k := range e.From().Keys() {
+			if k.Expression != "" {
+				columns = nil
+				break
+			}
+			columns = append(columns, k.Name)
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		var exists bool
+		if err := tx.QueryRow(ctx, referenceIndexExistsQuery, t.Oid, columns).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("cannot check index of table %s.%s: %w", t.Schema, t.Name, err)
+		}
+		if !exists {
+			res = append(res, &SubsetPlanReference{
+				Columns:          columns,
+				ReferencedSchema: e.To().Table().Schema,
+				ReferencedName:   e.To().Table().Name,
+			})
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExplainOutput(t *testing.T) {
+	t.Run("explain", func(t *testing.T) {
+		res, err := parseExplainOutput([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 1234}}]`))
+		require.NoError(t, err)
+		pt := &SubsetPlanTable{}
+		setExplainResult(pt, res)
+		assert.Equal(t, int64(1234), pt.EstimatedRows)
+		assert.Nil(t, pt.ActualRows)
+		assert.Nil(t, pt.ExecutionTime)
+	})
+
+	t.Run("explain analyze", func(t *testing.T) {
+		res, err := parseExplainOutput([]byte(
+			`[{"Plan": {"Plan Rows": 100, "Actual Rows": 20, "Actual Loops": 2}, "Execution Time": 1.5}]`,
+		))
+		require.NoError(t, err)
+		pt := &SubsetPlanTable{}
+		setExplainResult(pt, res)
+		assert.Equal(t, int64(100), pt.EstimatedRows)
+		require.NotNil(t, pt.ActualRows)
+		assert.Equal(t, int64(40), *pt.ActualRows)
+		require.NotNil(t, pt.ExecutionTime)
+		assert.Equal(t, 1.5, *pt.ExecutionTime)
+	})
+
+	t.Run("empty output", func(t *testing.T) {
+		_, err := parseExplainOutput([]byte(`[]`))
+		require.ErrorContains(t, err, "empty explain output")
+	})
+}
+
+func TestSubsetPlan_WriteText(t *testing.T) {
+	actualRows := int64(10)
+	plan := &SubsetPlan{
+		Tables: []*SubsetPlanTable{
+			{
+				Schema:        "public",
+				Name:          "orders",
+				Query:         `SELECT "public"."orders".* FROM "public"."orders"`,
+				EstimatedRows: 12,
+				ActualRows:    &actualRows,
+				UnindexedReferences: []*SubsetPlanReference{
+					{Columns: []string{"user_id"}, ReferencedSchema: "public", ReferencedName: "users"},
+				},
+			},
+		},
+		Cycles: [][]string{{"public.a", "public.b", "public.a"}},
+		Graph:  "digraph subset {\n}\n",
+	}
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, plan.WriteText(buf))
+	res := buf.String()
+	assert.Contains(t, res, "(user_id) -> public.users")
+	assert.Contains(t, res, "-- public.orders\nSELECT \"public\".\"orders\".* FROM \"public\".\"orders\";\n")
+	assert.Contains(t, res, "Cycles:\n  public.a -> public.b -> public.a\n")
+	assert.Contains(t, res, "\ndigraph subset {\n}\n")
+}
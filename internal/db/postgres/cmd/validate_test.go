@@ -0,0 +1,72 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/storages/directory"
+)
+
+func TestResolveDumpId(t *testing.T) {
+	ctx := context.Background()
+	root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+
+	id, err := ResolveDumpId(ctx, root, "")
+	require.NoError(t, err)
+	assert.Empty(t, id)
+
+	for dumpId, tags := range map[string][]string{
+		"100": {"release-1"},
+		"200": {"release-1", "nightly"},
+		"300": nil,
+	} {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, json.NewEncoder(buf).Encode(&storage.Metadata{Tags: tags}))
+		require.NoError(t, root.SubStorage(dumpId, true).PutObject(ctx, MetadataJsonFileName, buf))
+	}
+	// The dump in progress does not have metadata and must be skipped
+	require.NoError(t, root.SubStorage("400", true).PutObject(ctx, "toc.dat", bytes.NewBuffer(nil)))
+
+	tests := []struct {
+		baseline string
+		expected string
+	}{
+		{baseline: "", expected: "300"},
+		{baseline: "100", expected: "100"},
+		{baseline: "release-1", expected: "200"},
+		{baseline: "nightly", expected: "200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.baseline, func(t *testing.T) {
+			id, err := ResolveDumpId(ctx, root, tt.baseline)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, id)
+		})
+	}
+
+	_, err = ResolveDumpId(ctx, root, "400")
+	require.Error(t, err)
+	_, err = ResolveDumpId(ctx, root, "unknown")
+	require.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package validate_utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/discovery"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const (
+	// DriftCovered - the column is transformed by the transformation config
+	DriftCovered = "covered"
+	// DriftUncoveredInTransformedTable - the column is not transformed, but the table has transformers
+	DriftUncoveredInTransformedTable = "uncovered_in_transformed_table"
+	// DriftUncoveredPii - the column is not transformed and matches the PII classifier
+	DriftUncoveredPii = "uncovered_pii"
+	// DriftHarmless - the change does not require any changes in the transformation config
+	DriftHarmless = "harmless"
+)
+
+// DriftNode - schema change classified by the transformation config coverage
+type DriftNode struct {
+	Event      string            `json:"event"`
+	Signature  map[string]string `json:"signature"`
+	Schema     string            `json:"schema"`
+	Table      string            `json:"table"`
+	Column     string            `json:"column,omitempty"`
+	Coverage   string            `json:"coverage"`
+	Classifier string            `json:"classifier,omitempty"`
+	// Allowed - the change is listed in the allowlist
+	Allowed bool `json:"allowed"`
+}
+
+// IsActionable - the change is not covered by the transformation config and is not allowed
+func (dn *DriftNode) IsActionable() bool {
+	if dn.Allowed {
+		return false
+	}
+	return dn.Coverage == DriftUncoveredInTransformedTable || dn.Coverage == DriftUncoveredPii
+}
+
+// DriftAllowlistEntry - schema change that must not fail the validation. Schema, Table and Column are the
+// path.Match patterns. Empty field matches any value
+type DriftAllowlistEntry struct {
+	Schema string `yaml:"schema" json:"schema"`
+	Table  string `yaml:"table" json:"table"`
+	Column string `yaml:"column" json:"column"`
+	Event  string `yaml:"event" json:"event"`
+	Reason string `yaml:"reason" json:"reason"`
+}
+
+func (e *DriftAllowlistEntry) Match(n *DriftNode) bool {
+	if e.Event != "" && e.Event != n.Event {
+		return false
+	}
+	return matchPattern(e.Schema, n.Schema) && matchPattern(e.Table, n.Table) && matchPattern(e.Column, n.Column)
+}
+
+// LoadDriftAllowlist - read the allowlist YAML file
+func LoadDriftAllowlist(fileName string) ([]*DriftAllowlistEntry, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read allowlist file: %w", err)
+	}
+	var res []*DriftAllowlistEntry
+	if err = yaml.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("cannot decode allowlist file: %w", err)
+	}
+	for idx, e := range res {
+		for _, pattern := range []string{e.Schema, e.Table, e.Column} {
+			if _, err = path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("allowlist entry %d: wrong pattern \"%s\": %w", idx, pattern, err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// DriftClassifier - classifies the schema changes using the transformed columns of the transformation config and
+// the PII classifiers
+type DriftClassifier struct {
+	transformed   map[discovery.ColumnKey]struct{}
+	classifiers   []*discovery.Classifier
+	minConfidence float64
+	allowlist     []*DriftAllowlistEntry
+}
+
+func NewDriftClassifier(
+	transformed map[discovery.ColumnKey]struct{}, classifiers []*discovery.Classifier, minConfidence float64,
+	allowlist []*DriftAllowlistEntry,
+) *DriftClassifier {
+	return &DriftClassifier{
+		transformed:   transformed,
+		classifiers:   classifiers,
+		minConfidence: minConfidence,
+		allowlist:     allowlist,
+	}
+}
+
+// Classify - classify the schema diff. The created table is reported per column
+func (dc *DriftClassifier) Classify(diff []*toolkit.DiffNode, current toolkit.DatabaseSchema) []*DriftNode {
+	var res []*DriftNode
+	for _, node := range diff {
+		switch node.Event {
+		case toolkit.TableCreatedDiffEvent:
+			schemaName, tableName := node.Signature["SchemaName"], node.Signature["TableName"]
+			var columns []string
+			for _, t := range current {
+				if t.Schema == schemaName && t.Name == tableName {
+					for _, c := range t.Columns {
+						columns = append(columns, c.Name)
+					}
+					break
+				}
+			}
+			if len(columns) == 0 {
+				res = append(res, dc.newNode(node, schemaName, tableName, ""))
+			}
+			for _, c := range columns {
+				res = append(res, dc.newNode(node, schemaName, tableName, c))
+			}
+		case toolkit.ColumnCreatedDiffEvent, toolkit.ColumnTypeChangedDiffEvent:
+			res = append(res, dc.newNode(
+				node, node.Signature["TableSchema"], node.Signature["TableName"], node.Signature["ColumnName"],
+			))
+		case toolkit.ColumnRenamedDiffEvent:
+			res = append(res, dc.newNode(
+				node, node.Signature["TableSchema"], node.Signature["TableName"], node.Signature["CurrentColumnName"],
+			))
+		case toolkit.TableRenamedDiffEvent:
+			res = append(res, dc.newNode(node, node.Signature["SchemaName"], node.Signature["CurrentTableName"], ""))
+		case toolkit.TableMovedToAnotherSchemaDiffEvent:
+			res = append(res, dc.newNode(node, node.Signature["CurrentSchemaName"], node.Signature["TableName"], ""))
+		default:
+			res = append(res, dc.newNode(node, "", "", ""))
+		}
+	}
+	return res
+}
+
+func (dc *DriftClassifier) newNode(node *toolkit.DiffNode, schemaName, tableName, columnName string) *DriftNode {
+	res := &DriftNode{
+		Event:     node.Event,
+		Signature: node.Signature,
+		Schema:    schemaName,
+		Table:     tableName,
+		Column:    columnName,
+		Coverage:  DriftHarmless,
+	}
+	if columnName != "" {
+		_, covered := dc.transformed[discovery.ColumnKey{Schema: schemaName, Table: tableName, Column: columnName}]
+		_, tableTransformed := dc.transformed[discovery.ColumnKey{Schema: schemaName, Table: tableName}]
+		if covered {
+			res.Coverage = DriftCovered
+		} else if cl := discovery.ClassifyColumn(dc.classifiers, columnName, nil, dc.minConfidence); cl != nil {
+			res.Coverage = DriftUncoveredPii
+			res.Classifier = cl.Classifier.Name
+		} else if tableTransformed {
+			res.Coverage = DriftUncoveredInTransformedTable
+		}
+	}
+	for _, e := range dc.allowlist {
+		if e.Match(res) {
+			res.Allowed = true
+			break
+		}
+	}
+	return res
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
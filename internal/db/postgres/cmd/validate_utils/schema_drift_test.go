@@ -0,0 +1,129 @@
+package validate_utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/discovery"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func newTestDiff() []*toolkit.DiffNode {
+	return []*toolkit.DiffNode{
+		{
+			Event: toolkit.ColumnCreatedDiffEvent,
+			Signature: map[string]string{
+				"TableSchema": "public", "TableName": "users", "ColumnName": "email", "ColumnType": "text",
+			},
+		},
+		{
+			Event: toolkit.ColumnCreatedDiffEvent,
+			Signature: map[string]string{
+				"TableSchema": "public", "TableName": "users", "ColumnName": "nickname", "ColumnType": "text",
+			},
+		},
+		{
+			Event: toolkit.ColumnTypeChangedDiffEvent,
+			Signature: map[string]string{
+				"TableSchema": "public", "TableName": "orders", "ColumnName": "total",
+				"PreviousColumnType": "integer", "CurrentColumnType": "numeric",
+			},
+		},
+		{
+			Event: toolkit.ColumnCreatedDiffEvent,
+			Signature: map[string]string{
+				"TableSchema": "public", "TableName": "orders", "ColumnName": "phone", "ColumnType": "text",
+			},
+		},
+		{
+			Event:     toolkit.TableCreatedDiffEvent,
+			Signature: map[string]string{"SchemaName": "audit", "TableName": "log", "TableOid": "100"},
+		},
+		{
+			Event: toolkit.TableRenamedDiffEvent,
+			Signature: map[string]string{
+				"SchemaName": "public", "PreviousTableName": "items", "CurrentTableName": "products",
+			},
+		},
+	}
+}
+
+func newTestDriftClassifier(allowlist []*DriftAllowlistEntry) *DriftClassifier {
+	transformed := map[discovery.ColumnKey]struct{}{
+		{Schema: "public", Table: "users"}:                   {},
+		{Schema: "public", Table: "users", Column: "email"}:  {},
+		{Schema: "public", Table: "orders"}:                  {},
+		{Schema: "public", Table: "orders", Column: "total"}: {},
+	}
+	return NewDriftClassifier(transformed, discovery.DefaultClassifiers(), 0.5, allowlist)
+}
+
+func TestDriftClassifier_Classify(t *testing.T) {
+	current := toolkit.DatabaseSchema{
+		{
+			Schema: "audit", Name: "log",
+			Columns: []*toolkit.Column{{Name: "id"}, {Name: "ip_address"}},
+		},
+	}
+	res := newTestDriftClassifier(nil).Classify(newTestDiff(), current)
+
+	type expected struct {
+		table, column, coverage, classifier string
+		actionable                          bool
+	}
+	want := []expected{
+		{"users", "email", DriftCovered, "", false},
+		{"users", "nickname", DriftUncoveredInTransformedTable, "", true},
+		{"orders", "total", DriftCovered, "", false},
+		{"orders", "phone", DriftUncoveredPii, discovery.PhoneClassifierName, true},
+		{"log", "id", DriftHarmless, "", false},
+		{"log", "ip_address", DriftUncoveredPii, discovery.IpClassifierName, true},
+		{"products", "", DriftHarmless, "", false},
+	}
+	require.Len(t, res, len(want))
+	for idx, w := range want {
+		assert.Equal(t, w.table, res[idx].Table)
+		assert.Equal(t, w.column, res[idx].Column)
+		assert.Equal(t, w.coverage, res[idx].Coverage, w.column)
+		assert.Equal(t, w.classifier, res[idx].Classifier, w.column)
+		assert.Equal(t, w.actionable, res[idx].IsActionable(), w.column)
+	}
+}
+
+func TestDriftClassifier_Allowlist(t *testing.T) {
+	allowlist := []*DriftAllowlistEntry{
+		{Schema: "public", Table: "users", Column: "nick*"},
+		{Schema: "audit", Event: toolkit.TableCreatedDiffEvent},
+	}
+	res := newTestDriftClassifier(allowlist).Classify(newTestDiff(), nil)
+	var actionable []string
+	for _, n := range res {
+		if n.IsActionable() {
+			actionable = append(actionable, n.Table+"."+n.Column)
+		}
+	}
+	assert.Equal(t, []string{"orders.phone"}, actionable)
+}
+
+func TestLoadDriftAllowlist(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "allowlist.yml")
+	data := `
+- schema: public
+  table: "audit_*"
+  reason: "audit tables do not contain PII"
+`
+	require.NoError(t, os.WriteFile(fileName, []byte(data), 0600))
+	res, err := LoadDriftAllowlist(fileName)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.True(t, res[0].Match(&DriftNode{Schema: "public", Table: "audit_2024", Column: "data"}))
+	assert.False(t, res[0].Match(&DriftNode{Schema: "public", Table: "users", Column: "data"}))
+
+	require.NoError(t, os.WriteFile(fileName, []byte(`- column: "legacy_["`), 0600))
+	_, err = LoadDriftAllowlist(fileName)
+	require.Error(t, err)
+}
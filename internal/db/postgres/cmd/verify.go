@@ -0,0 +1,426 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog/log"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgcopy"
+	storageDto "github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
+	"github.com/greenmaskio/greenmask/internal/utils/reader"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const (
+	VerifyPassedStatus = "passed"
+	VerifyFailedStatus = "failed"
+)
+
+const tocFileName = "toc.dat"
+
+var (
+	errCopyTerminatorNotFound = errors.New("COPY stream is not terminated with \\.")
+	errDataAfterTerminator    = errors.New("COPY stream contains data after \\.")
+)
+
+// VerifyResult - the verification result of the dump object
+type VerifyResult struct {
+	DumpId     int32  `json:"dumpId"`
+	ObjectType string `json:"objectType"`
+	Schema     string `json:"schema,omitempty"`
+	Name       string `json:"name,omitempty"`
+	FileName   string `json:"fileName"`
+	// ReferencedDumpId - the dump that physically stores the object if the dump is incremental
+	ReferencedDumpId string   `json:"referencedDumpId,omitempty"`
+	Status           string   `json:"status"`
+	Errors           []string `json:"errors,omitempty"`
+	OriginalSize     int64    `json:"originalSize"`
+	CompressedSize   int64    `json:"compressedSize"`
+	Checksum         string   `json:"checksum,omitempty"`
+	Rows             int64    `json:"rows"`
+}
+
+func (vr *VerifyResult) addError(format string, args ...any) {
+	vr.Errors = append(vr.Errors, fmt.Sprintf(format, args...))
+}
+
+// VerifyReport - the verification result of the dump
+type VerifyReport struct {
+	DumpId  string          `json:"dumpId"`
+	Passed  bool            `json:"passed"`
+	Entries []*VerifyResult `json:"entries"`
+}
+
+// WriteJson - write the report as JSON document
+func (vr *VerifyReport) WriteJson(w io.Writer) error {
+	return json.NewEncoder(w).Encode(vr)
+}
+
+// WriteText - write the report as the table
+func (vr *VerifyReport) WriteText(w io.Writer) error {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"dump id", "type", "schema", "name", "file", "rows", "status", "errors"})
+	for _, e := range vr.Entries {
+		fileName := e.FileName
+		if e.ReferencedDumpId != "" {
+			fileName = fmt.Sprintf("%s/%s", e.ReferencedDumpId, e.FileName)
+		}
+		table.Append([]string{
+			strconv.FormatInt(int64(e.DumpId), 10), e.ObjectType, e.Schema, e.Name, fileName,
+			strconv.FormatInt(e.Rows, 10), e.Status, strings.Join(e.Errors, "; "),
+		})
+	}
+	table.Render()
+	status := VerifyPassedStatus
+	if !vr.Passed {
+		status = VerifyFailedStatus
+	}
+	_, err := fmt.Fprintf(w, "dump %s verification %s\n", vr.DumpId, status)
+	return err
+}
+
+// Verify - checks the dump objects integrity against the dump metadata
+type Verify struct {
+	st     storages.Storager
+	rootSt storages.Storager
+	dumpId string
+	// parseRows - decode every COPY row using the number of columns from the dump schema
+	parseRows bool
+}
+
+func NewVerify(rootSt storages.Storager, dumpId string, parseRows bool) *Verify {
+	return &Verify{
+		st:        rootSt.SubStorage(dumpId, true),
+		rootSt:    rootSt,
+		dumpId:    dumpId,
+		parseRows: parseRows,
+	}
+}
+
+func (v *Verify) Run(ctx context.Context) (*VerifyReport, error) {
+	md, err := readDumpMetadata(ctx, v.st)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{DumpId: v.dumpId, Passed: true}
+	report.Entries = append(report.Entries, v.verifyToc(ctx))
+	for _, e := range md.Entries {
+		var res *VerifyResult
+		switch e.ObjectType {
+		case toc.TableDataDesc:
+			res = v.verifyTableData(ctx, md, e)
+		case toc.BlobsDesc:
+			res = v.verifyBlobs(ctx, md, e)
+		default:
+			continue
+		}
+		report.Entries = append(report.Entries, res)
+	}
+
+	for _, res := range report.Entries {
+		if res.Status == VerifyFailedStatus {
+			report.Passed = false
+		}
+	}
+	return report, nil
+}
+
+func (v *Verify) verifyToc(ctx context.Context) *VerifyResult {
+	res := &VerifyResult{ObjectType: "TOC", FileName: tocFileName, Status: VerifyPassedStatus}
+	f, err := v.st.GetObject(ctx, tocFileName)
+	if err != nil {
+		res.Status = VerifyFailedStatus
+		res.addError("cannot open object: %s", err)
+		return res
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing toc file")
+		}
+	}()
+	tocObj, err := toc.NewReader(f).Read()
+	if err != nil {
+		res.Status = VerifyFailedStatus
+		res.addError("cannot read toc: %s", err)
+		return res
+	}
+	res.Rows = int64(len(tocObj.Entries))
+	return res
+}
+
+func (v *Verify) verifyTableData(ctx context.Context, md *storageDto.Metadata, e *storageDto.Entry) *VerifyResult {
+	res := &VerifyResult{
+		DumpId:     e.DumpId,
+		ObjectType: e.ObjectType,
+		Schema:     e.Schema,
+		Name:       e.Name,
+		FileName:   e.FileName,
+		Status:     VerifyPassedStatus,
+	}
+	st := v.st
+	if e.Reference != nil {
+		res.ReferencedDumpId = e.Reference.DumpId
+		res.FileName = e.Reference.FileName
+		st = v.rootSt.SubStorage(e.Reference.DumpId, true)
+	}
+
+	columns := -1
+	if v.parseRows {
+		columns = getCopyColumnsCount(md, e.DumpId)
+	}
+
+	f, err := st.GetObject(ctx, res.FileName)
+	if err != nil {
+		res.Status = VerifyFailedStatus
+		res.addError("cannot open object: %s", err)
+		return res
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing table data object")
+		}
+	}()
+	counted := ioutils.NewReader(f)
+	compressed := ioutils.NewChecksumReader(counted)
+	codec := ioutils.GetCodecByFileName(res.FileName, false)
+	res.Rows, res.OriginalSize, err = verifyCompressedCopyStream(compressed, codec, columns)
+	// Read the rest of the object if the stream is broken, so the checksum and size of the whole object are calculated
+	if _, drainErr := io.Copy(io.Discard, compressed); drainErr != nil {
+		res.addError("cannot read object: %s", drainErr)
+	}
+	res.CompressedSize = counted.GetCount()
+	res.Checksum = compressed.Checksum()
+	if err != nil {
+		res.addError("%s", err)
+	}
+
+	if res.CompressedSize != e.CompressedSize {
+		res.addError("compressed size mismatch: expected %d got %d", e.CompressedSize, res.CompressedSize)
+	}
+	if res.OriginalSize != e.OriginalSize {
+		res.addError("original size mismatch: expected %d got %d", e.OriginalSize, res.OriginalSize)
+	}
+	if e.Checksum != "" && res.Checksum != e.Checksum {
+		res.addError("checksum mismatch: expected %s got %s", e.Checksum, res.Checksum)
+	}
+	if len(res.Errors) > 0 {
+		res.Status = VerifyFailedStatus
+	}
+	return res
+}
+
+func (v *Verify) verifyBlobs(ctx context.Context, md *storageDto.Metadata, e *storageDto.Entry) *VerifyResult {
+	res := &VerifyResult{
+		DumpId:     e.DumpId,
+		ObjectType: e.ObjectType,
+		FileName:   e.FileName,
+		Status:     VerifyPassedStatus,
+	}
+	codec, err := ioutils.GetCodecByName(md.GetCodecName(), false)
+	if err != nil {
+		res.Status = VerifyFailedStatus
+		res.addError("%s", err)
+		return res
+	}
+	f, err := v.st.GetObject(ctx, e.FileName)
+	if err != nil {
+		res.Status = VerifyFailedStatus
+		res.addError("cannot open object: %s", err)
+		return res
+	}
+	data, err := io.ReadAll(f)
+	if closeErr := f.Close(); closeErr != nil {
+		log.Warn().Err(closeErr).Msg("error closing blobs toc")
+	}
+	if err != nil {
+		res.Status = VerifyFailedStatus
+		res.addError("cannot read object: %s", err)
+		return res
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		_, fileName, ok := strings.Cut(line, " ")
+		if !ok {
+			res.addError("wrong blobs toc line \"%s\"", line)
+			continue
+		}
+		fileName += codec.Extension()
+		size, compressedSize, err := v.verifyBlob(ctx, fileName, codec)
+		if err != nil {
+			res.addError("%s: %s", fileName, err)
+			continue
+		}
+		res.OriginalSize += size
+		res.CompressedSize += compressedSize
+		res.Rows++
+	}
+	if len(res.Errors) > 0 {
+		res.Status = VerifyFailedStatus
+	}
+	return res
+}
+
+func (v *Verify) verifyBlob(ctx context.Context, fileName string, codec ioutils.Codec) (int64, int64, error) {
+	f, err := v.st.GetObject(ctx, fileName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot open object: %w", err)
+	}
+	compressed := ioutils.NewReader(f)
+	gz, err := codec.NewReader(compressed)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err := gz.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing blob reader")
+		}
+	}()
+	size, err := io.Copy(io.Discard, gz)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot decompress object: %w", err)
+	}
+	return size, compressed.GetCount(), nil
+}
+
+// getCopyColumnsCount - get the number of the columns in the COPY stream of the table. It returns -1 if the
+// table is not found in the dump schema or the table is dumped using custom query
+func getCopyColumnsCount(md *storageDto.Metadata, dumpId int32) int {
+	oid, ok := md.DumpIdsToTableOid[dumpId]
+	if !ok {
+		return -1
+	}
+	idx := slices.IndexFunc(md.DatabaseSchema, func(t *toolkit.Table) bool {
+		return t.Oid == oid
+	})
+	if idx == -1 {
+		return -1
+	}
+	table := md.DatabaseSchema[idx]
+	if slices.ContainsFunc(md.Transformers, func(t *domains.Table) bool {
+		return t.Schema == table.Schema && t.Name == table.Name && t.Query != ""
+	}) {
+		return -1
+	}
+	var res int
+	for _, c := range table.Columns {
+		if !c.IsGenerated {
+			res++
+		}
+	}
+	if res == 0 {
+		return -1
+	}
+	return res
+}
+
+// verifyCompressedCopyStream - decompress the COPY stream and check it. It returns the number of rows and the size of
+// the decompressed data. The reader is not closed
+func verifyCompressedCopyStream(r io.Reader, codec ioutils.Codec, columns int) (int64, int64, error) {
+	gz, err := codec.NewReader(io.NopCloser(r))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err := gz.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing table data reader")
+		}
+	}()
+	decompressed := ioutils.NewReader(gz)
+	rows, err := verifyCopyStream(decompressed, columns)
+	if _, copyErr := io.Copy(io.Discard, decompressed); copyErr != nil && err == nil {
+		err = fmt.Errorf("cannot decompress object: %w", copyErr)
+	}
+	return rows, decompressed.GetCount(), err
+}
+
+// verifyCopyStream - check the COPY stream is terminated with \. and, if columns is not -1, decode each row
+// and check the number of columns
+func verifyCopyStream(r io.Reader, columns int) (int64, error) {
+	var row *pgcopy.Row
+	if columns > 0 {
+		row = pgcopy.NewRow(columns)
+	}
+	var rows int64
+	var line []byte
+	var err error
+	br := bufio.NewReader(r)
+	for {
+		line, err = reader.ReadLine(br, line)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return rows, errCopyTerminatorNotFound
+			}
+			return rows, fmt.Errorf("cannot read COPY stream: %w", err)
+		}
+		if bytes.Equal(line, pgcopy.DefaultCopyTerminationSeq) {
+			break
+		}
+		rows++
+		if row != nil {
+			if err = verifyCopyRow(row, line, columns); err != nil {
+				return rows, fmt.Errorf("line %d: %w", rows, err)
+			}
+		}
+	}
+
+	// Only empty lines are allowed after the terminator
+	for {
+		line, err = reader.ReadLine(br, line)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return rows, nil
+			}
+			return rows, fmt.Errorf("cannot read COPY stream: %w", err)
+		}
+		if len(line) > 0 {
+			return rows, errDataAfterTerminator
+		}
+	}
+}
+
+func verifyCopyRow(row *pgcopy.Row, line []byte, columns int) error {
+	if err := row.Decode(line); err != nil {
+		return fmt.Errorf("cannot decode row: %w", err)
+	}
+	if got := bytes.Count(line, []byte{pgcopy.DefaultCopyDelimiter}) + 1; got != columns {
+		return fmt.Errorf("expected %d columns got %d", columns, got)
+	}
+	for idx := 0; idx < columns; idx++ {
+		if _, err := row.GetColumn(idx); err != nil {
+			return fmt.Errorf("cannot decode column %d: %w", idx, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/storage"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/storages/directory"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func TestVerifyCopyStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		columns int
+		rows    int64
+		wantErr string
+	}{
+		{name: "valid", data: "1\ta\n2\tb\n\\.\n\n\n", columns: 2, rows: 2},
+		{name: "rows are not parsed", data: "1\ta\tc\n\\.\n", columns: -1, rows: 1},
+		{name: "empty table", data: "\\.\n\n", columns: 2, rows: 0},
+		{name: "terminator not found", data: "1\ta\n2\tb\n", columns: 2, rows: 2, wantErr: "not terminated"},
+		{name: "data after terminator", data: "1\ta\n\\.\n2\tb\n", columns: 2, rows: 1, wantErr: "data after"},
+		{name: "wrong columns count", data: "1\ta\n2\n\\.\n", columns: 2, rows: 2, wantErr: "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := verifyCopyStream(strings.NewReader(tt.data), tt.columns)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.rows, rows)
+		})
+	}
+}
+
+func compressTableData(t *testing.T, data string) []byte {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	_, err := gz.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func putVerifyDump(t *testing.T, st storages.Storager, objects map[string][]byte, md *storage.Metadata) {
+	ctx := context.Background()
+	for name, data := range objects {
+		require.NoError(t, st.PutObject(ctx, name, bytes.NewReader(data)))
+	}
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, json.NewEncoder(buf).Encode(md))
+	require.NoError(t, st.PutObject(ctx, MetadataJsonFileName, buf))
+}
+
+func TestVerify_Run(t *testing.T) {
+	data := "1\tjohn\n2\tjane\n\\.\n\n"
+	compressed := compressTableData(t, data)
+	sum := sha256.Sum256(compressed)
+
+	newMetadata := func() *storage.Metadata {
+		return &storage.Metadata{
+			DumpIdsToTableOid: map[int32]toolkit.Oid{10: 1000},
+			DatabaseSchema: []*toolkit.Table{
+				{
+					Oid: 1000, Schema: "public", Name: "users",
+					Columns: []*toolkit.Column{{Name: "id"}, {Name: "name"}},
+				},
+			},
+			Entries: []*storage.Entry{
+				{
+					DumpId: 10, ObjectType: toc.TableDataDesc, Schema: "public", Name: "users",
+					FileName: "10.dat.gz", OriginalSize: int64(len(data)), CompressedSize: int64(len(compressed)),
+					Checksum: hex.EncodeToString(sum[:]),
+				},
+			},
+		}
+	}
+
+	t.Run("passed", func(t *testing.T) {
+		root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+		require.NoError(t, err)
+		putVerifyDump(t, root.SubStorage("100", true), map[string][]byte{"10.dat.gz": compressed}, newMetadata())
+
+		report, err := NewVerify(root, "100", true).Run(context.Background())
+		require.NoError(t, err)
+		// toc.dat is not written in this test
+		require.Len(t, report.Entries, 2)
+		assert.Equal(t, VerifyFailedStatus, report.Entries[0].Status)
+		users := report.Entries[1]
+		assert.Equal(t, VerifyPassedStatus, users.Status, users.Errors)
+		assert.Equal(t, int64(2), users.Rows)
+		assert.Equal(t, int64(len(data)), users.OriginalSize)
+	})
+
+	t.Run("corrupted object", func(t *testing.T) {
+		root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+		require.NoError(t, err)
+		corrupted := bytes.Clone(compressed)
+		corrupted[len(corrupted)-9] ^= 0xff
+		putVerifyDump(t, root.SubStorage("100", true), map[string][]byte{"10.dat.gz": corrupted}, newMetadata())
+
+		report, err := NewVerify(root, "100", false).Run(context.Background())
+		require.NoError(t, err)
+		assert.False(t, report.Passed)
+		users := report.Entries[1]
+		assert.Equal(t, VerifyFailedStatus, users.Status)
+		assert.Contains(t, strings.Join(users.Errors, "; "), "checksum mismatch")
+	})
+
+	t.Run("referenced object", func(t *testing.T) {
+		root, err := directory.NewStorage(&directory.Config{Path: t.TempDir()})
+		require.NoError(t, err)
+		putVerifyDump(t, root.SubStorage("100", true), map[string][]byte{"7.dat.gz": compressed}, &storage.Metadata{})
+		md := newMetadata()
+		md.Entries[0].Reference = &storage.ObjectReference{DumpId: "100", FileName: "7.dat.gz"}
+		putVerifyDump(t, root.SubStorage("200", true), nil, md)
+
+		report, err := NewVerify(root, "200", true).Run(context.Background())
+		require.NoError(t, err)
+		users := report.Entries[1]
+		assert.Equal(t, VerifyPassedStatus, users.Status, users.Errors)
+		assert.Equal(t, "100", users.ReferencedDumpId)
+		assert.Equal(t, "7.dat.gz", users.FileName)
+	})
+}
@@ -0,0 +1,293 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	transformersUtils "github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const (
+	ChunkByPk   = "pk"
+	ChunkByCtid = "ctid"
+)
+
+const (
+	chunkPkRangeQuery = `SELECT min(%[1]s)::INT8, max(%[1]s)::INT8 FROM ONLY %[2]s`
+	chunkPagesQuery   = `SELECT (pg_catalog.pg_relation_size($1::OID) / current_setting('block_size')::INT8)::INT8`
+)
+
+// SplitTablesIntoChunks - splits the tables that have chunks setting in the transformation config into the chunks.
+// The chunk ranges are calculated in the snapshot of tx, so the chunks dumped in the same snapshot cover the
+// whole table data. Each chunk is a separate table entry with its own transformers, so the chunks can be dumped
+// concurrently
+func (rc *RuntimeContext) SplitTablesIntoChunks(ctx context.Context, tx pgx.Tx, cfg []*domains.Table) error {
+	ctx, err := withSalt(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot set salt: %w", err)
+	}
+	res := make([]entries.Entry, 0, len(rc.DataSectionObjects))
+	for _, obj := range rc.DataSectionObjects {
+		t, ok := obj.(*entries.Table)
+		if !ok || t.RelKind == 'p' {
+			res = append(res, obj)
+			continue
+		}
+		idx := slices.IndexFunc(cfg, func(tc *domains.Table) bool {
+			return tc.Chunks > 1 && isConfigForTable(tc, t.Schema, t.Name)
+		})
+		if idx == -1 {
+			res = append(res, obj)
+			continue
+		}
+		if t.Query != "" || len(t.SubsetConds) > 0 {
+			log.Warn().
+				Str("SchemaName", t.Schema).
+				Str("TableName", t.Name).
+				Msg("table with query or subset conditions cannot be split into chunks: dumping as a whole")
+			res = append(res, obj)
+			continue
+		}
+		chunkBy, conds, err := getChunkConditions(ctx, tx, t, cfg[idx])
+		if err != nil {
+			return fmt.Errorf("cannot get chunks of table %s.%s: %w", t.Schema, t.Name, err)
+		}
+		chunks, err := newTableChunks(ctx, t, chunkBy, conds)
+		if err != nil {
+			return fmt.Errorf("cannot create chunks of table %s.%s: %w", t.Schema, t.Name, err)
+		}
+		log.Debug().
+			Str("SchemaName", t.Schema).
+			Str("TableName", t.Name).
+			Int("Chunks", len(chunks)).
+			Msg("table is split into chunks")
+		for _, c := range chunks {
+			res = append(res, c)
+		}
+	}
+	rc.DataSectionObjects = res
+	return nil
+}
+
+// validateChunksUniqueMode - checks that the table split into chunks does not use the transformers in unique mode.
+// The unique values are tracked by the transformer instance and each chunk is transformed by its own instance, so
+// the values would be unique only within the chunk
+func validateChunksUniqueMode(t *entries.Table, cfg *domains.Table) (toolkit.ValidationWarnings, error) {
+	if cfg.Chunks <= 1 {
+		return nil, nil
+	}
+	var warnings toolkit.ValidationWarnings
+	for idx, tc := range t.TransformersContext {
+		p, ok := tc.StaticParameters[transformersUtils.UniqueParameterName]
+		if !ok {
+			continue
+		}
+		var unique bool
+		if err := p.Scan(&unique); err != nil {
+			return nil, fmt.Errorf("unable to scan \"%s\" param: %w", transformersUtils.UniqueParameterName, err)
+		}
+		if unique {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("TransformerName", cfg.Transformers[idx].Name).
+				AddMeta("Chunks", cfg.Chunks).
+				SetMsg("transformer in unique mode cannot be used for the table split into chunks: "+
+					"the values would be unique only within the chunk"),
+			)
+		}
+	}
+	return warnings, nil
+}
+
+// getChunkConditions - calculates the range conditions of the table chunks and returns them with the chunking
+// method. The conditions are nil if the table is too small to be split
+func getChunkConditions(
+	ctx context.Context, tx pgx.Tx, t *entries.Table, cfg *domains.Table,
+) (string, []string, error) {
+	pkColumn := getIntegerPkColumn(t)
+	chunkBy := cfg.ChunkBy
+	if chunkBy == "" {
+		chunkBy = ChunkByCtid
+		if pkColumn != "" {
+			chunkBy = ChunkByPk
+		}
+	}
+
+	switch chunkBy {
+	case ChunkByPk:
+		if pkColumn == "" {
+			return "", nil, fmt.Errorf("table must have single column integer primary key for chunking by %s", ChunkByPk)
+		}
+		var minVal, maxVal *int64
+		pkIdent := pgx.Identifier{pkColumn}.Sanitize()
+		query := fmt.Sprintf(chunkPkRangeQuery, pkIdent, pgx.Identifier{t.Schema, t.Name}.Sanitize())
+		if err := tx.QueryRow(ctx, query).Scan(&minVal, &maxVal); err != nil {
+			return "", nil, fmt.Errorf("cannot get primary key range: %w", err)
+		}
+		if minVal == nil || maxVal == nil {
+			// The table is empty
+			return chunkBy, nil, nil
+		}
+		var boundaries []string
+		for _, b := range splitRange(*minVal, *maxVal, cfg.Chunks) {
+			boundaries = append(boundaries, fmt.Sprintf("%d", b))
+		}
+		return chunkBy, buildRangeConditions(pkIdent, boundaries), nil
+	case ChunkByCtid:
+		var pages int64
+		if err := tx.QueryRow(ctx, chunkPagesQuery, t.Oid).Scan(&pages); err != nil {
+			return "", nil, fmt.Errorf("cannot get table pages count: %w", err)
+		}
+		if pages == 0 {
+			return chunkBy, nil, nil
+		}
+		var boundaries []string
+		for _, b := range splitRange(0, pages-1, cfg.Chunks) {
+			boundaries = append(boundaries, fmt.Sprintf("'(%d,0)'::TID", b))
+		}
+		return chunkBy, buildRangeConditions("ctid", boundaries), nil
+	}
+	return "", nil, fmt.Errorf("unknown chunk_by value \"%s\": expected %s or %s", chunkBy, ChunkByPk, ChunkByCtid)
+}
+
+// getIntegerPkColumn - returns the name of the primary key column if the primary key consists of the single integer
+// column
+func getIntegerPkColumn(t *entries.Table) string {
+	if len(t.PrimaryKey) != 1 {
+		return ""
+	}
+	idx := slices.IndexFunc(t.Columns, func(c *toolkit.Column) bool {
+		return c.Name == t.PrimaryKey[0]
+	})
+	if idx == -1 {
+		return ""
+	}
+	switch t.Columns[idx].TypeOid {
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID:
+		return t.PrimaryKey[0]
+	}
+	return ""
+}
+
+// splitRange - splits the closed range [minVal, maxVal] into count ranges of the same size and returns the start
+// values of all the ranges except the first one. The number of ranges is reduced if the range is too small
+func splitRange(minVal, maxVal int64, count int) []int64 {
+	if count < 2 || maxVal <= minVal {
+		return nil
+	}
+	// The values count is calculated in uint64 because it might not fit into int64
+	total := uint64(maxVal) - uint64(minVal) + 1
+	if total < uint64(count) {
+		count = int(total)
+	}
+	step, rem := total/uint64(count), total%uint64(count)
+	var res []int64
+	var offset uint64
+	for i := 1; i < count; i++ {
+		// The remainder is distributed between the first ranges
+		offset += step
+		if uint64(i) <= rem {
+			offset++
+		}
+		res = append(res, int64(uint64(minVal)+offset))
+	}
+	return res
+}
+
+// buildRangeConditions - builds conditions of the ranges split by the boundaries. The first and the last ranges are
+// open, so the rows outside the calculated range are not lost
+func buildRangeConditions(expr string, boundaries []string) []string {
+	if len(boundaries) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(boundaries)+1)
+	res = append(res, fmt.Sprintf("%s < %s", expr, boundaries[0]))
+	for i := 1; i < len(boundaries); i++ {
+		res = append(res, fmt.Sprintf("%s >= %s AND %s < %s", expr, boundaries[i-1], expr, boundaries[i]))
+	}
+	res = append(res, fmt.Sprintf("%s >= %s", expr, boundaries[len(boundaries)-1]))
+	return res
+}
+
+// newTableChunks - creates the chunk entries of the table. The table itself becomes the first chunk and the rest are
+// the copies of it with their own driver, when condition and transformers
+func newTableChunks(ctx context.Context, t *entries.Table, chunkBy string, conds []string) ([]*entries.Table, error) {
+	if len(conds) < 2 {
+		return []*entries.Table{t}, nil
+	}
+	res := make([]*entries.Table, 0, len(conds))
+	for i, cond := range conds {
+		chunk := t
+		if i > 0 {
+			var err error
+			chunk, err = cloneTableEntry(ctx, t)
+			if err != nil {
+				return nil, err
+			}
+		}
+		chunk.Chunk = &entries.TableChunk{
+			Idx:   i,
+			Count: len(conds),
+			Cond:  cond,
+			By:    chunkBy,
+		}
+		res = append(res, chunk)
+	}
+	return res, nil
+}
+
+// cloneTableEntry - creates a copy of the table entry. The driver, when condition and transformers are stateful, so
+// they are created again
+func cloneTableEntry(ctx context.Context, t *entries.Table) (*entries.Table, error) {
+	res := *t
+	res.TransformersContext = nil
+	if t.Driver == nil {
+		return &res, nil
+	}
+	driver, _, err := toolkit.NewDriver(t.Table, t.Driver.CustomTypes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialise driver: %w", err)
+	}
+	res.Driver = driver
+	if t.When != nil {
+		meta := map[string]any{
+			"TableSchema": t.Schema,
+			"TableName":   t.Name,
+		}
+		when, whenWarns := toolkit.NewWhenCond(t.When.Condition(), driver, meta)
+		if whenWarns.IsFatal() {
+			return nil, fmt.Errorf("cannot compile when condition: %+v", whenWarns)
+		}
+		res.When = when
+	}
+	for _, tc := range t.TransformersContext {
+		clone, err := tc.Clone(ctx, driver)
+		if err != nil {
+			return nil, fmt.Errorf("cannot clone transformer: %w", err)
+		}
+		res.TransformersContext = append(res.TransformersContext, clone)
+	}
+	return &res, nil
+}
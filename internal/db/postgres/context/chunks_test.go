@@ -0,0 +1,200 @@
+package context
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func Test_splitRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		minVal   int64
+		maxVal   int64
+		count    int
+		expected []int64
+	}{
+		{name: "even", minVal: 1, maxVal: 100, count: 4, expected: []int64{26, 51, 76}},
+		{name: "remainder", minVal: 1, maxVal: 10, count: 3, expected: []int64{5, 8}},
+		{name: "negative", minVal: -10, maxVal: 9, count: 2, expected: []int64{0}},
+		{name: "range is less than count", minVal: 1, maxVal: 3, count: 10, expected: []int64{2, 3}},
+		{name: "single value", minVal: 5, maxVal: 5, count: 4, expected: nil},
+		{name: "single chunk", minVal: 1, maxVal: 100, count: 1, expected: nil},
+		{name: "int64 bounds", minVal: math.MinInt64 + 1, maxVal: math.MaxInt64, count: 2, expected: []int64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitRange(tt.minVal, tt.maxVal, tt.count))
+		})
+	}
+}
+
+func Test_buildRangeConditions(t *testing.T) {
+	assert.Nil(t, buildRangeConditions(`"id"`, nil))
+	assert.Equal(t,
+		[]string{`"id" < 10`, `"id" >= 10 AND "id" < 20`, `"id" >= 20`},
+		buildRangeConditions(`"id"`, []string{"10", "20"}),
+	)
+	assert.Equal(t,
+		[]string{`ctid < '(5,0)'::TID`, `ctid >= '(5,0)'::TID`},
+		buildRangeConditions("ctid", []string{"'(5,0)'::TID"}),
+	)
+}
+
+func Test_getIntegerPkColumn(t *testing.T) {
+	columns := []*toolkit.Column{
+		{Name: "id", TypeOid: pgtype.Int8OID},
+		{Name: "code", TypeOid: pgtype.TextOID},
+	}
+	tests := []struct {
+		name     string
+		pk       []string
+		expected string
+	}{
+		{name: "integer", pk: []string{"id"}, expected: "id"},
+		{name: "text", pk: []string{"code"}, expected: ""},
+		{name: "composite", pk: []string{"id", "code"}, expected: ""},
+		{name: "no pk", pk: nil, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &entries.Table{Table: &toolkit.Table{Columns: columns, PrimaryKey: tt.pk}}
+			assert.Equal(t, tt.expected, getIntegerPkColumn(table))
+		})
+	}
+}
+
+func Test_newTableChunks(t *testing.T) {
+	table := &entries.Table{
+		Table: &toolkit.Table{
+			Oid: 1, Schema: "public", Name: "events",
+			Columns: []*toolkit.Column{
+				{Idx: 0, Name: "id", TypeName: "int8", TypeOid: pgtype.Int8OID, Num: 1},
+				{Idx: 1, Name: "payload", TypeName: "text", TypeOid: pgtype.TextOID, Num: 2},
+			},
+		},
+		DumpId: 10,
+	}
+	cfg := &domains.Table{
+		Schema:       "public",
+		Name:         "events",
+		When:         "record.id > 0",
+		Transformers: []*domains.TransformerConfig{newReplaceConfig("payload")},
+	}
+	warns, err := setGlobalDriverForTable(table, nil)
+	require.NoError(t, err)
+	require.False(t, warns.IsFatal())
+	require.False(t, compileAndSetWhenCondForTable(table, cfg).IsFatal())
+	warns, err = initAndSetupTransformers(context.Background(), table, cfg, utils.DefaultTransformerRegistry)
+	require.NoError(t, err)
+	require.False(t, warns.IsFatal())
+
+	t.Run("not split", func(t *testing.T) {
+		chunks, err := newTableChunks(context.Background(), table, ChunkByPk, nil)
+		require.NoError(t, err)
+		require.Len(t, chunks, 1)
+		assert.Same(t, table, chunks[0])
+		assert.Nil(t, chunks[0].Chunk)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		conds := []string{`"id" < 10`, `"id" >= 10`}
+		chunks, err := newTableChunks(context.Background(), table, ChunkByPk, conds)
+		require.NoError(t, err)
+		require.Len(t, chunks, 2)
+		assert.Same(t, table, chunks[0])
+		for i, c := range chunks {
+			require.NotNil(t, c.Chunk)
+			assert.Equal(t, i, c.Chunk.Idx)
+			assert.Equal(t, 2, c.Chunk.Count)
+			assert.Equal(t, conds[i], c.Chunk.Cond)
+			assert.Equal(t, ChunkByPk, c.Chunk.By)
+			require.Len(t, c.TransformersContext, 1)
+		}
+		clone := chunks[1]
+		assert.NotSame(t, table.Driver, clone.Driver)
+		assert.NotSame(t, table.When, clone.When)
+		assert.Equal(t, cfg.When, clone.When.Condition())
+		assert.NotSame(t, table.TransformersContext[0], clone.TransformersContext[0])
+		assert.NotSame(t, table.TransformersContext[0].Transformer, clone.TransformersContext[0].Transformer)
+	})
+}
+
+func Test_validateChunksUniqueMode(t *testing.T) {
+	newTable := func(t *testing.T, cfg *domains.Table) *entries.Table {
+		table := &entries.Table{
+			Table: &toolkit.Table{
+				Oid: 1, Schema: "public", Name: "events",
+				Columns: []*toolkit.Column{
+					{Idx: 0, Name: "id", TypeName: "int8", TypeOid: pgtype.Int8OID, Num: 1},
+					{Idx: 1, Name: "payload", TypeName: "text", TypeOid: pgtype.TextOID, Num: 2},
+				},
+			},
+			DumpId: 10,
+		}
+		warns, err := setGlobalDriverForTable(table, nil)
+		require.NoError(t, err)
+		require.False(t, warns.IsFatal())
+		warns, err = initAndSetupTransformers(context.Background(), table, cfg, utils.DefaultTransformerRegistry)
+		require.NoError(t, err)
+		require.False(t, warns.IsFatal())
+		return table
+	}
+	newRandomStringConfig := func(unique bool) *domains.TransformerConfig {
+		u := "false"
+		if unique {
+			u = "true"
+		}
+		return &domains.TransformerConfig{
+			Name: "RandomString",
+			Params: toolkit.StaticParameters{
+				"column":     toolkit.ParamsValue("payload"),
+				"min_length": toolkit.ParamsValue("10"),
+				"max_length": toolkit.ParamsValue("20"),
+				"unique":     toolkit.ParamsValue(u),
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		chunks    int
+		unique    bool
+		wantFatal bool
+	}{
+		{name: "unique mode with chunks", chunks: 2, unique: true, wantFatal: true},
+		{name: "unique mode without chunks", chunks: 0, unique: true},
+		{name: "chunks without unique mode", chunks: 2, unique: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &domains.Table{
+				Schema: "public",
+				Name:   "events",
+				Chunks: tt.chunks,
+				Transformers: []*domains.TransformerConfig{
+					newReplaceConfig("payload"), newRandomStringConfig(tt.unique),
+				},
+			}
+			table := newTable(t, cfg)
+			warns, err := validateChunksUniqueMode(table, cfg)
+			require.NoError(t, err)
+			if !tt.wantFatal {
+				assert.Empty(t, warns)
+				return
+			}
+			require.Len(t, warns, 1)
+			assert.True(t, warns.IsFatal())
+			assert.Equal(t, "RandomString", warns[0].Meta["TransformerName"])
+		})
+	}
+}
@@ -77,9 +77,21 @@ func validateAndBuildEntriesConfig(
 	warnings = append(warnings, setConfigWarns...)
 	for _, cfgMapping := range entriesWithTransformers {
 		// set subset conditions
-		setSubsetConds(cfgMapping.entry, cfgMapping.config)
+		subsetWarns := setSubsetConds(cfgMapping.entry, cfgMapping.config)
+		enrichWarningsWithTableName(subsetWarns, cfgMapping.entry)
+		warnings = append(warnings, subsetWarns...)
+		if subsetWarns.IsFatal() {
+			return subsetWarns, nil
+		}
 		// set query
 		setQuery(cfgMapping.entry, cfgMapping.config)
+		// set change indicator column for incremental dumps
+		updatedAtWarns := setUpdatedAtColumn(cfgMapping.entry, cfgMapping.config)
+		enrichWarningsWithTableName(updatedAtWarns, cfgMapping.entry)
+		warnings = append(warnings, updatedAtWarns...)
+		if updatedAtWarns.IsFatal() {
+			return updatedAtWarns, nil
+		}
 
 		// Set global driver for the table
 		driverWarnings, err := setGlobalDriverForTable(cfgMapping.entry, types)
@@ -132,6 +144,32 @@ func validateAndBuildEntriesConfig(
 				cfgMapping.entry.Schema, cfgMapping.entry.Name, err,
 			)
 		}
+
+		chunksWarns, err := validateChunksUniqueMode(cfgMapping.entry, cfgMapping.config)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot validate chunks of table %s.%s: %w", cfgMapping.entry.Schema, cfgMapping.entry.Name, err,
+			)
+		}
+		enrichWarningsWithTableName(chunksWarns, cfgMapping.entry)
+		warnings = append(warnings, chunksWarns...)
+	}
+
+	// The dependent tables conditions are built using the subset conditions of their parents, so they are set when
+	// the subset conditions of all the tables are set
+	for _, cfgMapping := range entriesWithTransformers {
+		dependentsWarns, err := setDependentsSubsetConds(graph, cfgMapping.entry, cfgMapping.config)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot set dependents subset conditions for table %s.%s: %w",
+				cfgMapping.entry.Schema, cfgMapping.entry.Name, err,
+			)
+		}
+		enrichWarningsWithTableName(dependentsWarns, cfgMapping.entry)
+		warnings = append(warnings, dependentsWarns...)
+		if dependentsWarns.IsFatal() {
+			return dependentsWarns, nil
+		}
 	}
 
 	return warnings, nil
@@ -185,8 +223,7 @@ func findTablesWithTransformers(
 	var entriesWithTransformers []*tableConfigMapping
 	for _, entry := range tables {
 		idx := slices.IndexFunc(cfg, func(table *domains.Table) bool {
-			return (table.Name == entry.Name || fmt.Sprintf(`"%s"`, table.Name) == entry.Name) &&
-				(table.Schema == entry.Schema || fmt.Sprintf(`"%s"`, table.Schema) == entry.Schema)
+			return isConfigForTable(table, entry.Schema, entry.Name)
 		})
 		if idx != -1 {
 			entriesWithTransformers = append(entriesWithTransformers, &tableConfigMapping{
@@ -198,6 +235,12 @@ func findTablesWithTransformers(
 	return entriesWithTransformers
 }
 
+// isConfigForTable - check the table config is defined for the table. The config names might be quoted
+func isConfigForTable(tc *domains.Table, schemaName, tableName string) bool {
+	return (tc.Name == tableName || fmt.Sprintf(`"%s"`, tc.Name) == tableName) &&
+		(tc.Schema == schemaName || fmt.Sprintf(`"%s"`, tc.Schema) == schemaName)
+}
+
 func setConfigToEntries(
 	ctx context.Context, tx pgx.Tx, cfg []*domains.Table, tables []*entries.Table, g *subset.Graph,
 	r *transformersUtils.TransformerRegistry,
@@ -213,7 +256,7 @@ func setConfigToEntries(
 				warnings = append(warnings, checkWarns...)
 				continue
 			}
-			refTables, warns := getRefTables(tcm.entry, tcm.config, g, cfg)
+			refTables, warns := getRefTables(tcm.entry, tcm.config, g, cfg, r)
 			warnings = append(warnings, warns...)
 			res = append(res, refTables...)
 		}
@@ -243,9 +286,10 @@ func setConfigToEntries(
 
 func getRefTables(
 	rootTable *entries.Table, rootTableCfg *domains.Table, graph *subset.Graph, allTrans []*domains.Table,
+	r *transformersUtils.TransformerRegistry,
 ) ([]*tableConfigMapping, toolkit.ValidationWarnings) {
 	var res []*tableConfigMapping
-	rootTrans := collectRootTransformers(rootTable, rootTableCfg)
+	rootTrans := collectRootTransformers(rootTable, rootTableCfg, r)
 
 	// Start DFS traversal from the root table
 	// visited tracks (EdgeID -> TransformerMappingIndex) to prevent infinite loops
@@ -334,10 +378,15 @@ func buildRefsWithEndToEndDfs(
 }
 
 // collectRootTransformers gathers all transformers in the root table's configuration
-func collectRootTransformers(rootTable *entries.Table, rootTableCfg *domains.Table) []*transformersMapping {
+func collectRootTransformers(
+	rootTable *entries.Table, rootTableCfg *domains.Table, r *transformersUtils.TransformerRegistry,
+) []*transformersMapping {
 	var rootTransformersMapping []*transformersMapping
 	for _, tr := range rootTableCfg.Transformers {
-		if !tr.ApplyForReferences || string(tr.Params[engineParameterName]) != "hash" {
+		if !tr.ApplyForReferences {
+			continue
+		}
+		if allowed, _ := isTransformerAllowedToApplyForReferences(tr, r); !allowed {
 			continue
 		}
 		idx := slices.Index(rootTable.PrimaryKey, string(tr.Params[columnParameterName]))
@@ -523,14 +572,129 @@ func findPartitionsOfPartitionedTable(ctx context.Context, tx pgx.Tx, t *toolkit
 	return parts, nil
 }
 
-func setSubsetConds(t *entries.Table, cfg *domains.Table) {
+// setSubsetConds - sets subset conditions of the table. The sample and the limit of the table rows are converted
+// into the subset condition
+func setSubsetConds(t *entries.Table, cfg *domains.Table) toolkit.ValidationWarnings {
 	t.SubsetConds = escapeSubsetConds(cfg.SubsetConds)
+	if cfg.SubsetSample == nil && cfg.SubsetLimit == 0 {
+		return nil
+	}
+	warnings := validateSubsetSampling(cfg)
+	if warnings.IsFatal() {
+		return warnings
+	}
+	t.SubsetConds = append(
+		t.SubsetConds, subset.GenerateSamplingCond(t, t.SubsetConds, cfg.SubsetSample, cfg.SubsetLimit),
+	)
+	return warnings
+}
+
+// validateSubsetSampling - validates subset_sample and subset_limit parameters of the table
+func validateSubsetSampling(cfg *domains.Table) toolkit.ValidationWarnings {
+	var warnings toolkit.ValidationWarnings
+	if cfg.SubsetLimit < 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("subset_limit cannot be negative").
+			AddMeta("SubsetLimit", cfg.SubsetLimit).
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	if cfg.SubsetSample == nil {
+		return warnings
+	}
+	if cfg.SubsetSample.Percent <= 0 || cfg.SubsetSample.Percent > 100 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("subset_sample percent must be in range (0, 100]").
+			AddMeta("Percent", cfg.SubsetSample.Percent).
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	switch strings.ToLower(cfg.SubsetSample.Method) {
+	case "", subset.SampleMethodHash, subset.SampleMethodBernoulli, subset.SampleMethodSystem:
+	default:
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsgf(
+				"unknown subset_sample method: expected one of %s, %s or %s",
+				subset.SampleMethodBernoulli, subset.SampleMethodSystem, subset.SampleMethodHash,
+			).
+			AddMeta("Method", cfg.SubsetSample.Method).
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	return warnings
+}
+
+// setDependentsSubsetConds - sets subset conditions of the tables that depend on the subset rows of the table if
+// include_dependents is set
+func setDependentsSubsetConds(
+	graph *subset.Graph, t *entries.Table, cfg *domains.Table,
+) (toolkit.ValidationWarnings, error) {
+	if cfg.IncludeDependents == nil {
+		return nil, nil
+	}
+	warnings := validateIncludeDependents(cfg.IncludeDependents)
+	if warnings.IsFatal() {
+		return warnings, nil
+	}
+	if err := subset.SetDependentsSubsetConds(graph, t, cfg.IncludeDependents); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// validateIncludeDependents - validates include_dependents parameters of the table
+func validateIncludeDependents(cfg *domains.IncludeDependents) toolkit.ValidationWarnings {
+	var warnings toolkit.ValidationWarnings
+	if cfg.MaxDepth < 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("include_dependents max_depth cannot be negative").
+			AddMeta("MaxDepth", cfg.MaxDepth).
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	if cfg.Limit < 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("include_dependents limit cannot be negative").
+			AddMeta("Limit", cfg.Limit).
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	for _, dt := range cfg.Tables {
+		if dt.Limit < 0 {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetMsg("include_dependents table limit cannot be negative").
+				AddMeta("DependentTableSchema", dt.Schema).
+				AddMeta("DependentTableName", dt.Name).
+				AddMeta("Limit", dt.Limit).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+			)
+		}
+	}
+	return warnings
 }
 
 func setQuery(t *entries.Table, cfg *domains.Table) {
 	t.Query = cfg.Query
 }
 
+func setUpdatedAtColumn(t *entries.Table, cfg *domains.Table) toolkit.ValidationWarnings {
+	if cfg.UpdatedAtColumn == "" {
+		return nil
+	}
+	if !slices.ContainsFunc(t.Columns, func(c *toolkit.Column) bool {
+		return c.Name == cfg.UpdatedAtColumn
+	}) {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetMsg("updated_at_column is not found in the table").
+				AddMeta("ColumnName", cfg.UpdatedAtColumn).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+		}
+	}
+	t.UpdatedAtColumn = cfg.UpdatedAtColumn
+	return nil
+}
+
 func setGlobalDriverForTable(
 	t *entries.Table, types []*toolkit.Type,
 ) (toolkit.ValidationWarnings, error) {
@@ -694,7 +858,7 @@ func isTransformerAllowedToApplyForReferences(
 	if !requireHashEngineParameterBool {
 		return true, nil
 	}
-	if string(cfg.Params[engineParameterName]) != transformers.HashEngineParameterName {
+	if getTransformerParamValue(cfg, td, engineParameterName) != transformers.HashEngineParameterName {
 		return false, toolkit.ValidationWarnings{
 			toolkit.NewValidationWarning().
 				SetMsg("cannot apply transformer for references: engine parameter is not hash").
@@ -705,6 +869,23 @@ func isTransformerAllowedToApplyForReferences(
 	return true, nil
 }
 
+// getTransformerParamValue - returns the raw value of the transformer parameter or its default value from the
+// transformer definition if the parameter is not set in the config
+func getTransformerParamValue(
+	cfg *domains.TransformerConfig, td *transformersUtils.TransformerDefinition, name string,
+) string {
+	if v, ok := cfg.Params[name]; ok {
+		return string(v)
+	}
+	idx := slices.IndexFunc(td.Parameters, func(p *toolkit.ParameterDefinition) bool {
+		return p.Name == name
+	})
+	if idx == -1 {
+		return ""
+	}
+	return string(td.Parameters[idx].DefaultValue)
+}
+
 func checkTransformerAlreadyExists(
 	conf []*domains.Table, schemaName, tableName, tranName, tColumn string,
 ) (bool, *domains.TransformerConfig) {
@@ -238,6 +238,50 @@ func Test_isTransformerAllowedToApplyForReferences(t *testing.T) {
 		require.False(t, ok)
 	})
 
+	t.Run("ShiftDate and hash engine", func(t *testing.T) {
+		cfg := &domains.TransformerConfig{
+			Name:               transformers.ShiftDateTransformerName,
+			ApplyForReferences: true,
+			Params: toolkit.StaticParameters{
+				"column":     toolkit.ParamsValue("id"),
+				"columns":    toolkit.ParamsValue(`[{"name": "created_at"}]`),
+				"max_offset": toolkit.ParamsValue("30 days"),
+				"engine":     toolkit.ParamsValue("hash"),
+			},
+		}
+		ok, w := isTransformerAllowedToApplyForReferences(cfg, r)
+		require.Empty(t, w)
+		require.True(t, ok)
+	})
+
+	t.Run("ShiftDate and default engine", func(t *testing.T) {
+		cfg := &domains.TransformerConfig{
+			Name:               transformers.ShiftDateTransformerName,
+			ApplyForReferences: true,
+			Params: toolkit.StaticParameters{
+				"column":     toolkit.ParamsValue("id"),
+				"columns":    toolkit.ParamsValue(`[{"name": "created_at"}]`),
+				"max_offset": toolkit.ParamsValue("30 days"),
+			},
+		}
+		ok, w := isTransformerAllowedToApplyForReferences(cfg, r)
+		require.Empty(t, w)
+		require.True(t, ok)
+	})
+
+	t.Run("RandomInt and default engine", func(t *testing.T) {
+		cfg := &domains.TransformerConfig{
+			Name:               transformers.RandomIntTransformerName,
+			ApplyForReferences: true,
+			Params: toolkit.StaticParameters{
+				"column": toolkit.ParamsValue("id"),
+			},
+		}
+		ok, w := isTransformerAllowedToApplyForReferences(cfg, r)
+		require.NotEmpty(t, w)
+		require.False(t, ok)
+	})
+
 	t.Run("Template", func(t *testing.T) {
 		cfg := &domains.TransformerConfig{
 			Name:               transformers.TemplateTransformerName,
@@ -265,6 +309,105 @@ func Test_isTransformerAllowedToApplyForReferences(t *testing.T) {
 	})
 }
 
+func Test_setSubsetConds(t *testing.T) {
+	newTable := func() *entries.Table {
+		return &entries.Table{
+			Table: &toolkit.Table{Schema: "public", Name: "customers", PrimaryKey: []string{"id"}},
+		}
+	}
+
+	t.Run("conditions only", func(t *testing.T) {
+		table := newTable()
+		warns := setSubsetConds(table, &domains.Table{SubsetConds: []string{"public.customers.id = 1"}})
+		require.Empty(t, warns)
+		assert.Equal(t, []string{"( public.customers.id = 1 )"}, table.SubsetConds)
+	})
+
+	t.Run("sample and limit", func(t *testing.T) {
+		table := newTable()
+		warns := setSubsetConds(table, &domains.Table{
+			SubsetConds:  []string{"public.customers.id > 1"},
+			SubsetSample: &domains.SubsetSample{Percent: 5, Method: subset.SampleMethodBernoulli},
+			SubsetLimit:  10,
+		})
+		require.Empty(t, warns)
+		require.Len(t, table.SubsetConds, 2)
+		assert.Contains(t, table.SubsetConds[1], "TABLESAMPLE BERNOULLI (5)")
+		assert.Contains(t, table.SubsetConds[1], "LIMIT 10")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name string
+			cfg  *domains.Table
+			msg  string
+		}{
+			{
+				name: "percent",
+				cfg:  &domains.Table{SubsetSample: &domains.SubsetSample{Percent: 101}},
+				msg:  "subset_sample percent must be in range",
+			},
+			{
+				name: "method",
+				cfg:  &domains.Table{SubsetSample: &domains.SubsetSample{Percent: 5, Method: "random"}},
+				msg:  "unknown subset_sample method",
+			},
+			{
+				name: "limit",
+				cfg:  &domains.Table{SubsetLimit: -1},
+				msg:  "subset_limit cannot be negative",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				table := newTable()
+				warns := setSubsetConds(table, tt.cfg)
+				require.True(t, warns.IsFatal())
+				assert.Contains(t, warns[0].Msg, tt.msg)
+				assert.Empty(t, table.SubsetConds)
+			})
+		}
+	})
+}
+
+func Test_validateIncludeDependents(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *domains.IncludeDependents
+		msg  string
+	}{
+		{
+			name: "max depth",
+			cfg:  &domains.IncludeDependents{MaxDepth: -1},
+			msg:  "include_dependents max_depth cannot be negative",
+		},
+		{
+			name: "limit",
+			cfg:  &domains.IncludeDependents{Limit: -1},
+			msg:  "include_dependents limit cannot be negative",
+		},
+		{
+			name: "table limit",
+			cfg: &domains.IncludeDependents{
+				Tables: []*domains.DependentTable{{Schema: "public", Name: "orders", Limit: -1}},
+			},
+			msg: "include_dependents table limit cannot be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warns := validateIncludeDependents(tt.cfg)
+			require.True(t, warns.IsFatal())
+			assert.Equal(t, tt.msg, warns[0].Msg)
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		warns := validateIncludeDependents(&domains.IncludeDependents{MaxDepth: 2, Limit: 100})
+		assert.Empty(t, warns)
+	})
+}
+
 func Test_runPostgresContainer(t *testing.T) {
 	ctx := context.Background()
 	// Start the PostgreSQL container
@@ -959,3 +1102,258 @@ func initTables(ctx context.Context, con *pgx.Conn, migration string) error {
 	_, err := con.Exec(ctx, migration)
 	return err
 }
+
+const multiGroupSccTestDb = `
+CREATE SCHEMA multi_cycle;
+
+CREATE TABLE multi_cycle.users
+(
+    id INT PRIMARY KEY
+);
+
+-- The component contains two cycle groups a -> b -> c -> a and b -> c -> e -> b. The references c -> a and c -> e
+-- are nullable
+CREATE TABLE multi_cycle.a
+(
+    id      INT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES multi_cycle.users (id),
+    b_id    INT NOT NULL
+);
+
+CREATE TABLE multi_cycle.b
+(
+    id   INT PRIMARY KEY,
+    c_id INT NOT NULL
+);
+
+CREATE TABLE multi_cycle.c
+(
+    id   INT PRIMARY KEY,
+    a_id INT,
+    e_id INT
+);
+
+CREATE TABLE multi_cycle.e
+(
+    id   INT PRIMARY KEY,
+    b_id INT NOT NULL
+);
+
+INSERT INTO multi_cycle.users (id)
+VALUES (1),
+       (2);
+
+INSERT INTO multi_cycle.a (id, user_id, b_id)
+VALUES (1, 1, 1),
+       (2, 2, 2);
+
+INSERT INTO multi_cycle.b (id, c_id)
+VALUES (1, 1),
+       (2, 2),
+       (3, 3),
+       (4, 4);
+
+INSERT INTO multi_cycle.c (id, a_id, e_id)
+VALUES (1, 1, 1),
+       (2, 2, NULL),
+       (3, NULL, 2),
+       (4, NULL, NULL);
+
+INSERT INTO multi_cycle.e (id, b_id)
+VALUES (1, 1),
+       (2, 2);
+
+ALTER TABLE multi_cycle.a
+    ADD CONSTRAINT a_b_id_fkey FOREIGN KEY (b_id) REFERENCES multi_cycle.b (id);
+ALTER TABLE multi_cycle.b
+    ADD CONSTRAINT b_c_id_fkey FOREIGN KEY (c_id) REFERENCES multi_cycle.c (id);
+ALTER TABLE multi_cycle.c
+    ADD CONSTRAINT c_a_id_fkey FOREIGN KEY (a_id) REFERENCES multi_cycle.a (id);
+ALTER TABLE multi_cycle.c
+    ADD CONSTRAINT c_e_id_fkey FOREIGN KEY (e_id) REFERENCES multi_cycle.e (id);
+ALTER TABLE multi_cycle.e
+    ADD CONSTRAINT e_b_id_fkey FOREIGN KEY (b_id) REFERENCES multi_cycle.b (id);
+`
+
+const nestedSccTestDb = `
+CREATE SCHEMA nested_cycle;
+
+CREATE TABLE nested_cycle.users
+(
+    id INT PRIMARY KEY
+);
+
+-- The component contains the cycle group b -> c -> b nested in the larger cycle group a -> b -> c -> d -> a. The
+-- references of c are nullable
+CREATE TABLE nested_cycle.a
+(
+    id      INT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES nested_cycle.users (id),
+    b_id    INT NOT NULL
+);
+
+CREATE TABLE nested_cycle.b
+(
+    id   INT PRIMARY KEY,
+    c_id INT NOT NULL
+);
+
+CREATE TABLE nested_cycle.c
+(
+    id   INT PRIMARY KEY,
+    b_id INT,
+    d_id INT
+);
+
+CREATE TABLE nested_cycle.d
+(
+    id   INT PRIMARY KEY,
+    a_id INT NOT NULL
+);
+
+INSERT INTO nested_cycle.users (id)
+VALUES (1),
+       (2);
+
+INSERT INTO nested_cycle.a (id, user_id, b_id)
+VALUES (1, 1, 1),
+       (2, 2, 2);
+
+INSERT INTO nested_cycle.b (id, c_id)
+VALUES (1, 1),
+       (2, 2),
+       (3, 3),
+       (4, 4);
+
+INSERT INTO nested_cycle.c (id, b_id, d_id)
+VALUES (1, NULL, 1),
+       (2, 3, 2),
+       (3, 2, NULL),
+       (4, NULL, NULL),
+       (5, 4, NULL);
+
+INSERT INTO nested_cycle.d (id, a_id)
+VALUES (1, 1),
+       (2, 2);
+
+ALTER TABLE nested_cycle.a
+    ADD CONSTRAINT a_b_id_fkey FOREIGN KEY (b_id) REFERENCES nested_cycle.b (id);
+ALTER TABLE nested_cycle.b
+    ADD CONSTRAINT b_c_id_fkey FOREIGN KEY (c_id) REFERENCES nested_cycle.c (id);
+ALTER TABLE nested_cycle.c
+    ADD CONSTRAINT c_b_id_fkey FOREIGN KEY (b_id) REFERENCES nested_cycle.b (id);
+ALTER TABLE nested_cycle.c
+    ADD CONSTRAINT c_d_id_fkey FOREIGN KEY (d_id) REFERENCES nested_cycle.d (id);
+ALTER TABLE nested_cycle.d
+    ADD CONSTRAINT d_a_id_fkey FOREIGN KEY (a_id) REFERENCES nested_cycle.a (id);
+`
+
+func Test_multiGroupSccSubset(t *testing.T) {
+	ctx := context.Background()
+	connStr, cleanup, err := runPostgresContainer(ctx)
+	require.NoError(t, err)
+	defer cleanup()
+
+	con, err := pgx.Connect(ctx, connStr)
+	require.NoError(t, err)
+	defer con.Close(ctx) // nolint: errcheck
+	require.NoError(t, initTables(ctx, con, multiGroupSccTestDb))
+	require.NoError(t, initTables(ctx, con, nestedSccTestDb))
+
+	tests := []struct {
+		name        string
+		schema      string
+		subsetConds map[string][]string
+		expected    map[string][]int
+	}{
+		{
+			// The row a2 references the filtered out user. The rows c2, b2, e2, c3 and b3 reference it transitively
+			// through both cycle groups. The row c4 has NULL references, so c4 and b4 remain
+			name:   "overlapping cycle groups",
+			schema: "multi_cycle",
+			subsetConds: map[string][]string{
+				"users": {"multi_cycle.users.id = 1"},
+			},
+			expected: map[string][]int{
+				"users": {1},
+				"a":     {1},
+				"b":     {1, 4},
+				"c":     {1, 4},
+				"e":     {1},
+			},
+		},
+		{
+			// The row a2 references the filtered out user and d2 references a2 through the larger cycle group. Then
+			// the rows c2, b2, c3 and b3 are filtered out through the nested cycle group. The rows c4 and c5 do not
+			// reference the filtered out rows, so they remain with b4
+			name:   "nested cycle group",
+			schema: "nested_cycle",
+			subsetConds: map[string][]string{
+				"users": {"nested_cycle.users.id = 1"},
+			},
+			expected: map[string][]int{
+				"users": {1},
+				"a":     {1},
+				"b":     {1, 4},
+				"c":     {1, 4, 5},
+				"d":     {1},
+			},
+		},
+		{
+			// Besides the rows filtered out by the user condition, the row c1 does not meet the condition of the
+			// table inside the component, and the rows b1, a1 and e1 reference it. Only c4 and b4 remain
+			name:   "subset condition inside component",
+			schema: "multi_cycle",
+			subsetConds: map[string][]string{
+				"users": {"multi_cycle.users.id = 1"},
+				"c":     {"multi_cycle.c.id <> 1"},
+			},
+			expected: map[string][]int{
+				"users": {1},
+				"a":     nil,
+				"b":     {4},
+				"c":     {4},
+				"e":     nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := con.Begin(ctx)
+			require.NoError(t, err)
+			defer tx.Rollback(ctx) // nolint: errcheck
+
+			pgVer := testContainerPgVersion * 10000
+			opt := &pgdump.Options{Schema: []string{tt.schema}}
+			tables, _, _, err := getDumpObjects(ctx, pgVer, tx, opt)
+			require.NoError(t, err)
+			graph, err := subset.NewGraph(ctx, tx, tables, nil)
+			require.NoError(t, err)
+
+			for name, conds := range tt.subsetConds {
+				idx := slices.IndexFunc(tables, func(t *entries.Table) bool {
+					return t.Name == name
+				})
+				require.NotEqual(t, -1, idx, "table %s", name)
+				tables[idx].SubsetConds = conds
+			}
+			require.NoError(t, subset.SetSubsetQueries(graph))
+
+			for _, table := range tables {
+				require.NotEmpty(t, table.Query, "table %s", table.Name)
+				rows, err := tx.Query(ctx, table.Query)
+				require.NoError(t, err, "table %s", table.Name)
+				var ids []int
+				for rows.Next() {
+					values, err := rows.Values()
+					require.NoError(t, err)
+					ids = append(ids, int(values[0].(int32)))
+				}
+				require.NoError(t, rows.Err())
+				slices.Sort(ids)
+				assert.Equal(t, tt.expected[table.Name], ids, "table %s", table.Name)
+			}
+		})
+	}
+}
@@ -16,9 +16,7 @@ package context
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
-	"os"
 	"slices"
 
 	"github.com/jackc/pgx/v5"
@@ -202,14 +200,9 @@ func debugQueries(tables []*entries.Table) {
 }
 
 func withSalt(ctx context.Context) (context.Context, error) {
-	var salt []byte
-	saltHex := os.Getenv("GREENMASK_GLOBAL_SALT")
-	if saltHex != "" {
-		salt = make([]byte, hex.DecodedLen(len(saltHex)))
-		_, err := hex.Decode(salt, []byte(saltHex))
-		if err != nil {
-			return nil, fmt.Errorf("error decoding salt from hex: %w", err)
-		}
+	salt, err := utils.GetGlobalSalt()
+	if err != nil {
+		return nil, err
 	}
 	return utils.WithSalt(ctx, salt), nil
 }
@@ -0,0 +1,313 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	transformersUtils "github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+// NewOfflineRuntimeContext - creating new runtime context using the database schema stored in the dump metadata
+// instead of the database introspection. It is used for the transformation of the existing dump. The tables are
+// created only for the objects listed in dumpIds (dump id -> table oid). The table split into chunks has an entry
+// per chunk. The features that require the database
+// connection (subset_conds, query, apply_for_references and transformers that use the database connection) are
+// reported as fatal validation warnings
+func NewOfflineRuntimeContext(
+	ctx context.Context, schema toolkit.DatabaseSchema, dumpIds map[int32]toolkit.Oid, cfg *domains.Dump,
+	r *transformersUtils.TransformerRegistry,
+) (*RuntimeContext, error) {
+	ctx, err := withSalt(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set salt: %w", err)
+	}
+	typeMap := pgtype.NewMap()
+
+	tables := getOfflineTables(schema, dumpIds)
+
+	warnings, err := validateAndBuildOfflineEntriesConfig(ctx, schema, tables, typeMap, cfg, r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot validate and build table config: %w", err)
+	}
+	if warnings.IsFatal() {
+		return &RuntimeContext{
+			Warnings: warnings,
+		}, nil
+	}
+
+	var dataSectionObjects, dataSectionObjectsToValidate []entries.Entry
+	for _, t := range tables {
+		dataSectionObjects = append(dataSectionObjects, t)
+		if len(t.TransformersContext) > 0 {
+			dataSectionObjectsToValidate = append(dataSectionObjectsToValidate, t)
+		}
+	}
+
+	return &RuntimeContext{
+		DataSectionObjects:           dataSectionObjects,
+		DataSectionObjectsToValidate: dataSectionObjectsToValidate,
+		Warnings:                     warnings,
+		Registry:                     r,
+		TypeMap:                      typeMap,
+		DatabaseSchema:               schema,
+	}, nil
+}
+
+// getOfflineTables - build table entries from the dump schema. The generated columns are excluded because they are
+// not present in the COPY stream
+func getOfflineTables(schema toolkit.DatabaseSchema, dumpIds map[int32]toolkit.Oid) []*entries.Table {
+	tableDumpIds := make(map[toolkit.Oid][]int32)
+	for dumpId, oid := range dumpIds {
+		tableDumpIds[oid] = append(tableDumpIds[oid], dumpId)
+	}
+	var res []*entries.Table
+	for _, t := range schema {
+		ids := tableDumpIds[t.Oid]
+		slices.Sort(ids)
+		for _, dumpId := range ids {
+			res = append(res, newOfflineTable(t, dumpId))
+		}
+	}
+	return res
+}
+
+// newOfflineTable - build table entry of the data object with dumpId
+func newOfflineTable(t *toolkit.Table, dumpId int32) *entries.Table {
+	table := *t
+	table.Columns = make([]*toolkit.Column, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		if c.IsGenerated {
+			continue
+		}
+		column := *c
+		column.Idx = len(table.Columns)
+		table.Columns = append(table.Columns, &column)
+	}
+	var relKind rune
+	if len(t.Kind) > 0 {
+		relKind = rune(t.Kind[0])
+	}
+	return &entries.Table{
+		Table:        &table,
+		RelKind:      relKind,
+		DumpId:       dumpId,
+		RootPtSchema: t.RootPtSchema,
+		RootPtName:   t.RootPtName,
+		RootPtOid:    t.RootPtOid,
+	}
+}
+
+// validateAndBuildOfflineEntriesConfig - the same as validateAndBuildEntriesConfig but it uses the dump schema
+// instead of the database introspection
+func validateAndBuildOfflineEntriesConfig(
+	ctx context.Context, schema toolkit.DatabaseSchema, tables []*entries.Table, typeMap *pgtype.Map,
+	cfg *domains.Dump, r *transformersUtils.TransformerRegistry,
+) (toolkit.ValidationWarnings, error) {
+	entriesWithTransformers, warnings := setOfflineConfigToEntries(schema, tables, cfg.Transformation, r)
+	if warnings.IsFatal() {
+		return warnings, nil
+	}
+	for _, cfgMapping := range entriesWithTransformers {
+		// Set global driver for the table. The custom types are not stored in the dump, so the columns of the
+		// custom types are handled as raw values
+		driverWarnings, err := setGlobalDriverForTable(cfgMapping.entry, nil)
+		warnings = append(warnings, driverWarnings...)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot set global driver for table %s.%s: %w",
+				cfgMapping.entry.Schema, cfgMapping.entry.Name, err,
+			)
+		}
+		enrichWarningsWithTableName(driverWarnings, cfgMapping.entry)
+		if driverWarnings.IsFatal() {
+			return driverWarnings, nil
+		}
+
+		// Compile when condition and set to the table entry
+		whenCondWarns := compileAndSetWhenCondForTable(cfgMapping.entry, cfgMapping.config)
+		enrichWarningsWithTableName(whenCondWarns, cfgMapping.entry)
+		warnings = append(warnings, whenCondWarns...)
+		if whenCondWarns.IsFatal() {
+			return whenCondWarns, nil
+		}
+
+		// Set column type overrides
+		setColumnTypeOverrides(cfgMapping.entry, cfgMapping.config, typeMap)
+
+		// Set transformers for the table
+		transformersInitWarns, err := initAndSetupTransformers(ctx, cfgMapping.entry, cfgMapping.config, r)
+		enrichWarningsWithTableName(transformersInitWarns, cfgMapping.entry)
+		warnings = append(warnings, transformersInitWarns...)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot initialise and set transformers for table %s.%s: %w",
+				cfgMapping.entry.Schema, cfgMapping.entry.Name, err,
+			)
+		}
+	}
+	return warnings, nil
+}
+
+// setOfflineConfigToEntries - map the transformation config to the table entries. The partitioned table config is
+// applied to all its partitions if apply_for_inherited is set
+func setOfflineConfigToEntries(
+	schema toolkit.DatabaseSchema, tables []*entries.Table, cfg []*domains.Table,
+	r *transformersUtils.TransformerRegistry,
+) ([]*tableConfigMapping, toolkit.ValidationWarnings) {
+	var res []*tableConfigMapping
+	var warnings toolkit.ValidationWarnings
+	for _, tc := range cfg {
+		idx := slices.IndexFunc(schema, func(t *toolkit.Table) bool {
+			return isConfigForTable(tc, t.Schema, t.Name)
+		})
+		if idx == -1 {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetMsgf("table is not found in the dump").
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("Schema", tc.Schema).
+				AddMeta("TableName", tc.Name),
+			)
+			continue
+		}
+		st := schema[idx]
+
+		offlineWarns := validateOfflineTableConfig(tc, r)
+		for _, w := range offlineWarns {
+			w.AddMeta("SchemaName", st.Schema).
+				AddMeta("TableName", st.Name)
+		}
+		warnings = append(warnings, offlineWarns...)
+		if offlineWarns.IsFatal() {
+			continue
+		}
+
+		if st.Kind == "p" {
+			if !tc.ApplyForInherited {
+				warnings = append(warnings, toolkit.NewValidationWarning().
+					SetMsg("the table is partitioned use apply_for_inherited").
+					AddMeta("SchemaName", st.Schema).
+					AddMeta("TableName", st.Name).
+					SetSeverity(toolkit.ErrorValidationSeverity),
+				)
+				continue
+			}
+			for _, t := range tables {
+				if isPartitionOf(schema, t.Table, st.Oid) {
+					res = append(res, &tableConfigMapping{entry: t, config: tc})
+				}
+			}
+			continue
+		}
+
+		var found bool
+		for _, t := range tables {
+			// The table split into chunks has several entries
+			if t.Oid == st.Oid {
+				res = append(res, &tableConfigMapping{entry: t, config: tc})
+				found = true
+			}
+		}
+		if !found {
+			log.Debug().
+				Str("SchemaName", st.Schema).
+				Str("TableName", st.Name).
+				Msg("table data is not found in the dump: skipping")
+		}
+	}
+	return res, warnings
+}
+
+// validateOfflineTableConfig - check the table config does not use the features that require the database
+// connection
+func validateOfflineTableConfig(
+	tc *domains.Table, r *transformersUtils.TransformerRegistry,
+) toolkit.ValidationWarnings {
+	var warnings toolkit.ValidationWarnings
+	if len(tc.SubsetConds) > 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("subset_conds cannot be applied without database connection").
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	if tc.SubsetSample != nil || tc.SubsetLimit != 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("subset_sample and subset_limit cannot be applied without database connection").
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	if tc.IncludeDependents != nil {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("include_dependents cannot be applied without database connection").
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	if tc.Query != "" {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetMsg("query cannot be applied without database connection").
+			SetSeverity(toolkit.ErrorValidationSeverity),
+		)
+	}
+	for _, tr := range tc.Transformers {
+		if tr.ApplyForReferences {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetMsg("apply_for_references cannot be applied without database connection").
+				AddMeta("TransformerName", tr.Name).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+			)
+		}
+		td, ok := r.Get(tr.Name)
+		if !ok {
+			// The unknown transformer is reported during the initialization
+			continue
+		}
+		if td.RequiresDatabaseConnection(tr.Params) {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetMsg("transformer requires database connection and cannot be used for the dump transformation").
+				AddMeta("TransformerName", tr.Name).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+			)
+		}
+	}
+	return warnings
+}
+
+// isPartitionOf - check the table is a partition (possibly nested) of the partitioned table with parentOid
+func isPartitionOf(schema toolkit.DatabaseSchema, t *toolkit.Table, parentOid toolkit.Oid) bool {
+	if t.RootPtOid == parentOid {
+		return true
+	}
+	for t.Parent != 0 {
+		if t.Parent == parentOid {
+			return true
+		}
+		idx := slices.IndexFunc(schema, func(item *toolkit.Table) bool {
+			return item.Oid == t.Parent
+		})
+		if idx == -1 {
+			return false
+		}
+		t = schema[idx]
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+package context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func getOfflineTestSchema() toolkit.DatabaseSchema {
+	return toolkit.DatabaseSchema{
+		{
+			Oid: 1, Schema: "public", Name: "users", Kind: "r",
+			Columns: []*toolkit.Column{
+				{Idx: 0, Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID, Num: 1},
+				{Idx: 1, Name: "name_upper", TypeName: "text", TypeOid: pgtype.TextOID, Num: 2, IsGenerated: true},
+				{Idx: 2, Name: "name", TypeName: "text", TypeOid: pgtype.TextOID, Num: 3},
+			},
+		},
+		{
+			Oid: 2, Schema: "public", Name: "orders", Kind: "p", Children: []toolkit.Oid{3},
+			Columns: []*toolkit.Column{
+				{Idx: 0, Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID, Num: 1},
+				{Idx: 1, Name: "address", TypeName: "text", TypeOid: pgtype.TextOID, Num: 2},
+			},
+		},
+		{
+			Oid: 3, Schema: "public", Name: "orders_2024", Kind: "r", Parent: 2,
+			RootPtSchema: "public", RootPtName: "orders", RootPtOid: 2,
+			Columns: []*toolkit.Column{
+				{Idx: 0, Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID, Num: 1},
+				{Idx: 1, Name: "address", TypeName: "text", TypeOid: pgtype.TextOID, Num: 2},
+			},
+		},
+	}
+}
+
+func newReplaceConfig(column string) *domains.TransformerConfig {
+	return &domains.TransformerConfig{
+		Name: transformers.ReplaceTransformerName,
+		Params: toolkit.StaticParameters{
+			"column": toolkit.ParamsValue(column),
+			"value":  toolkit.ParamsValue("masked"),
+		},
+	}
+}
+
+func TestNewOfflineRuntimeContext(t *testing.T) {
+	dumpIds := map[int32]toolkit.Oid{10: 1, 11: 3}
+	cfg := &domains.Dump{
+		Transformation: []*domains.Table{
+			{
+				Schema:       "public",
+				Name:         "users",
+				Transformers: []*domains.TransformerConfig{newReplaceConfig("name")},
+			},
+			{
+				Schema:            "public",
+				Name:              "orders",
+				ApplyForInherited: true,
+				Transformers:      []*domains.TransformerConfig{newReplaceConfig("address")},
+			},
+		},
+	}
+
+	rc, err := NewOfflineRuntimeContext(
+		context.Background(), getOfflineTestSchema(), dumpIds, cfg, utils.DefaultTransformerRegistry,
+	)
+	require.NoError(t, err)
+	require.False(t, rc.IsFatal(), rc.Warnings)
+	require.Len(t, rc.DataSectionObjects, 2)
+	require.Len(t, rc.DataSectionObjectsToValidate, 2)
+
+	users := rc.DataSectionObjects[0].(*entries.Table)
+	assert.Equal(t, int32(10), users.DumpId)
+	require.Len(t, users.Columns, 2)
+	assert.Equal(t, "name", users.Columns[1].Name)
+	assert.Equal(t, 1, users.Columns[1].Idx)
+	assert.Len(t, users.TransformersContext, 1)
+
+	partition := rc.DataSectionObjects[1].(*entries.Table)
+	assert.Equal(t, "orders_2024", partition.Name)
+	assert.Equal(t, int32(11), partition.DumpId)
+	assert.Equal(t, "orders", partition.RootPtName)
+	assert.Len(t, partition.TransformersContext, 1)
+}
+
+func TestNewOfflineRuntimeContext_chunks(t *testing.T) {
+	cfg := &domains.Dump{
+		Transformation: []*domains.Table{
+			{
+				Schema:       "public",
+				Name:         "users",
+				Transformers: []*domains.TransformerConfig{newReplaceConfig("name")},
+			},
+		},
+	}
+
+	rc, err := NewOfflineRuntimeContext(
+		context.Background(), getOfflineTestSchema(), map[int32]toolkit.Oid{12: 1, 10: 1, 11: 3}, cfg,
+		utils.DefaultTransformerRegistry,
+	)
+	require.NoError(t, err)
+	require.False(t, rc.IsFatal(), rc.Warnings)
+	require.Len(t, rc.DataSectionObjects, 3)
+	require.Len(t, rc.DataSectionObjectsToValidate, 2)
+
+	first := rc.DataSectionObjects[0].(*entries.Table)
+	second := rc.DataSectionObjects[1].(*entries.Table)
+	assert.Equal(t, int32(10), first.DumpId)
+	assert.Equal(t, int32(12), second.DumpId)
+	require.Len(t, first.TransformersContext, 1)
+	require.Len(t, second.TransformersContext, 1)
+	assert.NotSame(t, first.TransformersContext[0].Transformer, second.TransformersContext[0].Transformer)
+}
+
+func TestNewOfflineRuntimeContext_validation(t *testing.T) {
+	dbTransformer := *transformers.ReplaceTransformerDefinition
+	dbTransformer.Properties = utils.NewTransformerProperties("DbTransformer", "uses database")
+	dbTransformer.SetRequireDatabaseConnection(func(params map[string]toolkit.ParamsValue) bool {
+		return string(params["value"]) == "from_db"
+	})
+	r := utils.NewTransformerRegistry()
+	r.MustRegister(transformers.ReplaceTransformerDefinition)
+	r.MustRegister(&dbTransformer)
+	r.MustRegister(transformers.LookupTransformerDefinition)
+	r.MustRegister(transformers.ArrayMapTransformerDefinition)
+	r.MustRegister(transformers.JsonTransformerDefinition)
+
+	tests := []struct {
+		name string
+		cfg  *domains.Table
+		msg  string
+	}{
+		{
+			name: "table not found",
+			cfg:  &domains.Table{Schema: "public", Name: "unknown"},
+			msg:  "table is not found in the dump",
+		},
+		{
+			name: "subset conditions",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users", SubsetConds: []string{"public.users.id = 1"},
+			},
+			msg: "subset_conds cannot be applied without database connection",
+		},
+		{
+			name: "subset sample",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users", SubsetSample: &domains.SubsetSample{Percent: 5},
+			},
+			msg: "subset_sample and subset_limit cannot be applied without database connection",
+		},
+		{
+			name: "include dependents",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users", IncludeDependents: &domains.IncludeDependents{MaxDepth: 1},
+			},
+			msg: "include_dependents cannot be applied without database connection",
+		},
+		{
+			name: "query",
+			cfg:  &domains.Table{Schema: "public", Name: "users", Query: "select * from public.users"},
+			msg:  "query cannot be applied without database connection",
+		},
+		{
+			name: "apply for references",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users",
+				Transformers: []*domains.TransformerConfig{
+					{Name: transformers.ReplaceTransformerName, ApplyForReferences: true},
+				},
+			},
+			msg: "apply_for_references cannot be applied without database connection",
+		},
+		{
+			name: "transformer requires database connection",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users",
+				Transformers: []*domains.TransformerConfig{
+					{
+						Name: "DbTransformer",
+						Params: toolkit.StaticParameters{
+							"column": toolkit.ParamsValue("name"),
+							"value":  toolkit.ParamsValue("from_db"),
+						},
+					},
+				},
+			},
+			msg: "transformer requires database connection",
+		},
+		{
+			name: "lookup with query",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users",
+				Transformers: []*domains.TransformerConfig{
+					{
+						Name: transformers.LookupTransformerName,
+						Params: toolkit.StaticParameters{
+							"column":      toolkit.ParamsValue("name"),
+							"query":       toolkit.ParamsValue("SELECT name FROM masking.names"),
+							"value_field": toolkit.ParamsValue("name"),
+						},
+					},
+				},
+			},
+			msg: "transformer requires database connection",
+		},
+		{
+			name: "lookup with query in array map",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users",
+				Transformers: []*domains.TransformerConfig{
+					{
+						Name: transformers.ArrayMapTransformerName,
+						Params: toolkit.StaticParameters{
+							"column": toolkit.ParamsValue("name"),
+							"transformer": toolkit.ParamsValue(
+								`{"name": "Lookup", "params": {"query": "SELECT name FROM masking.names", "value_field": "name"}}`,
+							),
+						},
+					},
+				},
+			},
+			msg: "transformer requires database connection",
+		},
+		{
+			name: "lookup with query in json transform operation",
+			cfg: &domains.Table{
+				Schema: "public", Name: "users",
+				Transformers: []*domains.TransformerConfig{
+					{
+						Name: transformers.JsonTransformerName,
+						Params: toolkit.StaticParameters{
+							"column": toolkit.ParamsValue("name"),
+							"operations": toolkit.ParamsValue(
+								`[{"operation": "set", "path": "a", "value": 1}, ` +
+									`{"operation": "transform", "path": "name", "transformer": {"name": "Lookup", ` +
+									`"params": {"query": "SELECT name FROM masking.names", "value_field": "name"}}}]`,
+							),
+						},
+					},
+				},
+			},
+			msg: "transformer requires database connection",
+		},
+		{
+			name: "partitioned table without apply_for_inherited",
+			cfg: &domains.Table{
+				Schema: "public", Name: "orders",
+				Transformers: []*domains.TransformerConfig{newReplaceConfig("address")},
+			},
+			msg: "the table is partitioned use apply_for_inherited",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &domains.Dump{Transformation: []*domains.Table{tt.cfg}}
+			rc, err := NewOfflineRuntimeContext(
+				context.Background(), getOfflineTestSchema(), map[int32]toolkit.Oid{10: 1, 11: 3}, cfg, r,
+			)
+			require.NoError(t, err)
+			require.True(t, rc.IsFatal())
+			require.NotEmpty(t, rc.Warnings)
+			assert.Contains(t, rc.Warnings[0].Msg, tt.msg)
+		})
+	}
+
+	t.Run("transformer does not require database connection", func(t *testing.T) {
+		cfg := &domains.Dump{
+			Transformation: []*domains.Table{
+				{
+					Schema: "public", Name: "users",
+					Transformers: []*domains.TransformerConfig{
+						{
+							Name: "DbTransformer",
+							Params: toolkit.StaticParameters{
+								"column": toolkit.ParamsValue("name"),
+								"value":  toolkit.ParamsValue("masked"),
+							},
+						},
+					},
+				},
+			},
+		}
+		rc, err := NewOfflineRuntimeContext(
+			context.Background(), getOfflineTestSchema(), map[int32]toolkit.Oid{10: 1, 11: 3}, cfg, r,
+		)
+		require.NoError(t, err)
+		require.False(t, rc.IsFatal())
+	})
+
+	t.Run("nested transformer does not require database connection", func(t *testing.T) {
+		cfg := &domains.Dump{
+			Transformation: []*domains.Table{
+				{
+					Schema: "public", Name: "users",
+					Transformers: []*domains.TransformerConfig{
+						{
+							Name: transformers.JsonTransformerName,
+							Params: toolkit.StaticParameters{
+								"column": toolkit.ParamsValue("name"),
+								"operations": toolkit.ParamsValue(
+									`[{"operation": "transform", "path": "name", "transformer": {"name": "Lookup", ` +
+										`"params": {"file": "names.csv", "value_field": "name"}}}]`,
+								),
+							},
+						},
+					},
+				},
+			},
+		}
+		warnings := validateOfflineTableConfig(cfg.Transformation[0], r)
+		assert.Empty(t, warnings)
+	})
+}
@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/greenmaskio/greenmask/internal/db/postgres/mapping"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/custom"
 	transformersUtils "github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/greenmaskio/greenmask/internal/domains"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
@@ -45,5 +47,45 @@ func initTransformer(
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to init transformer: %w", err)
 	}
+	if transformer != nil && c.Mapping != nil {
+		if warn := setTransformerMapping(ctx, transformer, c.Mapping); warn != nil {
+			warnings = append(warnings, warn.AddMeta("SchemaName", d.Table.Schema).
+				AddMeta("TableName", d.Table.Name).
+				AddMeta("TransformerName", c.Name))
+		}
+	}
 	return transformer, warnings, nil
 }
+
+// setTransformerMapping - wraps the transformer, so its values are mapped through the mapping store namespace
+func setTransformerMapping(
+	ctx context.Context, tc *transformersUtils.TransformerContext, cfg *domains.TransformerMapping,
+) *toolkit.ValidationWarning {
+	if _, ok := tc.Transformer.(*custom.CmdTransformer); ok {
+		return toolkit.NewValidationWarning().
+			SetMsg("mapping is not supported for custom transformers").
+			SetSeverity(toolkit.ErrorValidationSeverity)
+	}
+	registry := mapping.GetRegistry(ctx)
+	if registry == nil {
+		return toolkit.NewValidationWarning().
+			SetMsg("mapping stores are not available").
+			SetSeverity(toolkit.ErrorValidationSeverity)
+	}
+	ns, err := registry.GetNamespace(cfg.Store, cfg.Namespace)
+	if err != nil {
+		return toolkit.NewValidationWarning().
+			SetMsg("cannot get mapping namespace").
+			AddMeta("Error", err.Error()).
+			AddMeta("Store", cfg.Store).
+			AddMeta("Namespace", cfg.Namespace).
+			SetSeverity(toolkit.ErrorValidationSeverity)
+	}
+	if err = tc.Wrap(mapping.NewTransformerWrapper(ns)); err != nil {
+		return toolkit.NewValidationWarning().
+			SetMsg("cannot apply mapping").
+			AddMeta("Error", err.Error()).
+			SetSeverity(toolkit.ErrorValidationSeverity)
+	}
+	return nil
+}
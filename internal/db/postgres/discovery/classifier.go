@@ -0,0 +1,280 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const (
+	// ColumnPlaceholder - placeholder in the transformer parameters that is replaced with the column name
+	ColumnPlaceholder = "$column"
+
+	// nameMatchScore - confidence of the classification when only the column name matches
+	nameMatchScore = 0.6
+)
+
+// Classifier - detects the PII kind of the column by its name and sampled values and proposes the transformer
+type Classifier struct {
+	Name              string
+	Transformer       string
+	TransformerParams map[string]any
+	ColumnNames       []*regexp.Regexp
+	ValuePattern      *regexp.Regexp
+	Dictionary        map[string]struct{}
+	// Validator - additional check of the value matched by ValuePattern. For instance, Luhn checksum
+	Validator func(v string) bool
+}
+
+func NewClassifier(cfg *domains.DiscoverClassifier) (*Classifier, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("classifier name is required")
+	}
+	if cfg.Transformer == nil || cfg.Transformer.Name == "" {
+		return nil, fmt.Errorf("classifier \"%s\": transformer name is required", cfg.Name)
+	}
+	c := &Classifier{
+		Name:              cfg.Name,
+		Transformer:       cfg.Transformer.Name,
+		TransformerParams: cfg.Transformer.Params,
+	}
+	for _, pattern := range cfg.ColumnNames {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("classifier \"%s\": cannot compile column name pattern: %w", cfg.Name, err)
+		}
+		c.ColumnNames = append(c.ColumnNames, re)
+	}
+	if cfg.ValuePattern != "" {
+		re, err := regexp.Compile(cfg.ValuePattern)
+		if err != nil {
+			return nil, fmt.Errorf("classifier \"%s\": cannot compile value pattern: %w", cfg.Name, err)
+		}
+		c.ValuePattern = re
+	}
+	if len(cfg.Dictionary) > 0 {
+		c.Dictionary = newDictionary(cfg.Dictionary)
+	}
+	if len(c.ColumnNames) == 0 && c.ValuePattern == nil && c.Dictionary == nil {
+		return nil, fmt.Errorf(
+			"classifier \"%s\": at least one of column_names, value_pattern or dictionary is required", cfg.Name,
+		)
+	}
+	return c, nil
+}
+
+// NewClassifiers - build classifiers list from the built-in classifiers and the user defined ones. The user defined
+// classifier replaces the built-in classifier with the same name
+func NewClassifiers(cfg []*domains.DiscoverClassifier) ([]*Classifier, error) {
+	res := DefaultClassifiers()
+	for _, cc := range cfg {
+		c, err := NewClassifier(cc)
+		if err != nil {
+			return nil, err
+		}
+		idx := slices.IndexFunc(res, func(item *Classifier) bool {
+			return item.Name == c.Name
+		})
+		if idx != -1 {
+			res[idx] = c
+		} else {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+// MatchColumnName - check the column name matches any of the column name patterns
+func (c *Classifier) MatchColumnName(name string) bool {
+	return slices.ContainsFunc(c.ColumnNames, func(re *regexp.Regexp) bool {
+		return re.MatchString(name)
+	})
+}
+
+// MatchValue - check the value matches the value pattern or the dictionary
+func (c *Classifier) MatchValue(v string) bool {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return false
+	}
+	if c.ValuePattern != nil && c.ValuePattern.MatchString(v) {
+		return c.Validator == nil || c.Validator(v)
+	}
+	if c.Dictionary != nil {
+		for _, word := range strings.Fields(v) {
+			if _, ok := c.Dictionary[strings.ToLower(word)]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CanMatchValues - the classifier is able to classify the column by values
+func (c *Classifier) CanMatchValues() bool {
+	return c.ValuePattern != nil || c.Dictionary != nil
+}
+
+// Classify - classify the column by the name and sampled values. The samples must not contain NULL values
+func (c *Classifier) Classify(column string, samples []string) *Classification {
+	res := &Classification{
+		Classifier:  c,
+		NameMatched: c.MatchColumnName(column),
+		Sampled:     len(samples),
+	}
+	var nameScore, valueScore float64
+	if res.NameMatched {
+		nameScore = nameMatchScore
+	}
+	if c.CanMatchValues() && len(samples) > 0 {
+		var matched int
+		for _, v := range samples {
+			if c.MatchValue(v) {
+				matched++
+			}
+		}
+		res.ValueMatchRatio = round(float64(matched) / float64(len(samples)))
+		valueScore = res.ValueMatchRatio
+	}
+	res.Confidence = round(1 - (1-nameScore)*(1-valueScore))
+	return res
+}
+
+// Params - transformer parameters for the column
+func (c *Classifier) Params(column string) map[string]any {
+	params := replacePlaceholder(c.TransformerParams, column).(map[string]any)
+	if params == nil {
+		params = make(map[string]any)
+	}
+	_, hasColumn := params["column"]
+	_, hasColumns := params["columns"]
+	if !hasColumn && !hasColumns {
+		params["column"] = column
+	}
+	return params
+}
+
+// Classification - result of the column classification by the classifier
+type Classification struct {
+	Classifier      *Classifier
+	NameMatched     bool
+	ValueMatchRatio float64
+	Sampled         int
+	Confidence      float64
+}
+
+// ClassifyColumn - classify the column using all the classifiers and return the one with the highest confidence.
+// It returns nil if the column does not contain PII with the confidence not less than minConfidence
+func ClassifyColumn(classifiers []*Classifier, column string, samples []string, minConfidence float64) *Classification {
+	var best *Classification
+	for _, c := range classifiers {
+		res := c.Classify(column, samples)
+		if res.Confidence == 0 || res.Confidence < minConfidence {
+			continue
+		}
+		if best == nil || res.Confidence > best.Confidence {
+			best = res
+		}
+	}
+	return best
+}
+
+func replacePlaceholder(v any, column string) any {
+	switch vv := v.(type) {
+	case string:
+		return strings.ReplaceAll(vv, ColumnPlaceholder, column)
+	case map[string]any:
+		if vv == nil {
+			return map[string]any(nil)
+		}
+		res := make(map[string]any, len(vv))
+		for k, item := range vv {
+			res[k] = replacePlaceholder(item, column)
+		}
+		return res
+	case []any:
+		res := make([]any, len(vv))
+		for i, item := range vv {
+			res[i] = replacePlaceholder(item, column)
+		}
+		return res
+	}
+	return v
+}
+
+func newDictionary(words []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		res[strings.ToLower(w)] = struct{}{}
+	}
+	return res
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// IsSampleable - the column values can be sampled for the classification. Only textual and network address columns
+// are sampled
+func IsSampleable(c *toolkit.Column) bool {
+	if c.IsGenerated {
+		return false
+	}
+	switch c.TypeOid {
+	case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID, pgtype.InetOID, pgtype.CIDROID:
+		return true
+	}
+	return c.TypeName == "citext"
+}
+
+// ClassifyTable - classify the table columns using samples (column name -> not NULL values)
+func ClassifyTable(
+	table *toolkit.Table, samples map[string][]string, classifiers []*Classifier, minConfidence float64,
+) []*ColumnReport {
+	var res []*ColumnReport
+	for _, c := range table.Columns {
+		if c.IsGenerated {
+			continue
+		}
+		cl := ClassifyColumn(classifiers, c.Name, samples[c.Name], minConfidence)
+		if cl == nil {
+			continue
+		}
+		res = append(res, &ColumnReport{
+			Schema:            table.Schema,
+			Table:             table.Name,
+			Column:            c.Name,
+			Type:              c.TypeName,
+			Classifier:        cl.Classifier.Name,
+			Confidence:        cl.Confidence,
+			NameMatched:       cl.NameMatched,
+			ValueMatchRatio:   cl.ValueMatchRatio,
+			Sampled:           cl.Sampled,
+			Transformer:       cl.Classifier.Transformer,
+			Params:            cl.Classifier.Params(c.Name),
+			ApplyForInherited: table.Kind == "p",
+		})
+	}
+	return res
+}
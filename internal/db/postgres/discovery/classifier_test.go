@@ -0,0 +1,215 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func TestClassifyColumn(t *testing.T) {
+	tests := []struct {
+		name           string
+		column         string
+		samples        []string
+		wantClassifier string
+		wantConfidence float64
+	}{
+		{
+			name:           "email by name and values",
+			column:         "contact_email",
+			samples:        []string{"john@example.com", "jane.doe@example.org"},
+			wantClassifier: EmailClassifierName,
+			wantConfidence: 1,
+		},
+		{
+			name:           "email by values only",
+			column:         "login",
+			samples:        []string{"john@example.com", "jane", "jane.doe@example.org", "bob@test.io"},
+			wantClassifier: EmailClassifierName,
+			wantConfidence: 0.75,
+		},
+		{
+			name:           "credit card with Luhn check",
+			column:         "payment",
+			samples:        []string{"4111 1111 1111 1111", "5500-0000-0000-0004"},
+			wantClassifier: CreditCardClassifierName,
+			wantConfidence: 1,
+		},
+		{
+			name:           "iban with checksum",
+			column:         "account",
+			samples:        []string{"DE89370400440532013000", "GB82WEST12345698765432"},
+			wantClassifier: IbanClassifierName,
+			wantConfidence: 1,
+		},
+		{
+			name:           "first name by dictionary",
+			column:         "given",
+			samples:        []string{"John", "Mary", "Xyz"},
+			wantClassifier: FirstNameClassifierName,
+			wantConfidence: 0.67,
+		},
+		{
+			name:           "name only match",
+			column:         "phone",
+			wantClassifier: PhoneClassifierName,
+			wantConfidence: nameMatchScore,
+		},
+		{
+			name:           "ip address",
+			column:         "remote",
+			samples:        []string{"10.0.0.1", "::1", "192.168.1.0/24"},
+			wantClassifier: IpClassifierName,
+			wantConfidence: 1,
+		},
+	}
+
+	classifiers := DefaultClassifiers()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := ClassifyColumn(classifiers, tt.column, tt.samples, 0.5)
+			require.NotNil(t, res)
+			assert.Equal(t, tt.wantClassifier, res.Classifier.Name)
+			assert.Equal(t, tt.wantConfidence, res.Confidence)
+		})
+	}
+}
+
+func TestClassifyColumn_NotPii(t *testing.T) {
+	classifiers := DefaultClassifiers()
+
+	res := ClassifyColumn(classifiers, "created_at", []string{"2024-01-01 10:00:00", "2024-02-01 12:00:00"}, 0.5)
+	assert.Nil(t, res)
+
+	res = ClassifyColumn(classifiers, "card_number", []string{"4111 1111 1111 1112", "1234 5678 9012 3456"}, 0.7)
+	assert.Nil(t, res, "wrong Luhn checksum must not be matched by values")
+
+	res = ClassifyColumn(classifiers, "comment", []string{"hello", "world"}, 0.5)
+	assert.Nil(t, res)
+}
+
+func TestNewClassifiers(t *testing.T) {
+	cfg := []*domains.DiscoverClassifier{
+		{
+			Name:        EmailClassifierName,
+			ColumnNames: []string{"(?i)^mail$"},
+			Transformer: &domains.DiscoverTransformerConfig{
+				Name: "RegexpReplace", Params: map[string]any{"regexp": ".*", "replace": "masked@example.com"},
+			},
+		},
+		{
+			Name:         "tax_id",
+			ValuePattern: `^[A-Z]{2}[0-9]{6}$`,
+			Transformer:  &domains.DiscoverTransformerConfig{Name: "RandomString"},
+		},
+	}
+	classifiers, err := NewClassifiers(cfg)
+	require.NoError(t, err)
+	require.Len(t, classifiers, len(DefaultClassifiers())+1)
+
+	res := ClassifyColumn(classifiers, "mail", nil, 0.5)
+	require.NotNil(t, res)
+	assert.Equal(t, "RegexpReplace", res.Classifier.Transformer)
+
+	res = ClassifyColumn(classifiers, "passport", []string{"AB123456"}, 0.5)
+	require.NotNil(t, res)
+	assert.Equal(t, "tax_id", res.Classifier.Name)
+}
+
+func TestNewClassifier_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *domains.DiscoverClassifier
+	}{
+		{
+			name: "no name",
+			cfg:  &domains.DiscoverClassifier{Transformer: &domains.DiscoverTransformerConfig{Name: "RandomString"}},
+		},
+		{
+			name: "no transformer",
+			cfg:  &domains.DiscoverClassifier{Name: "test", ColumnNames: []string{"test"}},
+		},
+		{
+			name: "no rules",
+			cfg: &domains.DiscoverClassifier{
+				Name: "test", Transformer: &domains.DiscoverTransformerConfig{Name: "RandomString"},
+			},
+		},
+		{
+			name: "wrong pattern",
+			cfg: &domains.DiscoverClassifier{
+				Name: "test", ValuePattern: "(",
+				Transformer: &domains.DiscoverTransformerConfig{Name: "RandomString"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClassifier(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestClassifier_Params(t *testing.T) {
+	classifiers := DefaultClassifiers()
+
+	email := classifiers[0]
+	assert.Equal(t, map[string]any{"column": "email", "engine": "hash"}, email.Params("email"))
+
+	firstName := classifiers[5]
+	require.Equal(t, FirstNameClassifierName, firstName.Name)
+	assert.Equal(t, map[string]any{
+		"columns": []any{map[string]any{"name": "fname", "template": "{{ .FirstName }}"}},
+		"engine":  "hash",
+	}, firstName.Params("fname"))
+	// The classifier params must not be changed
+	assert.Equal(t, ColumnPlaceholder, firstName.TransformerParams["columns"].([]any)[0].(map[string]any)["name"])
+}
+
+func TestClassifyTable(t *testing.T) {
+	table := &toolkit.Table{
+		Schema: "public",
+		Name:   "users",
+		Kind:   "p",
+		Columns: []*toolkit.Column{
+			{Name: "id", TypeName: "integer", TypeOid: pgtype.Int4OID},
+			{Name: "email", TypeName: "text", TypeOid: pgtype.TextOID},
+			{Name: "email_lower", TypeName: "text", TypeOid: pgtype.TextOID, IsGenerated: true},
+			{Name: "note", TypeName: "text", TypeOid: pgtype.TextOID},
+		},
+	}
+	assert.False(t, IsSampleable(table.Columns[0]))
+	assert.True(t, IsSampleable(table.Columns[1]))
+	assert.False(t, IsSampleable(table.Columns[2]))
+
+	samples := map[string][]string{
+		"email": {"john@example.com"},
+		"note":  {"hello"},
+	}
+	res := ClassifyTable(table, samples, DefaultClassifiers(), 0.5)
+	require.Len(t, res, 1)
+	assert.Equal(t, "email", res[0].Column)
+	assert.Equal(t, "RandomEmail", res[0].Transformer)
+	assert.True(t, res[0].ApplyForInherited)
+	assert.Equal(t, 1, res[0].Sampled)
+}
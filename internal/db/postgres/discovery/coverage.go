@@ -0,0 +1,52 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+)
+
+// ColumnKey - fully qualified column name. The empty Column means the table itself
+type ColumnKey struct {
+	Schema string
+	Table  string
+	Column string
+}
+
+// TransformedColumns - get the columns that are affected by the transformers of the tables. The tables that have at
+// least one transformer are added with the empty column name. The columns of the partitions are added for the
+// partitioned table as well
+func TransformedColumns(objects []entries.Entry) map[ColumnKey]struct{} {
+	res := make(map[ColumnKey]struct{})
+	for _, obj := range objects {
+		t, ok := obj.(*entries.Table)
+		if !ok || len(t.TransformersContext) == 0 {
+			continue
+		}
+		res[ColumnKey{Schema: t.Schema, Table: t.Name}] = struct{}{}
+		if t.RootPtOid != 0 {
+			res[ColumnKey{Schema: t.RootPtSchema, Table: t.RootPtName}] = struct{}{}
+		}
+		for _, tc := range t.TransformersContext {
+			for _, name := range tc.Transformer.GetAffectedColumns() {
+				res[ColumnKey{Schema: t.Schema, Table: t.Name, Column: name}] = struct{}{}
+				if t.RootPtOid != 0 {
+					res[ColumnKey{Schema: t.RootPtSchema, Table: t.RootPtName, Column: name}] = struct{}{}
+				}
+			}
+		}
+	}
+	return res
+}
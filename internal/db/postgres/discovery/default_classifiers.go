@@ -0,0 +1,206 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"math/big"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const (
+	EmailClassifierName      = "email"
+	PhoneClassifierName      = "phone"
+	IbanClassifierName       = "iban"
+	CreditCardClassifierName = "credit_card"
+	IpClassifierName         = "ip"
+	FirstNameClassifierName  = "first_name"
+	LastNameClassifierName   = "last_name"
+	FullNameClassifierName   = "full_name"
+)
+
+var (
+	datePrefixRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+	ipValueRegexp    = regexp.MustCompile(`^[0-9a-fA-F:.]+(/\d{1,3})?$`)
+
+	commonFirstNames = []string{
+		"james", "john", "robert", "michael", "william", "david", "richard", "joseph", "thomas", "charles",
+		"christopher", "daniel", "matthew", "anthony", "mark", "donald", "steven", "paul", "andrew", "joshua",
+		"mary", "patricia", "jennifer", "linda", "elizabeth", "barbara", "susan", "jessica", "sarah", "karen",
+		"nancy", "lisa", "betty", "margaret", "sandra", "ashley", "kimberly", "emily", "donna", "michelle",
+		"anna", "maria", "olga", "elena", "ivan", "alexander", "peter", "sophia", "emma", "olivia",
+	}
+
+	commonLastNames = []string{
+		"smith", "johnson", "williams", "brown", "jones", "garcia", "miller", "davis", "rodriguez", "martinez",
+		"hernandez", "lopez", "gonzalez", "wilson", "anderson", "thomas", "taylor", "moore", "jackson", "martin",
+		"lee", "perez", "thompson", "white", "harris", "sanchez", "clark", "ramirez", "lewis", "robinson",
+		"walker", "young", "allen", "king", "wright", "scott", "torres", "nguyen", "hill", "flores",
+		"green", "adams", "nelson", "baker", "hall", "rivera", "campbell", "mitchell", "carter", "roberts",
+	}
+)
+
+// DefaultClassifiers - built-in classifiers of the common PII kinds
+func DefaultClassifiers() []*Classifier {
+	return []*Classifier{
+		{
+			Name:              EmailClassifierName,
+			Transformer:       "RandomEmail",
+			TransformerParams: map[string]any{"engine": "hash"},
+			ColumnNames:       []*regexp.Regexp{regexp.MustCompile(`(?i)e_?mail`)},
+			ValuePattern:      regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}$`),
+		},
+		{
+			Name:        PhoneClassifierName,
+			Transformer: "RandomPhoneNumber",
+			ColumnNames: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)phone|mobile|(^|_)tel($|_)|telephone|msisdn`),
+			},
+			ValuePattern: regexp.MustCompile(`^\+?[0-9][0-9 ()\-.]{5,18}[0-9]$`),
+			Validator:    isPhoneNumber,
+		},
+		{
+			Name:        IbanClassifierName,
+			Transformer: "FPE",
+			TransformerParams: map[string]any{
+				"alphabet":        "0123456789",
+				"preserve_prefix": 4,
+			},
+			ColumnNames:  []*regexp.Regexp{regexp.MustCompile(`(?i)iban`)},
+			ValuePattern: regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9 ]{11,36}$`),
+			Validator:    isIban,
+		},
+		{
+			Name:        CreditCardClassifierName,
+			Transformer: "RandomCCNumber",
+			ColumnNames: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)(card|cc)_?(number|num|no)|credit_?card|^pan$`),
+			},
+			ValuePattern: regexp.MustCompile(`^[0-9][0-9 \-]{11,21}[0-9]$`),
+			Validator:    isCreditCardNumber,
+		},
+		{
+			Name:              IpClassifierName,
+			Transformer:       "RandomIp",
+			TransformerParams: map[string]any{"subnet": "192.168.0.0/16", "engine": "hash"},
+			ColumnNames:       []*regexp.Regexp{regexp.MustCompile(`(?i)(^|_)ip($|_)|ip_?addr`)},
+			ValuePattern:      ipValueRegexp,
+			Validator:         isIp,
+		},
+		{
+			Name:        FirstNameClassifierName,
+			Transformer: "RandomPerson",
+			TransformerParams: map[string]any{
+				"columns": []any{map[string]any{"name": ColumnPlaceholder, "template": "{{ .FirstName }}"}},
+				"engine":  "hash",
+			},
+			ColumnNames: []*regexp.Regexp{regexp.MustCompile(`(?i)(first|given|middle)_?name|^fname$`)},
+			Dictionary:  newDictionary(commonFirstNames),
+		},
+		{
+			Name:        LastNameClassifierName,
+			Transformer: "RandomPerson",
+			TransformerParams: map[string]any{
+				"columns": []any{map[string]any{"name": ColumnPlaceholder, "template": "{{ .LastName }}"}},
+				"engine":  "hash",
+			},
+			ColumnNames: []*regexp.Regexp{regexp.MustCompile(`(?i)(last|family|sur)_?name|^lname$`)},
+			Dictionary:  newDictionary(commonLastNames),
+		},
+		{
+			Name:        FullNameClassifierName,
+			Transformer: "RandomPerson",
+			TransformerParams: map[string]any{
+				"columns": []any{
+					map[string]any{"name": ColumnPlaceholder, "template": "{{ .FirstName }} {{ .LastName }}"},
+				},
+				"engine": "hash",
+			},
+			ColumnNames: []*regexp.Regexp{regexp.MustCompile(`(?i)full_?name|person_?name|customer_?name`)},
+		},
+	}
+}
+
+func digitsOnly(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, v)
+}
+
+func isPhoneNumber(v string) bool {
+	if datePrefixRegexp.MatchString(v) {
+		return false
+	}
+	digits := len(digitsOnly(v))
+	return digits >= 7 && digits <= 15
+}
+
+// isCreditCardNumber - check the number length and Luhn checksum
+func isCreditCardNumber(v string) bool {
+	digits := digitsOnly(v)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	var sum int
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// isIban - check the IBAN mod 97 checksum
+func isIban(v string) bool {
+	v = strings.ReplaceAll(v, " ", "")
+	if len(v) < 15 || len(v) > 34 {
+		return false
+	}
+	rearranged := v[4:] + v[:4]
+	var sb strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			sb.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func isIp(v string) bool {
+	if idx := strings.IndexByte(v, '/'); idx != -1 {
+		v = v[:idx]
+	}
+	return net.ParseIP(v) != nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ColumnReport - the discovered PII column and the proposed transformer
+type ColumnReport struct {
+	Schema          string         `json:"schema"`
+	Table           string         `json:"table"`
+	Column          string         `json:"column"`
+	Type            string         `json:"type"`
+	Classifier      string         `json:"classifier"`
+	Confidence      float64        `json:"confidence"`
+	NameMatched     bool           `json:"name_matched"`
+	ValueMatchRatio float64        `json:"value_match_ratio"`
+	Sampled         int            `json:"sampled"`
+	Transformer     string         `json:"transformer"`
+	Params          map[string]any `json:"params"`
+	// Masked - the column is already transformed by the current transformation config
+	Masked bool `json:"masked"`
+	// ApplyForInherited - the table is partitioned and the transformer must be applied for the partitions
+	ApplyForInherited bool `json:"apply_for_inherited,omitempty"`
+}
+
+type Report struct {
+	Columns []*ColumnReport `json:"columns"`
+}
+
+func NewReport() *Report {
+	return &Report{}
+}
+
+func (r *Report) Add(c *ColumnReport) {
+	r.Columns = append(r.Columns, c)
+}
+
+// Unmasked - the discovered columns that are not transformed by the current config
+func (r *Report) Unmasked() []*ColumnReport {
+	var res []*ColumnReport
+	for _, c := range r.Columns {
+		if !c.Masked {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+type jsonReport struct {
+	Columns  []*ColumnReport `json:"columns"`
+	Total    int             `json:"total"`
+	Unmasked int             `json:"unmasked"`
+}
+
+// WriteJson - write the report as JSON document
+func (r *Report) WriteJson(w io.Writer) error {
+	columns := r.Columns
+	if columns == nil {
+		columns = []*ColumnReport{}
+	}
+	return json.NewEncoder(w).Encode(&jsonReport{
+		Columns:  columns,
+		Total:    len(r.Columns),
+		Unmasked: len(r.Unmasked()),
+	})
+}
+
+// WriteYaml - write the transformation config for the unmasked columns. Each transformer is annotated with the
+// classifier name and confidence
+func (r *Report) WriteYaml(w io.Writer) error {
+	transformation := &yaml.Node{Kind: yaml.SequenceNode}
+	var tableNode, transformersNode *yaml.Node
+	var lastSchema, lastTable string
+	for _, c := range r.sortedUnmasked() {
+		if tableNode == nil || c.Schema != lastSchema || c.Table != lastTable {
+			transformersNode = &yaml.Node{Kind: yaml.SequenceNode}
+			tableNode = &yaml.Node{Kind: yaml.MappingNode}
+			tableNode.Content = append(tableNode.Content, scalar("schema"), scalar(c.Schema), scalar("name"), scalar(c.Table))
+			if c.ApplyForInherited {
+				tableNode.Content = append(tableNode.Content, scalar("apply_for_inherited"), boolScalar(true))
+			}
+			tableNode.Content = append(tableNode.Content, scalar("transformers"), transformersNode)
+			transformation.Content = append(transformation.Content, tableNode)
+			lastSchema, lastTable = c.Schema, c.Table
+		}
+
+		params := &yaml.Node{}
+		if err := params.Encode(c.Params); err != nil {
+			return fmt.Errorf("cannot encode transformer params: %w", err)
+		}
+		transformerNode := &yaml.Node{Kind: yaml.MappingNode}
+		nameNode := scalar(c.Transformer)
+		nameNode.LineComment = fmt.Sprintf("%s, confidence %.2f", c.Classifier, c.Confidence)
+		transformerNode.Content = append(transformerNode.Content, scalar("name"), nameNode, scalar("params"), params)
+		transformersNode.Content = append(transformersNode.Content, transformerNode)
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if len(transformation.Content) == 0 {
+		transformation.Style = yaml.FlowStyle
+	}
+	root.Content = append(root.Content, scalar("transformation"), transformation)
+	root.HeadComment = fmt.Sprintf(
+		"Discovered %d PII columns, %d of them are not transformed", len(r.Columns), len(r.Unmasked()),
+	)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return fmt.Errorf("cannot encode yaml: %w", err)
+	}
+	return enc.Close()
+}
+
+func (r *Report) sortedUnmasked() []*ColumnReport {
+	res := r.Unmasked()
+	slices.SortStableFunc(res, func(a, b *ColumnReport) int {
+		return cmp.Or(cmp.Compare(a.Schema, b.Schema), cmp.Compare(a.Table, b.Table))
+	})
+	return res
+}
+
+func scalar(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
+
+func boolScalar(v bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprintf("%t", v)}
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestReport() *Report {
+	r := NewReport()
+	r.Add(&ColumnReport{
+		Schema: "public", Table: "users", Column: "email", Classifier: EmailClassifierName, Confidence: 1,
+		Transformer: "RandomEmail", Params: map[string]any{"column": "email", "engine": "hash"},
+	})
+	r.Add(&ColumnReport{
+		Schema: "public", Table: "orders", Column: "ip", Classifier: IpClassifierName, Confidence: 0.6,
+		Transformer: "RandomIp", Params: map[string]any{"column": "ip"}, Masked: true,
+	})
+	r.Add(&ColumnReport{
+		Schema: "public", Table: "users", Column: "phone", Classifier: PhoneClassifierName, Confidence: 0.84,
+		Transformer: "RandomPhoneNumber", Params: map[string]any{"column": "phone"},
+	})
+	return r
+}
+
+func TestReport_WriteYaml(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, newTestReport().WriteYaml(buf))
+
+	expected := `# Discovered 3 PII columns, 2 of them are not transformed
+transformation:
+  - schema: public
+    name: users
+    transformers:
+      - name: RandomEmail # email, confidence 1.00
+        params:
+          column: email
+          engine: hash
+      - name: RandomPhoneNumber # phone, confidence 0.84
+        params:
+          column: phone
+`
+	assert.Equal(t, expected, buf.String())
+
+	var cfg struct {
+		Transformation []struct {
+			Schema       string `yaml:"schema"`
+			Name         string `yaml:"name"`
+			Transformers []struct {
+				Name   string         `yaml:"name"`
+				Params map[string]any `yaml:"params"`
+			} `yaml:"transformers"`
+		} `yaml:"transformation"`
+	}
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &cfg))
+	require.Len(t, cfg.Transformation, 1)
+	assert.Len(t, cfg.Transformation[0].Transformers, 2)
+}
+
+func TestReport_WriteYaml_Empty(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, NewReport().WriteYaml(buf))
+	assert.Equal(t, "# Discovered 0 PII columns, 0 of them are not transformed\ntransformation: []\n", buf.String())
+}
+
+func TestReport_WriteJson(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, newTestReport().WriteJson(buf))
+
+	var res struct {
+		Columns  []*ColumnReport `json:"columns"`
+		Total    int             `json:"total"`
+		Unmasked int             `json:"unmasked"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, 2, res.Unmasked)
+	require.Len(t, res.Columns, 3)
+	assert.True(t, res.Columns[1].Masked)
+}
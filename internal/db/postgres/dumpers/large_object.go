@@ -38,13 +38,12 @@ type BlobsDumper struct {
 	Blobs          *entries.Blobs
 	OriginalSize   int64
 	CompressedSize int64
-	usePgzip       bool
 }
 
-func NewLargeObjectDumper(blobs *entries.Blobs, usePgzip bool) *BlobsDumper {
+// NewLargeObjectDumper - create large objects dumper. The objects are compressed using the codec of the blobs
+func NewLargeObjectDumper(blobs *entries.Blobs) *BlobsDumper {
 	return &BlobsDumper{
-		Blobs:    blobs,
-		usePgzip: usePgzip,
+		Blobs: blobs,
 	}
 }
 
@@ -56,10 +55,10 @@ func (lod *BlobsDumper) Execute(ctx context.Context, tx pgx.Tx, st storages.Stor
 			Uint32("oid", uint32(lo.Oid)).
 			Msg("dumping large object")
 
-		w, r := ioutils.NewGzipPipe(lod.usePgzip)
+		w, r := ioutils.NewCompressionPipe(lod.Blobs.GetCodec())
 
 		// Writing goroutine
-		eg.Go(largeObjectWriter(gtx, st, lo, r))
+		eg.Go(largeObjectWriter(gtx, st, lo, lod.Blobs.GetCodec().Extension(), r))
 
 		// Dumping goroutine
 		eg.Go(largeObjectDumper(gtx, lo, w, tx))
@@ -76,6 +75,9 @@ func (lod *BlobsDumper) Execute(ctx context.Context, tx pgx.Tx, st storages.Stor
 			Msg("dumping large object completed")
 	}
 
+	lod.Blobs.OriginalSize = lod.OriginalSize
+	lod.Blobs.CompressedSize = lod.CompressedSize
+
 	// Writing blobs.toc
 	if err := lod.generateBlobsToc(ctx, st); err != nil {
 		return fmt.Errorf("cannot write large object blobs.toc: %w", err)
@@ -100,7 +102,9 @@ func (lod *BlobsDumper) generateBlobsToc(ctx context.Context, st storages.Storag
 	return nil
 }
 
-func largeObjectWriter(ctx context.Context, st storages.Storager, lo *entries.LargeObject, r ioutils.CountReadCloser) func() error {
+func largeObjectWriter(
+	ctx context.Context, st storages.Storager, lo *entries.LargeObject, ext string, r ioutils.CountReadCloser,
+) func() error {
 	return func() error {
 		defer func() {
 			log.Debug().
@@ -113,7 +117,7 @@ func largeObjectWriter(ctx context.Context, st storages.Storager, lo *entries.La
 					Msg("error closing LargeObject reader")
 			}
 		}()
-		err := st.PutObject(ctx, fmt.Sprintf("blob_%d.dat.gz", lo.Oid), r)
+		err := st.PutObject(ctx, fmt.Sprintf("blob_%d.dat%s", lo.Oid, ext), r)
 		if err != nil {
 			return fmt.Errorf("cannot write large object %d object: %w", lo.Oid, err)
 		}
@@ -0,0 +1,170 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dumpers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgcopy"
+	"github.com/greenmaskio/greenmask/internal/storages"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
+	"github.com/greenmaskio/greenmask/internal/utils/reader"
+)
+
+var errCopyTerminatorNotFound = errors.New("COPY stream is not terminated with \\.")
+
+// OfflineTableDumper - dumps the table data stored in the existing dump instead of the database. The COPY stream of
+// the source object is passed through the transformation pipeline and written as the table data of the new dump.
+// The source object codec is detected by the file extension, the result is compressed using the codec of the table
+type OfflineTableDumper struct {
+	table    *entries.Table
+	src      storages.Storager
+	fileName string
+	usePgzip bool
+}
+
+func NewOfflineTableDumper(
+	table *entries.Table, src storages.Storager, fileName string, usePgzip bool,
+) *OfflineTableDumper {
+	return &OfflineTableDumper{
+		table:    table,
+		src:      src,
+		fileName: fileName,
+		usePgzip: usePgzip,
+	}
+}
+
+// Table - the table that is dumped
+func (otd *OfflineTableDumper) Table() *entries.Table {
+	return otd.table
+}
+
+func (otd *OfflineTableDumper) Execute(ctx context.Context, st storages.Storager) error {
+	src, err := otd.src.GetObject(ctx, otd.fileName)
+	if err != nil {
+		return fmt.Errorf("cannot open source object %s: %w", otd.fileName, err)
+	}
+	gz, err := ioutils.GetCodecByFileName(otd.fileName, otd.usePgzip).NewReader(src)
+	if err != nil {
+		return fmt.Errorf("cannot decompress source object %s: %w", otd.fileName, err)
+	}
+	defer func() {
+		if err := gz.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing source object")
+		}
+	}()
+
+	w, r := ioutils.NewCompressionPipe(otd.table.GetCodec())
+	cr := ioutils.NewChecksumReader(r)
+
+	eg, gtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		defer func() {
+			if err := cr.Close(); err != nil {
+				log.Warn().Err(err).Msg("error closing OfflineTableDumper reader")
+			}
+		}()
+		if err := st.PutObject(gtx, otd.table.FileName(), cr); err != nil {
+			return fmt.Errorf("cannot write object: %w", err)
+		}
+		return nil
+	})
+	eg.Go(otd.dumper(gtx, eg, gz, w))
+
+	if err = eg.Wait(); err != nil {
+		return err
+	}
+
+	otd.table.OriginalSize = w.GetCount()
+	otd.table.CompressedSize = r.GetCount()
+	otd.table.Checksum = cr.Checksum()
+	return nil
+}
+
+func (otd *OfflineTableDumper) dumper(
+	ctx context.Context, eg *errgroup.Group, src io.Reader, w io.WriteCloser,
+) func() error {
+	return func() error {
+		var pipeline Pipeliner
+		var err error
+		if len(otd.table.TransformersContext) > 0 {
+			pipeline, err = NewTransformationPipeline(ctx, eg, otd.table, w)
+			if err != nil {
+				return fmt.Errorf("cannot initialize transformation pipeline: %w", err)
+			}
+		} else {
+			pipeline = NewPlainDumpPipeline(otd.table, w)
+		}
+		if err := pipeline.Init(ctx); err != nil {
+			return fmt.Errorf("error initializing transformation pipeline: %w", err)
+		}
+		if err := otd.process(ctx, src, w, pipeline); err != nil {
+			if doneErr := pipeline.Done(ctx); doneErr != nil {
+				log.Warn().Err(doneErr).Msg("error terminating transformation pipeline")
+			}
+			return fmt.Errorf("error processing table dump %s.%s: %w", otd.table.Schema, otd.table.Name, err)
+		}
+		return pipeline.Done(ctx)
+	}
+}
+
+func (otd *OfflineTableDumper) process(
+	ctx context.Context, src io.Reader, w io.WriteCloser, pipeline Pipeliner,
+) error {
+	defer func() {
+		if err := w.Close(); err != nil {
+			log.Warn().Err(err).Msg("error closing OfflineTableDumper writer")
+		}
+	}()
+
+	br := bufio.NewReader(src)
+	var line []byte
+	var err error
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		line, err = reader.ReadLine(br, line)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errCopyTerminatorNotFound
+			}
+			return fmt.Errorf("cannot read COPY stream: %w", err)
+		}
+		if bytes.Equal(line, pgcopy.DefaultCopyTerminationSeq) {
+			return pipeline.CompleteDump()
+		}
+		// The pipeline expects the line with the line break as it is received from the COPY protocol
+		line = append(line, '\n')
+		if err = pipeline.Dump(ctx, line); err != nil {
+			return fmt.Errorf("dump error: %w", err)
+		}
+	}
+}
+
+func (otd *OfflineTableDumper) DebugInfo() string {
+	return fmt.Sprintf("table %s.%s", otd.table.Schema, otd.table.Name)
+}
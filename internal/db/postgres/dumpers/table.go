@@ -25,6 +25,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/greenmaskio/greenmask/internal/storages"
 	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
 )
@@ -34,18 +35,22 @@ type TableDumper struct {
 	recordNum         uint64
 	validate          bool
 	validateRowsLimit uint64
-	usePgzip          bool
 }
 
-func NewTableDumper(table *entries.Table, validate bool, rowsLimit uint64, usePgzip bool) *TableDumper {
+// NewTableDumper - create table data dumper. The data is compressed using the codec of the table
+func NewTableDumper(table *entries.Table, validate bool, rowsLimit uint64) *TableDumper {
 	return &TableDumper{
 		table:             table,
 		validate:          validate,
-		usePgzip:          usePgzip,
 		validateRowsLimit: rowsLimit,
 	}
 }
 
+// Table - the table that is dumped
+func (td *TableDumper) Table() *entries.Table {
+	return td.table
+}
+
 // writer - writes the data to the storage
 func (td *TableDumper) writer(ctx context.Context, st storages.Storager, r io.ReadCloser) func() error {
 	return func() error {
@@ -54,7 +59,7 @@ func (td *TableDumper) writer(ctx context.Context, st storages.Storager, r io.Re
 				log.Warn().Err(err).Msg("error closing TableDumper reader")
 			}
 		}()
-		err := st.PutObject(ctx, fmt.Sprintf("%d.dat.gz", td.table.DumpId), r)
+		err := st.PutObject(ctx, td.table.FileName(), r)
 		if err != nil {
 			return fmt.Errorf("cannot write object: %w", err)
 		}
@@ -83,7 +88,8 @@ func (td *TableDumper) dumper(ctx context.Context, eg *errgroup.Group, w io.Writ
 		} else {
 			pipeline = NewPlainDumpPipeline(td.table, w)
 		}
-		if err := pipeline.Init(ctx); err != nil {
+		// The transformers may read the data from the dump snapshot during initialization
+		if err := pipeline.Init(utils.WithTx(ctx, tx)); err != nil {
 			return fmt.Errorf("error initializing transformation pipeline: %w", err)
 		}
 		if err := td.process(ctx, tx, w, pipeline); err != nil {
@@ -100,12 +106,13 @@ func (td *TableDumper) dumper(ctx context.Context, eg *errgroup.Group, w io.Writ
 
 func (td *TableDumper) Execute(ctx context.Context, tx pgx.Tx, st storages.Storager) error {
 
-	w, r := ioutils.NewGzipPipe(td.usePgzip)
+	w, r := ioutils.NewCompressionPipe(td.table.GetCodec())
+	cr := ioutils.NewChecksumReader(r)
 
 	eg, gtx := errgroup.WithContext(ctx)
 
 	// Storage writing goroutine
-	eg.Go(td.writer(gtx, st, r))
+	eg.Go(td.writer(gtx, st, cr))
 	// Dumping and transformation goroutine
 	eg.Go(td.dumper(gtx, eg, w, tx))
 
@@ -115,6 +122,7 @@ func (td *TableDumper) Execute(ctx context.Context, tx pgx.Tx, st storages.Stora
 
 	td.table.OriginalSize = w.GetCount()
 	td.table.CompressedSize = r.GetCount()
+	td.table.Checksum = cr.Checksum()
 	return nil
 }
 
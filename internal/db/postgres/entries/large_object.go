@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
 )
 
 type LargeObject struct {
@@ -166,6 +167,16 @@ type Blobs struct {
 	Dependencies   []int32
 	OriginalSize   int64
 	CompressedSize int64
+	// Codec - compression codec of the large objects. gzip is used if it is not set
+	Codec ioutils.Codec
+}
+
+// GetCodec - compression codec of the large objects
+func (b *Blobs) GetCodec() ioutils.Codec {
+	if b.Codec == nil {
+		return ioutils.DefaultCodec()
+	}
+	return b.Codec
 }
 
 func (b *Blobs) GetAllDDLs() []*toc.Entry {
@@ -23,6 +23,7 @@ import (
 	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/custom"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
@@ -51,11 +52,45 @@ type Table struct {
 	DumpId              int32
 	OriginalSize        int64
 	CompressedSize      int64
+	// Checksum - SHA-256 checksum of the stored (compressed) data object
+	Checksum string
 	//ExcludeData          bool
 	Driver      *toolkit.Driver
 	Scores      int64
 	SubsetConds []string
 	When        *toolkit.WhenCond
+	// UpdatedAtColumn - column that is used as the change indicator by the incremental dump
+	UpdatedAtColumn string
+	// Codec - compression codec of the data object. gzip is used if it is not set
+	Codec ioutils.Codec
+	// Chunk - the part of the table data that is dumped by this entry. It is nil if the table is dumped as a whole
+	Chunk *TableChunk
+}
+
+// TableChunk - the part of the table data defined by the range condition. The chunks of the same table are dumped
+// into separated objects concurrently within the same snapshot
+type TableChunk struct {
+	// Idx - index of the chunk starting from 0
+	Idx int
+	// Count - total number of the chunks of the table
+	Count int
+	// By - the chunking method: pk or ctid
+	By string
+	// Cond - SQL condition that selects the rows of the chunk
+	Cond string
+}
+
+// GetCodec - compression codec of the data object
+func (t *Table) GetCodec() ioutils.Codec {
+	if t.Codec == nil {
+		return ioutils.DefaultCodec()
+	}
+	return t.Codec
+}
+
+// FileName - name of the data object in the storage. The extension depends on the compression codec
+func (t *Table) FileName() string {
+	return fmt.Sprintf("%d.dat%s", t.DumpId, t.GetCodec().Extension())
 }
 
 // HasCustomTransformer - check if table has custom transformer
@@ -108,7 +143,7 @@ func (t *Table) Entry() (*toc.Entry, error) {
 	}
 	copyStmt := fmt.Sprintf(query, escapeIdent(schemaName), escapeIdent(tableName), strings.Join(columns, ", "))
 
-	fileName := fmt.Sprintf("%d.dat.gz", t.DumpId)
+	fileName := t.FileName()
 
 	dependencies := make([]int32, 0)
 	if len(t.Dependencies) != 0 {
@@ -149,6 +184,19 @@ func (t *Table) GetCopyFromStatement() (string, error) {
 	query := fmt.Sprintf("COPY \"%s\".\"%s\" TO STDOUT", escapeIdent(t.Schema), escapeIdent(t.Name))
 	if t.Query != "" {
 		query = fmt.Sprintf("COPY (%s) TO STDOUT", t.Query)
+	} else if t.Chunk != nil {
+		// The chunk is selected by query, so the generated columns must be excluded explicitly. ONLY is used because
+		// COPY of the table does not include the data of the inherited tables
+		columns := make([]string, 0, len(t.Columns))
+		for _, column := range t.Columns {
+			if !column.IsGenerated {
+				columns = append(columns, fmt.Sprintf(`"%s"`, escapeIdent(column.Name)))
+			}
+		}
+		query = fmt.Sprintf(
+			"COPY (SELECT %s FROM ONLY \"%s\".\"%s\" WHERE %s) TO STDOUT",
+			strings.Join(columns, ", "), escapeIdent(t.Schema), escapeIdent(t.Name), t.Chunk.Cond,
+		)
 	}
 	return query, nil
 }
@@ -0,0 +1,51 @@
+package entries
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func TestTable_GetCopyFromStatement(t *testing.T) {
+	table := &Table{
+		Table: &toolkit.Table{
+			Oid: 1, Schema: "public", Name: "events",
+			Columns: []*toolkit.Column{
+				{Name: "id", TypeOid: pgtype.Int8OID},
+				{Name: "id_text", TypeOid: pgtype.TextOID, IsGenerated: true},
+				{Name: "payload", TypeOid: pgtype.TextOID},
+			},
+		},
+	}
+
+	t.Run("table", func(t *testing.T) {
+		query, err := table.GetCopyFromStatement()
+		require.NoError(t, err)
+		assert.Equal(t, `COPY "public"."events" TO STDOUT`, query)
+	})
+
+	t.Run("chunk", func(t *testing.T) {
+		chunk := *table
+		chunk.Chunk = &TableChunk{Idx: 1, Count: 2, Cond: `"id" >= 100`}
+		query, err := chunk.GetCopyFromStatement()
+		require.NoError(t, err)
+		assert.Equal(t, `COPY (SELECT "id", "payload" FROM ONLY "public"."events" WHERE "id" >= 100) TO STDOUT`, query)
+
+		entry, err := chunk.Entry()
+		require.NoError(t, err)
+		assert.Equal(t, "COPY \"public\".\"events\" (\"id\", \"payload\") FROM stdin;\n", *entry.CopyStmt)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		q := *table
+		q.Query = "SELECT * FROM public.events LIMIT 1"
+		q.Chunk = &TableChunk{Idx: 0, Count: 2, Cond: `"id" < 100`}
+		query, err := q.GetCopyFromStatement()
+		require.NoError(t, err)
+		assert.Equal(t, "COPY (SELECT * FROM public.events LIMIT 1) TO STDOUT", query)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltOpenTimeout - the time to wait for the file lock. BoltDB file cannot be opened by several processes for writing
+const boltOpenTimeout = 10 * time.Second
+
+// BoltStore - stores the mappings in the local BoltDB file. Each namespace is stored in its own bucket
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string, readOnly bool) (*BoltStore, error) {
+	if readOnly {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			// Nothing was mapped yet
+			return &BoltStore{}, nil
+		}
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: boltOpenTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open bolt file \"%s\": %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Load(_ context.Context, namespace string, f func(m *Mapping) error) error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return f(&Mapping{OriginalHash: string(k), Mapped: string(v)})
+		})
+	})
+}
+
+func (s *BoltStore) Save(_ context.Context, namespace string, mappings []*Mapping) (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("store is opened in read only mode")
+	}
+	var conflicts int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return fmt.Errorf("cannot create bucket: %w", err)
+		}
+		for _, m := range mappings {
+			key := []byte(m.OriginalHash)
+			if v := b.Get(key); v != nil {
+				if string(v) != m.Mapped {
+					conflicts++
+				}
+				continue
+			}
+			if err = b.Put(key, []byte(m.Mapped)); err != nil {
+				return fmt.Errorf("cannot put mapping: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return conflicts, nil
+}
+
+func (s *BoltStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
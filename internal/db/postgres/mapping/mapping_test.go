@@ -0,0 +1,313 @@
+package mapping
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgcopy"
+	"github.com/greenmaskio/greenmask/internal/utils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+var testKey = []byte("secret")
+
+type testStore struct {
+	values map[string]map[string]string
+	saves  [][]*Mapping
+}
+
+func newTestStore() *testStore {
+	return &testStore{values: make(map[string]map[string]string)}
+}
+
+func (s *testStore) Load(_ context.Context, namespace string, f func(m *Mapping) error) error {
+	for k, v := range s.values[namespace] {
+		if err := f(&Mapping{OriginalHash: k, Mapped: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *testStore) Save(_ context.Context, namespace string, mappings []*Mapping) (int, error) {
+	s.saves = append(s.saves, mappings)
+	if s.values[namespace] == nil {
+		s.values[namespace] = make(map[string]string)
+	}
+	var conflicts int
+	for _, m := range mappings {
+		if v, ok := s.values[namespace][m.OriginalHash]; ok {
+			if v != m.Mapped {
+				conflicts++
+			}
+			continue
+		}
+		s.values[namespace][m.OriginalHash] = m.Mapped
+	}
+	return conflicts, nil
+}
+
+func (s *testStore) Close() error {
+	return nil
+}
+
+// testTransformer - appends the suffix to the value and counts the calls
+type testTransformer struct {
+	calls int
+}
+
+func (t *testTransformer) Init(context.Context) error {
+	return nil
+}
+
+func (t *testTransformer) Done(context.Context) error {
+	return nil
+}
+
+func (t *testTransformer) Transform(_ context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	t.calls++
+	v, err := r.GetRawColumnValueByIdx(0)
+	if err != nil {
+		return nil, err
+	}
+	if v.IsNull {
+		return r, nil
+	}
+	return r, r.SetRawColumnValueByIdx(0, toolkit.NewRawValue([]byte(fmt.Sprintf("%s_%d", v.Data, t.calls)), false))
+}
+
+func (t *testTransformer) GetAffectedColumns() map[int]string {
+	return map[int]string{0: "data"}
+}
+
+func newTestRecord(t *testing.T, value string) *toolkit.Record {
+	table := &toolkit.Table{
+		Schema: "public",
+		Name:   "test",
+		Oid:    1224,
+		Columns: []*toolkit.Column{
+			{Name: "data", TypeName: "text", TypeOid: pgtype.TextOID, Num: 1, Length: -1, TypeLength: -1},
+		},
+		Constraints: []toolkit.Constraint{},
+	}
+	driver, _, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	row := pgcopy.NewRow(1)
+	require.NoError(t, row.Decode([]byte(value)))
+	r := toolkit.NewRecord(driver)
+	r.SetRow(row)
+	return r
+}
+
+func getTestRecordValue(t *testing.T, r *toolkit.Record) *toolkit.RawValue {
+	v, err := r.GetRawColumnValueByIdx(0)
+	require.NoError(t, err)
+	return v
+}
+
+func TestBoltStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mapping.db")
+
+	t.Run("read only store without file", func(t *testing.T) {
+		s, err := NewBoltStore(path, true)
+		require.NoError(t, err)
+		var count int
+		require.NoError(t, s.Load(ctx, "emails", func(m *Mapping) error {
+			count++
+			return nil
+		}))
+		assert.Equal(t, 0, count)
+		_, err = s.Save(ctx, "emails", []*Mapping{{OriginalHash: "a", Mapped: "b"}})
+		require.ErrorContains(t, err, "read only")
+		require.NoError(t, s.Close())
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		s, err := NewBoltStore(path, false)
+		require.NoError(t, err)
+		conflicts, err := s.Save(ctx, "emails", []*Mapping{
+			{OriginalHash: "h1", Mapped: "a@example.com"},
+			{OriginalHash: "h2", Mapped: "b@example.com"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 0, conflicts)
+		// The same mapping is not a conflict
+		conflicts, err = s.Save(ctx, "emails", []*Mapping{
+			{OriginalHash: "h1", Mapped: "c@example.com"},
+			{OriginalHash: "h2", Mapped: "b@example.com"},
+			{OriginalHash: "h3", Mapped: "d@example.com"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, conflicts)
+		require.NoError(t, s.Close())
+
+		s, err = NewBoltStore(path, true)
+		require.NoError(t, err)
+		defer s.Close() // nolint: errcheck
+		res := make(map[string]string)
+		require.NoError(t, s.Load(ctx, "emails", func(m *Mapping) error {
+			res[m.OriginalHash] = m.Mapped
+			return nil
+		}))
+		assert.Equal(t, map[string]string{
+			"h1": "a@example.com",
+			"h2": "b@example.com",
+			"h3": "d@example.com",
+		}, res)
+	})
+}
+
+func TestNamespace(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("batches", func(t *testing.T) {
+		s := newTestStore()
+		ns := newNamespace("emails", "main", s, testKey, 2, false)
+		s.values["emails"] = map[string]string{ns.hashOriginal("a"): "x"}
+
+		mapped, ok, err := ns.Get(ctx, "a")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "x", mapped)
+
+		mapped, err = ns.Set(ctx, "a", "y")
+		require.NoError(t, err)
+		assert.Equal(t, "x", mapped)
+
+		for _, v := range []string{"b", "c", "d"} {
+			_, err = ns.Set(ctx, v, v+"1")
+			require.NoError(t, err)
+		}
+		require.Len(t, s.saves, 1)
+		assert.Len(t, s.saves[0], 2)
+
+		require.NoError(t, ns.Flush(ctx))
+		require.Len(t, s.saves, 2)
+		assert.Equal(t, []*Mapping{{OriginalHash: ns.hashOriginal("d"), Mapped: "d1"}}, s.saves[1])
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		s := newTestStore()
+		ns := newNamespace("emails", "main", s, testKey, 1, true)
+		mapped, err := ns.Set(ctx, "a", "b")
+		require.NoError(t, err)
+		assert.Equal(t, "b", mapped)
+		require.NoError(t, ns.Flush(ctx))
+		assert.Empty(t, s.saves)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		s := newTestStore()
+		ns := newNamespace("emails", "main", s, testKey, 2, false)
+		_, err := ns.Set(ctx, "a", "a1")
+		require.NoError(t, err)
+		// Another process maps the same value concurrently
+		s.values["emails"] = map[string]string{ns.hashOriginal("a"): "a2"}
+		_, err = ns.Set(ctx, "b", "b1")
+		require.ErrorIs(t, err, ErrMappingConflict)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mapping.db")
+
+	t.Run("unknown store type", func(t *testing.T) {
+		_, err := NewRegistry(ctx, []*Config{{Name: "main", Type: "redis", Key: "secret"}}, false)
+		require.ErrorContains(t, err, "unknown store type")
+	})
+
+	t.Run("duplicated store", func(t *testing.T) {
+		_, err := NewRegistry(ctx, []*Config{
+			{Name: "main", Type: StoreTypeBolt, Path: path, Key: "secret"},
+			{Name: "main", Type: StoreTypeBolt, Path: path, Key: "secret"},
+		}, false)
+		require.ErrorContains(t, err, "defined more than once")
+	})
+
+	t.Run("get namespace", func(t *testing.T) {
+		r, err := NewRegistry(ctx, []*Config{{Name: "main", Type: StoreTypeBolt, Path: path, Key: "secret"}}, false)
+		require.NoError(t, err)
+		ns1, err := r.GetNamespace("main", "emails")
+		require.NoError(t, err)
+		ns2, err := r.GetNamespace("main", "emails")
+		require.NoError(t, err)
+		assert.Same(t, ns1, ns2)
+
+		_, err = r.GetNamespace("other", "emails")
+		require.ErrorContains(t, err, "is not defined")
+		_, err = r.GetNamespace("main", "")
+		require.ErrorContains(t, err, "namespace is required")
+
+		_, err = ns1.Set(ctx, "a", "b")
+		require.NoError(t, err)
+		require.NoError(t, r.Close(ctx))
+		require.NoError(t, r.Close(ctx))
+
+		s, err := NewBoltStore(path, true)
+		require.NoError(t, err)
+		defer s.Close() // nolint: errcheck
+		var res []*Mapping
+		require.NoError(t, s.Load(ctx, "emails", func(m *Mapping) error {
+			res = append(res, m)
+			return nil
+		}))
+		// The original value is not stored
+		assert.Equal(t, []*Mapping{{OriginalHash: ns1.hashOriginal("a"), Mapped: "b"}}, res)
+		assert.NotEqual(t, "a", res[0].OriginalHash)
+	})
+
+	t.Run("key", func(t *testing.T) {
+		t.Setenv(utils.GlobalSaltEnvVariable, "")
+		_, err := NewRegistry(ctx, []*Config{{Name: "main", Type: StoreTypeBolt, Path: path}}, false)
+		require.ErrorContains(t, err, "requires the key for hashing the original values")
+
+		// The global salt is used if the key is not set
+		t.Setenv(utils.GlobalSaltEnvVariable, "0a0b0c")
+		r, err := NewRegistry(ctx, []*Config{{Name: "main", Type: StoreTypeBolt, Path: path}}, false)
+		require.NoError(t, err)
+		defer r.Close(ctx) // nolint: errcheck
+		ns, err := r.GetNamespace("main", "emails")
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x0a, 0x0b, 0x0c}, ns.key)
+	})
+}
+
+func TestTransformer(t *testing.T) {
+	ctx := context.Background()
+	ns := newNamespace("values", "main", newTestStore(), testKey, 10, false)
+	inner := &testTransformer{}
+	tr, err := NewTransformerWrapper(ns)(inner)
+	require.NoError(t, err)
+
+	r := newTestRecord(t, "john")
+	_, err = tr.Transform(ctx, r)
+	require.NoError(t, err)
+	assert.Equal(t, "john_1", string(getTestRecordValue(t, r).Data))
+
+	// The mapped value is used instead of the generated one
+	r = newTestRecord(t, "john")
+	_, err = tr.Transform(ctx, r)
+	require.NoError(t, err)
+	assert.Equal(t, "john_1", string(getTestRecordValue(t, r).Data))
+	assert.Equal(t, 1, inner.calls)
+
+	r = newTestRecord(t, "jane")
+	_, err = tr.Transform(ctx, r)
+	require.NoError(t, err)
+	assert.Equal(t, "jane_2", string(getTestRecordValue(t, r).Data))
+
+	// NULL values are not mapped
+	r = newTestRecord(t, "\\N")
+	_, err = tr.Transform(ctx, r)
+	require.NoError(t, err)
+	assert.True(t, getTestRecordValue(t, r).IsNull)
+	assert.Equal(t, 3, inner.calls)
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapping
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Namespace - the mappings of the namespace shared between all the transformers that use it. The stored mappings are
+// loaded into memory on the first use, so the lookups do not query the store. The new mappings are saved by batches.
+// The original values are identified by their keyed hash, so they are never kept in the store
+type Namespace struct {
+	name      string
+	storeName string
+	store     Store
+	key       []byte
+	batchSize int
+	readOnly  bool
+
+	mx      sync.Mutex
+	loaded  bool
+	values  map[string]string
+	pending []*Mapping
+	// saveMx - keeps the order of the batches saving
+	saveMx sync.Mutex
+}
+
+func newNamespace(name, storeName string, store Store, key []byte, batchSize int, readOnly bool) *Namespace {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return &Namespace{
+		name:      name,
+		storeName: storeName,
+		store:     store,
+		key:       key,
+		batchSize: batchSize,
+		readOnly:  readOnly,
+		values:    make(map[string]string),
+	}
+}
+
+// Get - returns the value the original value is mapped to
+func (n *Namespace) Get(ctx context.Context, original string) (string, bool, error) {
+	originalHash := n.hashOriginal(original)
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if err := n.load(ctx); err != nil {
+		return "", false, err
+	}
+	mapped, ok := n.values[originalHash]
+	return mapped, ok, nil
+}
+
+// Set - maps the original value to the generated one if it is not mapped yet and returns the value the original is
+// mapped to. The value generated concurrently by another transformer wins if it was set earlier
+func (n *Namespace) Set(ctx context.Context, original, mapped string) (string, error) {
+	originalHash := n.hashOriginal(original)
+	n.mx.Lock()
+	if err := n.load(ctx); err != nil {
+		n.mx.Unlock()
+		return "", err
+	}
+	if existing, ok := n.values[originalHash]; ok {
+		n.mx.Unlock()
+		return existing, nil
+	}
+	n.values[originalHash] = mapped
+	if n.readOnly {
+		n.mx.Unlock()
+		return mapped, nil
+	}
+	n.pending = append(n.pending, &Mapping{OriginalHash: originalHash, Mapped: mapped})
+	var batch []*Mapping
+	if len(n.pending) >= n.batchSize {
+		batch = n.pending
+		n.pending = nil
+	}
+	n.mx.Unlock()
+
+	if batch != nil {
+		if err := n.save(ctx, batch); err != nil {
+			return "", err
+		}
+	}
+	return mapped, nil
+}
+
+// Flush - saves the pending mappings
+func (n *Namespace) Flush(ctx context.Context) error {
+	n.mx.Lock()
+	batch := n.pending
+	n.pending = nil
+	n.mx.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	return n.save(ctx, batch)
+}
+
+// hashOriginal - returns the keyed hash of the original value. It is used instead of the value, so the store does not
+// contain the original data
+func (n *Namespace) hashOriginal(original string) string {
+	h := hmac.New(sha256.New, n.key)
+	h.Write([]byte(original))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (n *Namespace) load(ctx context.Context) error {
+	if n.loaded {
+		return nil
+	}
+	err := n.store.Load(ctx, n.name, func(m *Mapping) error {
+		n.values[m.OriginalHash] = m.Mapped
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot load namespace \"%s\" from store \"%s\": %w", n.name, n.storeName, err)
+	}
+	n.loaded = true
+	return nil
+}
+
+func (n *Namespace) save(ctx context.Context, batch []*Mapping) error {
+	n.saveMx.Lock()
+	defer n.saveMx.Unlock()
+	conflicts, err := n.store.Save(ctx, n.name, batch)
+	if err != nil {
+		return fmt.Errorf("cannot save namespace \"%s\" to store \"%s\": %w", n.name, n.storeName, err)
+	}
+	if conflicts > 0 {
+		// It happens when another process maps the same values concurrently. The values generated by this process
+		// might be already written into the dump, so it is not consistent with the store anymore
+		return fmt.Errorf(
+			"%w: %d values of namespace \"%s\" were mapped to other values by another process concurrently",
+			ErrMappingConflict, conflicts, n.name,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapping
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const createMappingTableQuery = `
+	CREATE TABLE IF NOT EXISTS %s
+	(
+		namespace     TEXT        NOT NULL,
+		original_hash TEXT        NOT NULL,
+		mapped        TEXT        NOT NULL,
+		created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (namespace, original_hash)
+	)
+`
+
+// loadMappingsQuery - reads the mappings page by page using the primary key order
+const loadMappingsQuery = `
+	SELECT original_hash, mapped
+	FROM %s
+	WHERE namespace = $1
+	  AND ($2::TEXT IS NULL OR original_hash > $2)
+	ORDER BY original_hash
+	LIMIT $3
+`
+
+const saveMappingsQuery = `
+	INSERT INTO %s (namespace, original_hash, mapped)
+	SELECT $1, u.original_hash, u.mapped
+	FROM unnest($2::TEXT[], $3::TEXT[]) AS u(original_hash, mapped)
+	ON CONFLICT (namespace, original_hash) DO NOTHING
+	RETURNING original_hash
+`
+
+// countConflictsQuery - counts the mappings that were not inserted because the value is already mapped to another one
+const countConflictsQuery = `
+	SELECT count(*)
+	FROM %s s
+	JOIN unnest($2::TEXT[], $3::TEXT[]) AS u(original_hash, mapped)
+	  ON s.namespace = $1 AND s.original_hash = u.original_hash
+	WHERE s.mapped <> u.mapped
+`
+
+// PostgresStore - stores the mappings in the table of the designated PostgreSQL database, so they can be shared
+// between the dumps of the different databases
+type PostgresStore struct {
+	// mx - the connection cannot be used concurrently
+	mx        sync.Mutex
+	conn      *pgx.Conn
+	table     string
+	batchSize int
+	readOnly  bool
+}
+
+func NewPostgresStore(
+	ctx context.Context, connStr, schema, table string, batchSize int, readOnly bool,
+) (*PostgresStore, error) {
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to mapping database: %w", err)
+	}
+	s := &PostgresStore{
+		conn:      conn,
+		table:     pgx.Identifier{schema, table}.Sanitize(),
+		batchSize: batchSize,
+		readOnly:  readOnly,
+	}
+	if !readOnly {
+		if _, err = conn.Exec(ctx, fmt.Sprintf(createMappingTableQuery, s.table)); err != nil {
+			_ = conn.Close(ctx)
+			return nil, fmt.Errorf("cannot create mapping table: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *PostgresStore) Load(ctx context.Context, namespace string, f func(m *Mapping) error) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.readOnly {
+		exists, err := s.tableExists(ctx)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return nil
+		}
+	}
+
+	// last - the last read original value hash. It is NULL for the first page
+	var last *string
+	for {
+		rows, err := s.conn.Query(ctx, fmt.Sprintf(loadMappingsQuery, s.table), namespace, last, s.batchSize)
+		if err != nil {
+			return fmt.Errorf("cannot load mappings: %w", err)
+		}
+		var count int
+		for rows.Next() {
+			m := &Mapping{}
+			if err = rows.Scan(&m.OriginalHash, &m.Mapped); err != nil {
+				rows.Close()
+				return fmt.Errorf("cannot scan mapping: %w", err)
+			}
+			if err = f(m); err != nil {
+				rows.Close()
+				return err
+			}
+			last = &m.OriginalHash
+			count++
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("cannot load mappings: %w", err)
+		}
+		if count < s.batchSize {
+			return nil
+		}
+	}
+}
+
+func (s *PostgresStore) Save(ctx context.Context, namespace string, mappings []*Mapping) (int, error) {
+	if s.readOnly {
+		return 0, fmt.Errorf("store is opened in read only mode")
+	}
+	hashes := make([]string, 0, len(mappings))
+	mapped := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		hashes = append(hashes, m.OriginalHash)
+		mapped = append(mapped, m.Mapped)
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(saveMappingsQuery, s.table), namespace, hashes, mapped)
+	if err != nil {
+		return 0, fmt.Errorf("cannot save mappings: %w", err)
+	}
+	inserted, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("cannot save mappings: %w", err)
+	}
+	if len(inserted) == len(mappings) {
+		return 0, nil
+	}
+
+	// The conflicts are checked by the separate statement, so the mappings committed concurrently are visible
+	insertedSet := make(map[string]struct{}, len(inserted))
+	for _, h := range inserted {
+		insertedSet[h] = struct{}{}
+	}
+	var existingHashes, existingMapped []string
+	for i, h := range hashes {
+		if _, ok := insertedSet[h]; !ok {
+			existingHashes = append(existingHashes, h)
+			existingMapped = append(existingMapped, mapped[i])
+		}
+	}
+	var conflicts int
+	err = s.conn.QueryRow(ctx, fmt.Sprintf(countConflictsQuery, s.table), namespace, existingHashes, existingMapped).
+		Scan(&conflicts)
+	if err != nil {
+		return 0, fmt.Errorf("cannot check mapping conflicts: %w", err)
+	}
+	return conflicts, nil
+}
+
+func (s *PostgresStore) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.conn.Close(context.Background())
+}
+
+func (s *PostgresStore) tableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := s.conn.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, s.table).Scan(&exists); err != nil {
+		return false, fmt.Errorf("cannot check mapping table: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/greenmaskio/greenmask/internal/utils"
+)
+
+type registryKey struct{}
+
+type storeEntry struct {
+	cfg   *Config
+	store Store
+	key   []byte
+}
+
+// Registry - the opened mapping stores and the namespaces used by the transformers
+type Registry struct {
+	readOnly   bool
+	stores     map[string]*storeEntry
+	mx         sync.Mutex
+	namespaces map[string]*Namespace
+}
+
+// NewRegistry - opens the mapping stores. The stores opened in read only mode do not save the new mappings. It is
+// used when the transformed data is not dumped, for instance by the validate command
+func NewRegistry(ctx context.Context, cfgs []*Config, readOnly bool) (*Registry, error) {
+	r := &Registry{
+		readOnly:   readOnly,
+		stores:     make(map[string]*storeEntry, len(cfgs)),
+		namespaces: make(map[string]*Namespace),
+	}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			_ = r.Close(ctx)
+			return nil, fmt.Errorf("mapping store name is required")
+		}
+		if _, ok := r.stores[cfg.Name]; ok {
+			_ = r.Close(ctx)
+			return nil, fmt.Errorf("mapping store \"%s\" is defined more than once", cfg.Name)
+		}
+		key := []byte(cfg.Key)
+		if len(key) == 0 {
+			salt, err := utils.GetGlobalSalt()
+			if err != nil {
+				_ = r.Close(ctx)
+				return nil, fmt.Errorf("cannot get key of mapping store \"%s\": %w", cfg.Name, err)
+			}
+			key = salt
+		}
+		if len(key) == 0 {
+			_ = r.Close(ctx)
+			return nil, fmt.Errorf(
+				"mapping store \"%s\" requires the key for hashing the original values: set key or %s",
+				cfg.Name, utils.GlobalSaltEnvVariable,
+			)
+		}
+		s, err := NewStore(ctx, cfg, readOnly)
+		if err != nil {
+			_ = r.Close(ctx)
+			return nil, fmt.Errorf("cannot open mapping store \"%s\": %w", cfg.Name, err)
+		}
+		r.stores[cfg.Name] = &storeEntry{cfg: cfg, store: s, key: key}
+	}
+	return r, nil
+}
+
+// GetNamespace - returns the namespace of the store. The same namespace object is returned for all the transformers,
+// so an original value is mapped to the same value in all the tables
+func (r *Registry) GetNamespace(storeName, namespace string) (*Namespace, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("mapping namespace is required")
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	se, ok := r.stores[storeName]
+	if !ok {
+		return nil, fmt.Errorf("mapping store \"%s\" is not defined", storeName)
+	}
+	key := fmt.Sprintf("%s.%s", storeName, namespace)
+	ns, ok := r.namespaces[key]
+	if !ok {
+		ns = newNamespace(namespace, storeName, se.store, se.key, se.cfg.BatchSize, r.readOnly)
+		r.namespaces[key] = ns
+	}
+	return ns, nil
+}
+
+// Close - saves the pending mappings of all the namespaces and closes the stores. The repeated calls do nothing
+func (r *Registry) Close(ctx context.Context) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	var errs []error
+	for _, ns := range r.namespaces {
+		if err := ns.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for name, se := range r.stores {
+		if err := se.store.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close mapping store \"%s\": %w", name, err))
+		}
+	}
+	r.namespaces = make(map[string]*Namespace)
+	r.stores = make(map[string]*storeEntry)
+	return errors.Join(errs...)
+}
+
+// WithRegistry - set the registry to the context. The transformers with the mapping option get the namespaces from it
+func WithRegistry(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, r)
+}
+
+// GetRegistry - get the registry from the context. Returns nil if the registry was not set
+func GetRegistry(ctx context.Context) *Registry {
+	r, _ := ctx.Value(registryKey{}).(*Registry)
+	return r
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	StoreTypeBolt     = "bolt"
+	StoreTypePostgres = "postgresql"
+)
+
+// ErrMappingConflict - the value was mapped to another value by another process concurrently. The dump that used the
+// generated value is not consistent with the store, so it must be run again
+var ErrMappingConflict = errors.New("mapping conflict")
+
+const (
+	defaultBatchSize = 1000
+	defaultSchema    = "public"
+	defaultTable     = "greenmask_mapping"
+)
+
+// Config - the mapping store settings
+type Config struct {
+	// Name - the name of the store that is used in the transformer mapping option
+	Name string `mapstructure:"name" yaml:"name" json:"name"`
+	// Type - the store type: bolt (local BoltDB file) or postgresql (table in the designated database)
+	Type string `mapstructure:"type" yaml:"type" json:"type"`
+	// Path - the path to the BoltDB file. The file is created if it does not exist
+	Path string `mapstructure:"path" yaml:"path" json:"path,omitempty"`
+	// Connection - the connection string of the database that stores the mappings
+	Connection string `mapstructure:"connection" yaml:"connection" json:"connection,omitempty"`
+	// Schema - the schema of the mapping table. public by default
+	Schema string `mapstructure:"schema" yaml:"schema" json:"schema,omitempty"`
+	// Table - the name of the mapping table. It is created if it does not exist. greenmask_mapping by default
+	Table string `mapstructure:"table" yaml:"table" json:"table,omitempty"`
+	// BatchSize - the number of the mappings that are read or written in one batch
+	BatchSize int `mapstructure:"batch_size" yaml:"batch_size" json:"batch_size,omitempty"`
+	// Key - the secret key of the keyed hash (HMAC-SHA256) of the original values. The global salt is used if it is
+	// not set. The same key must be used by all the dumps that share the mappings
+	Key string `mapstructure:"key" yaml:"key" json:"-"`
+}
+
+// Mapping - the keyed hash of the original value and the value it is mapped to. The original values are not stored
+type Mapping struct {
+	OriginalHash string
+	Mapped       string
+}
+
+// Store - the persistent storage of the mappings. The mappings are grouped by the namespaces, so the same original
+// value may be mapped differently in the different namespaces
+type Store interface {
+	// Load - reads all the mappings of the namespace by batches and calls f for each of them
+	Load(ctx context.Context, namespace string, f func(m *Mapping) error) error
+	// Save - saves the mappings of the namespace in one batch. The existing mappings are not overwritten. Returns the
+	// number of the mappings that already existed with another mapped value
+	Save(ctx context.Context, namespace string, mappings []*Mapping) (int, error)
+	Close() error
+}
+
+// NewStore - opens the store by the config. The store opened in read only mode does not create the file or table
+// and does not allow to save the mappings
+func NewStore(ctx context.Context, cfg *Config, readOnly bool) (Store, error) {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	switch cfg.Type {
+	case StoreTypeBolt:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("path is required for %s store", StoreTypeBolt)
+		}
+		return NewBoltStore(cfg.Path, readOnly)
+	case StoreTypePostgres:
+		if cfg.Connection == "" {
+			return nil, fmt.Errorf("connection is required for %s store", StoreTypePostgres)
+		}
+		schema := cfg.Schema
+		if schema == "" {
+			schema = defaultSchema
+		}
+		table := cfg.Table
+		if table == "" {
+			table = defaultTable
+		}
+		return NewPostgresStore(ctx, cfg.Connection, schema, table, batchSize, readOnly)
+	}
+	return nil, fmt.Errorf(
+		"unknown store type \"%s\": expected %s or %s", cfg.Type, StoreTypeBolt, StoreTypePostgres,
+	)
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapping
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+// Transformer - maps the original value of the affected column through the namespace. If the value is not mapped
+// yet the wrapped transformer generates it and the result is stored in the namespace. NULL values are not mapped
+type Transformer struct {
+	utils.Transformer
+	ns        *Namespace
+	columnIdx int
+}
+
+// NewTransformerWrapper - returns the wrapper that maps the values of the transformer through the namespace. The
+// transformer must affect exactly one column
+func NewTransformerWrapper(ns *Namespace) utils.TransformerWrapper {
+	return func(t utils.Transformer) (utils.Transformer, error) {
+		affectedColumns := t.GetAffectedColumns()
+		if len(affectedColumns) != 1 {
+			return nil, fmt.Errorf(
+				"mapping requires the transformer that affects exactly one column: got %d", len(affectedColumns),
+			)
+		}
+		var columnIdx int
+		for idx := range affectedColumns {
+			columnIdx = idx
+		}
+		return &Transformer{
+			Transformer: t,
+			ns:          ns,
+			columnIdx:   columnIdx,
+		}, nil
+	}
+}
+
+func (t *Transformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	original, err := r.GetRawColumnValueByIdx(t.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get raw value: %w", err)
+	}
+	if original.IsNull {
+		return t.Transformer.Transform(ctx, r)
+	}
+	// Copy the value since the row data is changed by the transformer
+	key := string(original.Data)
+
+	mapped, ok, err := t.ns.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if err = r.SetRawColumnValueByIdx(t.columnIdx, toolkit.NewRawValue([]byte(mapped), false)); err != nil {
+			return nil, fmt.Errorf("unable to set mapped value: %w", err)
+		}
+		return r, nil
+	}
+
+	if _, err = t.Transformer.Transform(ctx, r); err != nil {
+		return nil, err
+	}
+	transformed, err := r.GetRawColumnValueByIdx(t.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get raw value: %w", err)
+	}
+	if transformed.IsNull {
+		return r, nil
+	}
+	generated := string(transformed.Data)
+	mapped, err = t.ns.Set(ctx, key, generated)
+	if err != nil {
+		return nil, err
+	}
+	if mapped != generated {
+		if err = r.SetRawColumnValueByIdx(t.columnIdx, toolkit.NewRawValue([]byte(mapped), false)); err != nil {
+			return nil, fmt.Errorf("unable to set mapped value: %w", err)
+		}
+	}
+	return r, nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/greenmaskio/greenmask/internal/utils/cmd_runner"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
 )
 
 const pgDumpExecutable = "pg_dump"
@@ -55,6 +56,8 @@ type Options struct {
 	LockWaitTimeout int    `mapstructure:"lock-wait-timeout"`
 	NoSync          bool   `mapstructure:"no-sync"`
 	Description     string `mapstructure:"description"`
+	// Tags - tags of the dump that can be used instead of the dump id for selecting it
+	Tags []string `mapstructure:"tag"`
 
 	// Options controlling the output content
 	DataOnly                   bool     `mapstructure:"data-only"`
@@ -96,6 +99,17 @@ type Options struct {
 	// Custom options (not from pg_dump)
 	// Use pgzip compression instead of gzip
 	Pgzip bool `mapstructure:"pgzip"`
+	// DataCompression - compression codec of the table data and large objects: gzip, zstd, lz4 or none
+	DataCompression string `mapstructure:"data-compression"`
+	// DataCompressionLevel - compression level of the codec. 0 means the default level of the codec
+	DataCompressionLevel int `mapstructure:"data-compression-level"`
+	// ZstdLong - enable zstd long distance matching mode
+	ZstdLong bool `mapstructure:"zstd-long"`
+	// Parent - dump id of the parent dump. Unchanged tables are not dumped and referenced from the parent instead
+	Parent string `mapstructure:"parent"`
+	// Resume - dump id of the failed dump that must be continued. The objects completed by the failed run are not
+	// dumped again
+	Resume string `mapstructure:"resume"`
 
 	// Connection options:
 	DbName     string `mapstructure:"dbname"`
@@ -130,6 +144,11 @@ func (o *Options) GetPgDSN() (string, error) {
 	return strings.Join(parts, " "), nil
 }
 
+// GetCodec - compression codec of the dump objects. gzip is used by default
+func (o *Options) GetCodec() (ioutils.Codec, error) {
+	return ioutils.NewCodec(o.DataCompression, o.DataCompressionLevel, o.ZstdLong, o.Pgzip)
+}
+
 func (o *Options) GetParams() []string {
 	// TODO: dbname may be connection string itself, you have to prioritize it
 	var args []string
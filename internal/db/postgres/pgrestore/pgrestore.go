@@ -117,6 +117,10 @@ type Options struct {
 	Pgzip                            bool  `mapstructure:"pgzip"`
 	BatchSize                        int64 `mapstructure:"batch-size"`
 	UseSessionReplicationRoleReplica bool  `mapstructure:"use-session-replication-role-replica"`
+	// Resume - continue the failed restoration using the checkpoint file
+	Resume bool `mapstructure:"resume"`
+	// CheckpointFile - path to the file with the restoration progress
+	CheckpointFile string `mapstructure:"checkpoint-file"`
 
 	// Connection options:
 	Host       string `mapstructure:"host"`
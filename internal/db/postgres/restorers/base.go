@@ -6,7 +6,6 @@ import (
 	"io"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/rs/zerolog/log"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/pgrestore"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
@@ -154,7 +153,8 @@ func (rb *restoreBase) resetTx(ctx context.Context, tx pgx.Tx) error {
 	return nil
 }
 
-// getObject returns a reader for the dump file. It warps the file in a gzip reader.
+// getObject returns a reader for the dump file. It warps the file in a decompression reader of the codec that is
+// detected by the file extension.
 func (rb *restoreBase) getObject(ctx context.Context) (io.ReadCloser, error) {
 	if rb.entry.FileName == nil {
 		return nil, fmt.Errorf("file name in toc.Entry is empty")
@@ -165,14 +165,9 @@ func (rb *restoreBase) getObject(ctx context.Context) (io.ReadCloser, error) {
 		return nil, fmt.Errorf("cannot open dump file: %w", err)
 	}
 
-	gz, err := ioutils.NewGzipReader(r, rb.opt.UsePgzip)
+	gz, err := ioutils.GetCodecByFileName(*rb.entry.FileName, rb.opt.UsePgzip).NewReader(r)
 	if err != nil {
-		if err := r.Close(); err != nil {
-			log.Warn().
-				Err(err).
-				Msg("error closing dump file")
-		}
-		return nil, fmt.Errorf("cannot create gzip reader: %w", err)
+		return nil, fmt.Errorf("cannot create decompression reader: %w", err)
 	}
 
 	return gz, nil
@@ -33,18 +33,19 @@ import (
 )
 
 type BlobsRestorer struct {
-	Entry    *toc.Entry
-	St       storages.Storager
-	usePgzip bool
-	buf      []byte
+	Entry *toc.Entry
+	St    storages.Storager
+	codec ioutils.Codec
+	buf   []byte
 }
 
-func NewBlobsRestorer(entry *toc.Entry, st storages.Storager, usePgzip bool) *BlobsRestorer {
+// NewBlobsRestorer - create large objects restorer. The large objects are decompressed using the codec of the dump
+func NewBlobsRestorer(entry *toc.Entry, st storages.Storager, codec ioutils.Codec) *BlobsRestorer {
 	return &BlobsRestorer{
-		Entry:    entry,
-		St:       st,
-		usePgzip: usePgzip,
-		buf:      make([]byte, defaultBufferSize),
+		Entry: entry,
+		St:    st,
+		codec: codec,
+		buf:   make([]byte, defaultBufferSize),
 	}
 }
 
@@ -139,18 +140,16 @@ func (br *BlobsRestorer) restoreLargeObject(ctx context.Context, conn utils.PGCo
 
 // getLargeObjectDataReader - get reader for large object by oid
 func (br *BlobsRestorer) getLargeObjectDataReader(ctx context.Context, oid uint32) (io.ReadCloser, error) {
-	loReader, err := br.St.GetObject(ctx, fmt.Sprintf("blob_%d.dat.gz", oid))
+	fileName := fmt.Sprintf("blob_%d.dat%s", oid, br.codec.Extension())
+	loReader, err := br.St.GetObject(ctx, fileName)
 	if err != nil {
-		return nil, fmt.Errorf("error getting object %s: %w", fmt.Sprintf("blob_%d.dat.gz", oid), err)
+		return nil, fmt.Errorf("error getting object %s: %w", fileName, err)
 	}
-	gz, err := ioutils.GetGzipReadCloser(loReader, br.usePgzip)
+	r, err := br.codec.NewReader(loReader)
 	if err != nil {
-		if err := loReader.Close(); err != nil {
-			log.Warn().Err(err).Msg("error closing large object reader")
-		}
-		return nil, fmt.Errorf("cannot create gzip reader: %w", err)
+		return nil, fmt.Errorf("cannot create decompression reader: %w", err)
 	}
-	return gz, nil
+	return r, nil
 }
 
 // restoreLargeObjectData - restore large object data by oid by given reader withing transaction
@@ -29,7 +29,7 @@ func (s *restoresSuite) Test_BlobsRestorer_getBlobsOIds() {
 		st := new(testutils.StorageMock)
 		st.On("GetObject", ctx, mock.Anything).Return(objSrc, nil)
 
-		br := NewBlobsRestorer(&toc.Entry{}, st, false)
+		br := NewBlobsRestorer(&toc.Entry{}, st, ioutils.DefaultCodec())
 		loOids, err := br.getBlobsOids(ctx)
 		s.Require().NoError(err)
 		s.Require().Len(loOids, 2)
@@ -43,7 +43,7 @@ func (s *restoresSuite) Test_BlobsRestorer_getBlobsOIds() {
 		st.On("GetObject", ctx, mock.Anything).
 			Return(nil, errors.New("test err"))
 
-		br := NewBlobsRestorer(&toc.Entry{}, st, false)
+		br := NewBlobsRestorer(&toc.Entry{}, st, ioutils.DefaultCodec())
 		_, err := br.getBlobsOids(ctx)
 		s.Require().Error(err)
 	})
@@ -59,7 +59,7 @@ func (s *restoresSuite) Test_BlobsRestorer_getBlobsOIds() {
 		st := new(testutils.StorageMock)
 		st.On("GetObject", ctx, mock.Anything).Return(objSrc, nil)
 
-		br := NewBlobsRestorer(&toc.Entry{}, st, false)
+		br := NewBlobsRestorer(&toc.Entry{}, st, ioutils.DefaultCodec())
 		_, err := br.getBlobsOids(ctx)
 		s.Require().ErrorContains(err, "parse oid")
 	})
@@ -84,7 +84,7 @@ func (s *restoresSuite) Test_BlobsRestorer_getLargeObjectDataReader() {
 		st.On("GetObject", ctx, "blob_123.dat.gz").
 			Return(objSrc, nil)
 
-		br := NewBlobsRestorer(&toc.Entry{}, st, false)
+		br := NewBlobsRestorer(&toc.Entry{}, st, ioutils.DefaultCodec())
 		r, err := br.getLargeObjectDataReader(ctx, 123)
 		s.Require().NoError(err)
 		s.Require().NotNil(r)
@@ -102,7 +102,7 @@ func (s *restoresSuite) Test_BlobsRestorer_getLargeObjectDataReader() {
 		st.On("GetObject", ctx, "blob_123.dat.gz").
 			Return(nil, errors.New("test err"))
 
-		br := NewBlobsRestorer(&toc.Entry{}, st, false)
+		br := NewBlobsRestorer(&toc.Entry{}, st, ioutils.DefaultCodec())
 		_, err := br.getLargeObjectDataReader(ctx, 123)
 		s.Require().Error(err)
 		st.AssertNumberOfCalls(s.T(), "GetObject", 1)
@@ -134,7 +134,7 @@ func (s *restoresSuite) Test_BlobsRestorer_restoreLargeObjectData() {
 		s.Require().NoError(err)
 		st := new(testutils.StorageMock)
 
-		br := NewBlobsRestorer(&toc.Entry{}, st, false)
+		br := NewBlobsRestorer(&toc.Entry{}, st, ioutils.DefaultCodec())
 		tx, err := conn.Begin(ctx)
 		s.Require().NoError(err)
 		defer tx.Rollback(ctx) // nolint: errcheck
@@ -197,7 +197,7 @@ func (s *restoresSuite) Test_BlobsRestorer_Execute() {
 		st.On("GetObject", mock.Anything, fmt.Sprintf("blob_%d.dat.gz", loOid1)).Return(obj1, nil)
 		st.On("GetObject", mock.Anything, fmt.Sprintf("blob_%d.dat.gz", loOid2)).Return(obj2, nil)
 
-		br := NewBlobsRestorer(&toc.Entry{}, st, false)
+		br := NewBlobsRestorer(&toc.Entry{}, st, ioutils.DefaultCodec())
 		err = br.Execute(ctx, utils.NewPGConn(conn))
 		s.Require().NoError(err)
 
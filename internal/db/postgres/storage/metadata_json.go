@@ -16,18 +16,22 @@ package storage
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/toc"
 	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/internal/storages/encryption"
+	"github.com/greenmaskio/greenmask/internal/utils/ioutils"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
 type ObjectSizeStat struct {
 	Original   int64
 	Compressed int64
+	Checksum   string
 }
 
 type Header struct {
@@ -57,6 +61,32 @@ type Entry struct {
 	CompressedSize int64   `json:"compressedSize" yaml:"compressedSize"`
 	FileName       string  `json:"fileName" yaml:"fileName"`
 	Dependencies   []int32 `json:"dependencies" yaml:"dependencies"`
+	// Checksum - SHA-256 checksum of the stored object. It is used by the verify command
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	// Signature - change indicator of the table data that was collected at the dump time. It is used by the
+	// incremental dump for detecting unchanged tables
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"`
+	// Reference - the object is not stored in the dump and must be read from the referenced dump instead
+	Reference *ObjectReference `json:"reference,omitempty" yaml:"reference,omitempty"`
+	// Chunk - the part of the table data stored in the object. It is empty if the object stores the whole table
+	Chunk *TableChunk `json:"chunk,omitempty" yaml:"chunk,omitempty"`
+}
+
+// TableChunk - the range of the table data that is stored in the object. The chunks of the same table are separated
+// TABLE DATA entries, so they are restored concurrently
+type TableChunk struct {
+	Idx   int    `json:"idx" yaml:"idx"`
+	Count int    `json:"count" yaml:"count"`
+	Cond  string `json:"cond" yaml:"cond"`
+	// By - the chunking method: pk or ctid. It is empty for the dumps made before it was introduced
+	By string `json:"by,omitempty" yaml:"by,omitempty"`
+}
+
+// ObjectReference - points to the object stored in another dump. The reference always points to the dump that
+// physically stores the object, so the restoration does not need to walk the whole parent chain
+type ObjectReference struct {
+	DumpId   string `json:"dumpId" yaml:"dumpId"`
+	FileName string `json:"fileName" yaml:"fileName"`
 }
 
 type Metadata struct {
@@ -65,6 +95,7 @@ type Metadata struct {
 	OriginalSize      int64                  `yaml:"originalSize" json:"originalSize"`
 	CompressedSize    int64                  `yaml:"compressedSize" json:"compressedSize"`
 	Description       string                 `yaml:"description" json:"description"`
+	Tags              []string               `yaml:"tags,omitempty" json:"tags,omitempty"`
 	Transformers      []*domains.Table       `yaml:"transformers" json:"transformers"`
 	DatabaseSchema    toolkit.DatabaseSchema `yaml:"database_schema" json:"database_schema"`
 	Header            Header                 `yaml:"header" json:"header"`
@@ -74,6 +105,52 @@ type Metadata struct {
 	Cycles            [][]string             `yaml:"cycles" json:"cycles"`
 	TableOidToDumpId  map[toolkit.Oid]int32  `yaml:"table_dump_id" json:"table_dump_id"`
 	DumpIdsToTableOid map[int32]toolkit.Oid  `yaml:"dump_id_table" json:"dump_id_table"`
+	// Encryption - cipher and key id that were used for the dump objects encryption. It is empty if the dump is
+	// not encrypted
+	Encryption *encryption.Info `yaml:"encryption,omitempty" json:"encryption,omitempty"`
+	// Parent - dump id of the parent dump if the dump is incremental
+	Parent string `yaml:"parent,omitempty" json:"parent,omitempty"`
+	// DataCompression - codec that was used for the table data and large objects compression. It is empty for the
+	// dumps made before the codecs were introduced, such dumps are compressed using gzip
+	DataCompression *Compression `yaml:"data_compression,omitempty" json:"data_compression,omitempty"`
+}
+
+// Compression - compression codec settings of the dump objects
+type Compression struct {
+	Codec    string `yaml:"codec" json:"codec"`
+	Level    int    `yaml:"level,omitempty" json:"level,omitempty"`
+	ZstdLong bool   `yaml:"zstd_long,omitempty" json:"zstd_long,omitempty"`
+}
+
+// GetCodecName - returns the name of the codec of the dump objects
+func (m *Metadata) GetCodecName() string {
+	if m.DataCompression == nil || m.DataCompression.Codec == "" {
+		return ioutils.GzipCodecName
+	}
+	return m.DataCompression.Codec
+}
+
+// ReferencedDumpIds - returns sorted unique ids of the dumps that store objects referenced by this dump
+func (m *Metadata) ReferencedDumpIds() []string {
+	var res []string
+	for _, e := range m.Entries {
+		if e.Reference != nil && !slices.Contains(res, e.Reference.DumpId) {
+			res = append(res, e.Reference.DumpId)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// GetEntryByDumpId - returns metadata entry by toc dump id or nil if it was not found
+func (m *Metadata) GetEntryByDumpId(dumpId int32) *Entry {
+	idx := slices.IndexFunc(m.Entries, func(e *Entry) bool {
+		return e.DumpId == dumpId
+	})
+	if idx == -1 {
+		return nil
+	}
+	return m.Entries[idx]
 }
 
 func NewMetadata(
@@ -128,10 +205,12 @@ func NewMetadata(
 		}
 
 		var objCompressedSize, objOriginalSize int64
+		var objChecksum string
 		if entry.Section == toc.SectionData && *entry.Desc == toc.TableDataDesc {
 			s := stats[entry.DumpId]
 			objCompressedSize = s.Compressed
 			objOriginalSize = s.Original
+			objChecksum = s.Checksum
 			totalCompressedSize += s.Compressed
 			totalOriginalSize += s.Original
 		}
@@ -155,6 +234,7 @@ func NewMetadata(
 				Dependencies:   entry.Dependencies,
 				OriginalSize:   objOriginalSize,
 				CompressedSize: objCompressedSize,
+				Checksum:       objChecksum,
 				Section:        section,
 			},
 		)
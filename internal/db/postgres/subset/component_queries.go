@@ -0,0 +1,181 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subset
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+// generateQueriesForMultiGroupScc - generates the queries for the strongly connected component that contains
+// several groups of cycles (for instance A, B, C and B, C, E). The cycles of the different groups overlap, so
+// instead of walking each cycle the invalid rows of the whole component are collected by the recursive query.
+// Initially, they are the rows that do not meet the subset conditions of their table or the conditions of the
+// tables out of the component. Then the rows that reference the invalid rows are added until there are no new
+// ones. All the other rows of the component tables are valid
+func (g *Graph) generateQueriesForMultiGroupScc(
+	cq *cteQuery, scopeId int, c *Component, rest []*CondensedEdge, nextScopeEdges []*ScopeEdge,
+) {
+	overriddenTableNames := make(map[toolkit.Oid]string)
+	rest = slices.Clone(rest)
+	for _, se := range nextScopeEdges {
+		t := se.originalCondensedEdge.originalEdge.to.table
+		overriddenTableNames[t.Oid] = fmt.Sprintf("%s__%s__ids", t.Schema, t.Name)
+		rest = append(rest, se.originalCondensedEdge)
+	}
+
+	refsQueryName := fmt.Sprintf("__s%d__c%d__refs", scopeId, c.id)
+	invalidQueryName := fmt.Sprintf("__s%d__c%d__invalid", scopeId, c.id)
+	vertexes := getComponentVertexes(c)
+	cq.addItem(refsQueryName, generateComponentReferencesQuery(c, vertexes))
+	cq.addItem(
+		invalidQueryName,
+		generateComponentInvalidRowsQuery(c, vertexes, rest, overriddenTableNames, invalidQueryName, refsQueryName),
+	)
+	for _, v := range vertexes {
+		t := c.tables[v]
+		cq.addItem(fmt.Sprintf("%s__%s__ids", t.Schema, t.Name), generateComponentValidRowsQuery(v, t, invalidQueryName))
+	}
+}
+
+// generateComponentReferencesQuery - generates the query that selects all the references between the rows of the
+// component tables. The rows with NULL reference do not reference anything, so they are skipped
+func generateComponentReferencesQuery(c *Component, vertexes []int) string {
+	var parts []string
+	for _, v := range vertexes {
+		for _, e := range c.componentGraph[v] {
+			var toKeys, notNullConds []string
+			for _, k := range e.from.keys {
+				ref := k.GetKeyReference(e.from.table)
+				toKeys = append(toKeys, fmt.Sprintf("%s::TEXT", ref))
+				notNullConds = append(notNullConds, fmt.Sprintf("%s IS NOT NULL", ref))
+			}
+			notNullConds = append(notNullConds, e.from.polymorphicExprs...)
+			parts = append(parts, fmt.Sprintf(
+				`SELECT %d AS "from_vertex", %s AS "from_key", %d AS "to_vertex", ARRAY[%s] AS "to_key" FROM "%s"."%s" %s`,
+				e.from.idx, generateRowKeyArray(e.from.table), e.to.idx, strings.Join(toKeys, ", "),
+				e.from.table.Schema, e.from.table.Name, generateWhereClause(notNullConds),
+			))
+		}
+	}
+	return strings.Join(parts, " UNION ALL ")
+}
+
+// generateComponentInvalidRowsQuery - generates the recursive query that collects the invalid rows of the component
+// tables
+func generateComponentInvalidRowsQuery(
+	c *Component, vertexes []int, rest []*CondensedEdge, overriddenTables map[toolkit.Oid]string,
+	queryName, refsQueryName string,
+) string {
+	var initialParts []string
+	for _, v := range vertexes {
+		t := c.tables[v]
+		edges := getRestEdgesFromVertex(v, rest)
+		if len(t.SubsetConds) == 0 && len(edges) == 0 {
+			continue
+		}
+		initialParts = append(initialParts, fmt.Sprintf(
+			`SELECT %d AS "vertex", %s AS "key" FROM "%s"."%s" WHERE (%s) NOT IN (%s)`,
+			v, generateRowKeyArray(t), t.Schema, t.Name, strings.Join(generateRowKeys(t), ", "),
+			generateComponentTableValidQuery(t, edges, overriddenTables),
+		))
+	}
+	if len(initialParts) == 0 {
+		initialParts = append(initialParts, `SELECT NULL::INT AS "vertex", NULL::TEXT[] AS "key" WHERE FALSE`)
+	}
+
+	recursiveQuery := fmt.Sprintf(
+		`SELECT "%[2]s"."from_vertex", "%[2]s"."from_key" FROM "%[1]s" `+
+			`JOIN "%[2]s" ON "%[2]s"."to_vertex" = "%[1]s"."vertex" AND "%[2]s"."to_key" = "%[1]s"."key"`,
+		queryName, refsQueryName,
+	)
+	return fmt.Sprintf("( %s ) UNION ( %s )", strings.Join(initialParts, " UNION "), recursiveQuery)
+}
+
+// generateComponentTableValidQuery - generates the query that selects the primary keys of the table rows that meet
+// the subset conditions of the table and the conditions of the tables out of the component
+func generateComponentTableValidQuery(
+	t *entries.Table, edges []*Edge, overriddenTables map[toolkit.Oid]string,
+) string {
+	whereConds := slices.Clone(t.SubsetConds)
+	var joinClauses []string
+	nullabilityMap := make(map[int]bool)
+	for _, e := range edges {
+		isNullable := e.isNullable
+		if !isNullable {
+			isNullable = nullabilityMap[e.from.idx]
+		}
+		nullabilityMap[e.to.idx] = isNullable
+		joinType := joinTypeInner
+		if isNullable {
+			joinType = joinTypeLeft
+		}
+		joinClauses = append(joinClauses, generateJoinClauseV2(e, joinType, overriddenTables))
+	}
+	whereConds = append(whereConds, generateIntegrityChecksForNullableEdges(nullabilityMap, edges, overriddenTables)...)
+	return fmt.Sprintf(
+		`SELECT %s FROM "%s"."%s" %s %s`,
+		strings.Join(generateRowKeys(t), ", "), t.Schema, t.Name, strings.Join(joinClauses, " "),
+		generateWhereClause(whereConds),
+	)
+}
+
+// generateComponentValidRowsQuery - generates the query that selects the primary keys of the valid table rows
+func generateComponentValidRowsQuery(v int, t *entries.Table, invalidQueryName string) string {
+	var keys []string
+	for _, k := range t.PrimaryKey {
+		keys = append(keys, fmt.Sprintf(`"%s"."%s"."%s" AS "%s"`, t.Schema, t.Name, k, k))
+	}
+	return fmt.Sprintf(
+		`SELECT %s FROM "%s"."%s" WHERE %s NOT IN (SELECT "%s"."key" FROM "%s" WHERE "%s"."vertex" = %d)`,
+		strings.Join(keys, ", "), t.Schema, t.Name, generateRowKeyArray(t),
+		invalidQueryName, invalidQueryName, invalidQueryName, v,
+	)
+}
+
+// getRestEdgesFromVertex - returns the edges out of the component that are reachable from the vertex. The edges are
+// returned in the path order, so the tables are joined after the tables they are referenced from
+func getRestEdgesFromVertex(v int, rest []*CondensedEdge) []*Edge {
+	var res []*Edge
+	reached := []int{v}
+	used := make([]bool, len(rest))
+	for found := true; found; {
+		found = false
+		for idx, ce := range rest {
+			if used[idx] || !slices.Contains(reached, ce.originalEdge.from.idx) {
+				continue
+			}
+			used[idx] = true
+			found = true
+			res = append(res, ce.originalEdge)
+			reached = append(reached, ce.originalEdge.to.idx)
+		}
+	}
+	return res
+}
+
+// generateRowKeyArray - generates the array of the primary key values of the table row casted to TEXT. It is used to
+// compare the rows of the different tables in one query
+func generateRowKeyArray(t *entries.Table) string {
+	keys := make([]string, 0, len(t.PrimaryKey))
+	for _, k := range t.PrimaryKey {
+		keys = append(keys, fmt.Sprintf(`"%s"."%s"."%s"::TEXT`, t.Schema, t.Name, k))
+	}
+	return fmt.Sprintf("ARRAY[%s]", strings.Join(keys, ", "))
+}
@@ -0,0 +1,140 @@
+package subset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+// newMultiGroupTestGraph - creates the graph with the component that contains two cycle groups
+// a -> b -> c -> a and b -> c -> e -> b. The table a references the table users that is out of the component
+func newMultiGroupTestGraph(usersConds ...string) *Graph {
+	tables := []*entries.Table{
+		{Table: &toolkit.Table{Oid: 1, Schema: "public", Name: "a", PrimaryKey: []string{"id"}}},
+		{Table: &toolkit.Table{Oid: 2, Schema: "public", Name: "b", PrimaryKey: []string{"id"}}},
+		{Table: &toolkit.Table{Oid: 3, Schema: "public", Name: "c", PrimaryKey: []string{"id"}}},
+		{Table: &toolkit.Table{Oid: 4, Schema: "public", Name: "e", PrimaryKey: []string{"id"}}},
+		{
+			Table:       &toolkit.Table{Oid: 5, Schema: "public", Name: "users", PrimaryKey: []string{"id"}},
+			SubsetConds: usersConds,
+		},
+	}
+	g := &Graph{
+		tables:              tables,
+		graph:               make([][]*Edge, len(tables)),
+		paths:               make(map[int]*Path),
+		reversedSimpleGraph: make([][]int, len(tables)),
+		visited:             make([]int, len(tables)),
+	}
+	addEdge := func(from, to int, isNullable bool, column string) {
+		e := NewEdge(
+			len(g.edges), to, isNullable,
+			NewTableLink(from, tables[from], NewKeysByColumn([]string{column}), nil),
+			NewTableLink(to, tables[to], NewKeysByColumn(tables[to].PrimaryKey), nil),
+		)
+		g.graph[from] = append(g.graph[from], e)
+		g.reversedSimpleGraph[to] = append(g.reversedSimpleGraph[to], from)
+		g.edges = append(g.edges, e)
+	}
+	addEdge(0, 1, false, "b_id")
+	addEdge(1, 2, false, "c_id")
+	addEdge(2, 0, true, "a_id")
+	addEdge(2, 3, true, "e_id")
+	addEdge(3, 1, false, "b_id")
+	addEdge(0, 4, false, "user_id")
+	g.buildCondensedGraph()
+	return g
+}
+
+func TestSetSubsetQueries_MultiGroupScc(t *testing.T) {
+	g := newMultiGroupTestGraph("public.users.id < 100")
+	var c *Component
+	for _, scc := range g.scc {
+		if scc.hasCycle() {
+			c = scc
+		}
+	}
+	require.NotNil(t, c)
+	require.Len(t, c.groupedCycles, 2)
+
+	require.NotPanics(t, func() {
+		require.NoError(t, SetSubsetQueries(g))
+	})
+
+	refsName := fmt.Sprintf("__s0__c%d__refs", c.id)
+	invalidName := fmt.Sprintf("__s0__c%d__invalid", c.id)
+	for _, table := range g.tables[:4] {
+		assert.Contains(t, table.Query, "WITH RECURSIVE")
+		assert.Contains(t, table.Query, fmt.Sprintf(" %s AS (", refsName))
+		assert.Contains(t, table.Query, fmt.Sprintf(" %s AS (", invalidName))
+		assert.Contains(
+			t, table.Query,
+			fmt.Sprintf(`IN (SELECT "%s__%s__ids"."id" FROM "%s__%s__ids")`, table.Schema, table.Name, table.Schema, table.Name),
+		)
+		// The ids queries of the other component tables are not used
+		for _, other := range g.tables[:4] {
+			if other.Oid == table.Oid {
+				continue
+			}
+			assert.NotContains(t, table.Query, fmt.Sprintf(" %s__%s__ids AS (", other.Schema, other.Name))
+		}
+	}
+	assert.NotContains(t, g.tables[4].Query, "WITH RECURSIVE")
+
+	q := g.tables[0].Query
+	// The invalid rows of the table a are the rows that reference the filtered out users
+	assert.Contains(
+		t, q,
+		`SELECT 0 AS "vertex", ARRAY["public"."a"."id"::TEXT] AS "key" FROM "public"."a" WHERE ("public"."a"."id") NOT IN `+
+			`(SELECT "public"."a"."id" FROM "public"."a" INNER JOIN "public"."users" ON "public"."a"."user_id" = "public"."users"."id" `+
+			`AND public.users.id < 100 WHERE TRUE)`,
+	)
+	// The rows with NULL reference do not reference the invalid rows
+	assert.Contains(
+		t, q,
+		`SELECT 2 AS "from_vertex", ARRAY["public"."c"."id"::TEXT] AS "from_key", 3 AS "to_vertex", `+
+			`ARRAY["public"."c"."e_id"::TEXT] AS "to_key" FROM "public"."c" WHERE ( "public"."c"."e_id" IS NOT NULL )`,
+	)
+	assert.Contains(
+		t, q,
+		fmt.Sprintf(
+			`UNION ( SELECT "%[2]s"."from_vertex", "%[2]s"."from_key" FROM "%[1]s" JOIN "%[2]s" `+
+				`ON "%[2]s"."to_vertex" = "%[1]s"."vertex" AND "%[2]s"."to_key" = "%[1]s"."key" )`,
+			invalidName, refsName,
+		),
+	)
+	assert.Contains(
+		t, q,
+		fmt.Sprintf(
+			`public__a__ids AS (SELECT "public"."a"."id" AS "id" FROM "public"."a" WHERE ARRAY["public"."a"."id"::TEXT] `+
+				`NOT IN (SELECT "%[1]s"."key" FROM "%[1]s" WHERE "%[1]s"."vertex" = 0))`,
+			invalidName,
+		),
+	)
+}
+
+func TestGenerateComponentInvalidRowsQuery_NoConditions(t *testing.T) {
+	g := newMultiGroupTestGraph()
+	var c *Component
+	for _, scc := range g.scc {
+		if scc.hasCycle() {
+			c = scc
+		}
+	}
+	require.NotNil(t, c)
+	res := generateComponentInvalidRowsQuery(
+		c, getComponentVertexes(c), nil, make(map[toolkit.Oid]string), "invalid", "refs",
+	)
+	assert.Equal(
+		t,
+		`( SELECT NULL::INT AS "vertex", NULL::TEXT[] AS "key" WHERE FALSE ) UNION ( SELECT "refs"."from_vertex", `+
+			`"refs"."from_key" FROM "invalid" JOIN "refs" ON "refs"."to_vertex" = "invalid"."vertex" AND `+
+			`"refs"."to_key" = "invalid"."key" )`,
+		res,
+	)
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subset
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/domains"
+)
+
+// dependentVertex - the vertex of the dependent tables walk
+type dependentVertex struct {
+	idx   int
+	depth int
+	// selection - the conditions that select the rows of the table that are included into the subset
+	selection []string
+}
+
+// SetDependentsSubsetConds - walks the reversed graph from the table and sets the subset conditions of the
+// dependent tables. The dependent rows are the rows that reference the subset rows of the parent table. The tables
+// that are deeper than max depth or excluded get only the rows that do not reference the parent table (nullable
+// foreign keys). The number of the dependent rows of each table is limited by the limit setting. Each table is
+// visited once using the shortest path from the table. The conditions are regular subset conditions, so the
+// integrity of the references is still checked by the subset queries
+func SetDependentsSubsetConds(graph *Graph, table *entries.Table, cfg *domains.IncludeDependents) error {
+	rootIdx := slices.IndexFunc(graph.tables, func(t *entries.Table) bool {
+		return t.Oid == table.Oid
+	})
+	if rootIdx == -1 {
+		return fmt.Errorf("table %s.%s is not found in the graph", table.Schema, table.Name)
+	}
+
+	visited := map[int]struct{}{rootIdx: {}}
+	queue := []*dependentVertex{
+		{idx: rootIdx, selection: slices.Clone(table.SubsetConds)},
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, e := range graph.reversedGraph[v.idx] {
+			if _, ok := visited[e.idx]; ok {
+				continue
+			}
+			visited[e.idx] = struct{}{}
+			child := e.to.table
+			tableCfg := getDependentTableConfig(cfg, child)
+			if (tableCfg != nil && tableCfg.Exclude) || (cfg.MaxDepth > 0 && v.depth >= cfg.MaxDepth) {
+				child.SubsetConds = append(child.SubsetConds, generateDependentsExclusionCond(e))
+				continue
+			}
+
+			selection := append(slices.Clone(child.SubsetConds), generateReferenceCond(e, v.selection))
+			limit := cfg.Limit
+			if tableCfg != nil && tableCfg.Limit > 0 {
+				limit = tableCfg.Limit
+			}
+			if limit > 0 {
+				limitCond := generateDependentsLimitCond(e, selection, limit)
+				child.SubsetConds = append(child.SubsetConds, limitCond)
+				selection = []string{limitCond}
+			}
+			queue = append(queue, &dependentVertex{idx: e.idx, depth: v.depth + 1, selection: selection})
+		}
+	}
+	return nil
+}
+
+func getDependentTableConfig(cfg *domains.IncludeDependents, t *entries.Table) *domains.DependentTable {
+	idx := slices.IndexFunc(cfg.Tables, func(dt *domains.DependentTable) bool {
+		return dt.Schema == t.Schema && dt.Name == t.Name
+	})
+	if idx == -1 {
+		return nil
+	}
+	return cfg.Tables[idx]
+}
+
+// generateReferenceCond - generates the condition that selects the rows of the dependent table that reference
+// the selected rows of the parent table
+func generateReferenceCond(e *Edge, parentSelection []string) string {
+	parent, child := e.from.table, e.to.table
+	var parentKeys, childKeys []string
+	for idx := range e.to.keys {
+		parentKeys = append(parentKeys, e.from.keys[idx].GetKeyReference(parent))
+		childKeys = append(childKeys, e.to.keys[idx].GetKeyReference(child))
+	}
+	return fmt.Sprintf(
+		`(%s) IN (SELECT %s FROM ONLY "%s"."%s" %s)`,
+		strings.Join(childKeys, ", "), strings.Join(parentKeys, ", "),
+		parent.Schema, parent.Name, generateWhereClause(parentSelection),
+	)
+}
+
+// generateDependentsLimitCond - generates the condition that selects at most limit dependent rows of the table in
+// the primary key order. The rows with NULL reference are not dependent, so they are not limited
+func generateDependentsLimitCond(e *Edge, selection []string, limit int64) string {
+	child := e.to.table
+	keysExpr := strings.Join(generateRowKeys(child), ", ")
+	cond := fmt.Sprintf(
+		`(%s) IN (SELECT %s FROM ONLY "%s"."%s" %s ORDER BY %s LIMIT %d)`,
+		keysExpr, keysExpr, child.Schema, child.Name, generateWhereClause(selection), keysExpr, limit,
+	)
+	if !e.isNullable {
+		return fmt.Sprintf("( %s )", cond)
+	}
+	return fmt.Sprintf("( (%s) OR %s )", generateNullReferenceCond(e), cond)
+}
+
+// generateDependentsExclusionCond - generates the condition that excludes all the rows of the dependent table that
+// reference the parent table
+func generateDependentsExclusionCond(e *Edge) string {
+	if !e.isNullable {
+		return "FALSE"
+	}
+	return generateNullReferenceCond(e)
+}
+
+func generateNullReferenceCond(e *Edge) string {
+	var conds []string
+	for _, k := range e.to.keys {
+		conds = append(conds, fmt.Sprintf(`%s IS NULL`, k.GetKeyReference(e.to.table)))
+	}
+	return strings.Join(conds, " AND ")
+}
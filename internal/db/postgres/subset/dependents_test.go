@@ -0,0 +1,125 @@
+package subset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func newDependentsTestGraph() *Graph {
+	users := &entries.Table{
+		Table:       &toolkit.Table{Oid: 1, Schema: "public", Name: "users", PrimaryKey: []string{"id"}},
+		SubsetConds: []string{"public.users.id < 100"},
+	}
+	orders := &entries.Table{
+		Table: &toolkit.Table{Oid: 2, Schema: "public", Name: "orders", PrimaryKey: []string{"id"}},
+	}
+	orderItems := &entries.Table{
+		Table: &toolkit.Table{Oid: 3, Schema: "public", Name: "order_items", PrimaryKey: []string{"id"}},
+	}
+	payments := &entries.Table{
+		Table: &toolkit.Table{Oid: 4, Schema: "public", Name: "payments"},
+	}
+	tables := []*entries.Table{users, orders, orderItems, payments}
+	reversedGraph := [][]*Edge{
+		{
+			NewEdge(0, 1, false,
+				NewTableLink(0, users, NewKeysByColumn([]string{"id"}), nil),
+				NewTableLink(1, orders, NewKeysByColumn([]string{"user_id"}), nil),
+			),
+		},
+		{
+			NewEdge(1, 2, false,
+				NewTableLink(1, orders, NewKeysByColumn([]string{"id"}), nil),
+				NewTableLink(2, orderItems, NewKeysByColumn([]string{"order_id"}), nil),
+			),
+			NewEdge(2, 3, true,
+				NewTableLink(1, orders, NewKeysByColumn([]string{"id"}), nil),
+				NewTableLink(3, payments, NewKeysByColumn([]string{"order_id"}), nil),
+			),
+		},
+		nil,
+		nil,
+	}
+	return &Graph{tables: tables, reversedGraph: reversedGraph}
+}
+
+func TestSetDependentsSubsetConds(t *testing.T) {
+	ordersLimitCond := `( ("public"."orders"."id") IN (SELECT "public"."orders"."id" FROM ONLY "public"."orders" ` +
+		`WHERE ( ("public"."orders"."user_id") IN (SELECT "public"."users"."id" FROM ONLY "public"."users" ` +
+		`WHERE ( public.users.id < 100 )) ) ORDER BY "public"."orders"."id" LIMIT 10) )`
+
+	tests := []struct {
+		name     string
+		cfg      *domains.IncludeDependents
+		expected [][]string
+	}{
+		{
+			name: "limit",
+			cfg:  &domains.IncludeDependents{Limit: 10},
+			expected: [][]string{
+				{"public.users.id < 100"},
+				{ordersLimitCond},
+				{
+					`( ("public"."order_items"."id") IN (SELECT "public"."order_items"."id" ` +
+						`FROM ONLY "public"."order_items" WHERE ( ("public"."order_items"."order_id") IN ` +
+						`(SELECT "public"."orders"."id" FROM ONLY "public"."orders" WHERE ( ` + ordersLimitCond + ` )) ) ` +
+						`ORDER BY "public"."order_items"."id" LIMIT 10) )`,
+				},
+				{
+					`( ("public"."payments"."order_id" IS NULL) OR ("public"."payments".ctid) IN ` +
+						`(SELECT "public"."payments".ctid FROM ONLY "public"."payments" ` +
+						`WHERE ( ("public"."payments"."order_id") IN (SELECT "public"."orders"."id" ` +
+						`FROM ONLY "public"."orders" WHERE ( ` + ordersLimitCond + ` )) ) ` +
+						`ORDER BY "public"."payments".ctid LIMIT 10) )`,
+				},
+			},
+		},
+		{
+			name: "max depth",
+			cfg:  &domains.IncludeDependents{MaxDepth: 1},
+			expected: [][]string{
+				{"public.users.id < 100"},
+				nil,
+				{"FALSE"},
+				{`"public"."payments"."order_id" IS NULL`},
+			},
+		},
+		{
+			name: "table settings",
+			cfg: &domains.IncludeDependents{
+				Tables: []*domains.DependentTable{
+					{Schema: "public", Name: "orders", Limit: 10},
+					{Schema: "public", Name: "order_items", Exclude: true},
+				},
+			},
+			expected: [][]string{
+				{"public.users.id < 100"},
+				{ordersLimitCond},
+				{"FALSE"},
+				nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newDependentsTestGraph()
+			require.NoError(t, SetDependentsSubsetConds(g, g.tables[0], tt.cfg))
+			for idx, table := range g.tables {
+				assert.Equal(t, tt.expected[idx], table.SubsetConds, table.Name)
+			}
+		})
+	}
+
+	t.Run("table is not found", func(t *testing.T) {
+		g := newDependentsTestGraph()
+		table := &entries.Table{Table: &toolkit.Table{Oid: 5, Schema: "public", Name: "unknown"}}
+		err := SetDependentsSubsetConds(g, table, &domains.IncludeDependents{})
+		require.ErrorContains(t, err, "is not found in the graph")
+	})
+}
@@ -404,7 +404,8 @@ func (g *Graph) generateQueryForScc(cq *cteQuery, scopeId int, path *Path, prevS
 	}
 	//cycle := orderCycle(rootVertex.cycles[0], edges, path.scopeGraph[scopeId])
 	if len(rootVertex.groupedCycles) > 1 {
-		panic("IMPLEMENT ME: more than one cycle group found in SCC")
+		g.generateQueriesForMultiGroupScc(cq, scopeId, rootVertex, edges, nextScopeEdges)
+		return
 	}
 	cycleGroup := rootVertex.getOneCycleGroup()
 	overlapMap := g.getOverlapMap(cycleGroup)
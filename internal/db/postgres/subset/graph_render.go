@@ -0,0 +1,163 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subset
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+)
+
+const (
+	GraphFormatDot     = "dot"
+	GraphFormatMermaid = "mermaid"
+)
+
+// Edges - returns the references between the tables in the graph
+func (g *Graph) Edges() []*Edge {
+	return g.edges
+}
+
+// Render - renders the condensed graph of the tables in Graphviz DOT or Mermaid format. The strongly connected
+// components that contain cycles are rendered as clusters and the edges of the cycles are highlighted. The tables
+// with subset conditions are highlighted as well
+func (g *Graph) Render(format string) (string, error) {
+	switch format {
+	case GraphFormatDot:
+		return g.renderDot(), nil
+	case GraphFormatMermaid:
+		return g.renderMermaid(), nil
+	}
+	return "", fmt.Errorf(
+		"unknown graph format \"%s\": expected %s or %s", format, GraphFormatDot, GraphFormatMermaid,
+	)
+}
+
+func (g *Graph) renderDot() string {
+	var sb strings.Builder
+	sb.WriteString("digraph subset {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	for _, c := range g.scc {
+		indent := "  "
+		if c.hasCycle() {
+			fmt.Fprintf(&sb, "  subgraph cluster_%d {\n", c.id)
+			fmt.Fprintf(&sb, "    label=\"component %d (cycle)\";\n", c.id)
+			sb.WriteString("    color=red;\n")
+			indent = "    "
+		}
+		for _, idx := range getComponentVertexes(c) {
+			t := c.tables[idx]
+			attrs := fmt.Sprintf("label=%q", getTableLabel(t))
+			if len(t.SubsetConds) > 0 {
+				attrs += ", style=filled, fillcolor=lightblue"
+			}
+			fmt.Fprintf(&sb, "%st%d [%s];\n", indent, idx, attrs)
+		}
+		if c.hasCycle() {
+			sb.WriteString("  }\n")
+		}
+	}
+	for _, e := range g.edges {
+		attrs := fmt.Sprintf("label=%q", getEdgeLabel(e))
+		if g.isCycleEdge(e) {
+			attrs += ", color=red"
+		}
+		if e.isNullable {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&sb, "  t%d -> t%d [%s];\n", e.from.idx, e.to.idx, attrs)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func (g *Graph) renderMermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	for _, c := range g.scc {
+		indent := "  "
+		if c.hasCycle() {
+			fmt.Fprintf(&sb, "  subgraph c%d [\"component %d (cycle)\"]\n", c.id, c.id)
+			indent = "    "
+		}
+		for _, idx := range getComponentVertexes(c) {
+			t := c.tables[idx]
+			fmt.Fprintf(&sb, "%st%d[\"%s\"]", indent, idx, escapeMermaidLabel(getTableLabel(t)))
+			if len(t.SubsetConds) > 0 {
+				sb.WriteString(":::subset")
+			}
+			sb.WriteString("\n")
+		}
+		if c.hasCycle() {
+			sb.WriteString("  end\n")
+		}
+	}
+	for _, e := range g.edges {
+		arrow := "-->"
+		if g.isCycleEdge(e) {
+			arrow = "==>"
+		} else if e.isNullable {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(
+			&sb, "  t%d %s|\"%s\"| t%d\n", e.from.idx, arrow, escapeMermaidLabel(getEdgeLabel(e)), e.to.idx,
+		)
+	}
+	sb.WriteString("  classDef subset fill:#add8e6\n")
+	return sb.String()
+}
+
+// isCycleEdge - checks the edge connects the tables of the same strongly connected component
+func (g *Graph) isCycleEdge(e *Edge) bool {
+	for _, vertexes := range g.componentsToOriginalVertexes {
+		if slices.Contains(vertexes, e.from.idx) {
+			return slices.Contains(vertexes, e.to.idx)
+		}
+	}
+	return false
+}
+
+// getComponentVertexes - returns the sorted vertexes of the component, so the rendering result is stable
+func getComponentVertexes(c *Component) []int {
+	res := make([]int, 0, len(c.tables))
+	for idx := range c.tables {
+		res = append(res, idx)
+	}
+	slices.Sort(res)
+	return res
+}
+
+func getTableLabel(t *entries.Table) string {
+	return fmt.Sprintf("%s.%s", t.Schema, t.Name)
+}
+
+func getEdgeLabel(e *Edge) string {
+	keys := make([]string, 0, len(e.from.keys))
+	for _, k := range e.from.keys {
+		if k.Expression != "" {
+			keys = append(keys, k.Expression)
+			continue
+		}
+		keys = append(keys, k.Name)
+	}
+	return strings.Join(keys, ", ")
+}
+
+func escapeMermaidLabel(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
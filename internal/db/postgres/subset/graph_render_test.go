@@ -0,0 +1,76 @@
+package subset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func newRenderTestGraph() *Graph {
+	tables := []*entries.Table{
+		{
+			Table:       &toolkit.Table{Oid: 1, Schema: "public", Name: "users", PrimaryKey: []string{"id"}},
+			SubsetConds: []string{"public.users.id < 100"},
+		},
+		{Table: &toolkit.Table{Oid: 2, Schema: "public", Name: "orders", PrimaryKey: []string{"id"}}},
+		{Table: &toolkit.Table{Oid: 3, Schema: "public", Name: "a", PrimaryKey: []string{"id"}}},
+		{Table: &toolkit.Table{Oid: 4, Schema: "public", Name: "b", PrimaryKey: []string{"id"}}},
+	}
+	g := &Graph{
+		tables:              tables,
+		graph:               make([][]*Edge, len(tables)),
+		reversedSimpleGraph: make([][]int, len(tables)),
+		visited:             make([]int, len(tables)),
+	}
+	addEdge := func(from, to int, isNullable bool, column string) {
+		e := NewEdge(
+			len(g.edges), to, isNullable,
+			NewTableLink(from, tables[from], NewKeysByColumn([]string{column}), nil),
+			NewTableLink(to, tables[to], NewKeysByColumn(tables[to].PrimaryKey), nil),
+		)
+		g.graph[from] = append(g.graph[from], e)
+		g.reversedSimpleGraph[to] = append(g.reversedSimpleGraph[to], from)
+		g.edges = append(g.edges, e)
+	}
+	addEdge(1, 0, false, "user_id")
+	addEdge(2, 3, false, "b_id")
+	addEdge(3, 2, true, "a_id")
+	g.buildCondensedGraph()
+	return g
+}
+
+func TestGraph_Render(t *testing.T) {
+	g := newRenderTestGraph()
+
+	t.Run("dot", func(t *testing.T) {
+		res, err := g.Render(GraphFormatDot)
+		require.NoError(t, err)
+		assert.Contains(t, res, "digraph subset {\n")
+		assert.Contains(t, res, `t0 [label="public.users", style=filled, fillcolor=lightblue];`)
+		assert.Contains(t, res, `  t1 [label="public.orders"];`)
+		assert.Contains(t, res, `(cycle)";`)
+		assert.Contains(t, res, `    t2 [label="public.a"];`)
+		assert.Contains(t, res, `  t1 -> t0 [label="user_id"];`)
+		assert.Contains(t, res, `  t2 -> t3 [label="b_id", color=red];`)
+		assert.Contains(t, res, `  t3 -> t2 [label="a_id", color=red, style=dashed];`)
+	})
+
+	t.Run("mermaid", func(t *testing.T) {
+		res, err := g.Render(GraphFormatMermaid)
+		require.NoError(t, err)
+		assert.Contains(t, res, "flowchart LR\n")
+		assert.Contains(t, res, `  t0["public.users"]:::subset`)
+		assert.Contains(t, res, `    t3["public.b"]`)
+		assert.Contains(t, res, `  t1 -->|"user_id"| t0`)
+		assert.Contains(t, res, `  t3 ==>|"a_id"| t2`)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := g.Render("svg")
+		require.ErrorContains(t, err, "unknown graph format")
+	})
+}
@@ -120,3 +120,17 @@ func generateSelectByPrimaryKey(table *entries.Table, pk []string) string {
 		strings.Join(keys, ", "),
 	)
 }
+
+// generateRowKeys - generates the references to the columns that identify the table row. They are the primary key
+// columns or ctid if the table does not have the primary key
+func generateRowKeys(table *entries.Table) []string {
+	tableName := fmt.Sprintf(`"%s"."%s"`, table.Schema, table.Name)
+	if len(table.PrimaryKey) == 0 {
+		return []string{fmt.Sprintf(`%s.ctid`, tableName)}
+	}
+	keys := make([]string, 0, len(table.PrimaryKey))
+	for _, k := range table.PrimaryKey {
+		keys = append(keys, fmt.Sprintf(`%s."%s"`, tableName, k))
+	}
+	return keys
+}
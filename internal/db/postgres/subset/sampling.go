@@ -0,0 +1,107 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subset
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/domains"
+)
+
+const (
+	SampleMethodBernoulli = "bernoulli"
+	SampleMethodSystem    = "system"
+	SampleMethodHash      = "hash"
+)
+
+// hashSampleModulus - the number of the hash buckets of the hash sampling. It allows the percent with 4 decimal places
+const hashSampleModulus = 1000000
+
+// GenerateSamplingCond - generates the subset condition of the table that selects the sample and limits the number
+// of the rows. conds are the subset conditions of the table, they are applied before the limit, so the limit is
+// the number of the rows that met the conditions. The rows are identified by the primary key or by ctid if the
+// table does not have the primary key. The condition is used as the regular subset condition, so the references of
+// the table are filtered by the graph as well
+func GenerateSamplingCond(t *entries.Table, conds []string, sample *domains.SubsetSample, limit int64) string {
+	tableName := fmt.Sprintf(`"%s"."%s"`, t.Schema, t.Name)
+	keysExpr := strings.Join(generateRowKeys(t), ", ")
+
+	var tableSample string
+	whereConds := conds
+	if sample != nil {
+		if getSampleMethod(sample) == SampleMethodHash {
+			hashCond := generateHashSampleCond(t, tableName, keysExpr, sample)
+			if limit == 0 {
+				return fmt.Sprintf("( %s )", hashCond)
+			}
+			whereConds = append(append([]string{}, conds...), hashCond)
+		} else {
+			tableSample = generateTableSampleClause(sample)
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM ONLY %s`, keysExpr, tableName)
+	if tableSample != "" {
+		query = fmt.Sprintf(`%s %s`, query, tableSample)
+	}
+	if limit > 0 {
+		query = fmt.Sprintf(`%s %s ORDER BY %s LIMIT %d`, query, generateWhereClause(whereConds), keysExpr, limit)
+	}
+	return fmt.Sprintf(`( (%s) IN (%s) )`, keysExpr, query)
+}
+
+func getSampleMethod(sample *domains.SubsetSample) string {
+	if sample.Method == "" {
+		return SampleMethodHash
+	}
+	return strings.ToLower(sample.Method)
+}
+
+// generateHashSampleCond - generates the deterministic sample condition. The row is in the sample if the hash of its
+// key with the seed falls into the first percent of the buckets. The whole row is hashed if the table does not have
+// the primary key
+func generateHashSampleCond(t *entries.Table, tableName, keysExpr string, sample *domains.SubsetSample) string {
+	var seed int64
+	if sample.Seed != nil {
+		seed = *sample.Seed
+	}
+	rowExpr := fmt.Sprintf(`ROW(%s)::TEXT`, keysExpr)
+	if len(t.PrimaryKey) == 0 {
+		rowExpr = fmt.Sprintf(`ROW(%s.*)::TEXT`, tableName)
+	}
+	// The percent smaller than the bucket is rounded up, so the sample is not empty
+	threshold := max(int64(math.Round(sample.Percent*hashSampleModulus/100)), 1)
+	return fmt.Sprintf(
+		`abs(pg_catalog.hashtextextended(%s, %d) %% %d) < %d`, rowExpr, seed, hashSampleModulus, threshold,
+	)
+}
+
+// generateTableSampleClause - generates TABLESAMPLE clause. REPEATABLE is always used, because the condition is
+// copied into the queries of the referencing tables and all of them must select the same sample. The seed is 0 if it
+// is not set
+func generateTableSampleClause(sample *domains.SubsetSample) string {
+	var seed int64
+	if sample.Seed != nil {
+		seed = *sample.Seed
+	}
+	return fmt.Sprintf(
+		`TABLESAMPLE %s (%s) REPEATABLE (%d)`,
+		strings.ToUpper(getSampleMethod(sample)), strconv.FormatFloat(sample.Percent, 'f', -1, 64), seed,
+	)
+}
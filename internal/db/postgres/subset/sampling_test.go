@@ -0,0 +1,101 @@
+package subset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/entries"
+	"github.com/greenmaskio/greenmask/internal/domains"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func TestGenerateSamplingCond(t *testing.T) {
+	seed := int64(42)
+	customers := &entries.Table{
+		Table: &toolkit.Table{Schema: "public", Name: "customers", PrimaryKey: []string{"id"}},
+	}
+	logs := &entries.Table{
+		Table: &toolkit.Table{Schema: "public", Name: "logs"},
+	}
+	conds := []string{`( "public"."customers"."active" )`}
+
+	tests := []struct {
+		name     string
+		table    *entries.Table
+		conds    []string
+		sample   *domains.SubsetSample
+		limit    int64
+		expected string
+	}{
+		{
+			name:   "hash",
+			table:  customers,
+			sample: &domains.SubsetSample{Percent: 5, Seed: &seed},
+			expected: `( abs(pg_catalog.hashtextextended(ROW("public"."customers"."id")::TEXT, 42) % 1000000) ` +
+				`< 50000 )`,
+		},
+		{
+			name:   "hash without primary key",
+			table:  logs,
+			sample: &domains.SubsetSample{Percent: 0.5, Method: "hash"},
+			expected: `( abs(pg_catalog.hashtextextended(ROW("public"."logs".*)::TEXT, 0) % 1000000) ` +
+				`< 5000 )`,
+		},
+		{
+			name:   "hash with percent smaller than bucket",
+			table:  customers,
+			sample: &domains.SubsetSample{Percent: 0.00001, Seed: &seed},
+			expected: `( abs(pg_catalog.hashtextextended(ROW("public"."customers"."id")::TEXT, 42) % 1000000) ` +
+				`< 1 )`,
+		},
+		{
+			name:   "bernoulli with seed",
+			table:  customers,
+			sample: &domains.SubsetSample{Percent: 5, Method: "BERNOULLI", Seed: &seed},
+			expected: `( ("public"."customers"."id") IN (SELECT "public"."customers"."id" ` +
+				`FROM ONLY "public"."customers" TABLESAMPLE BERNOULLI (5) REPEATABLE (42)) )`,
+		},
+		{
+			name:   "system without primary key",
+			table:  logs,
+			sample: &domains.SubsetSample{Percent: 1.5, Method: "system"},
+			expected: `( ("public"."logs".ctid) IN (SELECT "public"."logs".ctid ` +
+				`FROM ONLY "public"."logs" TABLESAMPLE SYSTEM (1.5) REPEATABLE (0)) )`,
+		},
+		{
+			name:  "limit",
+			table: customers,
+			conds: conds,
+			limit: 100,
+			expected: `( ("public"."customers"."id") IN (SELECT "public"."customers"."id" ` +
+				`FROM ONLY "public"."customers" WHERE ( ( "public"."customers"."active" ) ) ` +
+				`ORDER BY "public"."customers"."id" LIMIT 100) )`,
+		},
+		{
+			name:   "limit and hash",
+			table:  customers,
+			conds:  conds,
+			sample: &domains.SubsetSample{Percent: 5, Seed: &seed},
+			limit:  100,
+			expected: `( ("public"."customers"."id") IN (SELECT "public"."customers"."id" ` +
+				`FROM ONLY "public"."customers" WHERE ( ( "public"."customers"."active" ) ) AND ` +
+				`( abs(pg_catalog.hashtextextended(ROW("public"."customers"."id")::TEXT, 42) % 1000000) < 50000 ) ` +
+				`ORDER BY "public"."customers"."id" LIMIT 100) )`,
+		},
+		{
+			name:   "limit and bernoulli",
+			table:  customers,
+			sample: &domains.SubsetSample{Percent: 5, Method: "bernoulli"},
+			limit:  10,
+			expected: `( ("public"."customers"."id") IN (SELECT "public"."customers"."id" ` +
+				`FROM ONLY "public"."customers" TABLESAMPLE BERNOULLI (5) REPEATABLE (0) WHERE TRUE ` +
+				`ORDER BY "public"."customers"."id" LIMIT 10) )`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GenerateSamplingCond(tt.table, tt.conds, tt.sample, tt.limit))
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const ArrayMapTransformerName = "ArrayMap"
+
+var ArrayMapTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		ArrayMapTransformerName,
+		"Apply the transformer to each element of the array",
+	),
+
+	NewArrayMapTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetSkipOnNull(true),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"transformer",
+		`transformer applied to each element of the array {"name": "transformer name", "params": {"param": "value"}}`,
+	).SetRequired(true),
+).SetRequireDatabaseConnection(arrayMapRequiresDatabaseConnection)
+
+// arrayMapRequiresDatabaseConnection - the nested transformer uses the database connection
+func arrayMapRequiresDatabaseConnection(params map[string]toolkit.ParamsValue) bool {
+	var cfg NestedTransformerConfig
+	if err := json.Unmarshal(params["transformer"], &cfg); err != nil {
+		// The invalid config is reported during the initialization
+		return false
+	}
+	return cfg.requiresDatabaseConnection()
+}
+
+// textElementArrayCodec - decodes and encodes the array elements as raw text values. The elements are quoted and
+// escaped by the codec and the dimensions are kept as is
+var textElementArrayCodec = &pgtype.ArrayCodec{
+	ElementType: &pgtype.Type{Name: "text", OID: pgtype.TextOID, Codec: pgtype.TextCodec{}},
+}
+
+type ArrayMapTransformer struct {
+	columnName      string
+	columnIdx       int
+	affectedColumns map[int]string
+	nested          *nestedTransformer
+	scanPlan        pgtype.ScanPlan
+	encodePlan      pgtype.EncodePlan
+	array           pgtype.Array[*string]
+	buf             []byte
+}
+
+func NewArrayMapTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName string
+	var cfg NestedTransformerConfig
+
+	p := parameters["column"]
+	if err := p.Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+
+	idx, c, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	p = parameters["transformer"]
+	if err := p.Scan(&cfg); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "transformer" param: %w`, err)
+	}
+	if cfg.Name == "" {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "transformer").
+				SetMsg("transformer name is required"),
+		}, nil
+	}
+
+	var elementType *pgtype.Type
+	if t, ok := driver.SharedTypeMap.TypeForOID(uint32(c.GetTypeOid())); ok {
+		if codec, ok := t.Codec.(*pgtype.ArrayCodec); ok {
+			elementType = codec.ElementType
+		}
+	}
+	if elementType == nil {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "column").
+				AddMeta("ColumnName", columnName).
+				AddMeta("TypeName", c.TypeName).
+				SetMsg("column is not an array"),
+		}, nil
+	}
+
+	elementColumn := newNestedTransformerColumn(c.Name, elementType)
+	nested, warnings, err := newNestedTransformer(ctx, driver, elementColumn, &cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, w := range warnings {
+		w.AddMeta("ParameterName", "transformer")
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	m := pgtype.NewMap()
+	var array pgtype.Array[*string]
+	scanPlan := textElementArrayCodec.PlanScan(m, pgtype.TextArrayOID, pgtype.TextFormatCode, &array)
+	encodePlan := textElementArrayCodec.PlanEncode(m, pgtype.TextArrayOID, pgtype.TextFormatCode, array)
+	if scanPlan == nil || encodePlan == nil {
+		return nil, nil, fmt.Errorf("unable to plan array decoding")
+	}
+
+	return &ArrayMapTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		affectedColumns: affectedColumns,
+		nested:          nested,
+		scanPlan:        scanPlan,
+		encodePlan:      encodePlan,
+	}, warnings, nil
+}
+
+func (amt *ArrayMapTransformer) GetAffectedColumns() map[int]string {
+	return amt.affectedColumns
+}
+
+func (amt *ArrayMapTransformer) Init(ctx context.Context) error {
+	return amt.nested.Init(ctx)
+}
+
+func (amt *ArrayMapTransformer) Done(ctx context.Context) error {
+	return amt.nested.Done(ctx)
+}
+
+func (amt *ArrayMapTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(amt.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	if err = amt.scanPlan.Scan(val.Data, &amt.array); err != nil {
+		return nil, fmt.Errorf("unable to decode array: %w", err)
+	}
+	for i, e := range amt.array.Elements {
+		// NULL elements are kept as is
+		if e == nil {
+			continue
+		}
+		res, err := amt.nested.Transform(ctx, toolkit.NewRawValue([]byte(*e), false))
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform array element %d: %w", i, err)
+		}
+		if res.IsNull {
+			amt.array.Elements[i] = nil
+			continue
+		}
+		s := string(res.Data)
+		amt.array.Elements[i] = &s
+	}
+
+	amt.buf, err = amt.encodePlan.Encode(amt.array, amt.buf[:0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode array: %w", err)
+	}
+	if err = r.SetRawColumnValueByIdx(amt.columnIdx, toolkit.NewRawValue(amt.buf, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(ArrayMapTransformerDefinition)
+}
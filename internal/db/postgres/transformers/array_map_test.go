@@ -0,0 +1,216 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func TestArrayMapTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name        string
+		columnName  string
+		original    string
+		transformer string
+		expected    string
+	}{
+		{
+			name:        "text array",
+			columnName:  "text_arr",
+			original:    `{a,"b c",d}`,
+			transformer: `{"name": "Replace", "params": {"value": "x"}}`,
+			expected:    `{x,x,x}`,
+		},
+		{
+			name:        "quoting",
+			columnName:  "text_arr",
+			original:    `{a,b}`,
+			transformer: `{"name": "Replace", "params": {"value": "x, \"y\""}}`,
+			expected:    `{"x, \\"y\\"","x, \\"y\\""}`,
+		},
+		{
+			name:        "null elements",
+			columnName:  "text_arr",
+			original:    `{a,NULL,b}`,
+			transformer: `{"name": "Replace", "params": {"value": "x", "keep_null": false}}`,
+			expected:    `{x,NULL,x}`,
+		},
+		{
+			name:        "multi-dimensional array",
+			columnName:  "int_arr",
+			original:    `{{1,2},{NULL,4}}`,
+			transformer: `{"name": "Replace", "params": {"value": 7}}`,
+			expected:    `{{7,7},{NULL,7}}`,
+		},
+		{
+			name:        "lower bounds",
+			columnName:  "int_arr",
+			original:    `[0:1]={1,2}`,
+			transformer: `{"name": "Replace", "params": {"value": "7"}}`,
+			expected:    `[0:1]={7,7}`,
+		},
+		{
+			name:        "empty array",
+			columnName:  "int_arr",
+			original:    `{}`,
+			transformer: `{"name": "Replace", "params": {"value": "7"}}`,
+			expected:    `{}`,
+		},
+		{
+			name:        "set null",
+			columnName:  "int_arr",
+			original:    `{1,2}`,
+			transformer: `{"name": "SetNull"}`,
+			expected:    `{NULL,NULL}`,
+		},
+		{
+			name:        "null value",
+			columnName:  "int_arr",
+			original:    `\N`,
+			transformer: `{"name": "SetNull"}`,
+			expected:    `\N`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, record := getDriverAndRecord(tt.columnName, tt.original)
+			transformerCtx, warnings, err := ArrayMapTransformerDefinition.Instance(
+				context.Background(),
+				driver,
+				map[string]toolkit.ParamsValue{
+					"column":      toolkit.ParamsValue(tt.columnName),
+					"transformer": toolkit.ParamsValue(tt.transformer),
+				},
+				nil,
+				"",
+				false,
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+			require.NoError(t, transformerCtx.Transformer.Init(context.Background()))
+
+			// Run twice to check the buffers are reused safely
+			for range 2 {
+				r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+				require.NoError(t, err)
+				encoded, err := r.Encode()
+				require.NoError(t, err)
+				res, err := encoded.Encode()
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, string(res))
+				require.NoError(t, r.Row.Decode([]byte(tt.original)))
+			}
+			require.NoError(t, transformerCtx.Transformer.Done(context.Background()))
+		})
+	}
+}
+
+func TestArrayMapTransformer_Transform_random(t *testing.T) {
+	driver, record := getDriverAndRecord("int_arr", `{1,2,NULL}`)
+	transformerCtx, warnings, err := ArrayMapTransformerDefinition.Instance(
+		context.Background(),
+		driver,
+		map[string]toolkit.ParamsValue{
+			"column":      toolkit.ParamsValue("int_arr"),
+			"transformer": toolkit.ParamsValue(`{"name": "RandomInt", "params": {"min": 100, "max": 200}}`),
+		},
+		nil,
+		"",
+		false,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+	require.NoError(t, err)
+	var res []*int32
+	isNull, err := r.ScanColumnValueByName("int_arr", &res)
+	require.NoError(t, err)
+	require.False(t, isNull)
+	require.Len(t, res, 3)
+	for _, v := range res[:2] {
+		require.NotNil(t, v)
+		assert.GreaterOrEqual(t, *v, int32(100))
+		assert.LessOrEqual(t, *v, int32(200))
+	}
+	assert.Nil(t, res[2])
+}
+
+func TestArrayMapTransformer_validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		columnName  string
+		transformer string
+		msg         string
+	}{
+		{
+			name:        "not an array",
+			columnName:  "data",
+			transformer: `{"name": "SetNull"}`,
+			msg:         "column is not an array",
+		},
+		{
+			name:        "unknown transformer",
+			columnName:  "int_arr",
+			transformer: `{"name": "Unknown"}`,
+			msg:         "unknown nested transformer",
+		},
+		{
+			name:        "empty transformer name",
+			columnName:  "int_arr",
+			transformer: `{"params": {"value": "7"}}`,
+			msg:         "transformer name is required",
+		},
+		{
+			name:        "nested transformer warning",
+			columnName:  "int_arr",
+			transformer: `{"name": "Replace", "params": {"value": "abc"}}`,
+			msg:         "error decoding \"value\" parameter from raw string to type",
+		},
+		{
+			name:        "unsupported element type",
+			columnName:  "text_arr",
+			transformer: `{"name": "RandomInt", "params": {"min": 1, "max": 2}}`,
+			msg:         "unsupported column type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, _ := getDriverAndRecord(tt.columnName, `{}`)
+			_, warnings, err := ArrayMapTransformerDefinition.Instance(
+				context.Background(),
+				driver,
+				map[string]toolkit.ParamsValue{
+					"column":      toolkit.ParamsValue(tt.columnName),
+					"transformer": toolkit.ParamsValue(tt.transformer),
+				},
+				nil,
+				"",
+				false,
+			)
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+			assert.Equal(t, tt.msg, warnings[0].Msg)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
@@ -24,6 +25,12 @@ var (
 		"indicates that NULL values must not be replaced with transformed values",
 	).SetDefaultValue(toolkit.ParamsValue("true"))
 
+	uniqueParameterDefinition = toolkit.MustNewParameterDefinition(
+		"unique",
+		"guarantee uniqueness of the generated values within the column. Generated values are kept in memory and "+
+			"regenerated in case of collision",
+	).SetDefaultValue(toolkit.ParamsValue("false"))
+
 	minRatioParameterDefinition = toolkit.MustNewParameterDefinition(
 		"min_ratio",
 		"min random percentage for noise",
@@ -34,12 +41,33 @@ var (
 		"max random percentage for noise",
 	).SetDefaultValue(toolkit.ParamsValue("0.2"))
 
+	localeParameterDefinition = toolkit.MustNewParameterDefinition(
+		"locale",
+		"the locale of the generated data (for instance de_DE). The default data is used if empty",
+	).SetRawValueValidator(localeValidator)
+
 	truncateDateParameterDefinition = toolkit.MustNewParameterDefinition(
 		"truncate",
 		fmt.Sprintf("truncate date till the part (%s)", strings.Join(truncateParts, ", ")),
 	).SetRawValueValidator(validateDateTruncationParameterValue)
 )
 
+func localeValidator(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	if _, err := locales.Get(string(v)); err != nil {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetMsg("unknown locale").
+				AddMeta("ParameterValue", string(v)).
+				AddMeta("AllowedValues", locales.DefaultRegistry.Locales()).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+		}, nil
+	}
+	return nil, nil
+}
+
 func engineValidator(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
 	value := string(v)
 	if value != RandomEngineParameterName && value != HashEngineParameterName {
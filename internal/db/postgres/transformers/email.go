@@ -75,6 +75,8 @@ var EmailTransformerDefinition = utils.NewTransformerDefinition(
 	keepNullParameterDefinition,
 
 	engineParameterDefinition,
+
+	uniqueParameterDefinition,
 )
 
 type EmailTransformer struct {
@@ -93,12 +95,13 @@ type EmailTransformer struct {
 	originalDomain           []byte
 	hexEncodedRandomBytesBuf []byte
 	rctx                     *toolkit.RecordContext
+	uniqueValues             *generators.UniqueValues
 }
 
 func NewEmailTransformer(ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer) (utils.Transformer, toolkit.ValidationWarnings, error) {
 
 	var columnName, engine, localPartTemplate, domainTemplate string
-	var keepNull, keepOriginalDomain /*unique,*/, validate bool
+	var keepNull, keepOriginalDomain, unique, validate bool
 	var domains []string
 	var err error
 	var domainTmpl, localTmpl *template.Template
@@ -114,6 +117,7 @@ func NewEmailTransformer(ctx context.Context, driver *toolkit.Driver, parameters
 	keepNullParam := parameters["keep_null"]
 	engineParam := parameters["engine"]
 	maxRandomLengthParam := parameters["max_random_length"]
+	uniqueParam := parameters["unique"]
 
 	if err = engineParam.Scan(&engine); err != nil {
 		return nil, nil, fmt.Errorf(`unable to scan "engine" param: %w`, err)
@@ -186,6 +190,10 @@ func NewEmailTransformer(ctx context.Context, driver *toolkit.Driver, parameters
 		return nil, nil, fmt.Errorf(`unable to scan "max_random_length" param: %w`, err)
 	}
 
+	if err := uniqueParam.Scan(&unique); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "unique" param: %w`, err)
+	}
+
 	if maxRandomLength < 1 {
 		return nil, nil, errors.New("max_random_length must be greater than 0")
 	}
@@ -209,6 +217,7 @@ func NewEmailTransformer(ctx context.Context, driver *toolkit.Driver, parameters
 		buf:                      bytes.NewBuffer(nil),
 		hexEncodedRandomBytesBuf: make([]byte, hex.EncodedLen(maxRandomLength)),
 		rctx:                     rrctx,
+		uniqueValues:             newUniqueValues(unique),
 	}, nil, nil
 }
 
@@ -235,28 +244,38 @@ func (rit *EmailTransformer) Transform(ctx context.Context, r *toolkit.Record) (
 		return r, nil
 	}
 
+	newVal, err := generateValue(rit.uniqueValues, val, func(data []byte) ([]byte, error) {
+		return rit.generate(data, val.Data, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.SetRawColumnValueByIdx(rit.columnIdx, toolkit.NewRawValue(newVal, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new raw value: %w", err)
+	}
+	return r, nil
+}
+
+func (rit *EmailTransformer) generate(data, originalEmail []byte, r *toolkit.Record) ([]byte, error) {
 	defer clear(rit.templateCtx)
 
-	data, err := rit.g.Generate(val.Data)
+	randomBytes, err := rit.g.Generate(data)
 	if err != nil {
 		return nil, fmt.Errorf("unable to generate bytes: %w", err)
 	}
 
-	hex.Encode(rit.hexEncodedRandomBytesBuf, data)
+	hex.Encode(rit.hexEncodedRandomBytesBuf, randomBytes)
 
-	if err := rit.setupTemplateContext(val.Data, r); err != nil {
+	if err := rit.setupTemplateContext(originalEmail, r); err != nil {
 		return nil, fmt.Errorf("unable to setup template context: %w", err)
 	}
 
-	newVal, err := rit.generateEmail(data)
+	newVal, err := rit.generateEmail(randomBytes)
 	if err != nil {
 		return nil, fmt.Errorf("unable to generate email: %w", err)
 	}
-
-	if err = r.SetRawColumnValueByIdx(rit.columnIdx, toolkit.NewRawValue(newVal, false)); err != nil {
-		return nil, fmt.Errorf("unable to set new raw value: %w", err)
-	}
-	return r, nil
+	return newVal, nil
 }
 
 func (rit *EmailTransformer) setupTemplateContext(originalEmail []byte, r *toolkit.Record) error {
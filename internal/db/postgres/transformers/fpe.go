@@ -0,0 +1,264 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators/transformers"
+	commonutils "github.com/greenmaskio/greenmask/internal/utils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const FpeTransformerName = "FPE"
+
+const FpeDefaultAlphabet = "0123456789"
+
+const (
+	fpeOnShortValueKeep  = "keep"
+	fpeOnShortValueError = "error"
+)
+
+var fpeTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		FpeTransformerName,
+		"Encrypt the value keeping its length and alphabet using FF1 format-preserving encryption. "+
+			"The value can be decrypted using decrypt-value command",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewFpeTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("text", "varchar", "char", "bpchar", "citext"),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"alphabet",
+		"the characters that are encrypted. The rest of the characters are kept as is",
+	).SetDefaultValue(toolkit.ParamsValue(FpeDefaultAlphabet)),
+
+	toolkit.MustNewParameterDefinition(
+		"key",
+		fmt.Sprintf(
+			"reference to hex encoded AES key: %sVAR_NAME or %s/path/to/key. If not set the key is derived from "+
+				"the global salt provided via GREENMASK_GLOBAL_SALT",
+			transformers.FpeKeyEnvPrefix, transformers.FpeKeyFilePrefix,
+		),
+	),
+
+	toolkit.MustNewParameterDefinition(
+		"tweak_column",
+		"column name which value is used as a tweak. The column value is required for decryption",
+	),
+
+	toolkit.MustNewParameterDefinition(
+		"preserve_prefix",
+		"number of characters at the beginning of the value that are kept as is",
+	).SetDefaultValue(toolkit.ParamsValue("0")),
+
+	toolkit.MustNewParameterDefinition(
+		"preserve_suffix",
+		"number of characters at the end of the value that are kept as is",
+	).SetDefaultValue(toolkit.ParamsValue("0")),
+
+	toolkit.MustNewParameterDefinition(
+		"on_short_value",
+		"the behaviour for the values that are too short to be encrypted: keep the value as is (keep) or fail "+
+			`the transformation (error). Possible values: "keep", "error"`,
+	).SetDefaultValue(toolkit.ParamsValue(fpeOnShortValueKeep)).
+		SetRawValueValidator(fpeValidateOnShortValue),
+)
+
+type FpeTransformer struct {
+	t               *transformers.FpeTransformer
+	columnName      string
+	columnIdx       int
+	tweakColumnIdx  int
+	keepShortValues bool
+	affectedColumns map[int]string
+}
+
+func NewFpeTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, alphabet, keyRef, tweakColumnName, onShortValue string
+	var preservePrefix, preserveSuffix int
+
+	p := parameters["column"]
+	if err := p.Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+
+	idx, _, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	p = parameters["alphabet"]
+	if err := p.Scan(&alphabet); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "alphabet" param: %w`, err)
+	}
+
+	p = parameters["key"]
+	if err := p.Scan(&keyRef); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "key" param: %w`, err)
+	}
+
+	p = parameters["tweak_column"]
+	if err := p.Scan(&tweakColumnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "tweak_column" param: %w`, err)
+	}
+
+	p = parameters["preserve_prefix"]
+	if err := p.Scan(&preservePrefix); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "preserve_prefix" param: %w`, err)
+	}
+
+	p = parameters["preserve_suffix"]
+	if err := p.Scan(&preserveSuffix); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "preserve_suffix" param: %w`, err)
+	}
+
+	p = parameters["on_short_value"]
+	if err := p.Scan(&onShortValue); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "on_short_value" param: %w`, err)
+	}
+
+	tweakColumnIdx := -1
+	if tweakColumnName != "" {
+		tweakColumnIdx, _, ok = driver.GetColumnByName(tweakColumnName)
+		if !ok {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "tweak_column").
+					AddMeta("ParameterValue", tweakColumnName).
+					SetMsg("column is not found"),
+			}, nil
+		}
+		if tweakColumnIdx == idx {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "tweak_column").
+					AddMeta("ParameterValue", tweakColumnName).
+					SetMsg("tweak column cannot be the transformed column"),
+			}, nil
+		}
+	}
+
+	key, err := transformers.ResolveFpeKey(keyRef, commonutils.SaltFromCtx(ctx))
+	if err != nil {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "key").
+				AddMeta("Error", err.Error()).
+				SetMsg("unable to get encryption key"),
+		}, nil
+	}
+
+	t, err := transformers.NewFpeTransformer(key, []rune(alphabet), preservePrefix, preserveSuffix)
+	if err != nil {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("Error", err.Error()).
+				SetMsg("unable to create FPE transformer"),
+		}, nil
+	}
+
+	return &FpeTransformer{
+		t:               t,
+		columnName:      columnName,
+		columnIdx:       idx,
+		tweakColumnIdx:  tweakColumnIdx,
+		keepShortValues: onShortValue == fpeOnShortValueKeep,
+		affectedColumns: affectedColumns,
+	}, nil, nil
+}
+
+func (ft *FpeTransformer) GetAffectedColumns() map[int]string {
+	return ft.affectedColumns
+}
+
+func (ft *FpeTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (ft *FpeTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (ft *FpeTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(ft.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	var tweak []byte
+	if ft.tweakColumnIdx != -1 {
+		tweakVal, err := r.GetRawColumnValueByIdx(ft.tweakColumnIdx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan tweak column value: %w", err)
+		}
+		if !tweakVal.IsNull {
+			tweak = tweakVal.Data
+		}
+	}
+
+	res, err := ft.t.Encrypt([]rune(string(val.Data)), tweak)
+	if err != nil {
+		if ft.keepShortValues && errors.Is(err, transformers.ErrFpeValueTooShort) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("unable to encrypt value: %w", err)
+	}
+
+	if err = r.SetRawColumnValueByIdx(ft.columnIdx, toolkit.NewRawValue([]byte(string(res)), false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func fpeValidateOnShortValue(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	value := string(v)
+	if value != fpeOnShortValueKeep && value != fpeOnShortValueError {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterValue", value).
+				SetMsg(`unsupported on_short_value: must be one of "keep" or "error"`),
+		}, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(fpeTransformerDefinition)
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/generators/transformers"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const fpeTestKeyHex = "2b7e151628aed2a6abf7158809cf4f3c"
+
+func TestFpeTransformer_Transform(t *testing.T) {
+	t.Setenv("TEST_FPE_KEY", fpeTestKeyHex)
+
+	tests := []struct {
+		name     string
+		original string
+		params   map[string]toolkit.ParamsValue
+		tweak    string
+		pattern  string
+		isNull   bool
+	}{
+		{
+			name:     "digits",
+			original: "1\t2023-01-01 00:00:00\t+1 (555) 123-4567",
+			params: map[string]toolkit.ParamsValue{
+				"key": toolkit.ParamsValue("env:TEST_FPE_KEY"),
+			},
+			pattern: `^\+\d \(\d{3}\) \d{3}-\d{4}$`,
+		},
+		{
+			name:     "tweak column and preserved prefix",
+			original: "1\t2023-01-01 00:00:00\tACC00012345678",
+			params: map[string]toolkit.ParamsValue{
+				"key":             toolkit.ParamsValue("env:TEST_FPE_KEY"),
+				"tweak_column":    toolkit.ParamsValue("id"),
+				"preserve_prefix": toolkit.ParamsValue("3"),
+			},
+			tweak:   "1",
+			pattern: `^ACC\d{11}$`,
+		},
+		{
+			name:     "null",
+			original: "1\t2023-01-01 00:00:00\t\\N",
+			params: map[string]toolkit.ParamsValue{
+				"key": toolkit.ParamsValue("env:TEST_FPE_KEY"),
+			},
+			isNull: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("data")
+			driver, record := getDriverAndRecordByColumns([]string{"id", "created_at", "data"}, tt.original)
+			transformerCtx, warnings, err := fpeTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "", false,
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			originalValue, err := record.GetRawColumnValueByIdx(2)
+			require.NoError(t, err)
+			original := string(originalValue.Data)
+
+			r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByIdx(2)
+			require.NoError(t, err)
+			if tt.isNull {
+				assert.True(t, res.IsNull)
+				return
+			}
+			require.Regexp(t, tt.pattern, string(res.Data))
+			assert.NotEqual(t, original, string(res.Data))
+
+			// The value can be decrypted with the same parameters
+			var preservePrefix int
+			if v, ok := tt.params["preserve_prefix"]; ok {
+				preservePrefix = int(v[0] - '0')
+			}
+			key, err := transformers.ResolveFpeKey("env:TEST_FPE_KEY", nil)
+			require.NoError(t, err)
+			ft, err := transformers.NewFpeTransformer(key, []rune(FpeDefaultAlphabet), preservePrefix, 0)
+			require.NoError(t, err)
+			decrypted, err := ft.Decrypt([]rune(string(res.Data)), []byte(tt.tweak))
+			require.NoError(t, err)
+			assert.Equal(t, original, string(decrypted))
+		})
+	}
+}
+
+func TestFpeTransformer_Validation(t *testing.T) {
+	driver, _ := getDriverAndRecordByColumns([]string{"id", "data"}, "1\tvalue")
+
+	_, warnings, err := fpeTransformerDefinition.Instance(
+		context.Background(), driver, map[string]toolkit.ParamsValue{"column": toolkit.ParamsValue("data")},
+		nil, "", false,
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal(), "key is not set and there is no global salt")
+
+	t.Setenv("TEST_FPE_KEY", fpeTestKeyHex)
+	_, warnings, err = fpeTransformerDefinition.Instance(
+		context.Background(), driver, map[string]toolkit.ParamsValue{
+			"column":       toolkit.ParamsValue("data"),
+			"key":          toolkit.ParamsValue("env:TEST_FPE_KEY"),
+			"tweak_column": toolkit.ParamsValue("data"),
+		},
+		nil, "", false,
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+
+	_, warnings, err = fpeTransformerDefinition.Instance(
+		context.Background(), driver, map[string]toolkit.ParamsValue{
+			"column":         toolkit.ParamsValue("data"),
+			"key":            toolkit.ParamsValue("env:TEST_FPE_KEY"),
+			"on_short_value": toolkit.ParamsValue("skip"),
+		},
+		nil, "", false,
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+}
+
+func TestFpeTransformer_Transform_shortValue(t *testing.T) {
+	t.Setenv("TEST_FPE_KEY", fpeTestKeyHex)
+
+	tests := []struct {
+		name         string
+		original     string
+		onShortValue string
+		wantErr      bool
+	}{
+		{
+			name:     "empty value is kept by default",
+			original: "1\t2023-01-01 00:00:00\t",
+		},
+		{
+			name:     "value not longer than prefix and suffix is kept by default",
+			original: "1\t2023-01-01 00:00:00\tAB12",
+		},
+		{
+			name:     "value with few alphabet characters is kept by default",
+			original: "1\t2023-01-01 00:00:00\tAB-12-CD",
+		},
+		{
+			name:         "keep",
+			original:     "1\t2023-01-01 00:00:00\tAB-12-CD",
+			onShortValue: "keep",
+		},
+		{
+			name:         "error",
+			original:     "1\t2023-01-01 00:00:00\tAB-12-CD",
+			onShortValue: "error",
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]toolkit.ParamsValue{
+				"column":          toolkit.ParamsValue("data"),
+				"key":             toolkit.ParamsValue("env:TEST_FPE_KEY"),
+				"preserve_prefix": toolkit.ParamsValue("2"),
+				"preserve_suffix": toolkit.ParamsValue("2"),
+			}
+			if tt.onShortValue != "" {
+				params["on_short_value"] = toolkit.ParamsValue(tt.onShortValue)
+			}
+			driver, record := getDriverAndRecordByColumns([]string{"id", "created_at", "data"}, tt.original)
+			transformerCtx, warnings, err := fpeTransformerDefinition.Instance(
+				context.Background(), driver, params, nil, "", false,
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			originalValue, err := record.GetRawColumnValueByIdx(2)
+			require.NoError(t, err)
+			original := string(originalValue.Data)
+
+			r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+			if tt.wantErr {
+				require.ErrorIs(t, err, transformers.ErrFpeValueTooShort)
+				return
+			}
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByIdx(2)
+			require.NoError(t, err)
+			assert.False(t, res.IsNull)
+			assert.Equal(t, original, string(res.Data))
+		})
+	}
+}
@@ -24,8 +24,10 @@ package transformers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
+	"strconv"
 	"text/template"
 
 	"github.com/tidwall/gjson"
@@ -36,10 +38,14 @@ import (
 )
 
 const (
-	JsonDeleteOpName = "delete"
-	JsonSetOpName    = "set"
+	JsonDeleteOpName    = "delete"
+	JsonSetOpName       = "set"
+	JsonTransformOpName = "transform"
 )
 
+// jsonTransformDefaultValueType - the type of the value passed to the nested transformer if "value_type" is not set
+const jsonTransformDefaultValueType = "text"
+
 const JsonTransformerName = "Json"
 
 var JsonTransformerDefinition = utils.NewTransformerDefinition(
@@ -61,14 +67,27 @@ var JsonTransformerDefinition = utils.NewTransformerDefinition(
 
 	toolkit.MustNewParameterDefinition(
 		"operations",
-		`list of operations that contains editing operation [{"operation": "set|delete", "path": "path to the part of the document", "value": "value in any type - string, int, float, list, object, null", "value_template": "go template", "error_not_exist", "raise error if not exists - boolean"}]`,
+		`list of operations that contains editing operation [{"operation": "set|delete|transform", "path": "path to the part of the document", "value": "value in any type - string, int, float, list, object, null", "value_template": "go template", "transformer": {"name": "transformer name", "params": {"param": "value"}}, "value_type": "type of the value passed to the transformer", "error_not_exist", "raise error if not exists - boolean"}]`,
 	).SetRequired(true),
 
 	toolkit.MustNewParameterDefinition(
 		"keep_null",
 		"apply changes in value is null",
 	).SetDefaultValue(toolkit.ParamsValue("true")),
-)
+).SetRequireDatabaseConnection(jsonRequiresDatabaseConnection)
+
+// jsonRequiresDatabaseConnection - the transformer of at least one "transform" operation uses the database
+// connection
+func jsonRequiresDatabaseConnection(params map[string]toolkit.ParamsValue) bool {
+	var ops []*Operation
+	if err := json.Unmarshal(params["operations"], &ops); err != nil {
+		// The invalid operations are reported during the initialization
+		return false
+	}
+	return slices.ContainsFunc(ops, func(o *Operation) bool {
+		return o.Operation == JsonTransformOpName && o.Transformer != nil && o.Transformer.requiresDatabaseConnection()
+	})
+}
 
 var jsonSetOpt = &sjson.Options{
 	ReplaceInPlace: true,
@@ -81,14 +100,20 @@ type Operation struct {
 	Path          string      `mapstructure:"path" json:"path"`
 	ErrorNotExist bool        `mapstructure:"error_not_exist" json:"error_not_exist"`
 	SkipNotExist  bool        `mapstructure:"skip_not_exist" json:"skip_not_exist"`
-	tmpl          *template.Template
+	// Transformer - the transformer applied to the scalar values found by the path in the "transform" operation
+	Transformer *NestedTransformerConfig `mapstructure:"transformer,omitempty" json:"transformer,omitempty"`
+	// ValueType - the database type of the value passed to the transformer in the "transform" operation
+	ValueType string `mapstructure:"value_type,omitempty" json:"value_type,omitempty"`
+	tmpl      *template.Template
+	nested    *nestedTransformer
+	pattern   []string
 }
 
 func doesKeyExist(data []byte, path string) bool {
 	return gjson.GetBytes(data, path).Exists()
 }
 
-func (o *Operation) Apply(inp []byte, tctx *JsonContext, buf *bytes.Buffer) ([]byte, error) {
+func (o *Operation) Apply(ctx context.Context, inp []byte, tctx *JsonContext, buf *bytes.Buffer) ([]byte, error) {
 	var res []byte
 	var err error
 
@@ -130,6 +155,19 @@ func (o *Operation) Apply(inp []byte, tctx *JsonContext, buf *bytes.Buffer) ([]b
 			return nil, fmt.Errorf("error applying delete operation: %w", err)
 		}
 
+	case JsonTransformOpName:
+		paths := matchJsonPath(inp, o.pattern)
+		if o.ErrorNotExist && len(paths) == 0 {
+			return nil, fmt.Errorf("value by path \"%s\" does not exist", o.Path)
+		}
+		res = inp
+		for _, path := range paths {
+			res, err = o.transformValue(ctx, res, path)
+			if err != nil {
+				return nil, fmt.Errorf("error transforming value by path \"%s\": %w", path, err)
+			}
+		}
+
 	default:
 		return nil, fmt.Errorf("unknown operation %s", o.Operation)
 	}
@@ -137,11 +175,140 @@ func (o *Operation) Apply(inp []byte, tctx *JsonContext, buf *bytes.Buffer) ([]b
 	return res, nil
 }
 
+// transformValue - applies the nested transformer to the scalar value by the path. The result is written with the
+// type of the original value if it is possible, otherwise it is written as string. NULL values, objects and arrays
+// are not transformed
+func (o *Operation) transformValue(ctx context.Context, inp []byte, path string) ([]byte, error) {
+	v := gjson.GetBytes(inp, path)
+	var raw []byte
+	switch v.Type {
+	case gjson.String:
+		raw = []byte(v.String())
+	case gjson.Number:
+		raw = []byte(v.Raw)
+	case gjson.True, gjson.False:
+		raw = []byte(strconv.FormatBool(v.Bool()))
+	default:
+		return inp, nil
+	}
+
+	res, err := o.nested.Transform(ctx, toolkit.NewRawValue(raw, false))
+	if err != nil {
+		return nil, err
+	}
+	if res.IsNull {
+		return sjson.SetRawBytesOptions(inp, path, []byte("null"), jsonSetOpt)
+	}
+	switch v.Type {
+	case gjson.Number:
+		if isJsonNumber(res.Data) {
+			return sjson.SetRawBytesOptions(inp, path, res.Data, jsonSetOpt)
+		}
+	case gjson.True, gjson.False:
+		switch string(res.Data) {
+		case "t", "true":
+			return sjson.SetRawBytesOptions(inp, path, []byte("true"), jsonSetOpt)
+		case "f", "false":
+			return sjson.SetRawBytesOptions(inp, path, []byte("false"), jsonSetOpt)
+		}
+	}
+	return sjson.SetBytesOptions(inp, path, string(res.Data), jsonSetOpt)
+}
+
+func isJsonNumber(data []byte) bool {
+	if len(data) == 0 || (data[0] != '-' && (data[0] < '0' || data[0] > '9')) {
+		return false
+	}
+	return json.Valid(data)
+}
+
+// parseJsonPathPattern - splits the path by unescaped dots. The "*" segment matches any key or array element and the
+// "**" segment matches any number of the nested levels including zero
+func parseJsonPathPattern(path string) []string {
+	var segments []string
+	var segment []byte
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			segment = append(segment, path[i])
+		case path[i] == '.':
+			segments = append(segments, string(segment))
+			segment = segment[:0]
+		default:
+			segment = append(segment, path[i])
+		}
+	}
+	return append(segments, string(segment))
+}
+
+// matchJsonPath - returns the paths of the values matched by the pattern in the document
+func matchJsonPath(data []byte, pattern []string) []string {
+	var res []string
+	seen := make(map[string]struct{})
+	matchJsonPathSegments(gjson.ParseBytes(data), pattern, "", &res, seen)
+	return res
+}
+
+func matchJsonPathSegments(v gjson.Result, pattern []string, prefix string, res *[]string, seen map[string]struct{}) {
+	if len(pattern) == 0 {
+		if _, ok := seen[prefix]; !ok && prefix != "" {
+			seen[prefix] = struct{}{}
+			*res = append(*res, prefix)
+		}
+		return
+	}
+	switch pattern[0] {
+	case "**":
+		matchJsonPathSegments(v, pattern[1:], prefix, res, seen)
+		forEachJsonChild(v, func(key string, child gjson.Result) {
+			matchJsonPathSegments(child, pattern, joinJsonPath(prefix, key), res, seen)
+		})
+	case "*":
+		forEachJsonChild(v, func(key string, child gjson.Result) {
+			matchJsonPathSegments(child, pattern[1:], joinJsonPath(prefix, key), res, seen)
+		})
+	default:
+		if !v.IsObject() && !v.IsArray() {
+			return
+		}
+		key := gjson.Escape(pattern[0])
+		if child := v.Get(key); child.Exists() {
+			matchJsonPathSegments(child, pattern[1:], joinJsonPath(prefix, key), res, seen)
+		}
+	}
+}
+
+func forEachJsonChild(v gjson.Result, f func(key string, child gjson.Result)) {
+	switch {
+	case v.IsObject():
+		v.ForEach(func(key, value gjson.Result) bool {
+			f(gjson.Escape(key.String()), value)
+			return true
+		})
+	case v.IsArray():
+		var idx int
+		v.ForEach(func(_, value gjson.Result) bool {
+			f(strconv.Itoa(idx), value)
+			idx++
+			return true
+		})
+	}
+}
+
+func joinJsonPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
 type JsonTransformer struct {
 	columnName      string
 	columnIdx       int
 	operations      []*Operation
 	affectedColumns map[int]string
+	nested          []*nestedTransformer
 	tctx            *JsonContext
 	buf             *bytes.Buffer
 	keepNull        bool
@@ -157,7 +324,7 @@ func NewJsonTransformer(ctx context.Context, driver *toolkit.Driver, parameters
 		return nil, nil, fmt.Errorf("unable to scan column param: %w", err)
 	}
 
-	idx, _, ok := driver.GetColumnByName(columnName)
+	idx, c, ok := driver.GetColumnByName(columnName)
 	if !ok {
 		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
 	}
@@ -186,15 +353,70 @@ func NewJsonTransformer(ctx context.Context, driver *toolkit.Driver, parameters
 		}
 	}
 
+	var warnings toolkit.ValidationWarnings
+	var nestedTransformers []*nestedTransformer
+	for opIdx, o := range ops {
+		if o.Operation != JsonTransformOpName {
+			continue
+		}
+		nested, opWarnings, err := newJsonOperationTransformer(ctx, driver, c, o)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating transformer op[%d] with path \"%s\": %w", opIdx, o.Path, err)
+		}
+		for _, w := range opWarnings {
+			w.AddMeta("ParameterName", "operations").
+				AddMeta("OperationIdx", opIdx).
+				AddMeta("OperationPath", o.Path)
+		}
+		warnings = append(warnings, opWarnings...)
+		if nested != nil {
+			o.nested = nested
+			o.pattern = parseJsonPathPattern(o.Path)
+			nestedTransformers = append(nestedTransformers, nested)
+		}
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
 	return &JsonTransformer{
 		columnName:      columnName,
 		operations:      ops,
 		columnIdx:       idx,
 		affectedColumns: affectedColumns,
+		nested:          nestedTransformers,
 		buf:             bytes.NewBuffer(nil),
 		tctx:            NewJsonContext(),
 		keepNull:        keepNull,
-	}, nil, nil
+	}, warnings, nil
+}
+
+// newJsonOperationTransformer - creates the nested transformer of the "transform" operation. The transformer works
+// with the column of the operation value type
+func newJsonOperationTransformer(
+	ctx context.Context, driver *toolkit.Driver, c *toolkit.Column, o *Operation,
+) (*nestedTransformer, toolkit.ValidationWarnings, error) {
+	if o.Transformer == nil || o.Transformer.Name == "" {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				SetMsg("transformer is required for transform operation"),
+		}, nil
+	}
+	valueType := o.ValueType
+	if valueType == "" {
+		valueType = jsonTransformDefaultValueType
+	}
+	t, ok := driver.SharedTypeMap.TypeForName(valueType)
+	if !ok {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ValueType", valueType).
+				SetMsg("unknown value type"),
+		}, nil
+	}
+	return newNestedTransformer(ctx, driver, newNestedTransformerColumn(c.Name, t), o.Transformer)
 }
 
 func (jt *JsonTransformer) GetAffectedColumns() map[int]string {
@@ -202,10 +424,20 @@ func (jt *JsonTransformer) GetAffectedColumns() map[int]string {
 }
 
 func (jt *JsonTransformer) Init(ctx context.Context) error {
+	for _, nt := range jt.nested {
+		if err := nt.Init(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (jt *JsonTransformer) Done(ctx context.Context) error {
+	for _, nt := range jt.nested {
+		if err := nt.Done(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -222,7 +454,7 @@ func (jt *JsonTransformer) Transform(ctx context.Context, r *toolkit.Record) (*t
 	res := slices.Clone(v.Data)
 	for idx, op := range jt.operations {
 		jt.buf.Reset()
-		res, err = op.Apply(res, jt.tctx, jt.buf)
+		res, err = op.Apply(ctx, res, jt.tctx, jt.buf)
 		if err != nil {
 			return nil, fmt.Errorf("cannot apply \"%s\" operation[%d] with path %s: %w", op.Operation, idx, op.Path, err)
 		}
@@ -270,3 +270,192 @@ func TestJsonTransformer_Transform_skip_does_not_exist(t *testing.T) {
 		require.JSONEq(t, `{"name":{"test":"modified"}}`, string(actual.Data))
 	})
 }
+
+func TestJsonTransformer_Transform_nested_transformer(t *testing.T) {
+	tests := []struct {
+		name       string
+		original   string
+		operations string
+		expected   string
+	}{
+		{
+			name:       "string value",
+			original:   `{"profile":{"email":"john@example.com","age":30}}`,
+			operations: `[{"operation": "transform", "path": "profile.email", "transformer": {"name": "Replace", "params": {"value": "a@example.com"}}}]`,
+			expected:   `{"profile":{"email":"a@example.com","age":30}}`,
+		},
+		{
+			name:       "wildcard",
+			original:   `{"contacts":[{"phone":"123"},{"phone":null},{"email":"a@b.c"},{"phone":"456"}]}`,
+			operations: `[{"operation": "transform", "path": "contacts.*.phone", "transformer": {"name": "Replace", "params": {"value": "000"}}}]`,
+			expected:   `{"contacts":[{"phone":"000"},{"phone":null},{"email":"a@b.c"},{"phone":"000"}]}`,
+		},
+		{
+			name:       "recursive",
+			original:   `{"email":"a","user":{"email":"b","friends":[{"email":"c"},{"name":"d"}]},"emails":["e"]}`,
+			operations: `[{"operation": "transform", "path": "**.email", "transformer": {"name": "Replace", "params": {"value": "x"}}}]`,
+			expected:   `{"email":"x","user":{"email":"x","friends":[{"email":"x"},{"name":"d"}]},"emails":["e"]}`,
+		},
+		{
+			name:       "escaped key",
+			original:   `{"a.b":{"c":"1"},"a":{"b":{"c":"2"}}}`,
+			operations: `[{"operation": "transform", "path": "a\\.b.c", "transformer": {"name": "Replace", "params": {"value": "x"}}}]`,
+			expected:   `{"a.b":{"c":"x"},"a":{"b":{"c":"2"}}}`,
+		},
+		{
+			name:       "number value",
+			original:   `{"scores":[1,2.5,"3"]}`,
+			operations: `[{"operation": "transform", "path": "scores.*", "value_type": "int4", "transformer": {"name": "Replace", "params": {"value": "7"}}}]`,
+			expected:   `{"scores":[7,7,"7"]}`,
+		},
+		{
+			name:       "number value replaced by string",
+			original:   `{"score":1}`,
+			operations: `[{"operation": "transform", "path": "score", "transformer": {"name": "Replace", "params": {"value": "high"}}}]`,
+			expected:   `{"score":"high"}`,
+		},
+		{
+			name:       "boolean value",
+			original:   `{"flags":[true,false]}`,
+			operations: `[{"operation": "transform", "path": "flags.*", "value_type": "bool", "transformer": {"name": "Replace", "params": {"value": "f"}}}]`,
+			expected:   `{"flags":[false,false]}`,
+		},
+		{
+			name:       "objects are not transformed",
+			original:   `{"profile":{"email":"a"}}`,
+			operations: `[{"operation": "transform", "path": "profile", "transformer": {"name": "Replace", "params": {"value": "x"}}}]`,
+			expected:   `{"profile":{"email":"a"}}`,
+		},
+		{
+			name:       "set null",
+			original:   `{"profile":{"email":"a"}}`,
+			operations: `[{"operation": "transform", "path": "profile.email", "transformer": {"name": "SetNull"}}]`,
+			expected:   `{"profile":{"email":null}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, record := getDriverAndRecord("doc", tt.original)
+			transformerCtx, warnings, err := JsonTransformerDefinition.Instance(
+				context.Background(),
+				driver, map[string]toolkit.ParamsValue{
+					"column":     toolkit.ParamsValue("doc"),
+					"operations": toolkit.ParamsValue(tt.operations),
+				},
+				nil,
+				"",
+				false,
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+			require.NoError(t, transformerCtx.Transformer.Init(context.Background()))
+			r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName("doc")
+			require.NoError(t, err)
+			require.False(t, res.IsNull)
+			require.JSONEq(t, tt.expected, string(res.Data))
+			require.NoError(t, transformerCtx.Transformer.Done(context.Background()))
+		})
+	}
+}
+
+func TestJsonTransformer_Transform_nested_transformer_hash(t *testing.T) {
+	driver, record := getDriverAndRecord(
+		"doc", `{"profile":{"email":"john@example.com"},"contacts":[{"email":"john@example.com"},{"email":"jane@example.com"}]}`,
+	)
+	transformerCtx, warnings, err := JsonTransformerDefinition.Instance(
+		context.Background(),
+		driver, map[string]toolkit.ParamsValue{
+			"column": toolkit.ParamsValue("doc"),
+			"operations": toolkit.ParamsValue(`[
+				{"operation": "transform", "path": "**.email", "transformer": {"name": "RandomEmail", "params": {"engine": "hash"}}}
+			]`),
+		},
+		nil,
+		"",
+		false,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+	require.NoError(t, err)
+	res, err := r.GetRawColumnValueByName("doc")
+	require.NoError(t, err)
+
+	profileEmail := gjson.GetBytes(res.Data, "profile.email")
+	assert.Equal(t, gjson.String, profileEmail.Type)
+	assert.NotEqual(t, "john@example.com", profileEmail.String())
+	assert.Equal(t, profileEmail.String(), gjson.GetBytes(res.Data, "contacts.0.email").String())
+	assert.NotEqual(t, profileEmail.String(), gjson.GetBytes(res.Data, "contacts.1.email").String())
+}
+
+func TestJsonTransformer_Transform_nested_transformer_error_not_exist(t *testing.T) {
+	driver, record := getDriverAndRecord("doc", `{"contacts":[]}`)
+	transformerCtx, warnings, err := JsonTransformerDefinition.Instance(
+		context.Background(),
+		driver, map[string]toolkit.ParamsValue{
+			"column": toolkit.ParamsValue("doc"),
+			"operations": toolkit.ParamsValue(`[
+				{"operation": "transform", "path": "contacts.*.phone", "error_not_exist": true, "transformer": {"name": "SetNull"}}
+			]`),
+		},
+		nil,
+		"",
+		false,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	_, err = transformerCtx.Transformer.Transform(context.Background(), record)
+	require.ErrorContains(t, err, `value by path "contacts.*.phone" does not exist`)
+}
+
+func TestJsonTransformer_nested_transformer_validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		msg        string
+	}{
+		{
+			name:       "transformer is not set",
+			operations: `[{"operation": "transform", "path": "email"}]`,
+			msg:        "transformer is required for transform operation",
+		},
+		{
+			name:       "unknown value type",
+			operations: `[{"operation": "transform", "path": "email", "value_type": "not_a_type", "transformer": {"name": "SetNull"}}]`,
+			msg:        "unknown value type",
+		},
+		{
+			name:       "unknown transformer",
+			operations: `[{"operation": "transform", "path": "email", "transformer": {"name": "Unknown"}}]`,
+			msg:        "unknown nested transformer",
+		},
+		{
+			name:       "nested transformer warning",
+			operations: `[{"operation": "transform", "path": "age", "value_type": "int4", "transformer": {"name": "Replace", "params": {"value": "abc"}}}]`,
+			msg:        "error decoding \"value\" parameter from raw string to type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, _ := getDriverAndRecord("doc", `{}`)
+			_, warnings, err := JsonTransformerDefinition.Instance(
+				context.Background(),
+				driver, map[string]toolkit.ParamsValue{
+					"column":     toolkit.ParamsValue("doc"),
+					"operations": toolkit.ParamsValue(tt.operations),
+				},
+				nil,
+				"",
+				false,
+			)
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+			assert.Equal(t, tt.msg, warnings[0].Msg)
+			assert.Equal(t, 0, warnings[0].Meta["OperationIdx"])
+		})
+	}
+}
@@ -0,0 +1,585 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators"
+	"github.com/greenmaskio/greenmask/internal/generators/transformers"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const LookupTransformerName = "Lookup"
+
+const (
+	lookupFormatCsv   = "csv"
+	lookupFormatJsonl = "jsonl"
+)
+
+// lookupKeySeparator - separates the key values in the group key. PostgreSQL text values cannot contain zero byte
+const lookupKeySeparator = 0
+
+var LookupTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		LookupTransformerName,
+		"Replace values with values from the file or query result matched by key columns",
+	),
+
+	NewLookupTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"file",
+		"path to the CSV file with header or JSONL file with lookup rows. Mutually exclusive with \"query\"",
+	).SetRequired(false),
+
+	toolkit.MustNewParameterDefinition(
+		"format",
+		"format of the file (possible values: csv, jsonl). By default it is detected by the file extension",
+	).SetRequired(false),
+
+	toolkit.MustNewParameterDefinition(
+		"query",
+		"SQL query that returns lookup rows. It is executed in the dump snapshot. Mutually exclusive with \"file\"",
+	).SetRequired(false),
+
+	toolkit.MustNewParameterDefinition(
+		"keys",
+		`list of key columns in format [{"column": "string", "field": "string"}] where "column" is the column of `+
+			`the table and "field" is the field of the lookup row. If "field" is empty the column name is used. `+
+			`If keys are empty all the lookup rows are candidates for any value`,
+	).SetRequired(false).
+		SetDefaultValue(toolkit.ParamsValue("[]")),
+
+	toolkit.MustNewParameterDefinition(
+		"value_field",
+		"field of the lookup row that contains the replacement value",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"default",
+		`default value if the row does not match any lookup row. The string with value "\N" supposed to be NULL value`,
+	).SetRequired(false),
+
+	toolkit.MustNewParameterDefinition(
+		"fail_not_matched",
+		`fail if the row does not match any lookup row and "default" is not set otherwise keep value`,
+	).SetRequired(false).
+		SetDefaultValue(toolkit.ParamsValue("true")),
+
+	toolkit.MustNewParameterDefinition(
+		"validate",
+		`perform decode procedure via PostgreSQL driver using column type, ensuring that lookup values have correct type`,
+	).SetRequired(false).
+		SetDefaultValue(toolkit.ParamsValue("true")),
+
+	keepNullParameterDefinition,
+
+	engineParameterDefinition,
+).SetRequireDatabaseConnection(lookupRequiresDatabaseConnection)
+
+// lookupRequiresDatabaseConnection - the lookup rows of the "query" source are loaded from the database
+func lookupRequiresDatabaseConnection(params map[string]toolkit.ParamsValue) bool {
+	return len(bytes.TrimSpace(params["query"])) > 0
+}
+
+type lookupKey struct {
+	Column    string `json:"column"`
+	Field     string `json:"field"`
+	columnIdx int
+}
+
+// lookupRow - the lookup row fields. The NULL value is stored as nil
+type lookupRow map[string]*string
+
+type LookupTransformer struct {
+	columnName      string
+	columnIdx       int
+	affectedColumns map[int]string
+	keys            []*lookupKey
+	valueField      string
+	file            string
+	format          string
+	query           string
+	defaultValue    *toolkit.RawValue
+	failNotMatched  bool
+	validate        bool
+	keepNull        bool
+	driver          *toolkit.Driver
+	generator       generators.Generator
+	// groups - candidate values grouped by the key values
+	groups map[string]*transformers.RandomChoiceTransformer
+	keyBuf []byte
+}
+
+func NewLookupTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var warnings toolkit.ValidationWarnings
+
+	p := parameters["column"]
+	var columnName string
+	if err := p.Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to parse "column" param: %w`, err)
+	}
+
+	columnIdx, _, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[columnIdx] = columnName
+
+	var file, format, query, valueField, engine string
+	var failNotMatched, validate, keepNull bool
+
+	p = parameters["file"]
+	if err := p.Scan(&file); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "file" param: %w`, err)
+	}
+
+	p = parameters["format"]
+	if err := p.Scan(&format); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "format" param: %w`, err)
+	}
+
+	p = parameters["query"]
+	if err := p.Scan(&query); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "query" param: %w`, err)
+	}
+
+	p = parameters["value_field"]
+	if err := p.Scan(&valueField); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "value_field" param: %w`, err)
+	}
+
+	p = parameters["fail_not_matched"]
+	if err := p.Scan(&failNotMatched); err != nil {
+		return nil, nil, fmt.Errorf(`unable to parse "fail_not_matched" param: %w`, err)
+	}
+
+	p = parameters["validate"]
+	if err := p.Scan(&validate); err != nil {
+		return nil, nil, fmt.Errorf(`unable to parse "validate" param: %w`, err)
+	}
+
+	p = parameters["keep_null"]
+	if err := p.Scan(&keepNull); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "keep_null" param: %w`, err)
+	}
+
+	p = parameters["engine"]
+	if err := p.Scan(&engine); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "engine" param: %w`, err)
+	}
+
+	switch {
+	case file == "" && query == "":
+		warnings = append(warnings,
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				SetMsg(`one of "file" or "query" parameters must be set`),
+		)
+	case file != "" && query != "":
+		warnings = append(warnings,
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				SetMsg(`"file" and "query" parameters are mutually exclusive`),
+		)
+	case file != "" && format == "":
+		format = getLookupFileFormat(file)
+		if format == "" {
+			warnings = append(warnings,
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "file").
+					AddMeta("ParameterValue", file).
+					SetMsg(`unable to detect file format by extension: set "format" parameter`),
+			)
+		}
+	case file != "" && format != lookupFormatCsv && format != lookupFormatJsonl:
+		warnings = append(warnings,
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "format").
+				AddMeta("ParameterValue", format).
+				SetMsg("unknown file format"),
+		)
+	}
+
+	p = parameters["keys"]
+	var keys []*lookupKey
+	if err := p.Scan(&keys); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "keys" param: %w`, err)
+	}
+	for idx, k := range keys {
+		keyColumnIdx, _, ok := driver.GetColumnByName(k.Column)
+		if !ok {
+			warnings = append(warnings,
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "keys").
+					AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), k.Column).
+					SetMsg("key column is not found"),
+			)
+			continue
+		}
+		k.columnIdx = keyColumnIdx
+		if k.Field == "" {
+			k.Field = k.Column
+		}
+	}
+
+	var defaultValue *toolkit.RawValue
+	p = parameters["default"]
+	isEmpty, err := p.IsEmpty()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error checking is parameter \"default\" empty: %w", err)
+	}
+	if !isEmpty {
+		rawDefaultValue, err := p.RawValue()
+		if err != nil {
+			return nil, nil, fmt.Errorf(`unable to scan "default" param: %w`, err)
+		}
+		if string(rawDefaultValue) == defaultNullSeq {
+			defaultValue = toolkit.NewRawValue(nil, true)
+		} else {
+			defaultValue = toolkit.NewRawValue(rawDefaultValue, false)
+			if validate {
+				if _, err := driver.DecodeValueByColumnIdx(columnIdx, rawDefaultValue); err != nil {
+					warnings = append(warnings,
+						toolkit.NewValidationWarning().
+							SetSeverity(toolkit.ErrorValidationSeverity).
+							AddMeta("ParameterValue", string(rawDefaultValue)).
+							AddMeta("ParameterName", "default").
+							AddMeta("Error", err.Error()).
+							SetMsg("error validating \"default\""),
+					)
+				}
+			}
+		}
+	}
+
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	g, err := getGenerateEngine(ctx, engine, transformers.NewRandomChoiceTransformer(nil).GetRequiredGeneratorByteLength())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+	}
+
+	return &LookupTransformer{
+		columnName:      columnName,
+		columnIdx:       columnIdx,
+		affectedColumns: affectedColumns,
+		keys:            keys,
+		valueField:      valueField,
+		file:            file,
+		format:          format,
+		query:           query,
+		defaultValue:    defaultValue,
+		failNotMatched:  failNotMatched,
+		validate:        validate,
+		keepNull:        keepNull,
+		driver:          driver,
+		generator:       g,
+	}, warnings, nil
+}
+
+func (lt *LookupTransformer) GetAffectedColumns() map[int]string {
+	return lt.affectedColumns
+}
+
+// Init - loads the lookup rows from the file or the query result
+func (lt *LookupTransformer) Init(ctx context.Context) error {
+	lt.groups = make(map[string]*transformers.RandomChoiceTransformer)
+	var rowNum int
+	addRow := func(row lookupRow) error {
+		rowNum++
+		if err := lt.addRow(row); err != nil {
+			return fmt.Errorf("lookup row %d: %w", rowNum, err)
+		}
+		return nil
+	}
+
+	var err error
+	if lt.query != "" {
+		err = lt.loadQuery(ctx, addRow)
+	} else {
+		err = lt.loadFile(addRow)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, g := range lt.groups {
+		if err = g.SetGenerator(lt.generator); err != nil {
+			return fmt.Errorf("unable to set generator: %w", err)
+		}
+	}
+	return nil
+}
+
+func (lt *LookupTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (lt *LookupTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(lt.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull && lt.keepNull {
+		return r, nil
+	}
+
+	var newVal *toolkit.RawValue
+	key, ok, err := lt.getRecordKey(r)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if g, ok := lt.groups[key]; ok {
+			newVal, err = g.Transform(val.Data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to transform value: %w", err)
+			}
+		}
+	}
+
+	if newVal == nil {
+		if lt.defaultValue != nil {
+			newVal = lt.defaultValue
+		} else if lt.failNotMatched {
+			return nil, fmt.Errorf(`unable to match lookup row for "%s"`, string(val.Data))
+		} else {
+			return r, nil
+		}
+	}
+
+	if err = r.SetRawColumnValueByIdx(lt.columnIdx, newVal); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+// getRecordKey - returns the group key of the record. The record with NULL key value cannot be matched
+func (lt *LookupTransformer) getRecordKey(r *toolkit.Record) (string, bool, error) {
+	lt.keyBuf = lt.keyBuf[:0]
+	for _, k := range lt.keys {
+		v, err := r.GetRawColumnValueByIdx(k.columnIdx)
+		if err != nil {
+			return "", false, fmt.Errorf("unable to scan key column \"%s\" value: %w", k.Column, err)
+		}
+		if v.IsNull {
+			return "", false, nil
+		}
+		lt.keyBuf = append(lt.keyBuf, v.Data...)
+		lt.keyBuf = append(lt.keyBuf, lookupKeySeparator)
+	}
+	return string(lt.keyBuf), true, nil
+}
+
+func (lt *LookupTransformer) addRow(row lookupRow) error {
+	var key []byte
+	for _, k := range lt.keys {
+		v, ok := row[k.Field]
+		if !ok {
+			return fmt.Errorf("key field \"%s\" is not found", k.Field)
+		}
+		if v == nil {
+			// The NULL key is never matched
+			return nil
+		}
+		key = append(key, *v...)
+		key = append(key, lookupKeySeparator)
+	}
+
+	v, ok := row[lt.valueField]
+	if !ok {
+		return fmt.Errorf("value field \"%s\" is not found", lt.valueField)
+	}
+	value := toolkit.NewRawValue(nil, true)
+	if v != nil {
+		if lt.validate {
+			if _, err := lt.driver.DecodeValueByColumnIdx(lt.columnIdx, []byte(*v)); err != nil {
+				return fmt.Errorf("unable to decode value \"%s\": %w", *v, err)
+			}
+		}
+		value = toolkit.NewRawValue([]byte(*v), false)
+	}
+
+	g, ok := lt.groups[string(key)]
+	if !ok {
+		g = transformers.NewRandomChoiceTransformer(nil)
+		lt.groups[string(key)] = g
+	}
+	g.AddValue(value)
+	return nil
+}
+
+func (lt *LookupTransformer) loadQuery(ctx context.Context, f func(row lookupRow) error) error {
+	tx := utils.TxFromCtx(ctx)
+	if tx == nil {
+		return errors.New("lookup query requires database connection")
+	}
+	// The simple protocol returns the values in text format that is the same as COPY uses
+	rows, err := tx.Query(ctx, lt.query, pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		return fmt.Errorf("unable to execute lookup query: %w", err)
+	}
+	defer rows.Close()
+	fields := rows.FieldDescriptions()
+	for rows.Next() {
+		row := make(lookupRow, len(fields))
+		for idx, v := range rows.RawValues() {
+			if v == nil {
+				row[fields[idx].Name] = nil
+				continue
+			}
+			s := string(v)
+			row[fields[idx].Name] = &s
+		}
+		if err = f(row); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("unable to read lookup query result: %w", err)
+	}
+	return nil
+}
+
+func (lt *LookupTransformer) loadFile(f func(row lookupRow) error) error {
+	file, err := os.Open(lt.file)
+	if err != nil {
+		return fmt.Errorf("unable to open lookup file: %w", err)
+	}
+	defer file.Close()
+
+	switch lt.format {
+	case lookupFormatCsv:
+		err = readLookupCsv(file, f)
+	case lookupFormatJsonl:
+		err = readLookupJsonl(file, f)
+	default:
+		err = fmt.Errorf("unknown lookup file format \"%s\"", lt.format)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read lookup file \"%s\": %w", lt.file, err)
+	}
+	return nil
+}
+
+// readLookupCsv - reads CSV file with header. The value "\N" is supposed to be NULL
+func readLookupCsv(r io.Reader, f func(row lookupRow) error) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read header: %w", err)
+	}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		row := make(lookupRow, len(header))
+		for idx, name := range header {
+			if record[idx] == defaultNullSeq {
+				row[name] = nil
+				continue
+			}
+			row[name] = &record[idx]
+		}
+		if err = f(row); err != nil {
+			return err
+		}
+	}
+}
+
+// readLookupJsonl - reads JSON object per line. String values are used as is, JSON null is supposed to be NULL and
+// other values are used in JSON representation
+func readLookupJsonl(r io.Reader, f func(row lookupRow) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 10*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(line, &fields); err != nil {
+			return fmt.Errorf("unable to parse line %d: %w", lineNum, err)
+		}
+		row := make(lookupRow, len(fields))
+		for name, raw := range fields {
+			switch {
+			case string(raw) == "null":
+				row[name] = nil
+			case raw[0] == '"':
+				var s string
+				if err := json.Unmarshal(raw, &s); err != nil {
+					return fmt.Errorf("unable to parse line %d field \"%s\": %w", lineNum, name, err)
+				}
+				row[name] = &s
+			default:
+				s := string(raw)
+				row[name] = &s
+			}
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func getLookupFileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return lookupFormatCsv
+	case ".jsonl", ".ndjson":
+		return lookupFormatJsonl
+	}
+	return ""
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(LookupTransformerDefinition)
+}
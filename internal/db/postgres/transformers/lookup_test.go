@@ -0,0 +1,343 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func writeLookupFile(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	return path
+}
+
+func TestLookupTransformer_Transform(t *testing.T) {
+	csvFile := writeLookupFile(t, "cities.csv", "country,city\n1,Berlin\n1,Munich\n2,Paris\n3,\\N\n")
+	jsonlFile := writeLookupFile(t, "cities.jsonl",
+		`{"country": 1, "city": "Berlin"}`+"\n"+
+			`{"country": 2, "city": "Paris"}`+"\n"+
+			`{"country": null, "city": "Nowhere"}`+"\n",
+	)
+
+	tests := []struct {
+		name     string
+		params   map[string]toolkit.ParamsValue
+		original string
+		expected []string
+		isNull   bool
+		wantErr  string
+	}{
+		{
+			name: "csv with key",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue(csvFile),
+				"keys": toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+			},
+			original: "1\tjohn",
+			expected: []string{"Berlin", "Munich"},
+		},
+		{
+			name: "csv single candidate",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue(csvFile),
+				"keys": toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+			},
+			original: "2\tjohn",
+			expected: []string{"Paris"},
+		},
+		{
+			name: "csv NULL value",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue(csvFile),
+				"keys": toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+			},
+			original: "3\tjohn",
+			isNull:   true,
+		},
+		{
+			name: "without keys",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue(csvFile),
+			},
+			original: "5\tjohn",
+			expected: []string{"Berlin", "Munich", "Paris"},
+		},
+		{
+			name: "jsonl",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue(jsonlFile),
+				"keys": toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+			},
+			original: "2\tjohn",
+			expected: []string{"Paris"},
+		},
+		{
+			name: "not matched with default",
+			params: map[string]toolkit.ParamsValue{
+				"file":    toolkit.ParamsValue(jsonlFile),
+				"keys":    toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+				"default": toolkit.ParamsValue("Unknown"),
+			},
+			original: "5\tjohn",
+			expected: []string{"Unknown"},
+		},
+		{
+			name: "NULL key is not matched",
+			params: map[string]toolkit.ParamsValue{
+				"file":    toolkit.ParamsValue(jsonlFile),
+				"keys":    toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+				"default": toolkit.ParamsValue(`\N`),
+			},
+			original: "\\N\tjohn",
+			isNull:   true,
+		},
+		{
+			name: "not matched keep value",
+			params: map[string]toolkit.ParamsValue{
+				"file":             toolkit.ParamsValue(jsonlFile),
+				"keys":             toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+				"fail_not_matched": toolkit.ParamsValue("false"),
+			},
+			original: "5\tjohn",
+			expected: []string{"john"},
+		},
+		{
+			name: "not matched fail",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue(jsonlFile),
+				"keys": toolkit.ParamsValue(`[{"column": "id", "field": "country"}]`),
+			},
+			original: "5\tjohn",
+			wantErr:  "unable to match lookup row",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("data")
+			tt.params["value_field"] = toolkit.ParamsValue("city")
+			tt.params["engine"] = toolkit.ParamsValue("hash")
+			driver, record := getDriverAndRecordByColumns([]string{"id", "data"}, tt.original)
+			transformerCtx, warnings, err := LookupTransformerDefinition.Instance(
+				context.Background(),
+				driver, tt.params,
+				nil,
+				"",
+				false,
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+			require.NoError(t, transformerCtx.Transformer.Init(context.Background()))
+
+			r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName("data")
+			require.NoError(t, err)
+			require.Equal(t, tt.isNull, res.IsNull)
+			if !tt.isNull {
+				assert.Contains(t, tt.expected, string(res.Data))
+			}
+		})
+	}
+}
+
+func TestLookupTransformer_Transform_deterministic(t *testing.T) {
+	file := writeLookupFile(t, "names.csv", "name\nAlice\nBob\nCarol\nDave\nEve\n")
+	params := map[string]toolkit.ParamsValue{
+		"column":      toolkit.ParamsValue("data"),
+		"file":        toolkit.ParamsValue(file),
+		"value_field": toolkit.ParamsValue("name"),
+		"engine":      toolkit.ParamsValue("hash"),
+	}
+
+	var results []string
+	for i := 0; i < 2; i++ {
+		driver, record := getDriverAndRecord("data", "john")
+		transformerCtx, warnings, err := LookupTransformerDefinition.Instance(
+			context.Background(),
+			driver, params,
+			nil,
+			"",
+			false,
+		)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		require.NoError(t, transformerCtx.Transformer.Init(context.Background()))
+		r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+		require.NoError(t, err)
+		res, err := r.GetRawColumnValueByName("data")
+		require.NoError(t, err)
+		results = append(results, string(res.Data))
+	}
+	assert.Equal(t, results[0], results[1])
+}
+
+func TestLookupTransformer_Init_errors(t *testing.T) {
+	file := writeLookupFile(t, "values.csv", "key,value\n1,abc\n")
+
+	tests := []struct {
+		name    string
+		params  map[string]toolkit.ParamsValue
+		wantErr string
+	}{
+		{
+			name: "validation error",
+			params: map[string]toolkit.ParamsValue{
+				"file":        toolkit.ParamsValue(file),
+				"value_field": toolkit.ParamsValue("value"),
+			},
+			wantErr: "unable to decode value",
+		},
+		{
+			name: "unknown value field",
+			params: map[string]toolkit.ParamsValue{
+				"file":        toolkit.ParamsValue(file),
+				"value_field": toolkit.ParamsValue("city"),
+			},
+			wantErr: "value field \"city\" is not found",
+		},
+		{
+			name: "query without connection",
+			params: map[string]toolkit.ParamsValue{
+				"query":       toolkit.ParamsValue("SELECT 1 AS value"),
+				"value_field": toolkit.ParamsValue("value"),
+			},
+			wantErr: "requires database connection",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("id")
+			driver, _ := getDriverAndRecord("id", "1")
+			transformerCtx, warnings, err := LookupTransformerDefinition.Instance(
+				context.Background(),
+				driver, tt.params,
+				nil,
+				"",
+				false,
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+			require.ErrorContains(t, transformerCtx.Transformer.Init(context.Background()), tt.wantErr)
+		})
+	}
+}
+
+func TestLookupTransformer_validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]toolkit.ParamsValue
+		msg    string
+	}{
+		{
+			name:   "no source",
+			params: map[string]toolkit.ParamsValue{},
+			msg:    `one of "file" or "query" parameters must be set`,
+		},
+		{
+			name: "both sources",
+			params: map[string]toolkit.ParamsValue{
+				"file":  toolkit.ParamsValue("values.csv"),
+				"query": toolkit.ParamsValue("SELECT 1"),
+			},
+			msg: `"file" and "query" parameters are mutually exclusive`,
+		},
+		{
+			name: "unknown format",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue("values.txt"),
+			},
+			msg: `unable to detect file format by extension: set "format" parameter`,
+		},
+		{
+			name: "unsupported format",
+			params: map[string]toolkit.ParamsValue{
+				"file":   toolkit.ParamsValue("values.csv"),
+				"format": toolkit.ParamsValue("xml"),
+			},
+			msg: "unknown file format",
+		},
+		{
+			name: "unknown key column",
+			params: map[string]toolkit.ParamsValue{
+				"file": toolkit.ParamsValue("values.csv"),
+				"keys": toolkit.ParamsValue(`[{"column": "country"}]`),
+			},
+			msg: "key column is not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("data")
+			tt.params["value_field"] = toolkit.ParamsValue("value")
+			driver, _ := getDriverAndRecord("data", "john")
+			_, warnings, err := LookupTransformerDefinition.Instance(
+				context.Background(),
+				driver, tt.params,
+				nil,
+				"",
+				false,
+			)
+			require.NoError(t, err)
+			require.Len(t, warnings, 1)
+			assert.Equal(t, toolkit.ErrorValidationSeverity, warnings[0].Severity)
+			assert.Equal(t, tt.msg, warnings[0].Msg)
+		})
+	}
+}
+
+func TestLookupTransformerDefinition_RequiresDatabaseConnection(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]toolkit.ParamsValue
+		want   bool
+	}{
+		{
+			name:   "file",
+			params: map[string]toolkit.ParamsValue{"file": toolkit.ParamsValue("values.csv")},
+			want:   false,
+		},
+		{
+			name:   "query",
+			params: map[string]toolkit.ParamsValue{"query": toolkit.ParamsValue("SELECT 1")},
+			want:   true,
+		},
+		{
+			name:   "blank query",
+			params: map[string]toolkit.ParamsValue{"query": toolkit.ParamsValue("  ")},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, LookupTransformerDefinition.RequiresDatabaseConnection(tt.params))
+		})
+	}
+}
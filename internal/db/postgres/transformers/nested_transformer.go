@@ -0,0 +1,182 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgcopy"
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+// NestedTransformerConfig - the config of the transformer applied to the parts of the column value, for instance,
+// to the array elements
+type NestedTransformerConfig struct {
+	Name string `json:"name"`
+	// Params - the transformer parameters. The string values are passed as is and the others as JSON, the same as in
+	// the transformation config
+	Params map[string]json.RawMessage `json:"params,omitempty"`
+}
+
+// getParams - converts the params into the raw transformer params
+func (c *NestedTransformerConfig) getParams() map[string]toolkit.ParamsValue {
+	params := make(map[string]toolkit.ParamsValue, len(c.Params)+1)
+	for name, v := range c.Params {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			params[name] = toolkit.ParamsValue(s)
+		} else {
+			params[name] = toolkit.ParamsValue(v)
+		}
+	}
+	return params
+}
+
+// requiresDatabaseConnection - check the nested transformer uses the database connection. The unknown transformer
+// is reported during the initialization
+func (c *NestedTransformerConfig) requiresDatabaseConnection() bool {
+	def, ok := utils.DefaultTransformerRegistry.Get(c.Name)
+	if !ok {
+		return false
+	}
+	return def.RequiresDatabaseConnection(c.getParams())
+}
+
+// fixedSizeTypeLengths - the lengths (pg_type.typlen) of the fixed-size types that the transformers use to detect the
+// value size. The other types are handled as variable-length
+var fixedSizeTypeLengths = map[string]int{
+	"bool":        1,
+	"int2":        2,
+	"int4":        4,
+	"int8":        8,
+	"float4":      4,
+	"float8":      8,
+	"date":        4,
+	"time":        8,
+	"timestamp":   8,
+	"timestamptz": 8,
+	"uuid":        16,
+	"oid":         4,
+}
+
+// nestedTransformer - transforms the single values using the transformer instance that works with the virtual table
+// with one column
+type nestedTransformer struct {
+	t      utils.Transformer
+	record *toolkit.Record
+}
+
+// newNestedTransformerColumn - creates the column of the provided type for the nested transformer
+func newNestedTransformerColumn(name string, t *pgtype.Type) *toolkit.Column {
+	typeLength := -1
+	if l, ok := fixedSizeTypeLengths[t.Name]; ok {
+		typeLength = l
+	}
+	return &toolkit.Column{
+		Name:              name,
+		TypeName:          t.Name,
+		CanonicalTypeName: t.Name,
+		TypeOid:           toolkit.Oid(t.OID),
+		Num:               1,
+		NotNull:           false,
+		Length:            -1,
+		TypeLength:        typeLength,
+	}
+}
+
+// newNestedTransformer - creates the transformer instance for the values of the column. The "column" parameter is set
+// to the column name if the transformer has it
+func newNestedTransformer(
+	ctx context.Context, parentDriver *toolkit.Driver, column *toolkit.Column, cfg *NestedTransformerConfig,
+) (*nestedTransformer, toolkit.ValidationWarnings, error) {
+	def, ok := utils.DefaultTransformerRegistry.Get(cfg.Name)
+	if !ok {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("NestedTransformerName", cfg.Name).
+				SetMsg("unknown nested transformer"),
+		}, nil
+	}
+
+	table := &toolkit.Table{
+		Schema:      parentDriver.Table.Schema,
+		Name:        parentDriver.Table.Name,
+		Oid:         parentDriver.Table.Oid,
+		Columns:     []*toolkit.Column{column},
+		Constraints: []toolkit.Constraint{},
+	}
+	driver, warnings, err := toolkit.NewDriver(table, parentDriver.CustomTypes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create driver for nested transformer: %w", err)
+	}
+
+	params := cfg.getParams()
+	hasColumnParam := slices.ContainsFunc(def.Parameters, func(p *toolkit.ParameterDefinition) bool {
+		return p.Name == "column"
+	})
+	if _, ok = params["column"]; !ok && hasColumnParam {
+		params["column"] = toolkit.ParamsValue(column.Name)
+	}
+
+	tc, instanceWarnings, err := def.Instance(ctx, driver, params, nil, "", false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create nested transformer \"%s\": %w", cfg.Name, err)
+	}
+	warnings = append(warnings, instanceWarnings...)
+	for _, w := range warnings {
+		w.AddMeta("NestedTransformerName", cfg.Name)
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	record := toolkit.NewRecord(driver)
+	record.SetRow(pgcopy.NewRow(1))
+	return &nestedTransformer{
+		t:      tc.Transformer,
+		record: record,
+	}, warnings, nil
+}
+
+func (nt *nestedTransformer) Init(ctx context.Context) error {
+	return nt.t.Init(ctx)
+}
+
+func (nt *nestedTransformer) Done(ctx context.Context) error {
+	return nt.t.Done(ctx)
+}
+
+// Transform - transforms the value. The returned data is copied, so it is not changed by the next calls
+func (nt *nestedTransformer) Transform(ctx context.Context, v *toolkit.RawValue) (*toolkit.RawValue, error) {
+	if err := nt.record.SetRawColumnValueByIdx(0, v); err != nil {
+		return nil, err
+	}
+	r, err := nt.t.Transform(ctx, nt.record)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.GetRawColumnValueByIdx(0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get transformed value: %w", err)
+	}
+	return toolkit.NewRawValue(slices.Clone(res.Data), res.IsNull), nil
+}
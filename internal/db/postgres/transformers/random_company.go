@@ -28,6 +28,8 @@ var randomCompanyTransformerDefinition = utils.NewTransformerDefinition(
 	).SetRequired(true).
 		SetIsColumnContainer(true),
 
+	localeParameterDefinition,
+
 	engineParameterDefinition,
 )
 
@@ -72,7 +74,19 @@ func NewRandomCompanyTransformer(ctx context.Context, driver *toolkit.Driver, pa
 		engineMode = hashEngineMode
 	}
 
-	t := transformers.NewRandomCompanyTransformer(nil)
+	pack, err := getLocalePack(parameters["locale"])
+	if err != nil {
+		return nil, nil, err
+	}
+	var companyDb map[string][]string
+	if pack != nil {
+		if pack.Company == nil {
+			return nil, toolkit.ValidationWarnings{newLocaleSectionWarning(pack, "company")}, nil
+		}
+		companyDb = transformers.NewCompanyMap(pack.Company)
+	}
+
+	t := transformers.NewRandomCompanyTransformer(companyDb)
 
 	g, err := getGenerateEngine(ctx, engine, t.GetRequiredGeneratorByteLength())
 	if err != nil {
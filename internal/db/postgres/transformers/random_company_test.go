@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 	"github.com/greenmaskio/greenmask/internal/generators/transformers"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
@@ -158,3 +159,29 @@ func TestRandomCompanyTransformer_Transform_static_nullable(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, rawVal.IsNull)
 }
+
+func TestRandomCompanyTransformer_Transform_locale(t *testing.T) {
+	columnName := "data"
+	params := map[string]toolkit.ParamsValue{
+		"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .CompanyName }} {{ .CompanySuffix }}"}]`),
+		"locale":  toolkit.ParamsValue("ja_JP"),
+	}
+	pack, err := locales.Get("ja_JP")
+	require.NoError(t, err)
+
+	driver, record := getDriverAndRecord(columnName, "ACME Corp.")
+	def, ok := utils.DefaultTransformerRegistry.Get("RandomCompany")
+	require.True(t, ok)
+
+	transformer, warnings, err := def.Instance(context.Background(), driver, params, nil, "", false)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	r, err := transformer.Transformer.Transform(context.Background(), record)
+	require.NoError(t, err)
+	rawVal, err := r.GetRawColumnValueByName(columnName)
+	require.NoError(t, err)
+	require.False(t, rawVal.IsNull)
+	require.True(t, testStringContainsOneOfItemFromList(string(rawVal.Data), pack.Company.Names))
+	require.True(t, testStringContainsOneOfItemFromList(string(rawVal.Data), pack.Company.Suffixes))
+}
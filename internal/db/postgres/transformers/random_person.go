@@ -54,7 +54,7 @@ var randomPersonTransformerDefinition = utils.NewTransformerDefinition(
 	).SetSupportTemplate(true).
 		SetDefaultValue(toolkit.ParamsValue("Any")),
 
-	// TODO: Allow user to override the default names, surnames and genders with kind of dictionary
+	localeParameterDefinition,
 
 	engineParameterDefinition,
 )
@@ -112,7 +112,19 @@ func NewRandomNameTransformer(ctx context.Context, driver *toolkit.Driver, param
 		engineMode = hashEngineMode
 	}
 
-	t := transformers.NewRandomPersonTransformer(gender, nil)
+	pack, err := getLocalePack(parameters["locale"])
+	if err != nil {
+		return nil, nil, err
+	}
+	var personDb transformers.Database
+	if pack != nil {
+		if pack.Person == nil {
+			return nil, toolkit.ValidationWarnings{newLocaleSectionWarning(pack, "person")}, nil
+		}
+		personDb = transformers.NewPersonMap(pack.Person)
+	}
+
+	t := transformers.NewRandomPersonTransformer(gender, personDb)
 
 	g, err := getGenerateEngine(ctx, engine, t.GetRequiredGeneratorByteLength())
 	if err != nil {
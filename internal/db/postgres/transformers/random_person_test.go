@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 	"github.com/greenmaskio/greenmask/internal/generators/transformers"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
@@ -351,3 +352,50 @@ func TestRandomPersonTransformer_Transform_keep_null(t *testing.T) {
 		assert.Equal(t, lastName, string(lastNameRawValue.Data))
 	})
 }
+
+func TestRandomPersonTransformer_Transform_locale(t *testing.T) {
+	columnName := "data"
+	params := map[string]toolkit.ParamsValue{
+		"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .FirstName }} {{ .LastName }}"}]`),
+		"engine":  toolkit.ParamsValue("hash"),
+		"locale":  toolkit.ParamsValue("de_DE"),
+	}
+	pack, err := locales.Get("de_DE")
+	require.NoError(t, err)
+
+	def, ok := utils.DefaultTransformerRegistry.Get("RandomPerson")
+	require.True(t, ok)
+
+	var results []string
+	for range 2 {
+		driver, record := getDriverAndRecord(columnName, "John Dust")
+		transformer, warnings, err := def.Instance(context.Background(), driver, params, nil, "", false)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+
+		r, err := transformer.Transformer.Transform(context.Background(), record)
+		require.NoError(t, err)
+		rawVal, err := r.GetRawColumnValueByName(columnName)
+		require.NoError(t, err)
+		require.False(t, rawVal.IsNull)
+		require.True(t, testStringContainsOneOfItemFromList(string(rawVal.Data), pack.Person.LastNames))
+		results = append(results, string(rawVal.Data))
+	}
+	// The hash engine generates the same value for the same locale
+	require.Equal(t, results[0], results[1])
+}
+
+func TestRandomPersonTransformer_Transform_unknown_locale(t *testing.T) {
+	params := map[string]toolkit.ParamsValue{
+		"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .FirstName }}"}]`),
+		"locale":  toolkit.ParamsValue("xx_XX"),
+	}
+	driver, _ := getDriverAndRecord("data", "John Dust")
+	def, ok := utils.DefaultTransformerRegistry.Get("RandomPerson")
+	require.True(t, ok)
+
+	_, warnings, err := def.Instance(context.Background(), driver, params, nil, "", false)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+	require.Equal(t, "unknown locale", warnings[0].Msg)
+}
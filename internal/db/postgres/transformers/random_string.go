@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators"
 	"github.com/greenmaskio/greenmask/internal/generators/transformers"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
@@ -67,6 +68,8 @@ var stringTransformerDefinition = utils.NewTransformerDefinition(
 	keepNullParameterDefinition,
 
 	engineParameterDefinition,
+
+	uniqueParameterDefinition,
 )
 
 type RandomStringTransformer struct {
@@ -75,13 +78,14 @@ type RandomStringTransformer struct {
 	keepNull        bool
 	affectedColumns map[int]string
 	columnIdx       int
+	uniqueValues    *generators.UniqueValues
 }
 
 func NewRandomStringTransformer(ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer) (utils.Transformer, toolkit.ValidationWarnings, error) {
 
 	var columnName, symbols, engine string
 	var minLength, maxLength int
-	var keepNull bool
+	var keepNull, unique bool
 
 	p := parameters["column"]
 	if err := p.Scan(&columnName); err != nil {
@@ -115,6 +119,11 @@ func NewRandomStringTransformer(ctx context.Context, driver *toolkit.Driver, par
 		return nil, nil, fmt.Errorf(`unable to scan "keep_null" param: %w`, err)
 	}
 
+	p = parameters["unique"]
+	if err := p.Scan(&unique); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "unique" param: %w`, err)
+	}
+
 	t, err := transformers.NewRandomStringTransformer([]rune(symbols), minLength, maxLength)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create transformer: %w", err)
@@ -139,6 +148,7 @@ func NewRandomStringTransformer(ctx context.Context, driver *toolkit.Driver, par
 		keepNull:        keepNull,
 		affectedColumns: affectedColumns,
 		columnIdx:       idx,
+		uniqueValues:    newUniqueValues(unique),
 	}, nil, nil
 }
 
@@ -163,12 +173,14 @@ func (rst *RandomStringTransformer) Transform(ctx context.Context, r *toolkit.Re
 		return r, nil
 	}
 
-	res := toolkit.NewRawValue(
-		[]byte(string(rst.t.Transform(val.Data))),
-		false,
-	)
+	data, err := generateValue(rst.uniqueValues, val, func(data []byte) ([]byte, error) {
+		return []byte(string(rst.t.Transform(data))), nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	if err = r.SetRawColumnValueByIdx(rst.columnIdx, res); err != nil {
+	if err = r.SetRawColumnValueByIdx(rst.columnIdx, toolkit.NewRawValue(data, false)); err != nil {
 		return nil, fmt.Errorf("unable to set new value: %w", err)
 	}
 
@@ -19,8 +19,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgcopy"
+	"github.com/greenmaskio/greenmask/internal/generators"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
@@ -110,3 +113,67 @@ func TestRandomStringTransformer_Transform(t *testing.T) {
 		})
 	}
 }
+
+func TestRandomStringTransformer_Transform_unique(t *testing.T) {
+	params := map[string]toolkit.ParamsValue{
+		"column":     toolkit.ParamsValue("data"),
+		"min_length": toolkit.ParamsValue("1"),
+		"max_length": toolkit.ParamsValue("1"),
+		"symbols":    toolkit.ParamsValue("abcd"),
+		"engine":     toolkit.ParamsValue("hash"),
+		"unique":     toolkit.ParamsValue("true"),
+	}
+	driver, record := getDriverAndRecord("data", "original")
+	transformerCtx, warnings, err := stringTransformerDefinition.Instance(
+		context.Background(), driver, params, nil, "", false,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	generated := make(map[string]struct{})
+	for _, original := range []string{"a1", "a2", "a3", "a4"} {
+		row := pgcopy.NewRow(1)
+		require.NoError(t, row.Decode([]byte(original)))
+		record.SetRow(row)
+		r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+		require.NoError(t, err)
+		val, err := r.GetRawColumnValueByIdx(0)
+		require.NoError(t, err)
+		require.NotContains(t, generated, string(val.Data))
+		generated[string(val.Data)] = struct{}{}
+	}
+
+	// All the possible values were generated
+	row := pgcopy.NewRow(1)
+	require.NoError(t, row.Decode([]byte("a5")))
+	record.SetRow(row)
+	_, err = transformerCtx.Transformer.Transform(context.Background(), record)
+	require.ErrorIs(t, err, generators.ErrUniqueValueExhausted)
+}
+
+func TestRandomStringTransformer_uniqueValidation(t *testing.T) {
+	params := map[string]toolkit.ParamsValue{
+		"column":     toolkit.ParamsValue("data"),
+		"min_length": toolkit.ParamsValue("10"),
+		"max_length": toolkit.ParamsValue("10"),
+	}
+	driver, _ := getDriverAndRecord("data", "original")
+	driver.Table.Constraints = []toolkit.Constraint{
+		toolkit.NewUnique("public", "test_data_key", "UNIQUE (data)", 1, []toolkit.AttNum{3}),
+	}
+
+	_, warnings, err := stringTransformerDefinition.Instance(
+		context.Background(), driver, params, nil, "", false,
+	)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, toolkit.UniqueConstraintType, warnings[0].Meta["ConstraintType"])
+	assert.Contains(t, warnings[0].Meta, "Hint")
+
+	params["unique"] = toolkit.ParamsValue("true")
+	_, warnings, err = stringTransformerDefinition.Instance(
+		context.Background(), driver, params, nil, "", false,
+	)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators"
 	"github.com/greenmaskio/greenmask/internal/generators/transformers"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
@@ -45,6 +46,8 @@ var uuidTransformerDefinition = utils.NewTransformerDefinition(
 	keepNullParameterDefinition,
 
 	engineParameterDefinition,
+
+	uniqueParameterDefinition,
 )
 
 type RandomUuidTransformer struct {
@@ -53,11 +56,12 @@ type RandomUuidTransformer struct {
 	columnIdx       int
 	keepNull        bool
 	affectedColumns map[int]string
+	uniqueValues    *generators.UniqueValues
 }
 
 func NewRandomUuidTransformer(ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer) (utils.Transformer, toolkit.ValidationWarnings, error) {
 	var columnName, engine string
-	var keepNull bool
+	var keepNull, unique bool
 
 	p := parameters["column"]
 	if err := p.Scan(&columnName); err != nil {
@@ -81,6 +85,11 @@ func NewRandomUuidTransformer(ctx context.Context, driver *toolkit.Driver, param
 		return nil, nil, fmt.Errorf(`unable to scan "engine" param: %w`, err)
 	}
 
+	p = parameters["unique"]
+	if err := p.Scan(&unique); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "unique" param: %w`, err)
+	}
+
 	t := transformers.NewRandomUuidTransformer()
 
 	g, err := getGenerateEngine(ctx, engine, t.GetRequiredGeneratorByteLength())
@@ -97,6 +106,7 @@ func NewRandomUuidTransformer(ctx context.Context, driver *toolkit.Driver, param
 		keepNull:        keepNull,
 		affectedColumns: affectedColumns,
 		columnIdx:       idx,
+		uniqueValues:    newUniqueValues(unique),
 	}, nil, nil
 }
 
@@ -121,19 +131,27 @@ func (rut *RandomUuidTransformer) Transform(ctx context.Context, r *toolkit.Reco
 		return r, nil
 	}
 
-	uuidVal, err := rut.t.Transform(val.Data)
+	data, err := generateValue(rut.uniqueValues, val, rut.generate)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.SetRawColumnValueByIdx(rut.columnIdx, toolkit.NewRawValue(data, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func (rut *RandomUuidTransformer) generate(data []byte) ([]byte, error) {
+	uuidVal, err := rut.t.Transform(data)
 	if err != nil {
 		return nil, fmt.Errorf("error transforming value: %w", err)
 	}
 
-	data, err := uuidVal.MarshalText()
+	res, err := uuidVal.MarshalText()
 	if err != nil {
 		return nil, fmt.Errorf("error umarshalling uuid: %w", err)
 	}
-	if err = r.SetRawColumnValueByIdx(rut.columnIdx, toolkit.NewRawValue(data, false)); err != nil {
-		return nil, fmt.Errorf("unable to set new value: %w", err)
-	}
-	return r, nil
+	return res, nil
 }
 
 func init() {
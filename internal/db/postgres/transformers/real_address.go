@@ -17,18 +17,25 @@ package transformers
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
+	"math"
 	"slices"
 	"text/template"
+	"unicode/utf8"
 
 	"github.com/go-faker/faker/v4"
 
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
 const RealAddressTransformerName = "RealAddress"
 
+// realAddressGeneratorByteLength - the bytes required for choosing the address index
+const realAddressGeneratorByteLength = 4
+
 var RealAddressTransformerDefinition = utils.NewTransformerDefinition(
 	utils.NewTransformerProperties(
 		RealAddressTransformerName,
@@ -48,12 +55,24 @@ var RealAddressTransformerDefinition = utils.NewTransformerDefinition(
 			`}`,
 	).SetRequired(true).
 		SetIsColumnContainer(true),
+
+	localeParameterDefinition,
+
+	toolkit.MustNewParameterDefinition(
+		"engine",
+		"The engine used for choosing the address [random, hash]. The hash engine is supported only with locale",
+	).SetDefaultValue([]byte("random")).
+		SetRawValueValidator(engineValidator),
 )
 
 type RealAddressTransformer struct {
 	columns         []*RealAddressColumn
 	affectedColumns map[int]string
 	buf             *bytes.Buffer
+	// addresses - the addresses of the locale pack. The faker addresses are used if empty
+	addresses    []*RealAddressValue
+	generator    generators.Generator
+	originalData []byte
 }
 
 type RealAddressColumn struct {
@@ -61,7 +80,9 @@ type RealAddressColumn struct {
 	KeepNull  bool   `json:"keep_null"`
 	Template  string `json:"template"`
 	columnIdx int
-	tmpl      *template.Template
+	// maxChars - the declared length of varchar(n) and char(n) column or -1 if the length is not limited
+	maxChars int
+	tmpl     *template.Template
 }
 
 type RealAddressValue struct {
@@ -86,11 +107,12 @@ func NewRealAddressTransformer(ctx context.Context, driver *toolkit.Driver, para
 
 	testBuf := bytes.NewBuffer(nil)
 	for _, col := range columns {
-		idx, _, ok := driver.GetColumnByName(col.Name)
+		idx, c, ok := driver.GetColumnByName(col.Name)
 		if !ok {
 			return nil, nil, fmt.Errorf("column with name %s is not found", col.Name)
 		}
 		col.columnIdx = idx // Set the correct column index
+		col.maxChars = getColumnMaxChars(c)
 		affectedColumns[idx] = col.Name
 
 		if col.Template == "" {
@@ -137,10 +159,70 @@ func NewRealAddressTransformer(ctx context.Context, driver *toolkit.Driver, para
 		return nil, warnings, nil
 	}
 
+	var engine string
+	p = parameters["engine"]
+	if err := p.Scan(&engine); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "engine" param: %w`, err)
+	}
+
+	pack, err := getLocalePack(parameters["locale"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var addresses []*RealAddressValue
+	var g generators.Generator
+	if pack != nil {
+		if len(pack.Addresses) == 0 {
+			return nil, toolkit.ValidationWarnings{newLocaleSectionWarning(pack, "addresses")}, nil
+		}
+		addresses = make([]*RealAddressValue, 0, len(pack.Addresses))
+		for _, a := range pack.Addresses {
+			address := &RealAddressValue{
+				Address:    a.Address,
+				City:       a.City,
+				State:      a.State,
+				PostalCode: a.PostalCode,
+				Latitude:   a.Latitude,
+				Longitude:  a.Longitude,
+			}
+			fits, err := isRealAddressFitColumns(columns, address, testBuf)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fits {
+				addresses = append(addresses, address)
+			}
+		}
+		if len(addresses) == 0 {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "columns").
+					AddMeta("Locale", pack.Locale).
+					SetMsg("locale pack does not contain addresses that fit the column length"),
+			}, nil
+		}
+		g, err = getGenerateEngine(ctx, engine, realAddressGeneratorByteLength)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+		}
+	} else if engine == HashEngineParameterName {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "engine").
+				AddMeta("ParameterValue", engine).
+				SetMsg("hash engine is supported only with \"locale\" parameter"),
+		}, nil
+	}
+
 	return &RealAddressTransformer{
 		columns:         columns,
 		affectedColumns: affectedColumns,
 		buf:             bytes.NewBuffer(nil),
+		addresses:       addresses,
+		generator:       g,
 	}, warnings, nil
 }
 
@@ -157,7 +239,10 @@ func (rat *RealAddressTransformer) Done(ctx context.Context) error {
 }
 
 func (rat *RealAddressTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
-	address := getRealAddress()
+	address, err := rat.getAddress(r)
+	if err != nil {
+		return nil, err
+	}
 
 	// Iterate over the columns and update the record with generated address data
 	for _, col := range rat.columns {
@@ -174,7 +259,9 @@ func (rat *RealAddressTransformer) Transform(ctx context.Context, r *toolkit.Rec
 			return nil, fmt.Errorf("error executing template for column \"%s\": %w", col.Name, err)
 		}
 
-		newRawValue := toolkit.NewRawValue(slices.Clone(rat.buf.Bytes()), false)
+		// The faker addresses are truncated, the locale pack addresses are chosen from the ones that fit
+		newValue := truncateChars(rat.buf.Bytes(), col.maxChars)
+		newRawValue := toolkit.NewRawValue(slices.Clone(newValue), false)
 
 		// Update the record for the current column with the generated value
 		if err := r.SetRawColumnValueByIdx(col.columnIdx, newRawValue); err != nil {
@@ -185,6 +272,51 @@ func (rat *RealAddressTransformer) Transform(ctx context.Context, r *toolkit.Rec
 	return r, nil
 }
 
+// getAddress - chooses the address from the locale pack or gets the random faker address if locale is not set. The
+// hash engine uses the original values of the affected columns. Each value is prefixed with its length, so the
+// values cannot be shifted between the columns without changing the address
+func (rat *RealAddressTransformer) getAddress(r *toolkit.Record) (*RealAddressValue, error) {
+	if len(rat.addresses) == 0 {
+		return getRealAddress(), nil
+	}
+	rat.originalData = rat.originalData[:0]
+	for _, col := range rat.columns {
+		rawValue, err := r.GetRawColumnValueByIdx(col.columnIdx)
+		if err != nil {
+			return nil, err
+		}
+		if rawValue.IsNull {
+			rat.originalData = binary.LittleEndian.AppendUint32(rat.originalData, math.MaxUint32)
+			continue
+		}
+		rat.originalData = binary.LittleEndian.AppendUint32(rat.originalData, uint32(len(rawValue.Data)))
+		rat.originalData = append(rat.originalData, rawValue.Data...)
+	}
+	resBytes, err := rat.generator.Generate(rat.originalData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate address index: %w", err)
+	}
+	idx := binary.LittleEndian.Uint32(resBytes) % uint32(len(rat.addresses))
+	return rat.addresses[idx], nil
+}
+
+// isRealAddressFitColumns - check the values of all the columns generated from the address fit the column lengths
+func isRealAddressFitColumns(columns []*RealAddressColumn, address *RealAddressValue, buf *bytes.Buffer) (bool, error) {
+	for _, col := range columns {
+		if col.maxChars < 0 {
+			continue
+		}
+		buf.Reset()
+		if err := col.tmpl.Execute(buf, address); err != nil {
+			return false, fmt.Errorf("error executing template for column \"%s\": %w", col.Name, err)
+		}
+		if utf8.RuneCount(buf.Bytes()) > col.maxChars {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 func getRealAddress() *RealAddressValue {
 	addr := faker.GetRealAddress()
 
@@ -17,10 +17,16 @@ package transformers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 
+	"github.com/greenmaskio/greenmask/internal/db/postgres/pgcopy"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
@@ -114,3 +120,193 @@ func TestMakeNewFakeTransformerFunction_validation_error(t *testing.T) {
 	require.Len(t, warnings, 1)
 	require.Equal(t, "error validating template", warnings[0].Msg)
 }
+
+func TestRealAddressTransformer_Transform_locale(t *testing.T) {
+	params := map[string]toolkit.ParamsValue{
+		"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .Address }}|{{ .City }}|{{ .PostalCode }}"}]`),
+		"locale":  toolkit.ParamsValue("pt_BR"),
+		"engine":  toolkit.ParamsValue("hash"),
+	}
+	pack, err := locales.Get("pt_BR")
+	require.NoError(t, err)
+	expected := make([]string, 0, len(pack.Addresses))
+	for _, a := range pack.Addresses {
+		expected = append(expected, fmt.Sprintf("%s|%s|%s", a.Address, a.City, a.PostalCode))
+	}
+
+	var results []string
+	for _, original := range []string{"Av. Paulista 1", "Av. Paulista 1"} {
+		driver, record := getDriverAndRecord("data", original)
+		transformer, warnings, err := RealAddressTransformerDefinition.Instance(
+			context.Background(), driver, params, nil, "", false,
+		)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+
+		_, err = transformer.Transformer.Transform(context.Background(), record)
+		require.NoError(t, err)
+		rawValue, err := record.GetRawColumnValueByName("data")
+		require.NoError(t, err)
+		require.False(t, rawValue.IsNull)
+		require.Contains(t, expected, string(rawValue.Data))
+		results = append(results, string(rawValue.Data))
+	}
+	require.Equal(t, results[0], results[1])
+}
+
+func TestRealAddressTransformer_hash_without_locale(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "somaval")
+	_, warnings, err := RealAddressTransformerDefinition.Instance(
+		context.Background(),
+		driver,
+		map[string]toolkit.ParamsValue{
+			"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .City }}"}]`),
+			"engine":  toolkit.ParamsValue("hash"),
+		},
+		nil,
+		"",
+		false,
+	)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Equal(t, `hash engine is supported only with "locale" parameter`, warnings[0].Msg)
+}
+
+// getRealAddressVarcharDriverAndRecord - creates the record of the table with the varchar(maxChars) columns
+func getRealAddressVarcharDriverAndRecord(
+	t *testing.T, names []string, maxChars int, value string,
+) (*toolkit.Driver, *toolkit.Record) {
+	table := &toolkit.Table{
+		Schema:      "public",
+		Name:        "test",
+		Oid:         1224,
+		Constraints: []toolkit.Constraint{},
+	}
+	for idx, name := range names {
+		table.Columns = append(table.Columns, &toolkit.Column{
+			Idx:        idx,
+			Name:       name,
+			TypeName:   fmt.Sprintf("character varying(%d)", maxChars),
+			TypeOid:    pgtype.VarcharOID,
+			Num:        toolkit.AttNum(idx + 1),
+			Length:     maxChars + varcharHeaderSize,
+			TypeLength: -1,
+		})
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	row := pgcopy.NewRow(len(names))
+	require.NoError(t, row.Decode([]byte(value)))
+	record := toolkit.NewRecord(driver)
+	record.SetRow(row)
+	return driver, record
+}
+
+func TestRealAddressTransformer_Transform_varchar(t *testing.T) {
+	t.Run("faker address is truncated", func(t *testing.T) {
+		driver, record := getRealAddressVarcharDriverAndRecord(t, []string{"data"}, 5, "somaval")
+		transformer, warnings, err := RealAddressTransformerDefinition.Instance(
+			context.Background(), driver,
+			map[string]toolkit.ParamsValue{
+				"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .Address }} {{ .City }}"}]`),
+			},
+			nil, "", false,
+		)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+
+		_, err = transformer.Transformer.Transform(context.Background(), record)
+		require.NoError(t, err)
+		rawValue, err := record.GetRawColumnValueByName("data")
+		require.NoError(t, err)
+		require.NotEmpty(t, rawValue.Data)
+		require.LessOrEqual(t, utf8.RuneCount(rawValue.Data), 5)
+	})
+
+	t.Run("locale address fits", func(t *testing.T) {
+		pack, err := locales.Get("pt_BR")
+		require.NoError(t, err)
+		maxChars := utf8.RuneCountInString(pack.Addresses[0].City)
+		for _, a := range pack.Addresses {
+			maxChars = min(maxChars, utf8.RuneCountInString(a.City))
+		}
+		var expected []string
+		for _, a := range pack.Addresses {
+			if utf8.RuneCountInString(a.City) <= maxChars {
+				expected = append(expected, a.City)
+			}
+		}
+
+		for _, original := range []string{"Recife", "Curitiba", "Salvador", "Natal"} {
+			driver, record := getRealAddressVarcharDriverAndRecord(t, []string{"data"}, maxChars, original)
+			transformer, warnings, err := RealAddressTransformerDefinition.Instance(
+				context.Background(), driver,
+				map[string]toolkit.ParamsValue{
+					"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .City }}"}]`),
+					"locale":  toolkit.ParamsValue("pt_BR"),
+					"engine":  toolkit.ParamsValue("hash"),
+				},
+				nil, "", false,
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			_, err = transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			rawValue, err := record.GetRawColumnValueByName("data")
+			require.NoError(t, err)
+			require.Contains(t, expected, string(rawValue.Data))
+		}
+	})
+
+	t.Run("no locale address fits", func(t *testing.T) {
+		driver, _ := getRealAddressVarcharDriverAndRecord(t, []string{"data"}, 1, "somaval")
+		_, warnings, err := RealAddressTransformerDefinition.Instance(
+			context.Background(), driver,
+			map[string]toolkit.ParamsValue{
+				"columns": toolkit.ParamsValue(`[{"name": "data", "template": "{{ .Address }}"}]`),
+				"locale":  toolkit.ParamsValue("pt_BR"),
+			},
+			nil, "", false,
+		)
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		require.Equal(t, "locale pack does not contain addresses that fit the column length", warnings[0].Msg)
+	})
+}
+
+func TestRealAddressTransformer_getAddress_hashInput(t *testing.T) {
+	getHashInput := func(t *testing.T, value string) []byte {
+		driver, record := getRealAddressVarcharDriverAndRecord(t, []string{"street", "city"}, 100, value)
+		transformer, warnings, err := RealAddressTransformerDefinition.Instance(
+			context.Background(), driver,
+			map[string]toolkit.ParamsValue{
+				"columns": toolkit.ParamsValue(
+					`[{"name": "street", "template": "{{ .Address }}"}, {"name": "city", "template": "{{ .City }}"}]`,
+				),
+				"locale": toolkit.ParamsValue("pt_BR"),
+				"engine": toolkit.ParamsValue("hash"),
+			},
+			nil, "", false,
+		)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		rat := transformer.Transformer.(*RealAddressTransformer)
+		_, err = rat.getAddress(record)
+		require.NoError(t, err)
+		return slices.Clone(rat.originalData)
+	}
+
+	// The values moved between the columns produce different hash input
+	require.NotEqual(t, getHashInput(t, "ab\tc"), getHashInput(t, "a\tbc"))
+	require.NotEqual(t, getHashInput(t, "ab\t\\N"), getHashInput(t, "\\N\tab"))
+	require.Equal(t, getHashInput(t, "ab\tc"), getHashInput(t, "ab\tc"))
+}
+
+func TestTruncateChars(t *testing.T) {
+	require.Equal(t, "Säo", string(truncateChars([]byte("Säo Paulo"), 3)))
+	require.Equal(t, "Rio", string(truncateChars([]byte("Rio"), 3)))
+	require.Equal(t, "", string(truncateChars([]byte("Rio"), 0)))
+	require.Equal(t, "Rio", string(truncateChars([]byte("Rio"), -1)))
+}
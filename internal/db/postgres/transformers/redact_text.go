@@ -0,0 +1,398 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const RedactTextTransformerName = "RedactText"
+
+// redactHashByteLength - the length of the hash used for the hash tokens
+const redactHashByteLength = 32
+
+// redactHashTokenLength - the count of the hex characters of the hash in the token
+const redactHashTokenLength = 8
+
+// redactReplaceSeedLength - the length of the seed of the random source used by the replace action
+const redactReplaceSeedLength = 32
+
+var RedactTextTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		RedactTextTransformerName,
+		"Find and redact the personal data (emails, phones, card numbers, etc.) inside the free text",
+	),
+
+	NewRedactTextTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("text", "varchar", "char", "bpchar", "citext", "json", "jsonb").
+		SetSkipOnNull(true),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"detectors",
+		"list of detectors."+
+			"The structure:"+
+			`{`+
+			`"type": "type:string, required:true, description: detector type `+
+			`(email, phone, iban, pan, ipv4, ipv6, url, name)",`+
+			`"action": "type:string, required:false, description: action (mask, replace, hash), default mask",`+
+			`"mask_char": "type:string, required:false, description: character used by mask action, default *",`+
+			`"regions": "type:[]string, required:false, description: phone regions (INTL, US, GB, DE, FR, BR, JP)",`+
+			`"names": "type:[]string, required:false, description: name dictionary"`+
+			`}`,
+	).SetDefaultValue(toolkit.ParamsValue(
+		`[{"type": "email"}, {"type": "phone"}, {"type": "iban"}, {"type": "pan"}, {"type": "ipv4"}, `+
+			`{"type": "ipv6"}, {"type": "url"}]`,
+	)),
+
+	toolkit.MustNewParameterDefinition(
+		"preserve_offsets",
+		"keep the length of the redacted values in characters, so the offsets of the text are not changed",
+	).SetDefaultValue(toolkit.ParamsValue("false")),
+
+	engineParameterDefinition,
+)
+
+type RedactTextTransformer struct {
+	columnName      string
+	columnIdx       int
+	affectedColumns map[int]string
+	detectors       []*redactDetector
+	preserveOffsets bool
+	isJson          bool
+	hashGenerator   generators.Generator
+	// generator - generates the seed of the random source of the replace action from the original value
+	generator generators.Generator
+	rndSource *rand.ChaCha8
+	rnd       *rand.Rand
+	buf       *bytes.Buffer
+	spans     []redactSpan
+}
+
+// redactSpan - the byte offsets of the detected value in the text
+type redactSpan struct {
+	start    int
+	end      int
+	detector *redactDetector
+}
+
+func NewRedactTextTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, engine string
+	var preserveOffsets bool
+	var detectorConfigs []*RedactDetectorConfig
+	var warnings toolkit.ValidationWarnings
+
+	if err := parameters["column"].Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+	idx, column, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+
+	if err := parameters["detectors"].Scan(&detectorConfigs); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "detectors" param: %w`, err)
+	}
+	if len(detectorConfigs) == 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "detectors").
+			SetMsg("at least one detector is required"),
+		)
+	}
+	detectors := make([]*redactDetector, 0, len(detectorConfigs))
+	for i, cfg := range detectorConfigs {
+		d, detectorWarnings := newRedactDetector(i, cfg)
+		warnings = append(warnings, detectorWarnings...)
+		detectors = append(detectors, d)
+	}
+
+	if err := parameters["preserve_offsets"].Scan(&preserveOffsets); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "preserve_offsets" param: %w`, err)
+	}
+
+	if err := parameters["engine"].Scan(&engine); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "engine" param: %w`, err)
+	}
+
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	var hashGenerator generators.Generator
+	if slices.ContainsFunc(detectors, func(d *redactDetector) bool {
+		return d.action == RedactActionHash
+	}) {
+		var err error
+		hashGenerator, err = getGenerateEngine(ctx, HashEngineParameterName, redactHashByteLength)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+		}
+	}
+
+	g, err := getGenerateEngine(ctx, engine, redactReplaceSeedLength)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+	}
+	rndSource := rand.NewChaCha8([redactReplaceSeedLength]byte{})
+
+	return &RedactTextTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		affectedColumns: map[int]string{idx: columnName},
+		detectors:       detectors,
+		preserveOffsets: preserveOffsets,
+		isJson:          column.TypeName == "json" || column.TypeName == "jsonb",
+		hashGenerator:   hashGenerator,
+		generator:       g,
+		rndSource:       rndSource,
+		rnd:             rand.New(rndSource),
+		buf:             bytes.NewBuffer(nil),
+	}, warnings, nil
+}
+
+func (rtt *RedactTextTransformer) GetAffectedColumns() map[int]string {
+	return rtt.affectedColumns
+}
+
+func (rtt *RedactTextTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (rtt *RedactTextTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (rtt *RedactTextTransformer) Transform(_ context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(rtt.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	var res []byte
+	if rtt.isJson {
+		res, err = rtt.redactJson(val.Data)
+	} else {
+		var text string
+		text, err = rtt.redact(string(val.Data))
+		res = []byte(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.SetRawColumnValueByIdx(rtt.columnIdx, toolkit.NewRawValue(res, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+// redact - replaces the detected values in the text. If the detected values overlap, the value that starts earlier
+// or the longest one is redacted
+func (rtt *RedactTextTransformer) redact(text string) (string, error) {
+	rtt.spans = rtt.spans[:0]
+	for _, d := range rtt.detectors {
+		for _, s := range d.find(text) {
+			rtt.spans = append(rtt.spans, redactSpan{start: s[0], end: s[1], detector: d})
+		}
+	}
+	if len(rtt.spans) == 0 {
+		return text, nil
+	}
+	slices.SortFunc(rtt.spans, func(a, b redactSpan) int {
+		if a.start != b.start {
+			return cmp.Compare(a.start, b.start)
+		}
+		return cmp.Compare(b.end, a.end)
+	})
+
+	var sb strings.Builder
+	var pos int
+	for _, s := range rtt.spans {
+		if s.start < pos {
+			continue
+		}
+		sb.WriteString(text[pos:s.start])
+		replacement, err := rtt.getReplacement(s.detector, text[s.start:s.end])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(replacement)
+		pos = s.end
+	}
+	sb.WriteString(text[pos:])
+	return sb.String(), nil
+}
+
+// redactJson - redacts the string values of the JSON document. The keys and the order of the object fields are kept
+func (rtt *RedactTextTransformer) redactJson(data []byte) ([]byte, error) {
+	if !gjson.ValidBytes(data) {
+		return nil, fmt.Errorf("invalid json value")
+	}
+	rtt.buf.Reset()
+	if err := rtt.redactJsonValue(gjson.ParseBytes(data)); err != nil {
+		return nil, err
+	}
+	return slices.Clone(rtt.buf.Bytes()), nil
+}
+
+func (rtt *RedactTextTransformer) redactJsonValue(v gjson.Result) error {
+	var err error
+	switch {
+	case v.IsObject() || v.IsArray():
+		isObject := v.IsObject()
+		if isObject {
+			rtt.buf.WriteByte('{')
+		} else {
+			rtt.buf.WriteByte('[')
+		}
+		first := true
+		v.ForEach(func(key, value gjson.Result) bool {
+			if !first {
+				rtt.buf.WriteByte(',')
+			}
+			first = false
+			if isObject {
+				rtt.buf.WriteString(key.Raw)
+				rtt.buf.WriteByte(':')
+			}
+			err = rtt.redactJsonValue(value)
+			return err == nil
+		})
+		if isObject {
+			rtt.buf.WriteByte('}')
+		} else {
+			rtt.buf.WriteByte(']')
+		}
+	case v.Type == gjson.String:
+		var text string
+		text, err = rtt.redact(v.String())
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(rtt.buf)
+		enc.SetEscapeHTML(false)
+		if err = enc.Encode(text); err != nil {
+			return fmt.Errorf("unable to encode json string: %w", err)
+		}
+		// The encoder adds the new line after the value
+		rtt.buf.Truncate(rtt.buf.Len() - 1)
+	default:
+		rtt.buf.WriteString(v.Raw)
+	}
+	return err
+}
+
+func (rtt *RedactTextTransformer) getReplacement(d *redactDetector, original string) (string, error) {
+	switch d.action {
+	case RedactActionHash:
+		return rtt.getHashToken(d, original)
+	case RedactActionReplace:
+		if err := rtt.seedReplacement(original); err != nil {
+			return "", err
+		}
+		if rtt.preserveOffsets || d.generate == nil {
+			return original[:d.keepPrefix] + replaceRedactChars(rtt.rnd, original[d.keepPrefix:]), nil
+		}
+		return d.generate(rtt.rnd), nil
+	default:
+		return maskRedactChars(original, d.maskChar), nil
+	}
+}
+
+// seedReplacement - seeds the random source of the replace action by the value generated from the original one, so
+// the hash engine replaces the same values by the same ones
+func (rtt *RedactTextTransformer) seedReplacement(original string) error {
+	data, err := rtt.generator.Generate([]byte(original))
+	if err != nil {
+		return fmt.Errorf("unable to generate replacement seed: %w", err)
+	}
+	var seed [redactReplaceSeedLength]byte
+	copy(seed[:], data)
+	rtt.rndSource.Seed(seed)
+	return nil
+}
+
+// getHashToken - returns the token with the hash of the value, so the same values get the same tokens. The token
+// is the hex string of the value length if the offsets are preserved
+func (rtt *RedactTextTransformer) getHashToken(d *redactDetector, original string) (string, error) {
+	hash, err := rtt.hashGenerator.Generate([]byte(original))
+	if err != nil {
+		return "", fmt.Errorf("unable to generate hash: %w", err)
+	}
+	hexHash := hex.EncodeToString(hash)
+	if !rtt.preserveOffsets {
+		return fmt.Sprintf("[%s:%s]", strings.ToUpper(d.kind), hexHash[:redactHashTokenLength]), nil
+	}
+	length := utf8.RuneCountInString(original)
+	return strings.Repeat(hexHash, length/len(hexHash)+1)[:length], nil
+}
+
+// maskRedactChars - replaces the letters and digits by the mask character. The separators are kept
+func maskRedactChars(s string, maskChar rune) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return maskChar
+		}
+		return r
+	}, s)
+}
+
+// replaceRedactChars - replaces the letters and digits by the random ones of the same class. The separators are kept,
+// so the value keeps its format and length
+func replaceRedactChars(rnd *rand.Rand, s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsDigit(r):
+			return rune('0' + rnd.IntN(10))
+		case unicode.IsUpper(r):
+			return rune('A' + rnd.IntN(26))
+		case unicode.IsLetter(r):
+			return rune('a' + rnd.IntN(26))
+		}
+		return r
+	}, s)
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(RedactTextTransformerDefinition)
+}
@@ -0,0 +1,487 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/greenmaskio/greenmask/internal/generators/transformers"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const (
+	RedactDetectorEmail = "email"
+	RedactDetectorPhone = "phone"
+	RedactDetectorIban  = "iban"
+	RedactDetectorPan   = "pan"
+	RedactDetectorIpv4  = "ipv4"
+	RedactDetectorIpv6  = "ipv6"
+	RedactDetectorUrl   = "url"
+	RedactDetectorName  = "name"
+)
+
+const (
+	RedactActionMask    = "mask"
+	RedactActionReplace = "replace"
+	RedactActionHash    = "hash"
+)
+
+const (
+	RedactPhoneRegionIntl = "INTL"
+	defaultRedactMaskChar = '*'
+	redactUrlTokenLength  = 16
+	redactTokenAlphabet   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	redactEmailAlphabet   = "abcdefghijklmnopqrstuvwxyz0123456789"
+	redactEmailLength     = 10
+	redactEmailDomain     = "example.com"
+	redactPanLength       = 16
+)
+
+var redactDetectorTypes = []string{
+	RedactDetectorEmail, RedactDetectorPhone, RedactDetectorIban, RedactDetectorPan, RedactDetectorIpv4,
+	RedactDetectorIpv6, RedactDetectorUrl, RedactDetectorName,
+}
+
+var redactActions = []string{RedactActionMask, RedactActionReplace, RedactActionHash}
+
+var (
+	redactEmailRegexp = regexp.MustCompile(
+		`[\p{L}\p{N}._%+\-]+@[\p{L}\p{N}\-]+(?:\.[\p{L}\p{N}\-]+)*\.\p{L}{2,}`,
+	)
+	redactIbanRegexp = regexp.MustCompile(`[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}`)
+	redactPanRegexp  = regexp.MustCompile(`\d(?:[ \-]?\d){12,18}`)
+	redactIpv4Regexp = regexp.MustCompile(`(?:\d{1,3}\.){3}\d{1,3}`)
+	redactIpv6Regexp = regexp.MustCompile(`(?i)[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}`)
+	redactUrlRegexp  = regexp.MustCompile(`https?://[^\s"'<>]+`)
+)
+
+// redactPhoneRegion - the phone number pattern of the region and the allowed count of digits including the country
+// code
+type redactPhoneRegion struct {
+	re        *regexp.Regexp
+	minDigits int
+	maxDigits int
+	generate  func(rnd *rand.Rand) string
+}
+
+var redactPhoneRegions = map[string]*redactPhoneRegion{
+	RedactPhoneRegionIntl: {
+		re:        regexp.MustCompile(`\+[1-9]\d{0,2}(?:[ .\-]?\(?\d{1,4}\)?){1,5}`),
+		minDigits: 8,
+		maxDigits: 15,
+		generate: func(rnd *rand.Rand) string {
+			return fmt.Sprintf("+%d%s", 1+rnd.IntN(9), randomRedactDigits(rnd, "##########"))
+		},
+	},
+	"US": {
+		re:        regexp.MustCompile(`(?:\+1[ .\-]?)?(?:\(\d{3}\)|\d{3})[ .\-]?\d{3}[ .\-]?\d{4}`),
+		minDigits: 10,
+		maxDigits: 11,
+		generate: func(rnd *rand.Rand) string {
+			return fmt.Sprintf("%d%s", 2+rnd.IntN(8), randomRedactDigits(rnd, "##-###-####"))
+		},
+	},
+	"GB": {
+		re:        regexp.MustCompile(`(?:\+44 ?(?:\(0\))? ?|0)\d{2,4} ?\d{3,4} ?\d{3,4}`),
+		minDigits: 10,
+		maxDigits: 13,
+	},
+	"DE": {
+		re:        regexp.MustCompile(`(?:\+49 ?(?:\(0\))? ?|0)\d{2,5}[ /\-]?\d{3,9}`),
+		minDigits: 9,
+		maxDigits: 14,
+	},
+	"FR": {
+		re:        regexp.MustCompile(`(?:\+33 ?(?:\(0\))? ?|0)[1-9](?:[ .\-]?\d{2}){4}`),
+		minDigits: 10,
+		maxDigits: 12,
+	},
+	"BR": {
+		re:        regexp.MustCompile(`(?:\+55 ?)?\(?\d{2}\)? ?9?\d{4}[ \-]?\d{4}`),
+		minDigits: 10,
+		maxDigits: 13,
+	},
+	"JP": {
+		re:        regexp.MustCompile(`(?:\+81[ \-]?|0)\d{1,4}[ \-]?\d{1,4}[ \-]?\d{4}`),
+		minDigits: 10,
+		maxDigits: 12,
+	},
+}
+
+// RedactDetectorConfig - the detector of the RedactText transformer
+type RedactDetectorConfig struct {
+	// Type - the kind of the detected values
+	Type string `json:"type"`
+	// Action - what to do with the detected value: mask, replace or hash
+	Action string `json:"action,omitempty"`
+	// MaskChar - the character used by the mask action
+	MaskChar string `json:"mask_char,omitempty"`
+	// Regions - the regions of the phone numbers
+	Regions []string `json:"regions,omitempty"`
+	// Names - the name dictionary. The built-in first and last names are used if empty
+	Names []string `json:"names,omitempty"`
+}
+
+// redactDetector - finds the values of the kind in the text
+type redactDetector struct {
+	kind     string
+	action   string
+	maskChar rune
+	// find - returns the byte offsets of the values to redact
+	find func(text string) [][2]int
+	// generate - generates the value of the same kind for the replace action using the random source seeded by the
+	// original value. The characters of the original value are replaced by the random ones of the same class if nil
+	generate func(rnd *rand.Rand) string
+	// keepPrefix - the count of the first characters that are kept when the characters are replaced. For instance,
+	// the country code of IBAN
+	keepPrefix int
+}
+
+func newRedactDetector(idx int, cfg *RedactDetectorConfig) (*redactDetector, toolkit.ValidationWarnings) {
+	var warnings toolkit.ValidationWarnings
+	newWarning := func(msg string) *toolkit.ValidationWarning {
+		return toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "detectors").
+			AddMeta("DetectorIdx", idx).
+			SetMsg(msg)
+	}
+
+	d := &redactDetector{
+		kind:     cfg.Type,
+		action:   cfg.Action,
+		maskChar: defaultRedactMaskChar,
+	}
+	if d.action == "" {
+		d.action = RedactActionMask
+	}
+	if !slices.Contains(redactActions, d.action) {
+		warnings = append(warnings, newWarning("unknown action").
+			AddMeta("ParameterValue", d.action).
+			AddMeta("AllowedValues", redactActions),
+		)
+	}
+	if cfg.MaskChar != "" {
+		maskChar := []rune(cfg.MaskChar)
+		if len(maskChar) != 1 {
+			warnings = append(warnings, newWarning("mask_char must be one character").
+				AddMeta("ParameterValue", cfg.MaskChar),
+			)
+		} else {
+			d.maskChar = maskChar[0]
+		}
+	}
+
+	switch cfg.Type {
+	case RedactDetectorEmail:
+		d.find = newRedactRegexpFinder(redactEmailRegexp, nil)
+		d.generate = func(rnd *rand.Rand) string {
+			return randomRedactString(rnd, redactEmailAlphabet, redactEmailLength) + "@" + redactEmailDomain
+		}
+	case RedactDetectorPhone:
+		regions := cfg.Regions
+		if len(regions) == 0 {
+			regions = []string{RedactPhoneRegionIntl}
+		}
+		var finders []func(text string) [][2]int
+		var generate func(rnd *rand.Rand) string
+		for _, name := range regions {
+			region, ok := redactPhoneRegions[name]
+			if !ok {
+				warnings = append(warnings, newWarning("unknown phone region").
+					AddMeta("ParameterValue", name).
+					AddMeta("AllowedValues", redactPhoneRegionNames()),
+				)
+				continue
+			}
+			finders = append(finders, newRedactRegexpFinder(region.re, newRedactDigitsValidator(region)))
+			if generate == nil && region.generate != nil {
+				generate = region.generate
+			}
+		}
+		d.find = func(text string) [][2]int {
+			var res [][2]int
+			for _, f := range finders {
+				res = append(res, f(text)...)
+			}
+			return res
+		}
+		d.generate = generate
+	case RedactDetectorIban:
+		d.find = newRedactRegexpFinder(redactIbanRegexp, validateRedactIban)
+		d.keepPrefix = 2
+	case RedactDetectorPan:
+		d.find = newRedactRegexpFinder(redactPanRegexp, validateRedactPan)
+		d.generate = randomRedactPan
+	case RedactDetectorIpv4:
+		d.find = newRedactRegexpFinder(redactIpv4Regexp, func(s string) (int, bool) {
+			return len(s), net.ParseIP(s) != nil
+		})
+		d.generate = func(rnd *rand.Rand) string {
+			return net.IPv4(byte(rnd.IntN(256)), byte(rnd.IntN(256)), byte(rnd.IntN(256)), byte(rnd.IntN(256))).String()
+		}
+	case RedactDetectorIpv6:
+		d.find = newRedactRegexpFinder(redactIpv6Regexp, func(s string) (int, bool) {
+			ip := net.ParseIP(s)
+			return len(s), ip != nil && ip.To4() == nil && strings.ContainsFunc(s, isRedactHexDigit)
+		})
+		d.generate = randomRedactIpv6
+	case RedactDetectorUrl:
+		d.find = findRedactUrlQueryValues
+		d.generate = func(rnd *rand.Rand) string {
+			return randomRedactString(rnd, redactTokenAlphabet, redactUrlTokenLength)
+		}
+	case RedactDetectorName:
+		names := cfg.Names
+		if len(names) == 0 {
+			names = slices.Concat(
+				transformers.DefaultFirstNamesMale, transformers.DefaultFirstNamesFemale, transformers.DefaultLastNames,
+			)
+		}
+		d.find = newRedactRegexpFinder(newRedactNamesRegexp(names), nil)
+		d.generate = func(rnd *rand.Rand) string {
+			return names[rnd.IntN(len(names))]
+		}
+	default:
+		warnings = append(warnings, newWarning("unknown detector type").
+			AddMeta("ParameterValue", cfg.Type).
+			AddMeta("AllowedValues", redactDetectorTypes),
+		)
+	}
+	return d, warnings
+}
+
+func redactPhoneRegionNames() []string {
+	res := make([]string, 0, len(redactPhoneRegions))
+	for name := range redactPhoneRegions {
+		res = append(res, name)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// newRedactRegexpFinder - returns the finder of the regexp matches that are not a part of a word. The validate
+// function checks the match and returns the length of the valid value from the match start
+func newRedactRegexpFinder(re *regexp.Regexp, validate func(s string) (int, bool)) func(text string) [][2]int {
+	return func(text string) [][2]int {
+		var res [][2]int
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			start, end := m[0], m[1]
+			if start == end {
+				continue
+			}
+			if validate != nil {
+				length, ok := validate(text[start:end])
+				if !ok {
+					continue
+				}
+				end = start + length
+			}
+			if !isRedactWordBoundary(text, start, end) {
+				continue
+			}
+			res = append(res, [2]int{start, end})
+		}
+		return res
+	}
+}
+
+// isRedactWordBoundary - checks that the value is not surrounded by letters or digits
+func isRedactWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isRedactHexDigit(r rune) bool {
+	return unicode.Is(unicode.ASCII_Hex_Digit, r)
+}
+
+func countRedactDigits(s string) int {
+	var res int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			res++
+		}
+	}
+	return res
+}
+
+func newRedactDigitsValidator(region *redactPhoneRegion) func(s string) (int, bool) {
+	return func(s string) (int, bool) {
+		digits := countRedactDigits(s)
+		return len(s), digits >= region.minDigits && digits <= region.maxDigits
+	}
+}
+
+// validateRedactIban - checks the IBAN checksum. The regexp may capture the words after the IBAN, so the trailing
+// groups are dropped until the checksum is valid
+func validateRedactIban(s string) (int, bool) {
+	for end := len(s); end > 0; {
+		if checkIbanChecksum(strings.ReplaceAll(s[:end], " ", "")) {
+			return end, true
+		}
+		end = strings.LastIndexByte(s[:end], ' ')
+	}
+	return 0, false
+}
+
+func checkIbanChecksum(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	var sb strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			sb.WriteString(fmt.Sprintf("%d", r-'A'+10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validateRedactPan - checks the card number with the Luhn algorithm
+func validateRedactPan(s string) (int, bool) {
+	var sum, count int
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if count%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		count++
+	}
+	return len(s), count >= 13 && count <= 19 && sum%10 == 0
+}
+
+// findRedactUrlQueryValues - finds the query parameter values of the URLs. They often contain the tokens and the
+// personal data, whereas the URL itself is kept
+func findRedactUrlQueryValues(text string) [][2]int {
+	var res [][2]int
+	for _, m := range redactUrlRegexp.FindAllStringIndex(text, -1) {
+		u := strings.TrimRight(text[m[0]:m[1]], ".,;:!?)]")
+		queryStart := strings.IndexByte(u, '?')
+		if queryStart == -1 {
+			continue
+		}
+		query := u[queryStart+1:]
+		if fragmentStart := strings.IndexByte(query, '#'); fragmentStart != -1 {
+			query = query[:fragmentStart]
+		}
+		offset := m[0] + queryStart + 1
+		for _, pair := range strings.Split(query, "&") {
+			if _, value, ok := strings.Cut(pair, "="); ok && value != "" {
+				valueStart := offset + len(pair) - len(value)
+				res = append(res, [2]int{valueStart, valueStart + len(value)})
+			}
+			offset += len(pair) + 1
+		}
+	}
+	return res
+}
+
+// randomRedactString - generates the random string of the alphabet characters
+func randomRedactString(rnd *rand.Rand, alphabet string, length int) string {
+	res := make([]byte, length)
+	for i := range res {
+		res[i] = alphabet[rnd.IntN(len(alphabet))]
+	}
+	return string(res)
+}
+
+// randomRedactDigits - replaces the "#" characters of the format by the random digits
+func randomRedactDigits(rnd *rand.Rand, format string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '#' {
+			return rune('0' + rnd.IntN(10))
+		}
+		return r
+	}, format)
+}
+
+// randomRedactPan - generates the random card number that passes the Luhn check
+func randomRedactPan(rnd *rand.Rand) string {
+	digits := []byte("4" + randomRedactDigits(rnd, strings.Repeat("#", redactPanLength-1)))
+	for checkDigit := byte('0'); checkDigit <= '9'; checkDigit++ {
+		digits[len(digits)-1] = checkDigit
+		if _, ok := validateRedactPan(string(digits)); ok {
+			break
+		}
+	}
+	return string(digits)
+}
+
+// randomRedactIpv6 - generates the random global unicast IPv6 address
+func randomRedactIpv6(rnd *rand.Rand) string {
+	ip := make(net.IP, net.IPv6len)
+	ip[0] = 0x20
+	for i := 1; i < len(ip); i++ {
+		ip[i] = byte(rnd.IntN(256))
+	}
+	return ip.String()
+}
+
+// newRedactNamesRegexp - builds the regexp of the names. The longer names go first, so they are preferred to their
+// prefixes
+func newRedactNamesRegexp(names []string) *regexp.Regexp {
+	sorted := slices.Clone(names)
+	slices.SortFunc(sorted, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	sorted = slices.Compact(sorted)
+	quoted := make([]string, 0, len(sorted))
+	for _, n := range sorted {
+		if n != "" {
+			quoted = append(quoted, regexp.QuoteMeta(n))
+		}
+	}
+	return regexp.MustCompile(`(?:` + strings.Join(quoted, "|") + `)`)
+}
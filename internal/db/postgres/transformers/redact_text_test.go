@@ -0,0 +1,268 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func redactTestValue(t *testing.T, columnName, original string, params map[string]toolkit.ParamsValue) string {
+	params["column"] = toolkit.ParamsValue(columnName)
+	driver, record := getDriverAndRecord(columnName, original)
+	transformerCtx, warnings, err := RedactTextTransformerDefinition.Instance(
+		context.Background(), driver, params, nil, "", false,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+	require.NoError(t, err)
+	res, err := r.GetRawColumnValueByName(columnName)
+	require.NoError(t, err)
+	require.False(t, res.IsNull)
+	return string(res.Data)
+}
+
+func TestRedactTextTransformer_Transform_mask(t *testing.T) {
+	tests := []struct {
+		name      string
+		detectors string
+		original  string
+		expected  string
+	}{
+		{
+			name:      "email",
+			detectors: `[{"type": "email"}]`,
+			original:  "Contact john.doe@example.com now",
+			expected:  "Contact ****.***@*******.*** now",
+		},
+		{
+			name:      "international phone",
+			detectors: `[{"type": "phone"}]`,
+			original:  "Call +49 30 1234567 today",
+			expected:  "Call +** ** ******* today",
+		},
+		{
+			name:      "US phone",
+			detectors: `[{"type": "phone", "regions": ["US"], "mask_char": "#"}]`,
+			original:  "Call (555) 123-4567.",
+			expected:  "Call (###) ###-####.",
+		},
+		{
+			name:      "iban",
+			detectors: `[{"type": "iban"}]`,
+			original:  "IBAN DE89 3704 0044 0532 0130 00 OK",
+			expected:  "IBAN **** **** **** **** **** ** OK",
+		},
+		{
+			name:      "pan",
+			detectors: `[{"type": "pan"}]`,
+			original:  "card 4111 1111 1111 1111 and 4111 1111 1111 1112",
+			expected:  "card **** **** **** **** and 4111 1111 1111 1112",
+		},
+		{
+			name:      "ipv4",
+			detectors: `[{"type": "ipv4"}]`,
+			original:  "from 192.168.0.1. and 999.1.1.1",
+			expected:  "from ***.***.*.*. and 999.1.1.1",
+		},
+		{
+			name:      "ipv6",
+			detectors: `[{"type": "ipv6"}]`,
+			original:  "host 2001:db8::1 at 10:30:45",
+			expected:  "host ****:***::* at 10:30:45",
+		},
+		{
+			name:      "url query",
+			detectors: `[{"type": "url"}]`,
+			original:  "open https://example.com/reset?token=abc123&user=42#top now",
+			expected:  "open https://example.com/reset?token=******&user=**#top now",
+		},
+		{
+			name:      "names",
+			detectors: `[{"type": "name", "names": ["Anna", "John"]}]`,
+			original:  "Anna met John Johnson",
+			expected:  "**** met **** Johnson",
+		},
+		{
+			name:      "default names",
+			detectors: `[{"type": "name"}]`,
+			original:  "Agent Smith",
+			expected:  "Agent *****",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := redactTestValue(t, "data", tt.original, map[string]toolkit.ParamsValue{
+				"detectors": toolkit.ParamsValue(tt.detectors),
+			})
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestRedactTextTransformer_Transform_default_detectors(t *testing.T) {
+	res := redactTestValue(t, "data", "Mail a@b.io or call +1 202 555 0143 from 10.0.0.1", map[string]toolkit.ParamsValue{})
+	assert.Equal(t, "Mail *@*.** or call +* *** *** **** from **.*.*.*", res)
+}
+
+func TestRedactTextTransformer_Transform_hash(t *testing.T) {
+	params := func() map[string]toolkit.ParamsValue {
+		return map[string]toolkit.ParamsValue{
+			"detectors": toolkit.ParamsValue(`[{"type": "email", "action": "hash"}]`),
+		}
+	}
+	res1 := redactTestValue(t, "data", "from john@example.com to jane@example.com", params())
+	res2 := redactTestValue(t, "data", "cc john@example.com", params())
+	assert.Regexp(t, `^from \[EMAIL:[0-9a-f]{8}\] to \[EMAIL:[0-9a-f]{8}\]$`, res1)
+	// The same value gets the same token
+	assert.Equal(t, res1[5:21], res2[3:19])
+	assert.NotEqual(t, res1[5:21], res1[25:41])
+
+	t.Run("preserve offsets", func(t *testing.T) {
+		p := params()
+		p["preserve_offsets"] = toolkit.ParamsValue("true")
+		res := redactTestValue(t, "data", "from john@example.com now", p)
+		assert.Regexp(t, `^from [0-9a-f]{16} now$`, res)
+	})
+}
+
+func TestRedactTextTransformer_Transform_replace(t *testing.T) {
+	original := "Mail josé@example.com, card 4111-1111-1111-1111, IBAN DE89370400440532013000"
+	detectors := toolkit.ParamsValue(
+		`[{"type": "email", "action": "replace"}, {"type": "pan", "action": "replace"}, ` +
+			`{"type": "iban", "action": "replace"}]`,
+	)
+
+	t.Run("generated values", func(t *testing.T) {
+		res := redactTestValue(t, "data", original, map[string]toolkit.ParamsValue{"detectors": detectors})
+		assert.NotContains(t, res, "josé@example.com")
+		assert.NotContains(t, res, "4111-1111-1111-1111")
+		assert.Regexp(t, regexp.MustCompile(`^Mail \S+@\S+, card \d+, IBAN DE\d{20}$`), res)
+	})
+
+	t.Run("preserve offsets", func(t *testing.T) {
+		res := redactTestValue(t, "data", original, map[string]toolkit.ParamsValue{
+			"detectors":        detectors,
+			"preserve_offsets": toolkit.ParamsValue("true"),
+		})
+		assert.Equal(t, utf8.RuneCountInString(original), utf8.RuneCountInString(res))
+		assert.Regexp(t, `^Mail [a-z]{4}@[a-z]{7}\.[a-z]{3}, card \d{4}-\d{4}-\d{4}-\d{4}, IBAN [A-Z]{2}\d{20}$`, res)
+	})
+}
+
+func TestRedactTextTransformer_Transform_replace_hash_engine(t *testing.T) {
+	original := "Mail jane@example.com or +49 30 1234567 from 10.0.0.1 and 2001:db8::1, " +
+		"card 4111 1111 1111 1111, IBAN DE89370400440532013000, https://example.com/?token=abc123, Anna"
+	detectors := toolkit.ParamsValue(
+		`[{"type": "email", "action": "replace"}, {"type": "phone", "action": "replace"}, ` +
+			`{"type": "ipv4", "action": "replace"}, {"type": "ipv6", "action": "replace"}, ` +
+			`{"type": "pan", "action": "replace"}, {"type": "iban", "action": "replace"}, ` +
+			`{"type": "url", "action": "replace"}, {"type": "name", "action": "replace", "names": ["Anna", "Maria"]}]`,
+	)
+	for _, preserveOffsets := range []string{"false", "true"} {
+		t.Run("preserve_offsets "+preserveOffsets, func(t *testing.T) {
+			params := func() map[string]toolkit.ParamsValue {
+				return map[string]toolkit.ParamsValue{
+					"detectors":        detectors,
+					"preserve_offsets": toolkit.ParamsValue(preserveOffsets),
+					"engine":           toolkit.ParamsValue("hash"),
+				}
+			}
+			res1 := redactTestValue(t, "data", original, params())
+			res2 := redactTestValue(t, "data", original, params())
+			assert.NotContains(t, res1, "jane@example.com")
+			assert.NotContains(t, res1, "4111 1111 1111 1111")
+			// The same values are replaced by the same ones
+			assert.Equal(t, res1, res2)
+		})
+	}
+
+	t.Run("generated values are valid", func(t *testing.T) {
+		res := redactTestValue(t, "data", original, map[string]toolkit.ParamsValue{
+			"detectors": detectors,
+			"engine":    toolkit.ParamsValue("hash"),
+		})
+		assert.Regexp(t, regexp.MustCompile(
+			`^Mail [a-z0-9]{10}@example\.com or \+\d{11} from (\d{1,3}\.){3}\d{1,3} and 2[0-9a-f:]+, `+
+				`card \d{16}, IBAN DE\d{20}, https://example\.com/\?token=[a-zA-Z0-9]{16}, (Anna|Maria)$`,
+		), res)
+		card := regexp.MustCompile(`card (\d{16})`).FindStringSubmatch(res)[1]
+		_, ok := validateRedactPan(card)
+		assert.True(t, ok)
+	})
+}
+
+func TestRedactTextTransformer_Transform_json(t *testing.T) {
+	res := redactTestValue(t, "doc", `{"z": "mail a@b.io", "a": [1, "ip 10.0.0.1", {"k": null}], "b": "<ok>"}`,
+		map[string]toolkit.ParamsValue{},
+	)
+	assert.Equal(t, `{"z":"mail *@*.**","a":[1,"ip **.*.*.*",{"k":null}],"b":"<ok>"}`, res)
+}
+
+func TestRedactTextTransformer_validation(t *testing.T) {
+	tests := []struct {
+		name      string
+		detectors string
+		wantMsg   string
+	}{
+		{
+			name:      "empty detectors",
+			detectors: `[]`,
+			wantMsg:   "at least one detector is required",
+		},
+		{
+			name:      "unknown type",
+			detectors: `[{"type": "ssn"}]`,
+			wantMsg:   "unknown detector type",
+		},
+		{
+			name:      "unknown action",
+			detectors: `[{"type": "email", "action": "drop"}]`,
+			wantMsg:   "unknown action",
+		},
+		{
+			name:      "unknown region",
+			detectors: `[{"type": "phone", "regions": ["XX"]}]`,
+			wantMsg:   "unknown phone region",
+		},
+		{
+			name:      "wrong mask char",
+			detectors: `[{"type": "email", "mask_char": "**"}]`,
+			wantMsg:   "mask_char must be one character",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, _ := getDriverAndRecord("data", "text")
+			_, warnings, err := RedactTextTransformerDefinition.Instance(
+				context.Background(), driver, map[string]toolkit.ParamsValue{
+					"column":    toolkit.ParamsValue("data"),
+					"detectors": toolkit.ParamsValue(tt.detectors),
+				}, nil, "", false,
+			)
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+			assert.Equal(t, tt.wantMsg, warnings[0].Msg)
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/greenmaskio/greenmask/internal/generators"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const ShiftDateTransformerName = "ShiftDate"
+
+// shiftDateGeneratorByteLength - the bytes required for the offset generation
+const shiftDateGeneratorByteLength = 8
+
+const microsecondsPerDay = int64(24 * time.Hour / time.Microsecond)
+
+var shiftDateAllowedColumnTypes = []string{"date", "timestamp", "timestamptz"}
+
+var ShiftDateTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		ShiftDateTransformerName,
+		"Shift the date columns by the offset derived from the entity key, so the intervals between the dates "+
+			"of the entity are kept",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, true),
+
+	NewShiftDateTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"the entity key column name. The offset is derived from its value",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(false),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"columns",
+		"date columns to shift."+
+			"The structure:"+
+			`{`+
+			`"name": "type:string, required:true, description: column name",`+
+			`"table": "type:string, required:false, description: table name in format schema.table or table. `+
+			`The column is shifted only in this table"`+
+			`}`,
+	).SetRequired(true).
+		SetIsColumnContainer(true),
+
+	toolkit.MustNewParameterDefinition(
+		"min_offset",
+		"min offset. By default the negative max_offset",
+	).SetCastDbType("interval"),
+
+	toolkit.MustNewParameterDefinition(
+		"max_offset",
+		"max offset",
+	).SetRequired(true).
+		SetCastDbType("interval"),
+
+	toolkit.MustNewParameterDefinition(
+		"min",
+		"min threshold date (and/or time) of the shifted value",
+	).SetCastDbType("timestamptz"),
+
+	toolkit.MustNewParameterDefinition(
+		"max",
+		"max threshold date (and/or time) of the shifted value",
+	).SetCastDbType("timestamptz"),
+
+	toolkit.MustNewParameterDefinition(
+		"timezone",
+		"the time zone used for shifting timestamptz values by days. The time of day is kept in this time zone",
+	).SetDefaultValue(toolkit.ParamsValue("UTC")),
+
+	toolkit.MustNewParameterDefinition(
+		"engine",
+		"The engine used for generating the offset [random, hash]. The hash engine keeps the same offset of the "+
+			"entity in all the rows and tables",
+	).SetDefaultValue([]byte("hash")).
+		SetRawValueValidator(engineValidator),
+)
+
+// ShiftDateColumn - the date column shifted by the transformer
+type ShiftDateColumn struct {
+	Name string `json:"name"`
+	// Table - the table of the column in format schema.table or table. It is used when the transformer is applied
+	// for the several tables with apply_for_references
+	Table       string `json:"table,omitempty"`
+	columnIdx   int
+	isDate      bool
+	isTimestamp bool
+}
+
+type ShiftDateTransformer struct {
+	columns         []*ShiftDateColumn
+	keyColumnIdx    int
+	affectedColumns map[int]string
+	minOffset       int64
+	maxOffset       int64
+	min             *time.Time
+	max             *time.Time
+	location        *time.Location
+	generator       generators.Generator
+}
+
+func NewShiftDateTransformer(ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var keyColumnName, engine, timezone string
+	var warnings toolkit.ValidationWarnings
+
+	if err := parameters["column"].Scan(&keyColumnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+	keyColumnIdx, _, ok := driver.GetColumnByName(keyColumnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", keyColumnName)
+	}
+
+	if err := parameters["engine"].Scan(&engine); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "engine" param: %w`, err)
+	}
+
+	columns, colWarnings, err := getShiftDateColumns(driver, parameters["columns"])
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings = append(warnings, colWarnings...)
+
+	var maxOffsetInterval pgtype.Interval
+	if err = parameters["max_offset"].Scan(&maxOffsetInterval); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "max_offset" param: %w`, err)
+	}
+	maxOffset := intervalToMicroseconds(maxOffsetInterval)
+	minOffset := -maxOffset
+	empty, err := parameters["min_offset"].IsEmpty()
+	if err != nil {
+		return nil, nil, fmt.Errorf(`unable to check "min_offset" param: %w`, err)
+	}
+	if !empty {
+		var minOffsetInterval pgtype.Interval
+		if err = parameters["min_offset"].Scan(&minOffsetInterval); err != nil {
+			return nil, nil, fmt.Errorf(`unable to scan "min_offset" param: %w`, err)
+		}
+		minOffset = intervalToMicroseconds(minOffsetInterval)
+	}
+	if minOffset > maxOffset {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "min_offset").
+			SetMsg(`"min_offset" must be less than or equal to "max_offset"`),
+		)
+	}
+
+	minValue, err := scanShiftDateThreshold(parameters["min"], "min")
+	if err != nil {
+		return nil, nil, err
+	}
+	maxValue, err := scanShiftDateThreshold(parameters["max"], "max")
+	if err != nil {
+		return nil, nil, err
+	}
+	if minValue != nil && maxValue != nil && minValue.After(*maxValue) {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "min").
+			SetMsg(`"min" must be less than or equal to "max"`),
+		)
+	}
+
+	if err = parameters["timezone"].Scan(&timezone); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "timezone" param: %w`, err)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "timezone").
+			AddMeta("ParameterValue", timezone).
+			AddMeta("Error", err.Error()).
+			SetMsg("unknown time zone"),
+		)
+	}
+
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	g, err := getGenerateEngine(ctx, engine, shiftDateGeneratorByteLength)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+	}
+
+	affectedColumns := make(map[int]string, len(columns))
+	for _, c := range columns {
+		affectedColumns[c.columnIdx] = c.Name
+	}
+
+	return &ShiftDateTransformer{
+		columns:         columns,
+		keyColumnIdx:    keyColumnIdx,
+		affectedColumns: affectedColumns,
+		minOffset:       minOffset,
+		maxOffset:       maxOffset,
+		min:             minValue,
+		max:             maxValue,
+		location:        location,
+		generator:       g,
+	}, warnings, nil
+}
+
+// getShiftDateColumns - returns the columns of the table to shift. The columns with the table that does not match
+// the driver table are skipped. The columns without the table that do not exist are skipped with the warning, since
+// the config is shared between the tables when the transformer is applied for references
+func getShiftDateColumns(
+	driver *toolkit.Driver, p toolkit.Parameterizer,
+) ([]*ShiftDateColumn, toolkit.ValidationWarnings, error) {
+	var configured []*ShiftDateColumn
+	if err := p.Scan(&configured); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "columns" param: %w`, err)
+	}
+	var warnings toolkit.ValidationWarnings
+	var res []*ShiftDateColumn
+	for _, c := range configured {
+		if c.Table != "" && !isShiftDateColumnTable(driver.Table, c.Table) {
+			continue
+		}
+		idx, column, ok := driver.GetColumnByName(c.Name)
+		if !ok {
+			severity := toolkit.WarningValidationSeverity
+			msg := "column is not found: skipping"
+			if c.Table != "" {
+				severity = toolkit.ErrorValidationSeverity
+				msg = "column is not found"
+			}
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(severity).
+				AddMeta("ParameterName", "columns").
+				AddMeta("ColumnName", c.Name).
+				SetMsg(msg),
+			)
+			continue
+		}
+		if !slices.Contains(shiftDateAllowedColumnTypes, column.TypeName) {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "columns").
+				AddMeta("ColumnName", c.Name).
+				AddMeta("ColumnType", column.TypeName).
+				AddMeta("AllowedTypes", shiftDateAllowedColumnTypes).
+				SetMsg("unsupported column type"),
+			)
+			continue
+		}
+		c.columnIdx = idx
+		c.isDate = column.TypeName == "date"
+		c.isTimestamp = column.TypeName == "timestamp"
+		res = append(res, c)
+	}
+	if len(res) == 0 && !warnings.IsFatal() {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "columns").
+			SetMsg("no columns to shift in the table"),
+		)
+	}
+	return res, warnings, nil
+}
+
+func isShiftDateColumnTable(t *toolkit.Table, name string) bool {
+	schema, table, ok := strings.Cut(name, ".")
+	if !ok {
+		return name == t.Name
+	}
+	return schema == t.Schema && table == t.Name
+}
+
+func scanShiftDateThreshold(p toolkit.Parameterizer, name string) (*time.Time, error) {
+	empty, err := p.IsEmpty()
+	if err != nil {
+		return nil, fmt.Errorf(`unable to check "%s" param: %w`, name, err)
+	}
+	if empty {
+		return nil, nil
+	}
+	res := &time.Time{}
+	if err = p.Scan(res); err != nil {
+		return nil, fmt.Errorf(`unable to scan "%s" param: %w`, name, err)
+	}
+	return res, nil
+}
+
+// intervalToMicroseconds - converts the interval to microseconds. The month is considered as 30 days
+func intervalToMicroseconds(v pgtype.Interval) int64 {
+	return (int64(v.Months)*30+int64(v.Days))*microsecondsPerDay + v.Microseconds
+}
+
+func (sdt *ShiftDateTransformer) GetAffectedColumns() map[int]string {
+	return sdt.affectedColumns
+}
+
+func (sdt *ShiftDateTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (sdt *ShiftDateTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+// getOffset - generates the offset in microseconds. The hash engine generates the same offset for the same key
+// value, so the dates of the entity are shifted equally in all the tables
+func (sdt *ShiftDateTransformer) getOffset(key []byte) (int64, error) {
+	resBytes, err := sdt.generator.Generate(key)
+	if err != nil {
+		return 0, fmt.Errorf("unable to generate offset: %w", err)
+	}
+	span := uint64(sdt.maxOffset-sdt.minOffset) + 1
+	return sdt.minOffset + int64(binary.LittleEndian.Uint64(resBytes)%span), nil
+}
+
+// shift - shifts the value by the offset. The days are added in the time zone for timestamptz values, so the time
+// of day is kept across DST changes. The date values are shifted by the whole days only and the timestamp values
+// do not have the time zone
+func (sdt *ShiftDateTransformer) shift(v time.Time, offset int64, c *ShiftDateColumn) time.Time {
+	days := int(offset / microsecondsPerDay)
+	rest := time.Duration(offset%microsecondsPerDay) * time.Microsecond
+	var res time.Time
+	switch {
+	case c.isDate:
+		res = v.AddDate(0, 0, days)
+	case c.isTimestamp:
+		res = v.AddDate(0, 0, days).Add(rest)
+	default:
+		res = v.In(sdt.location).AddDate(0, 0, days).Add(rest)
+	}
+	if sdt.min != nil && res.Before(*sdt.min) {
+		res = *sdt.min
+	}
+	if sdt.max != nil && res.After(*sdt.max) {
+		res = *sdt.max
+	}
+	return res
+}
+
+func (sdt *ShiftDateTransformer) Transform(_ context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	key, err := r.GetRawColumnValueByIdx(sdt.keyColumnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get key value: %w", err)
+	}
+	if key.IsNull {
+		return r, nil
+	}
+	offset, err := sdt.getOffset(key.Data)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range sdt.columns {
+		var v time.Time
+		isNull, err := r.ScanColumnValueByIdx(c.columnIdx, &v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan attribute value: %w", err)
+		}
+		if isNull {
+			continue
+		}
+		if err = r.SetColumnValueByIdx(c.columnIdx, sdt.shift(v, offset, c)); err != nil {
+			return nil, fmt.Errorf("unable to set new value: %w", err)
+		}
+	}
+	return r, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(ShiftDateTransformerDefinition)
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+func scanShiftDateTestValue(t *testing.T, r *toolkit.Record, name string) time.Time {
+	var res time.Time
+	isNull, err := r.ScanColumnValueByName(name, &res)
+	require.NoError(t, err)
+	require.False(t, isNull)
+	return res
+}
+
+func transformShiftDateTestRecord(
+	t *testing.T, columns []string, value string, params map[string]toolkit.ParamsValue,
+) *toolkit.Record {
+	driver, record := getDriverAndRecordByColumns(columns, value)
+	transformerCtx, warnings, err := ShiftDateTransformerDefinition.Instance(
+		context.Background(), driver, params, nil, "", false,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	r, err := transformerCtx.Transformer.Transform(context.Background(), record)
+	require.NoError(t, err)
+	return r
+}
+
+func TestShiftDateTransformer_Transform(t *testing.T) {
+	params := map[string]toolkit.ParamsValue{
+		"column":     toolkit.ParamsValue("id"),
+		"columns":    toolkit.ParamsValue(`[{"name": "date_ts"}, {"name": "date_tstz"}]`),
+		"max_offset": toolkit.ParamsValue("30 days"),
+		"engine":     toolkit.ParamsValue("hash"),
+	}
+	originalTs := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+	originalTstz := time.Date(2024, 1, 15, 17, 30, 0, 0, time.UTC)
+
+	r := transformShiftDateTestRecord(
+		t, []string{"id", "date_ts", "date_tstz"},
+		"42\t2024-01-10 08:00:00\t2024-01-15 17:30:00+00", params,
+	)
+	ts := scanShiftDateTestValue(t, r, "date_ts")
+	tstz := scanShiftDateTestValue(t, r, "date_tstz")
+	offset := ts.Sub(originalTs)
+	assert.NotZero(t, offset)
+	assert.LessOrEqual(t, offset.Abs(), 30*24*time.Hour)
+	// The interval between the dates is kept
+	assert.Equal(t, originalTstz.Sub(originalTs), tstz.Sub(ts))
+
+	t.Run("same offset in another table", func(t *testing.T) {
+		params := map[string]toolkit.ParamsValue{
+			"column":     toolkit.ParamsValue("id4"),
+			"columns":    toolkit.ParamsValue(`[{"name": "date_ts"}, {"name": "created_at", "table": "other"}]`),
+			"max_offset": toolkit.ParamsValue("30 days"),
+			"engine":     toolkit.ParamsValue("hash"),
+		}
+		r := transformShiftDateTestRecord(
+			t, []string{"id4", "date_ts", "created_at"},
+			"42\t2023-06-01 12:00:00\t2023-06-01 12:00:00", params,
+		)
+		assert.Equal(t, offset, scanShiftDateTestValue(t, r, "date_ts").Sub(
+			time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)),
+		)
+		// The column of another table is not shifted
+		assert.Equal(t, time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC), scanShiftDateTestValue(t, r, "created_at"))
+	})
+
+	t.Run("date is shifted by days", func(t *testing.T) {
+		params := map[string]toolkit.ParamsValue{
+			"column":     toolkit.ParamsValue("id"),
+			"columns":    toolkit.ParamsValue(`[{"name": "date_date", "table": "public.test"}]`),
+			"max_offset": toolkit.ParamsValue("30 days"),
+			"engine":     toolkit.ParamsValue("hash"),
+		}
+		r := transformShiftDateTestRecord(t, []string{"id", "date_date"}, "42\t2024-01-10", params)
+		original := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, int64(offset/(24*time.Hour)), int64(scanShiftDateTestValue(t, r, "date_date").Sub(original)/(24*time.Hour)))
+	})
+
+	t.Run("hash engine by default", func(t *testing.T) {
+		params := map[string]toolkit.ParamsValue{
+			"column":     toolkit.ParamsValue("id"),
+			"columns":    toolkit.ParamsValue(`[{"name": "date_ts"}]`),
+			"max_offset": toolkit.ParamsValue("30 days"),
+		}
+		r := transformShiftDateTestRecord(t, []string{"id", "date_ts"}, "42\t2024-01-10 08:00:00", params)
+		assert.Equal(t, offset, scanShiftDateTestValue(t, r, "date_ts").Sub(originalTs))
+	})
+
+	t.Run("null key", func(t *testing.T) {
+		r := transformShiftDateTestRecord(
+			t, []string{"id", "date_ts", "date_tstz"}, "\\N\t2024-01-10 08:00:00\t\\N", params,
+		)
+		assert.Equal(t, originalTs, scanShiftDateTestValue(t, r, "date_ts"))
+	})
+}
+
+func TestShiftDateTransformer_Transform_bounds(t *testing.T) {
+	params := map[string]toolkit.ParamsValue{
+		"column":     toolkit.ParamsValue("id"),
+		"columns":    toolkit.ParamsValue(`[{"name": "date_ts"}]`),
+		"min_offset": toolkit.ParamsValue("10 days"),
+		"max_offset": toolkit.ParamsValue("20 days"),
+		"max":        toolkit.ParamsValue("2024-01-15 00:00:00+00"),
+		"engine":     toolkit.ParamsValue("hash"),
+	}
+	r := transformShiftDateTestRecord(t, []string{"id", "date_ts"}, "1\t2024-01-01 00:00:00", params)
+	assert.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), scanShiftDateTestValue(t, r, "date_ts"))
+
+	r = transformShiftDateTestRecord(t, []string{"id", "date_ts"}, "1\t2023-01-01 00:00:00", params)
+	shifted := scanShiftDateTestValue(t, r, "date_ts")
+	offset := shifted.Sub(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.GreaterOrEqual(t, offset, 10*24*time.Hour)
+	assert.LessOrEqual(t, offset, 20*24*time.Hour)
+}
+
+func TestShiftDateTransformer_Transform_timezone(t *testing.T) {
+	params := map[string]toolkit.ParamsValue{
+		"column":     toolkit.ParamsValue("id"),
+		"columns":    toolkit.ParamsValue(`[{"name": "date_tstz"}]`),
+		"min_offset": toolkit.ParamsValue("1 day"),
+		"max_offset": toolkit.ParamsValue("1 day"),
+		"timezone":   toolkit.ParamsValue("Europe/Berlin"),
+		"engine":     toolkit.ParamsValue("hash"),
+	}
+	// The DST starts on 2024-03-31 in Berlin: the local time of day is kept
+	r := transformShiftDateTestRecord(t, []string{"id", "date_tstz"}, "1\t2024-03-30 10:00:00+00", params)
+	assert.True(t, time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC).Equal(scanShiftDateTestValue(t, r, "date_tstz")))
+}
+
+func TestShiftDateTransformer_validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]toolkit.ParamsValue
+		wantMsg string
+	}{
+		{
+			name: "no columns in the table",
+			params: map[string]toolkit.ParamsValue{
+				"columns": toolkit.ParamsValue(`[{"name": "date_ts", "table": "public.other"}]`),
+			},
+			wantMsg: "no columns to shift in the table",
+		},
+		{
+			name: "column is not found",
+			params: map[string]toolkit.ParamsValue{
+				"columns": toolkit.ParamsValue(`[{"name": "admitted_at", "table": "test"}]`),
+			},
+			wantMsg: "column is not found",
+		},
+		{
+			name: "unsupported type",
+			params: map[string]toolkit.ParamsValue{
+				"columns": toolkit.ParamsValue(`[{"name": "id"}]`),
+			},
+			wantMsg: "unsupported column type",
+		},
+		{
+			name: "wrong offsets",
+			params: map[string]toolkit.ParamsValue{
+				"columns":    toolkit.ParamsValue(`[{"name": "date_ts"}]`),
+				"min_offset": toolkit.ParamsValue("40 days"),
+			},
+			wantMsg: `"min_offset" must be less than or equal to "max_offset"`,
+		},
+		{
+			name: "unknown time zone",
+			params: map[string]toolkit.ParamsValue{
+				"columns":  toolkit.ParamsValue(`[{"name": "date_ts"}]`),
+				"timezone": toolkit.ParamsValue("Mars/Olympus"),
+			},
+			wantMsg: "unknown time zone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("id")
+			tt.params["max_offset"] = toolkit.ParamsValue("30 days")
+			driver, _ := getDriverAndRecordByColumns([]string{"id", "date_ts"}, "1\t2024-01-01 00:00:00")
+			_, warnings, err := ShiftDateTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "", false,
+			)
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+			assert.Equal(t, tt.wantMsg, warnings[0].Msg)
+		})
+	}
+}
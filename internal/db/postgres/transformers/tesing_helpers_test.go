@@ -189,6 +189,24 @@ var columnList = []*toolkit.Column{
 		NotNull:  false,
 		Length:   -1,
 	},
+	{
+		Name:       "text_arr",
+		TypeName:   "_text",
+		TypeOid:    pgtype.TextArrayOID,
+		Num:        20,
+		NotNull:    false,
+		Length:     -1,
+		TypeLength: -1,
+	},
+	{
+		Name:       "int_arr",
+		TypeName:   "_int4",
+		TypeOid:    pgtype.Int4ArrayOID,
+		Num:        21,
+		NotNull:    false,
+		Length:     -1,
+		TypeLength: -1,
+	},
 }
 
 // getDriverAndRecord - return adhoc table for testing
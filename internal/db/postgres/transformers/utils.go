@@ -6,9 +6,13 @@ import (
 	"encoding/binary"
 	"fmt"
 
+	"github.com/jackc/pgx/v5/pgtype"
+
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/greenmaskio/greenmask/internal/generators"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 	commonutils "github.com/greenmaskio/greenmask/internal/utils"
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
 const (
@@ -27,6 +31,59 @@ func getGenerateEngine(ctx context.Context, engineName string, size int) (genera
 	return nil, fmt.Errorf("unknown engine %s", engineName)
 }
 
+// getLocalePack - returns the pack of the locale set in the "locale" parameter. Returns nil if the parameter is empty
+func getLocalePack(p toolkit.Parameterizer) (*locales.Pack, error) {
+	var locale string
+	if err := p.Scan(&locale); err != nil {
+		return nil, fmt.Errorf(`unable to scan "locale" param: %w`, err)
+	}
+	if locale == "" {
+		return nil, nil
+	}
+	return locales.Get(locale)
+}
+
+// newLocaleSectionWarning - the warning about the locale pack that does not contain the data required by the
+// transformer
+func newLocaleSectionWarning(pack *locales.Pack, section string) *toolkit.ValidationWarning {
+	return toolkit.NewValidationWarning().
+		SetSeverity(toolkit.ErrorValidationSeverity).
+		AddMeta("ParameterName", "locale").
+		AddMeta("ParameterValue", pack.Locale).
+		AddMeta("Section", section).
+		SetMsg("locale pack does not contain the required data")
+}
+
+// varcharHeaderSize - the varlena header size added to the declared length in the type modifier of varchar(n) and
+// char(n) columns
+const varcharHeaderSize = 4
+
+// getColumnMaxChars - returns the declared length in characters of varchar(n) and char(n) columns or -1 if the length
+// is not limited
+func getColumnMaxChars(c *toolkit.Column) int {
+	switch c.TypeOid {
+	case pgtype.VarcharOID, pgtype.BPCharOID:
+		if c.Length > varcharHeaderSize {
+			return c.Length - varcharHeaderSize
+		}
+	}
+	return -1
+}
+
+// truncateChars - truncates the UTF-8 encoded value to maxChars characters
+func truncateChars(data []byte, maxChars int) []byte {
+	if maxChars < 0 || len(data) <= maxChars {
+		return data
+	}
+	for i := range string(data) {
+		if maxChars == 0 {
+			return data[:i]
+		}
+		maxChars--
+	}
+	return data
+}
+
 func getRandomBytesGen(size int) (generators.Generator, error) {
 	buf := make([]byte, 8)
 	_, err := rand.Read(buf)
@@ -37,6 +94,28 @@ func getRandomBytesGen(size int) (generators.Generator, error) {
 	return generators.NewRandomBytes(seed, size), nil
 }
 
+// newUniqueValues - create the unique values tracker if unique mode is enabled
+func newUniqueValues(unique bool) *generators.UniqueValues {
+	if !unique {
+		return nil
+	}
+	return generators.NewUniqueValues(generators.DefaultUniqueMaxRetries)
+}
+
+// generateValue - generate a new value for the original one. If the unique values tracker is set the generated value
+// is guaranteed to be unique within the column
+func generateValue(
+	u *generators.UniqueValues, val *toolkit.RawValue, gen func(data []byte) ([]byte, error),
+) ([]byte, error) {
+	if u == nil {
+		return gen(val.Data)
+	}
+	if val.IsNull {
+		return u.GenerateForNull(gen)
+	}
+	return u.Generate(val.Data, gen)
+}
+
 //func composeGeneratorWithProjectedOutput(hashFunction string, salt []byte, outputLength int) (generators.Generator, error) {
 //	switch hashFunction {
 //	case Sha1HashFunction:
@@ -28,11 +28,16 @@ type NewTransformerFunc func(ctx context.Context, driver *toolkit.Driver, parame
 	Transformer, toolkit.ValidationWarnings, error,
 )
 
+// RequireDatabaseConnectionFunc - reports whether the transformer instance with the provided raw parameters uses
+// the database connection, so it cannot be used for the offline transformation of the existing dump
+type RequireDatabaseConnectionFunc func(params map[string]toolkit.ParamsValue) bool
+
 type TransformerDefinition struct {
-	Properties      *TransformerProperties         `json:"properties"`
-	New             NewTransformerFunc             `json:"-"`
-	Parameters      []*toolkit.ParameterDefinition `json:"parameters"`
-	SchemaValidator SchemaValidationFunc           `json:"-"`
+	Properties                *TransformerProperties         `json:"properties"`
+	New                       NewTransformerFunc             `json:"-"`
+	Parameters                []*toolkit.ParameterDefinition `json:"parameters"`
+	SchemaValidator           SchemaValidationFunc           `json:"-"`
+	RequireDatabaseConnection RequireDatabaseConnectionFunc  `json:"-"`
 }
 
 func NewTransformerDefinition(
@@ -52,6 +57,20 @@ func (d *TransformerDefinition) SetSchemaValidator(v SchemaValidationFunc) *Tran
 	return d
 }
 
+func (d *TransformerDefinition) SetRequireDatabaseConnection(f RequireDatabaseConnectionFunc) *TransformerDefinition {
+	d.RequireDatabaseConnection = f
+	return d
+}
+
+// RequiresDatabaseConnection - check the transformer instance with the provided raw parameters uses the database
+// connection
+func (d *TransformerDefinition) RequiresDatabaseConnection(params map[string]toolkit.ParamsValue) bool {
+	if d.RequireDatabaseConnection == nil {
+		return false
+	}
+	return d.RequireDatabaseConnection(params)
+}
+
 func (d *TransformerDefinition) Instance(
 	ctx context.Context, driver *toolkit.Driver, rawParams map[string]toolkit.ParamsValue,
 	dynamicParameters map[string]*toolkit.DynamicParamValue, whenCond string, interpolate bool,
@@ -114,14 +133,65 @@ func (d *TransformerDefinition) Instance(
 		StaticParameters:  staticParams,
 		DynamicParameters: dynamicParams,
 		When:              when,
+		definition:        d,
+		rawParams:         rawParams,
+		rawDynamicParams:  dynamicParameters,
+		whenCond:          whenCond,
+		interpolate:       interpolate,
 	}, res, nil
 }
 
+// TransformerWrapper - wraps the transformer instance to extend its behaviour, for instance to map the values through
+// the mapping store
+type TransformerWrapper func(t Transformer) (Transformer, error)
+
 type TransformerContext struct {
 	Transformer       Transformer
 	StaticParameters  map[string]*toolkit.StaticParameter
 	DynamicParameters map[string]*toolkit.DynamicParameter
 	When              *toolkit.WhenCond
+	// definition and the raw parameters the transformer was instantiated with. They are used for cloning
+	definition       *TransformerDefinition
+	rawParams        map[string]toolkit.ParamsValue
+	rawDynamicParams map[string]*toolkit.DynamicParamValue
+	whenCond         string
+	interpolate      bool
+	wrappers         []TransformerWrapper
+}
+
+// Clone - create a new instance of the transformer with the same parameters for the provided driver. The
+// transformers keep the state between the records, so the dumps of the same table that run concurrently must use
+// their own instances
+func (tc *TransformerContext) Clone(ctx context.Context, driver *toolkit.Driver) (*TransformerContext, error) {
+	if tc.definition == nil {
+		return nil, fmt.Errorf("transformer context was not created from the definition")
+	}
+	res, warnings, err := tc.definition.Instance(
+		ctx, driver, tc.rawParams, tc.rawDynamicParams, tc.whenCond, tc.interpolate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if warnings.IsFatal() {
+		return nil, fmt.Errorf("fatal validation warnings during transformer cloning: %+v", warnings)
+	}
+	for _, w := range tc.wrappers {
+		if err = res.Wrap(w); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Wrap - wraps the transformer. The wrapper is applied to the clones as well
+func (tc *TransformerContext) Wrap(w TransformerWrapper) error {
+	t, err := w(tc.Transformer)
+	if err != nil {
+		return err
+	}
+	tc.Transformer = t
+	tc.wrappers = append(tc.wrappers, w)
+	return nil
 }
 
 func (tc *TransformerContext) EvaluateWhen(r *toolkit.Record) (bool, error) {
@@ -100,7 +100,23 @@ func TestDefinition(t *testing.T) {
 		"replace": []byte("2023-08-27 12:08:11.304895"),
 	}
 
-	_, warnings, err := TestTransformerDefinition.Instance(context.Background(), driver, rawParams, nil, "", false)
+	tc, warnings, err := TestTransformerDefinition.Instance(context.Background(), driver, rawParams, nil, "", false)
 	require.NoError(t, err)
 	assert.Empty(t, warnings)
+
+	t.Run("wrap and clone", func(t *testing.T) {
+		type wrappedTransformer struct {
+			Transformer
+		}
+		err := tc.Wrap(func(t Transformer) (Transformer, error) {
+			return &wrappedTransformer{Transformer: t}, nil
+		})
+		require.NoError(t, err)
+		require.IsType(t, &wrappedTransformer{}, tc.Transformer)
+
+		clone, err := tc.Clone(context.Background(), driver)
+		require.NoError(t, err)
+		require.IsType(t, &wrappedTransformer{}, clone.Transformer)
+		assert.NotSame(t, tc.Transformer, clone.Transformer)
+	})
 }
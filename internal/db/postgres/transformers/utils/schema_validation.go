@@ -16,11 +16,15 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"slices"
 
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
 )
 
+// UniqueParameterName - the name of the parameter that enables generation of unique values in the transformer
+const UniqueParameterName = "unique"
+
 type SchemaValidationFunc func(ctx context.Context, table *toolkit.Driver, properties *TransformerProperties, parameters map[string]*toolkit.StaticParameter) (toolkit.ValidationWarnings, error)
 
 func ValidateSchema(
@@ -44,6 +48,11 @@ func DefaultSchemaValidator(
 		return nil, nil
 	}
 
+	uniqueMode, supportsUniqueMode, err := getUniqueMode(parameters)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, p := range parameters {
 		if !p.GetDefinition().IsColumn || p.GetDefinition().IsColumn && !p.GetDefinition().ColumnProperties.Affected {
 			// We assume that if parameter is not a column or is a column but not affected - it should not
@@ -77,12 +86,22 @@ func DefaultSchemaValidator(
 		}
 
 		// Performing checks constraint checks with the affected column
+		columnProperties := p.GetDefinition().ColumnProperties
+		if uniqueMode && columnProperties != nil {
+			// The transformer guarantees uniqueness of the generated values
+			cp := *columnProperties
+			cp.Unique = true
+			columnProperties = &cp
+		}
 		for _, c := range driver.Table.Constraints {
 			if p.GetDefinition().IsColumn && (p.GetDefinition().ColumnProperties == nil ||
 				p.GetDefinition().ColumnProperties != nil && p.GetDefinition().ColumnProperties.Affected) {
-				if warns := c.IsAffected(p.Column, p.GetDefinition().ColumnProperties); len(warns) > 0 {
+				if warns := c.IsAffected(p.Column, columnProperties); len(warns) > 0 {
 					for _, w := range warns {
 						w.AddMeta("ParameterName", p.GetDefinition().Name)
+						if supportsUniqueMode && !uniqueMode && isUniquenessWarning(w) {
+							w.AddMeta("Hint", `set "unique" parameter to true to generate unique values`)
+						}
 					}
 					warnings = append(warnings, warns...)
 				}
@@ -103,3 +122,20 @@ func DefaultSchemaValidator(
 
 	return warnings, nil
 }
+
+// getUniqueMode - returns whether the transformer supports unique mode and whether it is enabled
+func getUniqueMode(parameters map[string]*toolkit.StaticParameter) (enabled bool, supported bool, err error) {
+	p, ok := parameters[UniqueParameterName]
+	if !ok {
+		return false, false, nil
+	}
+	if err = p.Scan(&enabled); err != nil {
+		return false, true, fmt.Errorf(`unable to scan "%s" param: %w`, UniqueParameterName, err)
+	}
+	return enabled, true, nil
+}
+
+func isUniquenessWarning(w *toolkit.ValidationWarning) bool {
+	ct := w.Meta["ConstraintType"]
+	return ct == toolkit.UniqueConstraintType || ct == toolkit.PkConstraintType
+}
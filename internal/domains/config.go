@@ -17,12 +17,17 @@ package domains
 import (
 	"maps"
 	"sync"
+	"time"
 
+	"github.com/greenmaskio/greenmask/internal/db/postgres/mapping"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/pgdump"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/pgrestore"
 	"github.com/greenmaskio/greenmask/internal/db/postgres/transformers/custom"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
+	"github.com/greenmaskio/greenmask/internal/hooks"
 	"github.com/greenmaskio/greenmask/internal/storages/azure"
 	"github.com/greenmaskio/greenmask/internal/storages/directory"
+	"github.com/greenmaskio/greenmask/internal/storages/encryption"
 	"github.com/greenmaskio/greenmask/internal/storages/s3"
 	sshstorage "github.com/greenmaskio/greenmask/internal/storages/ssh"
 	"github.com/greenmaskio/greenmask/pkg/toolkit"
@@ -46,11 +51,12 @@ func NewConfig() *Config {
 					TempDirectory: defaultDirectoryStoragePath,
 				},
 				Storage: StorageConfig{
-					Type:      defaultStorageType,
-					S3:        s3.NewConfig(),
-					Azure:     azure.NewConfig(),
-					Directory: directory.NewConfig(),
-					SSH:       sshstorage.NewConfig(),
+					Type:       defaultStorageType,
+					S3:         s3.NewConfig(),
+					Azure:      azure.NewConfig(),
+					Directory:  directory.NewConfig(),
+					SSH:        sshstorage.NewConfig(),
+					Encryption: encryption.NewConfig(),
 				},
 			}
 		},
@@ -65,7 +71,15 @@ type Config struct {
 	Dump               Dump                            `mapstructure:"dump" yaml:"dump" json:"dump"`
 	Validate           Validate                        `mapstructure:"validate" yaml:"validate" json:"validate"`
 	Restore            Restore                         `mapstructure:"restore" yaml:"restore" json:"restore"`
+	Discover           Discover                        `mapstructure:"discover" yaml:"discover" json:"discover"`
 	CustomTransformers []*custom.TransformerDefinition `mapstructure:"custom_transformers" yaml:"custom_transformers" json:"custom_transformers,omitempty"`
+	// MappingStores - the persistent stores of the mappings between the original and the generated values. They are
+	// used by the transformers with the mapping option
+	MappingStores []*mapping.Config `mapstructure:"mapping_stores" yaml:"mapping_stores" json:"mapping_stores,omitempty"`
+	// Hooks - the HTTP endpoints and commands that receive the dump and restoration lifecycle events
+	Hooks []*hooks.Config `mapstructure:"hooks" yaml:"hooks" json:"hooks,omitempty"`
+	// LocalePacks - the user-supplied locale data packs used by the transformers with the locale parameter
+	LocalePacks []*locales.PackConfig `mapstructure:"locale_packs" yaml:"locale_packs" json:"locale_packs,omitempty"`
 }
 
 type Validate struct {
@@ -80,6 +94,47 @@ type Validate struct {
 	OnlyTransformed  bool     `mapstructure:"transformed_only" yaml:"transformed_only" json:"transformed_only,omitempty"`
 	Warnings         bool     `mapstructure:"warnings" yaml:"warnings" json:"warnings,omitempty"`
 	Strict           bool     `mapstructure:"strict" yaml:"strict" json:"strict,omitempty"`
+	// SchemaBaseline - dump id or tag of the dump which schema is compared with the current one. The latest dump is
+	// used if empty
+	SchemaBaseline string `mapstructure:"schema_baseline" yaml:"schema_baseline" json:"schema_baseline,omitempty"`
+	// SchemaAllowlist - path to the file with the schema changes that must not fail the validation
+	SchemaAllowlist string `mapstructure:"schema_allowlist" yaml:"schema_allowlist" json:"schema_allowlist,omitempty"`
+	// Subset - print the subset plan: the generated subset queries, their row estimates and the references without
+	// indexes
+	Subset bool `mapstructure:"subset" yaml:"subset" json:"subset,omitempty"`
+	// SubsetAnalyze - use EXPLAIN ANALYZE for the subset queries to get the actual row counts
+	SubsetAnalyze bool `mapstructure:"subset_analyze" yaml:"subset_analyze" json:"subset_analyze,omitempty"`
+	// SubsetTimeout - the statement timeout of each subset query EXPLAIN. 0 means no timeout
+	SubsetTimeout time.Duration `mapstructure:"subset_timeout" yaml:"subset_timeout" json:"subset_timeout,omitempty"`
+	// SubsetGraphFormat - the format of the rendered tables graph: dot or mermaid. The graph is not rendered if empty
+	SubsetGraphFormat string `mapstructure:"subset_graph_format" yaml:"subset_graph_format" json:"subset_graph_format,omitempty"`
+}
+
+type Discover struct {
+	// SampleSize - number of rows sampled from each table for values classification
+	SampleSize int `mapstructure:"sample_size" yaml:"sample_size" json:"sample_size,omitempty"`
+	// MinConfidence - the minimal confidence of the column classification that is reported
+	MinConfidence  float64               `mapstructure:"min_confidence" yaml:"min_confidence" json:"min_confidence,omitempty"`
+	Format         string                `mapstructure:"format" yaml:"format" json:"format,omitempty"`
+	FailOnUnmasked bool                  `mapstructure:"fail_on_unmasked" yaml:"fail_on_unmasked" json:"fail_on_unmasked,omitempty"`
+	Classifiers    []*DiscoverClassifier `mapstructure:"classifiers" yaml:"classifiers" json:"classifiers,omitempty"`
+}
+
+// DiscoverClassifier - user defined PII classifier. If the name matches the built-in classifier it replaces it
+type DiscoverClassifier struct {
+	Name string `mapstructure:"name" yaml:"name" json:"name"`
+	// ColumnNames - regular expressions that are matched against the column name
+	ColumnNames []string `mapstructure:"column_names" yaml:"column_names" json:"column_names,omitempty"`
+	// ValuePattern - regular expression that is matched against the sampled values
+	ValuePattern string `mapstructure:"value_pattern" yaml:"value_pattern" json:"value_pattern,omitempty"`
+	// Dictionary - list of the known values. The sampled values are matched case-insensitively
+	Dictionary  []string                   `mapstructure:"dictionary" yaml:"dictionary" json:"dictionary,omitempty"`
+	Transformer *DiscoverTransformerConfig `mapstructure:"transformer" yaml:"transformer" json:"transformer,omitempty"`
+}
+
+type DiscoverTransformerConfig struct {
+	Name   string         `mapstructure:"name" yaml:"name" json:"name"`
+	Params map[string]any `mapstructure:"params" yaml:"params" json:"params,omitempty"`
 }
 
 type Common struct {
@@ -93,6 +148,8 @@ type StorageConfig struct {
 	Azure     *azure.Config      `mapstructure:"azure" json:"azure,omitempty" yaml:"azure"`
 	Directory *directory.Config  `mapstructure:"directory" json:"directory,omitempty" yaml:"directory"`
 	SSH       *sshstorage.Config `mapstructure:"ssh" json:"ssh,omitempty" yaml:"ssh"`
+	// Encryption - client-side encryption of the dump objects
+	Encryption *encryption.Config `mapstructure:"encryption" json:"encryption,omitempty" yaml:"encryption"`
 }
 
 type LogConfig struct {
@@ -149,6 +206,9 @@ type TransformerConfig struct {
 	MetadataParams map[string]any            `mapstructure:"-" yaml:"params,omitempty" json:"params,omitempty"`
 	DynamicParams  toolkit.DynamicParameters `mapstructure:"dynamic_params" yaml:"dynamic_params" json:"dynamic_params,omitempty"`
 	When           string                    `mapstructure:"when" yaml:"when" json:"when,omitempty"`
+	// Mapping - map the original values through the mapping store, so the same original value is always
+	// transformed to the same value across the runs and the databases
+	Mapping *TransformerMapping `mapstructure:"mapping" yaml:"mapping" json:"mapping,omitempty"`
 }
 
 func (tc *TransformerConfig) Clone() *TransformerConfig {
@@ -158,10 +218,19 @@ func (tc *TransformerConfig) Clone() *TransformerConfig {
 		Params:             maps.Clone(tc.Params),
 		DynamicParams:      maps.Clone(tc.DynamicParams),
 		When:               tc.When,
+		Mapping:            tc.Mapping,
 	}
 
 }
 
+// TransformerMapping - the mapping store and the namespace of the transformer values
+type TransformerMapping struct {
+	// Store - the name of the store defined in mapping_stores
+	Store string `mapstructure:"store" yaml:"store" json:"store"`
+	// Namespace - the namespace of the mappings. The transformers with the same namespace share the mappings
+	Namespace string `mapstructure:"namespace" yaml:"namespace" json:"namespace"`
+}
+
 type Table struct {
 	Schema              string               `mapstructure:"schema" yaml:"schema" json:"schema,omitempty"`
 	Name                string               `mapstructure:"name" yaml:"name" json:"name,omitempty"`
@@ -171,6 +240,55 @@ type Table struct {
 	ColumnsTypeOverride map[string]string    `mapstructure:"columns_type_override" yaml:"columns_type_override" json:"columns_type_override,omitempty"`
 	SubsetConds         []string             `mapstructure:"subset_conds" yaml:"subset_conds" json:"subset_conds,omitempty"`
 	When                string               `mapstructure:"when" yaml:"when" json:"when,omitempty"`
+	// SubsetSample - random sample of the table rows that is used as the subset condition
+	SubsetSample *SubsetSample `mapstructure:"subset_sample" yaml:"subset_sample" json:"subset_sample,omitempty"`
+	// SubsetLimit - the maximal number of the table rows in the subset. The rows are selected in the primary key order
+	SubsetLimit int64 `mapstructure:"subset_limit" yaml:"subset_limit" json:"subset_limit,omitempty"`
+	// IncludeDependents - the settings of the dependent rows of the table subset. The dependent rows are the rows of
+	// the tables that reference the subset rows directly or through other dependent tables
+	IncludeDependents *IncludeDependents `mapstructure:"include_dependents" yaml:"include_dependents" json:"include_dependents,omitempty"`
+	// UpdatedAtColumn - column that is updated on each row change. It is used by the incremental dump as the table
+	// change indicator. The tables without it are always dumped
+	UpdatedAtColumn string `mapstructure:"updated_at_column" yaml:"updated_at_column" json:"updated_at_column,omitempty"`
+	// Chunks - number of the chunks the table data is split into. Each chunk is dumped by a separate worker into its
+	// own object. The table is dumped as a whole if it is less than 2
+	Chunks int `mapstructure:"chunks" yaml:"chunks" json:"chunks,omitempty"`
+	// ChunkBy - the way the table is split into chunks. It is either "pk" (ranges of the integer primary key) or
+	// "ctid" (ranges of the table pages). pk is used if the table has a single column integer primary key and
+	// ctid otherwise
+	ChunkBy string `mapstructure:"chunk_by" yaml:"chunk_by" json:"chunk_by,omitempty"`
+}
+
+// SubsetSample - the sampling settings of the table subset
+type SubsetSample struct {
+	// Percent - the percent of the table rows in the sample
+	Percent float64 `mapstructure:"percent" yaml:"percent" json:"percent"`
+	// Method - sampling method: bernoulli, system (TABLESAMPLE methods) or hash. hash is used by default
+	Method string `mapstructure:"method" yaml:"method" json:"method,omitempty"`
+	// Seed - the seed of the sampling. The same seed gives the same sample on every run
+	Seed *int64 `mapstructure:"seed" yaml:"seed" json:"seed,omitempty"`
+}
+
+// IncludeDependents - the settings of the dependent rows inclusion. The dependent tables are found by walking the
+// foreign keys from the referenced table to the referencing one
+type IncludeDependents struct {
+	// MaxDepth - the maximal number of the reference levels from the table. The tables that are deeper get only the
+	// rows that do not reference the subset rows. 0 means unlimited
+	MaxDepth int `mapstructure:"max_depth" yaml:"max_depth" json:"max_depth,omitempty"`
+	// Limit - the maximal number of the dependent rows of each dependent table. 0 means unlimited
+	Limit int64 `mapstructure:"limit" yaml:"limit" json:"limit,omitempty"`
+	// Tables - the settings of the particular dependent tables
+	Tables []*DependentTable `mapstructure:"tables" yaml:"tables" json:"tables,omitempty"`
+}
+
+// DependentTable - the settings of the dependent table that override the IncludeDependents settings
+type DependentTable struct {
+	Schema string `mapstructure:"schema" yaml:"schema" json:"schema"`
+	Name   string `mapstructure:"name" yaml:"name" json:"name"`
+	// Limit - the maximal number of the dependent rows of the table. It overrides IncludeDependents.Limit if set
+	Limit int64 `mapstructure:"limit" yaml:"limit" json:"limit,omitempty"`
+	// Exclude - do not include the dependent rows of the table and its own dependent tables
+	Exclude bool `mapstructure:"exclude" yaml:"exclude" json:"exclude,omitempty"`
 }
 
 // DummyConfig - This is a dummy config to the viper workaround
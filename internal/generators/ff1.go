@@ -0,0 +1,187 @@
+package generators
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const (
+	ff1Rounds = 10
+	// ff1MinDomainSize - the minimal domain size radix^minlen required by NIST SP 800-38G Rev. 1
+	ff1MinDomainSize = 1_000_000
+	ff1MaxRadix      = 1 << 16
+)
+
+var ErrFF1DomainTooSmall = errors.New("the domain is too small for format-preserving encryption")
+
+// FF1 - format-preserving encryption FF1 mode defined in NIST SP 800-38G. The input and output are numeral strings -
+// sequences of integers in range [0, radix)
+type FF1 struct {
+	block cipher.Block
+	radix int
+	// minLength - the minimal length of the numeral string that satisfies the domain size requirement
+	minLength int
+	bigRadix  *big.Int
+}
+
+// NewFF1 - create FF1 cipher. The key must be AES-128, AES-192 or AES-256 key
+func NewFF1(key []byte, radix int) (*FF1, error) {
+	if radix < 2 || radix > ff1MaxRadix {
+		return nil, fmt.Errorf("radix must be in range [2, %d]: got %d", ff1MaxRadix, radix)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create AES cipher: %w", err)
+	}
+	minLength := int(math.Ceil(math.Log(ff1MinDomainSize) / math.Log(float64(radix))))
+	if minLength < 2 {
+		minLength = 2
+	}
+	return &FF1{
+		block:     block,
+		radix:     radix,
+		minLength: minLength,
+		bigRadix:  big.NewInt(int64(radix)),
+	}, nil
+}
+
+// MinLength - the minimal length of the numeral string that can be encrypted
+func (f *FF1) MinLength() int {
+	return f.minLength
+}
+
+// Encrypt - encrypt numeral string x using tweak
+func (f *FF1) Encrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.cipher(x, tweak, true)
+}
+
+// Decrypt - decrypt numeral string x using tweak
+func (f *FF1) Decrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.cipher(x, tweak, false)
+}
+
+func (f *FF1) cipher(x []uint16, tweak []byte, encrypt bool) ([]uint16, error) {
+	n := len(x)
+	if n < f.minLength {
+		return nil, fmt.Errorf("%w: length %d is lower than minimal %d", ErrFF1DomainTooSmall, n, f.minLength)
+	}
+	for _, v := range x {
+		if int(v) >= f.radix {
+			return nil, fmt.Errorf("numeral %d is out of radix %d", v, f.radix)
+		}
+	}
+	u := n / 2
+	v := n - u
+	b := int(math.Ceil(math.Ceil(float64(v)*math.Log2(float64(f.radix))) / 8))
+	d := 4*((b+3)/4) + 4
+
+	p := make([]byte, aes.BlockSize)
+	p[0] = 1
+	p[1] = 2
+	p[2] = 1
+	p[3] = byte(f.radix >> 16)
+	p[4] = byte(f.radix >> 8)
+	p[5] = byte(f.radix)
+	p[6] = ff1Rounds
+	p[7] = byte(u % 256)
+	binary.BigEndian.PutUint32(p[8:12], uint32(n))
+	binary.BigEndian.PutUint32(p[12:16], uint32(len(tweak)))
+
+	// Q = T || [0]^((-t-b-1) mod 16) || [i]^1 || [NUM(B)]^b
+	padLen := (16 - (len(tweak)+b+1)%16) % 16
+	q := make([]byte, len(tweak)+padLen+1+b)
+	copy(q, tweak)
+
+	modU := new(big.Int).Exp(f.bigRadix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(f.bigRadix, big.NewInt(int64(v)), nil)
+
+	a := f.num(x[:u])
+	bb := f.num(x[u:])
+	y := new(big.Int)
+	numBuf := make([]byte, b)
+	s := make([]byte, ((d+aes.BlockSize-1)/aes.BlockSize)*aes.BlockSize)
+
+	for r := 0; r < ff1Rounds; r++ {
+		i := r
+		if !encrypt {
+			i = ff1Rounds - 1 - r
+		}
+		// On encryption the round function is applied to B, on decryption - to A
+		input := bb
+		if !encrypt {
+			input = a
+		}
+		q[len(tweak)+padLen] = byte(i)
+		clear(numBuf)
+		input.FillBytes(numBuf)
+		copy(q[len(tweak)+padLen+1:], numBuf)
+
+		f.prf(p, q, s[:aes.BlockSize])
+		for j := 1; j*aes.BlockSize < d; j++ {
+			block := s[j*aes.BlockSize : (j+1)*aes.BlockSize]
+			copy(block, s[:aes.BlockSize])
+			binary.BigEndian.PutUint64(block[8:], binary.BigEndian.Uint64(block[8:])^uint64(j))
+			f.block.Encrypt(block, block)
+		}
+		y.SetBytes(s[:d])
+
+		m := modU
+		if i%2 == 1 {
+			m = modV
+		}
+		c := new(big.Int)
+		if encrypt {
+			c.Add(a, y)
+			c.Mod(c, m)
+			a, bb = bb, c
+		} else {
+			c.Sub(bb, y)
+			c.Mod(c, m)
+			bb, a = a, c
+		}
+	}
+
+	res := make([]uint16, 0, n)
+	res = append(res, f.str(a, u)...)
+	res = append(res, f.str(bb, v)...)
+	return res, nil
+}
+
+// prf - AES CBC-MAC with zero IV over P || Q
+func (f *FF1) prf(p, q, dst []byte) {
+	clear(dst)
+	for _, data := range [][]byte{p, q} {
+		for i := 0; i < len(data); i += aes.BlockSize {
+			for j := 0; j < aes.BlockSize; j++ {
+				dst[j] ^= data[i+j]
+			}
+			f.block.Encrypt(dst, dst)
+		}
+	}
+}
+
+func (f *FF1) num(x []uint16) *big.Int {
+	res := new(big.Int)
+	digit := new(big.Int)
+	for _, v := range x {
+		res.Mul(res, f.bigRadix)
+		res.Add(res, digit.SetInt64(int64(v)))
+	}
+	return res
+}
+
+func (f *FF1) str(x *big.Int, length int) []uint16 {
+	res := make([]uint16, length)
+	x = new(big.Int).Set(x)
+	rem := new(big.Int)
+	for i := length - 1; i >= 0; i-- {
+		x.QuoRem(x, f.bigRadix, rem)
+		res[i] = uint16(rem.Int64())
+	}
+	return res
+}
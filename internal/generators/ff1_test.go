@@ -0,0 +1,107 @@
+package generators
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ff1Digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func ff1Numerals(s string) []uint16 {
+	res := make([]uint16, len(s))
+	for i, c := range s {
+		res[i] = uint16(strings.IndexRune(ff1Digits, c))
+	}
+	return res
+}
+
+func ff1String(x []uint16) string {
+	var sb strings.Builder
+	for _, v := range x {
+		sb.WriteByte(ff1Digits[v])
+	}
+	return sb.String()
+}
+
+func TestFF1_NistVectors(t *testing.T) {
+	// Test vectors from NIST SP 800-38G FF1 samples
+	tests := []struct {
+		name       string
+		key        string
+		radix      int
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{
+			name:       "sample 1",
+			key:        "2b7e151628aed2a6abf7158809cf4f3c",
+			radix:      10,
+			plaintext:  "0123456789",
+			ciphertext: "2433477484",
+		},
+		{
+			name:       "sample 2",
+			key:        "2b7e151628aed2a6abf7158809cf4f3c",
+			radix:      10,
+			tweak:      "39383736353433323130",
+			plaintext:  "0123456789",
+			ciphertext: "6124200773",
+		},
+		{
+			name:       "sample 3",
+			key:        "2b7e151628aed2a6abf7158809cf4f3c",
+			radix:      36,
+			tweak:      "3737373770717273373737",
+			plaintext:  "0123456789abcdefghi",
+			ciphertext: "a9tv40mll9kdu509eum",
+		},
+		{
+			name:       "sample 6",
+			key:        "2b7e151628aed2a6abf7158809cf4f3cef4359d8d580aa4f",
+			radix:      36,
+			tweak:      "3737373770717273373737",
+			plaintext:  "0123456789abcdefghi",
+			ciphertext: "xbj3kv35jrawxv32ysr",
+		},
+		{
+			name:       "sample 9",
+			key:        "2b7e151628aed2a6abf7158809cf4f3cef4359d8d580aa4f7f036d6f04fc6a94",
+			radix:      36,
+			tweak:      "3737373770717273373737",
+			plaintext:  "0123456789abcdefghi",
+			ciphertext: "xs8a0azh2avyalyzuwd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := hex.DecodeString(tt.key)
+			require.NoError(t, err)
+			tweak, err := hex.DecodeString(tt.tweak)
+			require.NoError(t, err)
+			f, err := NewFF1(key, tt.radix)
+			require.NoError(t, err)
+
+			encrypted, err := f.Encrypt(ff1Numerals(tt.plaintext), tweak)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ciphertext, ff1String(encrypted))
+
+			decrypted, err := f.Decrypt(encrypted, tweak)
+			require.NoError(t, err)
+			assert.Equal(t, tt.plaintext, ff1String(decrypted))
+		})
+	}
+}
+
+func TestFF1_DomainTooSmall(t *testing.T) {
+	f, err := NewFF1(make([]byte, 16), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 6, f.MinLength())
+	_, err = f.Encrypt(ff1Numerals("12345"), nil)
+	require.ErrorIs(t, err, ErrFF1DomainTooSmall)
+}
@@ -0,0 +1,240 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locales
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	PackFormatJson = "json"
+	PackFormatCsv  = "csv"
+)
+
+// CSV pack files. Each file is optional, but the section must be complete if any of its files exist
+const (
+	csvFirstNamesMaleFile   = "first_names_male.csv"
+	csvFirstNamesFemaleFile = "first_names_female.csv"
+	csvLastNamesFile        = "last_names.csv"
+	csvTitlesMaleFile       = "titles_male.csv"
+	csvTitlesFemaleFile     = "titles_female.csv"
+	csvCompanyNamesFile     = "company_names.csv"
+	csvCompanySuffixesFile  = "company_suffixes.csv"
+	csvAddressesFile        = "addresses.csv"
+)
+
+var csvAddressesHeader = []string{"address", "city", "state", "postal_code", "latitude", "longitude"}
+
+// PackConfig - the user defined locale pack
+type PackConfig struct {
+	// Locale - the locale name. It overrides the locale of the JSON pack if set
+	Locale string `mapstructure:"locale" yaml:"locale" json:"locale,omitempty"`
+	// Path - the path to the JSON file or to the directory with CSV files
+	Path string `mapstructure:"path" yaml:"path" json:"path"`
+	// Format - json or csv. By default, the directory is read as CSV pack and the file as JSON pack
+	Format string `mapstructure:"format" yaml:"format" json:"format,omitempty"`
+}
+
+// LoadPacks - reads the user defined packs and registers them in the default registry
+func LoadPacks(cfgs []*PackConfig) error {
+	for _, cfg := range cfgs {
+		p, err := ReadPack(cfg)
+		if err != nil {
+			return fmt.Errorf("cannot read locale pack \"%s\": %w", cfg.Path, err)
+		}
+		if err = DefaultRegistry.Register(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPack - reads the pack in the format of the config
+func ReadPack(cfg *PackConfig) (*Pack, error) {
+	format := cfg.Format
+	if format == "" {
+		info, err := os.Stat(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		format = PackFormatJson
+		if info.IsDir() {
+			format = PackFormatCsv
+		}
+	}
+
+	var p *Pack
+	var err error
+	switch format {
+	case PackFormatJson:
+		p, err = readJsonPack(cfg.Path)
+	case PackFormatCsv:
+		p, err = readCsvPack(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown pack format \"%s\"", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Locale != "" {
+		p.Locale = cfg.Locale
+	}
+	return p, nil
+}
+
+func readJsonPack(path string) (*Pack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pack{}
+	if err = json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("cannot parse pack: %w", err)
+	}
+	return p, nil
+}
+
+func readCsvPack(dir string) (*Pack, error) {
+	p := &Pack{}
+
+	person := &PersonPack{}
+	personFiles := []struct {
+		name   string
+		values *[]string
+	}{
+		{csvFirstNamesMaleFile, &person.FirstNamesMale},
+		{csvFirstNamesFemaleFile, &person.FirstNamesFemale},
+		{csvLastNamesFile, &person.LastNames},
+		{csvTitlesMaleFile, &person.TitlesMale},
+		{csvTitlesFemaleFile, &person.TitlesFemale},
+	}
+	for _, f := range personFiles {
+		found, err := readCsvList(filepath.Join(dir, f.name), f.values)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			p.Person = person
+		}
+	}
+
+	company := &CompanyPack{}
+	companyFiles := []struct {
+		name   string
+		values *[]string
+	}{
+		{csvCompanyNamesFile, &company.Names},
+		{csvCompanySuffixesFile, &company.Suffixes},
+	}
+	for _, f := range companyFiles {
+		found, err := readCsvList(filepath.Join(dir, f.name), f.values)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			p.Company = company
+		}
+	}
+
+	addresses, err := readCsvAddresses(filepath.Join(dir, csvAddressesFile))
+	if err != nil {
+		return nil, err
+	}
+	p.Addresses = addresses
+	return p, nil
+}
+
+// readCsvList - reads the file with one value per line. It returns false if the file does not exist
+func readCsvList(path string, values *[]string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close() // nolint: errcheck
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 1
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("cannot read \"%s\": %w", path, err)
+		}
+		*values = append(*values, record[0])
+	}
+}
+
+// readCsvAddresses - reads the addresses file with the header. It returns nil if the file does not exist
+func readCsvAddresses(path string) ([]*Address, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint: errcheck
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(csvAddressesHeader)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read \"%s\" header: %w", path, err)
+	}
+	for idx, name := range csvAddressesHeader {
+		if header[idx] != name {
+			return nil, fmt.Errorf("unexpected \"%s\" header: expected %v", path, csvAddressesHeader)
+		}
+	}
+
+	var res []*Address
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read \"%s\": %w", path, err)
+		}
+		latitude, err := strconv.ParseFloat(record[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse latitude at line %d of \"%s\": %w", line, path, err)
+		}
+		longitude, err := strconv.ParseFloat(record[5], 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse longitude at line %d of \"%s\": %w", line, path, err)
+		}
+		res = append(res, &Address{
+			Address:    record[0],
+			City:       record[1],
+			State:      record[2],
+			PostalCode: record[3],
+			Latitude:   latitude,
+			Longitude:  longitude,
+		})
+	}
+}
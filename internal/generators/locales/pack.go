@@ -0,0 +1,197 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locales
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+)
+
+//go:embed packs/*.json
+var embeddedPacks embed.FS
+
+// Pack - the locale specific data used by the transformers. The sections that are not set are not supported by the
+// locale
+type Pack struct {
+	Locale    string       `json:"locale"`
+	Person    *PersonPack  `json:"person,omitempty"`
+	Company   *CompanyPack `json:"company,omitempty"`
+	Addresses []*Address   `json:"addresses,omitempty"`
+}
+
+type PersonPack struct {
+	FirstNamesMale   []string `json:"first_names_male"`
+	FirstNamesFemale []string `json:"first_names_female"`
+	LastNames        []string `json:"last_names"`
+	TitlesMale       []string `json:"titles_male"`
+	TitlesFemale     []string `json:"titles_female"`
+}
+
+type CompanyPack struct {
+	Names    []string `json:"names"`
+	Suffixes []string `json:"suffixes"`
+}
+
+type Address struct {
+	Address    string  `json:"address"`
+	City       string  `json:"city"`
+	State      string  `json:"state"`
+	PostalCode string  `json:"postal_code"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+}
+
+// Validate - checks that the sections of the pack are complete. The values are chosen by index from the lists, so
+// the lists must not be empty
+func (p *Pack) Validate() error {
+	if p.Locale == "" {
+		return fmt.Errorf("locale is required")
+	}
+	if p.Person != nil {
+		lists := []struct {
+			name   string
+			values []string
+		}{
+			{"first_names_male", p.Person.FirstNamesMale},
+			{"first_names_female", p.Person.FirstNamesFemale},
+			{"last_names", p.Person.LastNames},
+			{"titles_male", p.Person.TitlesMale},
+			{"titles_female", p.Person.TitlesFemale},
+		}
+		for _, l := range lists {
+			if len(l.values) == 0 {
+				return fmt.Errorf("person \"%s\" list is empty", l.name)
+			}
+		}
+	}
+	if p.Company != nil {
+		if len(p.Company.Names) == 0 {
+			return fmt.Errorf("company \"names\" list is empty")
+		}
+		if len(p.Company.Suffixes) == 0 {
+			return fmt.Errorf("company \"suffixes\" list is empty")
+		}
+	}
+	return nil
+}
+
+// merge - overrides the sections of the pack by the sections that are set in the other pack
+func (p *Pack) merge(other *Pack) {
+	if other.Person != nil {
+		p.Person = other.Person
+	}
+	if other.Company != nil {
+		p.Company = other.Company
+	}
+	if len(other.Addresses) > 0 {
+		p.Addresses = other.Addresses
+	}
+}
+
+// Registry - the locale packs by locale name
+type Registry struct {
+	mx    sync.RWMutex
+	packs map[string]*Pack
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		packs: make(map[string]*Pack),
+	}
+}
+
+// Register - adds the pack to the registry. If the pack of the locale is already registered, its sections are
+// overridden by the sections that are set in the new pack
+func (r *Registry) Register(p *Pack) error {
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid locale pack \"%s\": %w", p.Locale, err)
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if existing, ok := r.packs[p.Locale]; ok {
+		merged := *existing
+		merged.merge(p)
+		r.packs[p.Locale] = &merged
+		return nil
+	}
+	r.packs[p.Locale] = p
+	return nil
+}
+
+// Get - returns the pack of the locale
+func (r *Registry) Get(locale string) (*Pack, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	p, ok := r.packs[locale]
+	if !ok {
+		return nil, fmt.Errorf("locale \"%s\" is not found: available locales %v", locale, r.locales())
+	}
+	return p, nil
+}
+
+// Locales - returns the sorted list of the registered locales
+func (r *Registry) Locales() []string {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.locales()
+}
+
+func (r *Registry) locales() []string {
+	res := make([]string, 0, len(r.packs))
+	for l := range r.packs {
+		res = append(res, l)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// DefaultRegistry - the registry with the embedded packs. The user defined packs are added to it on startup
+var DefaultRegistry = NewRegistry()
+
+// Get - returns the pack of the locale from the default registry
+func Get(locale string) (*Pack, error) {
+	return DefaultRegistry.Get(locale)
+}
+
+func registerEmbeddedPacks(r *Registry) error {
+	files, err := embeddedPacks.ReadDir("packs")
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := embeddedPacks.ReadFile(path.Join("packs", f.Name()))
+		if err != nil {
+			return err
+		}
+		p := &Pack{}
+		if err = json.Unmarshal(data, p); err != nil {
+			return fmt.Errorf("cannot parse embedded pack \"%s\": %w", f.Name(), err)
+		}
+		if err = r.Register(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	if err := registerEmbeddedPacks(DefaultRegistry); err != nil {
+		panic(fmt.Sprintf("cannot register embedded locale packs: %s", err))
+	}
+}
@@ -0,0 +1,106 @@
+package locales
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedPacks(t *testing.T) {
+	assert.Equal(t, []string{"de_DE", "fr_FR", "ja_JP", "pt_BR"}, DefaultRegistry.Locales())
+	for _, locale := range DefaultRegistry.Locales() {
+		p, err := Get(locale)
+		require.NoError(t, err)
+		assert.Equal(t, locale, p.Locale)
+		require.NotNil(t, p.Person, locale)
+		require.NotNil(t, p.Company, locale)
+		assert.NotEmpty(t, p.Addresses, locale)
+	}
+
+	_, err := Get("xx_XX")
+	require.ErrorContains(t, err, `locale "xx_XX" is not found`)
+}
+
+func TestReadPack(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pack.json")
+		data := `{
+			"locale": "es_ES",
+			"company": {"names": ["Iberia"], "suffixes": ["S.A."]},
+			"addresses": [{"address": "Calle Mayor 1", "city": "Madrid", "state": "MD", "postal_code": "28013",
+				"latitude": 40.41, "longitude": -3.70}]
+		}`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+		p, err := ReadPack(&PackConfig{Path: path})
+		require.NoError(t, err)
+		assert.Equal(t, "es_ES", p.Locale)
+		assert.Nil(t, p.Person)
+		assert.Equal(t, &CompanyPack{Names: []string{"Iberia"}, Suffixes: []string{"S.A."}}, p.Company)
+		require.Len(t, p.Addresses, 1)
+		assert.Equal(t, "Madrid", p.Addresses[0].City)
+
+		p, err = ReadPack(&PackConfig{Path: path, Locale: "es_MX"})
+		require.NoError(t, err)
+		assert.Equal(t, "es_MX", p.Locale)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		dir := t.TempDir()
+		files := map[string]string{
+			csvCompanyNamesFile:    "Iberia\n\"Telefonica, Grupo\"\n",
+			csvCompanySuffixesFile: "S.A.\n",
+			csvAddressesFile: "address,city,state,postal_code,latitude,longitude\n" +
+				"Calle Mayor 1,Madrid,MD,28013,40.41,-3.70\n",
+		}
+		for name, data := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0600))
+		}
+
+		p, err := ReadPack(&PackConfig{Path: dir, Locale: "es_ES"})
+		require.NoError(t, err)
+		assert.Equal(t, "es_ES", p.Locale)
+		assert.Nil(t, p.Person)
+		assert.Equal(t, []string{"Iberia", "Telefonica, Grupo"}, p.Company.Names)
+		assert.Equal(t, []*Address{{
+			Address: "Calle Mayor 1", City: "Madrid", State: "MD", PostalCode: "28013", Latitude: 40.41, Longitude: -3.70,
+		}}, p.Addresses)
+	})
+
+	t.Run("wrong addresses header", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(
+			filepath.Join(dir, csvAddressesFile), []byte("street,city,state,postal_code,latitude,longitude\n"), 0600,
+		))
+		_, err := ReadPack(&PackConfig{Path: dir, Locale: "es_ES"})
+		require.ErrorContains(t, err, "unexpected")
+	})
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, registerEmbeddedPacks(r))
+
+	t.Run("incomplete section", func(t *testing.T) {
+		err := r.Register(&Pack{Locale: "de_DE", Company: &CompanyPack{Names: []string{"Acme"}}})
+		require.ErrorContains(t, err, `company "suffixes" list is empty`)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		embedded, err := r.Get("de_DE")
+		require.NoError(t, err)
+		company := &CompanyPack{Names: []string{"Acme"}, Suffixes: []string{"GmbH"}}
+		require.NoError(t, r.Register(&Pack{Locale: "de_DE", Company: company}))
+
+		p, err := r.Get("de_DE")
+		require.NoError(t, err)
+		assert.Same(t, company, p.Company)
+		assert.Same(t, embedded.Person, p.Person)
+		assert.Equal(t, embedded.Addresses, p.Addresses)
+		// The registered pack is not changed
+		assert.NotSame(t, company, embedded.Company)
+	})
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/greenmaskio/greenmask/internal/generators"
+)
+
+const (
+	FpeKeyEnvPrefix  = "env:"
+	FpeKeyFilePrefix = "file:"
+)
+
+// ErrFpeValueTooShort - the value does not contain enough alphabet characters out of the preserved prefix and suffix
+// to be encrypted
+var ErrFpeValueTooShort = errors.New("value is too short for format-preserving encryption")
+
+// FpeTransformer - format-preserving encryption of the strings. Only the characters from the alphabet are encrypted,
+// the rest of the characters and the preserved prefix and suffix are kept as is. The result has the same length and
+// alphabet as the original value.
+type FpeTransformer struct {
+	ff1            *generators.FF1
+	alphabet       []rune
+	alphabetIdx    map[rune]uint16
+	preservePrefix int
+	preserveSuffix int
+	numerals       []uint16
+	positions      []int
+}
+
+func NewFpeTransformer(key []byte, alphabet []rune, preservePrefix, preserveSuffix int) (*FpeTransformer, error) {
+	if len(alphabet) < 2 {
+		return nil, errors.New("alphabet must contain at least 2 characters")
+	}
+	alphabetIdx := make(map[rune]uint16, len(alphabet))
+	for idx, c := range alphabet {
+		if _, ok := alphabetIdx[c]; ok {
+			return nil, fmt.Errorf("alphabet contains duplicate character '%c'", c)
+		}
+		alphabetIdx[c] = uint16(idx)
+	}
+	if preservePrefix < 0 || preserveSuffix < 0 {
+		return nil, errors.New("preserved prefix and suffix length cannot be negative")
+	}
+
+	ff1, err := generators.NewFF1(key, len(alphabet))
+	if err != nil {
+		return nil, err
+	}
+	return &FpeTransformer{
+		ff1:            ff1,
+		alphabet:       alphabet,
+		alphabetIdx:    alphabetIdx,
+		preservePrefix: preservePrefix,
+		preserveSuffix: preserveSuffix,
+	}, nil
+}
+
+// Encrypt - encrypt the value using tweak
+func (ft *FpeTransformer) Encrypt(value []rune, tweak []byte) ([]rune, error) {
+	return ft.transform(value, tweak, ft.ff1.Encrypt)
+}
+
+// Decrypt - decrypt the value encrypted with the same key, alphabet, preserved prefix and suffix and tweak
+func (ft *FpeTransformer) Decrypt(value []rune, tweak []byte) ([]rune, error) {
+	return ft.transform(value, tweak, ft.ff1.Decrypt)
+}
+
+func (ft *FpeTransformer) transform(
+	value []rune, tweak []byte, f func(x []uint16, tweak []byte) ([]uint16, error),
+) ([]rune, error) {
+	if ft.preservePrefix+ft.preserveSuffix >= len(value) {
+		return nil, fmt.Errorf(
+			"%w: value length %d must be greater than preserved prefix and suffix length %d",
+			ErrFpeValueTooShort, len(value), ft.preservePrefix+ft.preserveSuffix,
+		)
+	}
+	ft.numerals = ft.numerals[:0]
+	ft.positions = ft.positions[:0]
+	for pos := ft.preservePrefix; pos < len(value)-ft.preserveSuffix; pos++ {
+		if idx, ok := ft.alphabetIdx[value[pos]]; ok {
+			ft.numerals = append(ft.numerals, idx)
+			ft.positions = append(ft.positions, pos)
+		}
+	}
+	if len(ft.numerals) < ft.MinLength() {
+		return nil, fmt.Errorf(
+			"%w: value contains %d alphabet characters, minimal is %d",
+			ErrFpeValueTooShort, len(ft.numerals), ft.MinLength(),
+		)
+	}
+
+	numerals, err := f(ft.numerals, tweak)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]rune, len(value))
+	copy(res, value)
+	for i, pos := range ft.positions {
+		res[pos] = ft.alphabet[numerals[i]]
+	}
+	return res, nil
+}
+
+// MinLength - the minimal number of alphabet characters in the value that can be encrypted
+func (ft *FpeTransformer) MinLength() int {
+	return ft.ff1.MinLength()
+}
+
+// ResolveFpeKey - get the AES key by the reference. The reference might be "env:VAR_NAME" or "file:/path/to/key"
+// containing hex encoded 16, 24 or 32 bytes key. If the reference is empty the key is derived from the global salt
+func ResolveFpeKey(ref string, salt []byte) ([]byte, error) {
+	var keyHex string
+	switch {
+	case ref == "":
+		if len(salt) == 0 {
+			return nil, errors.New("key is not provided and global salt is not set")
+		}
+		key := sha256.Sum256(salt)
+		return key[:], nil
+	case strings.HasPrefix(ref, FpeKeyEnvPrefix):
+		name := strings.TrimPrefix(ref, FpeKeyEnvPrefix)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable \"%s\" is not set", name)
+		}
+		keyHex = v
+	case strings.HasPrefix(ref, FpeKeyFilePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(ref, FpeKeyFilePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read key file: %w", err)
+		}
+		keyHex = string(data)
+	default:
+		return nil, fmt.Errorf(
+			"unknown key reference \"%s\": expected %s or %s prefix", ref, FpeKeyEnvPrefix, FpeKeyFilePrefix,
+		)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(keyHex))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode hex encoded key: %w", err)
+	}
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("key must be 16, 24 or 32 bytes length: got %d", len(key))
+	}
+	return key, nil
+}
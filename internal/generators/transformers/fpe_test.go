@@ -0,0 +1,117 @@
+package transformers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFpeTransformer_EncryptDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef")
+
+	tests := []struct {
+		name           string
+		alphabet       string
+		preservePrefix int
+		preserveSuffix int
+		value          string
+		tweak          []byte
+		expectedFixed  map[int]rune
+	}{
+		{
+			name:     "digits",
+			alphabet: "0123456789",
+			value:    "4111111111111111",
+		},
+		{
+			name:          "formatted phone number",
+			alphabet:      "0123456789",
+			value:         "+1 (555) 123-4567",
+			expectedFixed: map[int]rune{0: '+', 2: ' ', 3: '(', 7: ')', 8: ' ', 12: '-'},
+		},
+		{
+			name:           "preserved prefix and suffix",
+			alphabet:       "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+			preservePrefix: 2,
+			preserveSuffix: 2,
+			value:          "DE89370400440532013000",
+			tweak:          []byte("42"),
+			expectedFixed:  map[int]rune{0: 'D', 1: 'E', 20: '0', 21: '0'},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft, err := NewFpeTransformer(key, []rune(tt.alphabet), tt.preservePrefix, tt.preserveSuffix)
+			require.NoError(t, err)
+
+			encrypted, err := ft.Encrypt([]rune(tt.value), tt.tweak)
+			require.NoError(t, err)
+			assert.Len(t, encrypted, len([]rune(tt.value)))
+			assert.NotEqual(t, tt.value, string(encrypted))
+			for pos, c := range tt.expectedFixed {
+				assert.Equal(t, c, encrypted[pos])
+			}
+
+			decrypted, err := ft.Decrypt(encrypted, tt.tweak)
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, string(decrypted))
+
+			// Another tweak produces another result
+			encryptedWithTweak, err := ft.Encrypt([]rune(tt.value), []byte("another"))
+			require.NoError(t, err)
+			assert.NotEqual(t, string(encrypted), string(encryptedWithTweak))
+		})
+	}
+}
+
+func TestFpeTransformer_Errors(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	_, err := NewFpeTransformer(key, []rune("0"), 0, 0)
+	require.Error(t, err)
+	_, err = NewFpeTransformer(key, []rune("0120"), 0, 0)
+	require.Error(t, err)
+
+	ft, err := NewFpeTransformer(key, []rune("0123456789"), 2, 2)
+	require.NoError(t, err)
+	_, err = ft.Encrypt([]rune("1234"), nil)
+	require.ErrorIs(t, err, ErrFpeValueTooShort)
+	_, err = ft.Encrypt([]rune(""), nil)
+	require.ErrorIs(t, err, ErrFpeValueTooShort)
+	// Only 5 digits are encrypted - the domain is too small
+	_, err = ft.Encrypt([]rune("12-345"), nil)
+	require.ErrorIs(t, err, ErrFpeValueTooShort)
+}
+
+func TestResolveFpeKey(t *testing.T) {
+	keyHex := "2b7e151628aed2a6abf7158809cf4f3c"
+	expected := []byte{0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c}
+
+	t.Setenv("TEST_FPE_KEY", keyHex)
+	key, err := ResolveFpeKey("env:TEST_FPE_KEY", nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, key)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(keyHex+"\n"), 0600))
+	key, err = ResolveFpeKey("file:"+keyFile, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, key)
+
+	key, err = ResolveFpeKey("", []byte("salt"))
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	_, err = ResolveFpeKey("", nil)
+	require.Error(t, err)
+	_, err = ResolveFpeKey("env:TEST_FPE_KEY_NOT_SET", nil)
+	require.Error(t, err)
+	_, err = ResolveFpeKey("unknown:value", nil)
+	require.Error(t, err)
+	t.Setenv("TEST_FPE_KEY", "abcd")
+	_, err = ResolveFpeKey("env:TEST_FPE_KEY", nil)
+	require.Error(t, err)
+}
@@ -30,6 +30,11 @@ func (rc *RandomChoiceTransformer) Transform(original []byte) (*toolkit.RawValue
 	return rc.values[idx], nil
 }
 
+// AddValue - add the value to the list of choices
+func (rc *RandomChoiceTransformer) AddValue(v *toolkit.RawValue) {
+	rc.values = append(rc.values, v)
+}
+
 func (rc *RandomChoiceTransformer) GetRequiredGeneratorByteLength() int {
 	return rc.byteLength
 }
@@ -6,6 +6,7 @@ import (
 	"slices"
 
 	"github.com/greenmaskio/greenmask/internal/generators"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 )
 
 var DefaultCompanyNames = []string{
@@ -74,6 +75,14 @@ var DefaultCompanyMap = map[string][]string{
 
 var DefaultCompanyDb = NewCompanyDatabase(DefaultCompanyMap)
 
+// NewCompanyMap - builds the company database from the locale pack
+func NewCompanyMap(p *locales.CompanyPack) map[string][]string {
+	return map[string][]string{
+		"CompanySuffix": p.Suffixes,
+		"CompanyName":   p.Names,
+	}
+}
+
 type RandomCompanyTransformer struct {
 	byteLength int
 	generator  generators.Generator
@@ -6,6 +6,7 @@ import (
 	"slices"
 
 	"github.com/greenmaskio/greenmask/internal/generators"
+	"github.com/greenmaskio/greenmask/internal/generators/locales"
 )
 
 const (
@@ -149,6 +150,8 @@ func NewPersonalDatabase(data Database) *PersonDatabase {
 	}
 
 	slices.Sort(attributes)
+	// The gender is chosen by index, so the order must be stable for the deterministic generation
+	slices.Sort(genders)
 
 	return &PersonDatabase{
 		Db:              data,
@@ -173,6 +176,22 @@ var DefaultPersonMap = map[string]map[string][]string{
 
 var DefaultDb = NewPersonalDatabase(DefaultPersonMap)
 
+// NewPersonMap - builds the person database from the locale pack
+func NewPersonMap(p *locales.PersonPack) Database {
+	return Database{
+		MaleGenderName: {
+			"Title":     p.TitlesMale,
+			"LastName":  p.LastNames,
+			"FirstName": p.FirstNamesMale,
+		},
+		FemaleGenderName: {
+			"Title":     p.TitlesFemale,
+			"LastName":  p.LastNames,
+			"FirstName": p.FirstNamesFemale,
+		},
+	}
+}
+
 type NameAttrs struct {
 	FirstName string
 	LastName  string
@@ -0,0 +1,70 @@
+package generators
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const DefaultUniqueMaxRetries = 100
+
+var ErrUniqueValueExhausted = errors.New("unable to generate unique value")
+
+// UniqueValues - guarantees uniqueness of the generated values within a single transformer instance (column). It
+// stores every generated value and in case of collision regenerates the value using the original data extended
+// with the attempt number. The same original value always gets the same generated value, so the hash engine
+// stays deterministic for repeated input values.
+type UniqueValues struct {
+	// seen - generated value -> original value that produced it
+	seen       map[string][]byte
+	maxRetries int
+	buf        []byte
+	nulls      uint64
+}
+
+func NewUniqueValues(maxRetries int) *UniqueValues {
+	return &UniqueValues{
+		seen:       make(map[string][]byte),
+		maxRetries: maxRetries,
+	}
+}
+
+// Generate - call gen until it returns a value that was not produced for another original value
+func (u *UniqueValues) Generate(original []byte, gen func(data []byte) ([]byte, error)) ([]byte, error) {
+	data := original
+	for attempt := 0; attempt <= u.maxRetries; attempt++ {
+		if attempt > 0 {
+			u.buf = append(u.buf[:0], original...)
+			u.buf = append(u.buf, 0)
+			u.buf = binary.AppendUvarint(u.buf, uint64(attempt))
+			data = u.buf
+		}
+		res, err := gen(data)
+		if err != nil {
+			return nil, err
+		}
+		producedBy, ok := u.seen[string(res)]
+		if !ok {
+			u.seen[string(res)] = slices.Clone(original)
+			return res, nil
+		}
+		if slices.Equal(producedBy, original) {
+			return res, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: all %d attempts collided", ErrUniqueValueExhausted, u.maxRetries+1)
+}
+
+// GenerateForNull - generate a unique value for NULL input. Every NULL is treated as a distinct value, so the data
+// for the generator is derived from the sequence number of the NULL value
+func (u *UniqueValues) GenerateForNull(gen func(data []byte) ([]byte, error)) ([]byte, error) {
+	u.nulls++
+	original := binary.AppendUvarint([]byte{0, 'n', 'u', 'l', 'l'}, u.nulls)
+	return u.Generate(original, gen)
+}
+
+// Len - number of tracked unique values
+func (u *UniqueValues) Len() int {
+	return len(u.seen)
+}
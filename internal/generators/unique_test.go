@@ -0,0 +1,56 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniqueValues_Generate(t *testing.T) {
+	// The generator that maps any input into the small domain - a lot of collisions
+	g, err := GetHashBytesGen([]byte{}, 1)
+	require.NoError(t, err)
+	gen := func(data []byte) ([]byte, error) {
+		res, err := g.Generate(data)
+		if err != nil {
+			return nil, err
+		}
+		return []byte{res[0] % 16}, nil
+	}
+
+	u := NewUniqueValues(DefaultUniqueMaxRetries)
+	generated := make(map[byte][]byte)
+	for i := 0; i < 16; i++ {
+		res, err := u.Generate([]byte{byte(i)}, gen)
+		require.NoError(t, err)
+		_, ok := generated[res[0]]
+		require.Falsef(t, ok, "duplicate value %d", res[0])
+		generated[res[0]] = []byte{byte(i)}
+	}
+	assert.Equal(t, 16, u.Len())
+
+	// The same original value produces the same generated value
+	for v, original := range generated {
+		res, err := u.Generate(original, gen)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{v}, res)
+	}
+
+	// The domain is exhausted
+	_, err = u.Generate([]byte{255}, gen)
+	require.ErrorIs(t, err, ErrUniqueValueExhausted)
+}
+
+func TestUniqueValues_GenerateForNull(t *testing.T) {
+	g, err := GetHashBytesGen([]byte{}, 8)
+	require.NoError(t, err)
+
+	u := NewUniqueValues(DefaultUniqueMaxRetries)
+	first, err := u.GenerateForNull(g.Generate)
+	require.NoError(t, err)
+	first = append([]byte{}, first...)
+	second, err := u.GenerateForNull(g.Generate)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
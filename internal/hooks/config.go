@@ -0,0 +1,95 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	HookTypeHttp = "http"
+	HookTypeCmd  = "cmd"
+)
+
+const (
+	defaultHttpMethod    = "POST"
+	defaultTimeout       = 10 * time.Second
+	defaultRetryInterval = time.Second
+)
+
+// Config - the hook that receives the events. The event is sent as JSON in the HTTP request body or passed to the
+// command stdin
+type Config struct {
+	Name string `mapstructure:"name" yaml:"name" json:"name"`
+	// Type - the hook type: http or cmd
+	Type string `mapstructure:"type" yaml:"type" json:"type"`
+	// Events - the event types the hook receives. All the events are sent if empty
+	Events []string `mapstructure:"events" yaml:"events" json:"events,omitempty"`
+	// Url - the endpoint of the http hook
+	Url string `mapstructure:"url" yaml:"url" json:"url,omitempty"`
+	// Method - the HTTP method of the http hook. Default is POST
+	Method string `mapstructure:"method" yaml:"method" json:"method,omitempty"`
+	// Headers - the additional HTTP headers of the http hook
+	Headers map[string]string `mapstructure:"headers" yaml:"headers" json:"headers,omitempty"`
+	// Executable - the executable of the cmd hook
+	Executable string `mapstructure:"executable" yaml:"executable" json:"executable,omitempty"`
+	// Args - the arguments of the cmd hook executable
+	Args []string `mapstructure:"args" yaml:"args" json:"args,omitempty"`
+	// Timeout - the timeout of the single attempt. Default is 10s
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout" json:"timeout,omitempty"`
+	// Retries - the number of the retries after the failed attempt
+	Retries int `mapstructure:"retries" yaml:"retries" json:"retries,omitempty"`
+	// RetryInterval - the delay between the attempts. Default is 1s
+	RetryInterval time.Duration `mapstructure:"retry_interval" yaml:"retry_interval" json:"retry_interval,omitempty"`
+}
+
+// Validate - checks the hook settings and sets the defaults
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("hook name is required")
+	}
+	switch c.Type {
+	case HookTypeHttp:
+		if c.Url == "" {
+			return fmt.Errorf("\"url\" is required for %s hook", HookTypeHttp)
+		}
+		if c.Method == "" {
+			c.Method = defaultHttpMethod
+		}
+	case HookTypeCmd:
+		if c.Executable == "" {
+			return fmt.Errorf("\"executable\" is required for %s hook", HookTypeCmd)
+		}
+	default:
+		return fmt.Errorf("unknown hook type \"%s\"", c.Type)
+	}
+	for _, e := range c.Events {
+		if !slices.Contains(EventTypes, e) {
+			return fmt.Errorf("unknown event type \"%s\"", e)
+		}
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("\"retries\" cannot be negative")
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = defaultRetryInterval
+	}
+	return nil
+}
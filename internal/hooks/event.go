@@ -0,0 +1,100 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"time"
+
+	"github.com/greenmaskio/greenmask/pkg/toolkit"
+)
+
+const (
+	EventDumpStarted           = "dump.started"
+	EventDumpCompleted         = "dump.completed"
+	EventDumpFailed            = "dump.failed"
+	EventDumpTableCompleted    = "dump.table_completed"
+	EventRestoreStarted        = "restore.started"
+	EventRestoreCompleted      = "restore.completed"
+	EventRestoreFailed         = "restore.failed"
+	EventRestoreTableCompleted = "restore.table_completed"
+	EventValidationWarnings    = "validation.warnings"
+)
+
+// EventTypes - all the supported event types
+var EventTypes = []string{
+	EventDumpStarted,
+	EventDumpCompleted,
+	EventDumpFailed,
+	EventDumpTableCompleted,
+	EventRestoreStarted,
+	EventRestoreCompleted,
+	EventRestoreFailed,
+	EventRestoreTableCompleted,
+	EventValidationWarnings,
+}
+
+// Event - the lifecycle event that is sent to the hooks as JSON
+type Event struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	DumpId string    `json:"dump_id,omitempty"`
+	// Schema and Table - the table of the per-table events
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table,omitempty"`
+	// OriginalSize and CompressedSize - the size of the dumped data in bytes
+	OriginalSize   int64                      `json:"original_size,omitempty"`
+	CompressedSize int64                      `json:"compressed_size,omitempty"`
+	DurationMs     int64                      `json:"duration_ms,omitempty"`
+	Error          string                     `json:"error,omitempty"`
+	Warnings       toolkit.ValidationWarnings `json:"warnings,omitempty"`
+}
+
+func NewEvent(eventType, dumpId string) *Event {
+	return &Event{
+		Type:   eventType,
+		Time:   time.Now(),
+		DumpId: dumpId,
+	}
+}
+
+// SetDuration - set the duration since the start time
+func (e *Event) SetDuration(startedAt time.Time) *Event {
+	e.DurationMs = time.Since(startedAt).Milliseconds()
+	return e
+}
+
+func (e *Event) SetTable(schema, table string) *Event {
+	e.Schema = schema
+	e.Table = table
+	return e
+}
+
+func (e *Event) SetSize(original, compressed int64) *Event {
+	e.OriginalSize = original
+	e.CompressedSize = compressed
+	return e
+}
+
+func (e *Event) SetError(err error) *Event {
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+func (e *Event) SetWarnings(warnings toolkit.ValidationWarnings) *Event {
+	e.Warnings = warnings
+	return e
+}
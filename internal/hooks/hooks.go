@@ -0,0 +1,138 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// eventsQueueSize - the number of the events that can be emitted without waiting for the delivery
+const eventsQueueSize = 1024
+
+type encodedEvent struct {
+	eventType string
+	payload   []byte
+}
+
+type hook struct {
+	cfg    *Config
+	sender Sender
+}
+
+// Hooks - delivers the events to the hooks in the background. The events are delivered in the emitting order. The
+// delivery errors are logged and do not break the dump or restoration
+type Hooks struct {
+	hooks  []*hook
+	events chan *encodedEvent
+	done   chan struct{}
+}
+
+// New - validates the hooks and starts the delivery. The nil value is returned if there are no hooks
+func New(cfgs []*Config) (*Hooks, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	h := &Hooks{
+		events: make(chan *encodedEvent, eventsQueueSize),
+		done:   make(chan struct{}),
+	}
+	names := make(map[string]struct{}, len(cfgs))
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("hook \"%s\" validation error: %w", cfg.Name, err)
+		}
+		if _, ok := names[cfg.Name]; ok {
+			return nil, fmt.Errorf("hook \"%s\" is defined more than once", cfg.Name)
+		}
+		names[cfg.Name] = struct{}{}
+		sender, err := newSender(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("hook \"%s\": %w", cfg.Name, err)
+		}
+		h.hooks = append(h.hooks, &hook{cfg: cfg, sender: sender})
+	}
+	go h.run()
+	return h, nil
+}
+
+// Emit - queue the event for the delivery. The event is encoded immediately, so it can be changed after the call. It
+// does nothing if the hooks are not set
+func (h *Hooks) Emit(e *Event) {
+	if h == nil {
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Warn().Err(err).Str("Event", e.Type).Msg("cannot encode hook event")
+		return
+	}
+	h.events <- &encodedEvent{eventType: e.Type, payload: payload}
+}
+
+// Close - waits until all the emitted events are delivered. It does nothing if the hooks are not set
+func (h *Hooks) Close() {
+	if h == nil {
+		return
+	}
+	close(h.events)
+	<-h.done
+}
+
+func (h *Hooks) run() {
+	defer close(h.done)
+	for e := range h.events {
+		for _, hk := range h.hooks {
+			if len(hk.cfg.Events) > 0 && !slices.Contains(hk.cfg.Events, e.eventType) {
+				continue
+			}
+			if err := hk.send(e.eventType, e.payload); err != nil {
+				log.Warn().
+					Err(err).
+					Str("Hook", hk.cfg.Name).
+					Str("Event", e.eventType).
+					Msg("cannot deliver hook event")
+			}
+		}
+	}
+}
+
+// send - sends the event with retries. The delivery is not bound to the command context, so the failure events are
+// delivered after the cancellation too
+func (hk *hook) send(eventType string, payload []byte) error {
+	var err error
+	for attempt := 0; attempt <= hk.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			log.Debug().
+				Err(err).
+				Str("Hook", hk.cfg.Name).
+				Int("Attempt", attempt).
+				Msg("retrying hook event delivery")
+			time.Sleep(hk.cfg.RetryInterval)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), hk.cfg.Timeout)
+		err = hk.sender.Send(ctx, eventType, payload)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr string
+	}{
+		{name: "without name", cfg: &Config{Type: HookTypeHttp, Url: "http://localhost"}, wantErr: "name is required"},
+		{name: "unknown type", cfg: &Config{Name: "a", Type: "smtp"}, wantErr: "unknown hook type"},
+		{name: "http without url", cfg: &Config{Name: "a", Type: HookTypeHttp}, wantErr: "\"url\" is required"},
+		{name: "cmd without executable", cfg: &Config{Name: "a", Type: HookTypeCmd}, wantErr: "\"executable\" is required"},
+		{
+			name:    "unknown event",
+			cfg:     &Config{Name: "a", Type: HookTypeHttp, Url: "http://localhost", Events: []string{"dump.paused"}},
+			wantErr: "unknown event type",
+		},
+		{
+			name:    "negative retries",
+			cfg:     &Config{Name: "a", Type: HookTypeHttp, Url: "http://localhost", Retries: -1},
+			wantErr: "cannot be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.cfg.Validate(), tt.wantErr)
+		})
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg := &Config{Name: "a", Type: HookTypeHttp, Url: "http://localhost"}
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, defaultHttpMethod, cfg.Method)
+		assert.Equal(t, defaultTimeout, cfg.Timeout)
+		assert.Equal(t, defaultRetryInterval, cfg.RetryInterval)
+	})
+}
+
+func TestNew(t *testing.T) {
+	h, err := New(nil)
+	require.NoError(t, err)
+	assert.Nil(t, h)
+	// The nil hooks do nothing
+	h.Emit(NewEvent(EventDumpStarted, "1"))
+	h.Close()
+
+	_, err = New([]*Config{
+		{Name: "a", Type: HookTypeHttp, Url: "http://localhost"},
+		{Name: "a", Type: HookTypeHttp, Url: "http://localhost"},
+	})
+	require.ErrorContains(t, err, "defined more than once")
+}
+
+func TestHooks_Http(t *testing.T) {
+	var mx sync.Mutex
+	var received []*Event
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		calls++
+		// The first attempt fails to check the retries
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		e := &Event{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(e))
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	h, err := New([]*Config{{
+		Name:          "webhook",
+		Type:          HookTypeHttp,
+		Url:           srv.URL,
+		Headers:       map[string]string{"X-Token": "secret"},
+		Events:        []string{EventDumpCompleted, EventDumpFailed},
+		Retries:       1,
+		RetryInterval: time.Millisecond,
+	}})
+	require.NoError(t, err)
+
+	h.Emit(NewEvent(EventDumpStarted, "1"))
+	h.Emit(NewEvent(EventDumpFailed, "1").SetError(errors.New("connection refused")))
+	h.Emit(NewEvent(EventDumpCompleted, "2").SetSize(100, 10))
+	h.Close()
+
+	require.Len(t, received, 2)
+	assert.Equal(t, EventDumpFailed, received[0].Type)
+	assert.Equal(t, "connection refused", received[0].Error)
+	assert.Equal(t, EventDumpCompleted, received[1].Type)
+	assert.Equal(t, "2", received[1].DumpId)
+	assert.Equal(t, int64(100), received[1].OriginalSize)
+	assert.Equal(t, int64(10), received[1].CompressedSize)
+}
+
+func TestHttpSender_Send_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, "bad payload\n")
+	}))
+	defer srv.Close()
+
+	hk := &hook{
+		cfg:    &Config{Name: "webhook", Method: http.MethodPost, Url: srv.URL, Retries: 2, Timeout: time.Second},
+		sender: &HttpSender{cfg: &Config{Method: http.MethodPost, Url: srv.URL}, client: srv.Client()},
+	}
+	err := hk.send(EventDumpStarted, []byte("{}"))
+	require.ErrorContains(t, err, "unexpected response status 400: bad payload")
+}
+
+func TestCmdSender_Send(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event.json")
+	s := &CmdSender{cfg: &Config{
+		Executable: "sh",
+		Args:       []string{"-c", `cat > "$0" && echo "$GREENMASK_HOOK_EVENT" >> "$0"`, out},
+	}}
+	require.NoError(t, s.Send(t.Context(), EventRestoreCompleted, []byte(`{"type":"restore.completed"}`)))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"type\":\"restore.completed\"}restore.completed\n", string(data))
+
+	s = &CmdSender{cfg: &Config{Executable: "sh", Args: []string{"-c", "echo failed >&2; exit 1"}}}
+	require.ErrorContains(t, s.Send(t.Context(), EventRestoreCompleted, nil), "failed")
+}
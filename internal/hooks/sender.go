@@ -0,0 +1,96 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// eventEnvVariable - the environment variable of the cmd hook that contains the event type
+const eventEnvVariable = "GREENMASK_HOOK_EVENT"
+
+// maxResponseErrorSize - the max size of the response body or stderr that is added to the error
+const maxResponseErrorSize = 1024
+
+// Sender - delivers the encoded event
+type Sender interface {
+	Send(ctx context.Context, eventType string, payload []byte) error
+}
+
+func newSender(cfg *Config) (Sender, error) {
+	switch cfg.Type {
+	case HookTypeHttp:
+		return &HttpSender{cfg: cfg, client: &http.Client{}}, nil
+	case HookTypeCmd:
+		return &CmdSender{cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("unknown hook type \"%s\"", cfg.Type)
+}
+
+// HttpSender - sends the event in the request body. The response with status code other than 2xx is an error
+type HttpSender struct {
+	cfg    *Config
+	client *http.Client
+}
+
+func (s *HttpSender) Send(ctx context.Context, _ string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, s.cfg.Method, s.cfg.Url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send request: %w", err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseErrorSize))
+		return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// CmdSender - runs the command and passes the event to its stdin. The event type is set to the GREENMASK_HOOK_EVENT
+// environment variable
+type CmdSender struct {
+	cfg *Config
+}
+
+func (s *CmdSender) Send(ctx context.Context, eventType string, payload []byte) error {
+	cmd := exec.CommandContext(ctx, s.cfg.Executable, s.cfg.Args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", eventEnvVariable, eventType))
+	cmd.Stdin = bytes.NewReader(payload)
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		msg := stderr.String()
+		if len(msg) > maxResponseErrorSize {
+			msg = msg[:maxResponseErrorSize]
+		}
+		return fmt.Errorf("command error: %w: %s", err, strings.TrimSpace(msg))
+	}
+	return nil
+}
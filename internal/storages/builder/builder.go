@@ -24,6 +24,7 @@ import (
 	"github.com/greenmaskio/greenmask/internal/storages"
 	"github.com/greenmaskio/greenmask/internal/storages/azure"
 	"github.com/greenmaskio/greenmask/internal/storages/directory"
+	"github.com/greenmaskio/greenmask/internal/storages/encryption"
 	"github.com/greenmaskio/greenmask/internal/storages/s3"
 	sshstorage "github.com/greenmaskio/greenmask/internal/storages/ssh"
 )
@@ -35,8 +36,31 @@ const (
 	SSHStorageType       = "ssh"
 )
 
+// GetStorage - creates storage by the provided config. The storage is always wrapped into encryption.Storage that
+// encrypts objects if storage.encryption is configured and reports clear error when encrypted dump is read without
+// the key
 func GetStorage(ctx context.Context, stCfg *domains.StorageConfig, logCgf *domains.LogConfig) (
 	storages.Storager, error,
+) {
+	st, err := getBackend(ctx, stCfg, logCgf)
+	if err != nil {
+		return nil, err
+	}
+	if stCfg.Encryption == nil {
+		stCfg.Encryption = encryption.NewConfig()
+	}
+	encSt, err := encryption.NewStorage(st, stCfg.Encryption)
+	if err != nil {
+		if err := st.Close(); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("error closing storage")
+		}
+		return nil, fmt.Errorf("storage encryption config validation failed: %w", err)
+	}
+	return encSt, nil
+}
+
+func getBackend(ctx context.Context, stCfg *domains.StorageConfig, logCgf *domains.LogConfig) (
+	storages.Storager, error,
 ) {
 	log.Ctx(ctx).Debug().Str("type", stCfg.Type).Msg("creating storage")
 
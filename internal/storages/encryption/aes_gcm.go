@@ -0,0 +1,241 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	keySize     = 32
+	segmentSize = 64 * 1024
+	keyIdSize   = 8
+)
+
+var ErrWrongKey = errors.New("wrong encryption key")
+
+// aesGcmCipher - envelope encryption with AES-256-GCM. Each object is encrypted with a random data key which is
+// sealed by the master key and stored in the object prefix. The payload is split into segments that are sealed
+// separately (STREAM construction) so the objects of any size might be encrypted and decrypted on the fly.
+type aesGcmCipher struct {
+	kek   cipher.AEAD
+	keyId string
+}
+
+func newAesGcmCipher(cfg *Config) (*aesGcmCipher, error) {
+	var encodedKey string
+	if cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read key file: %w", err)
+		}
+		encodedKey = string(data)
+	} else {
+		var ok bool
+		encodedKey, ok = os.LookupEnv(cfg.KeyEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", cfg.KeyEnv)
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64 encoded: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes length but got %d", keySize, len(key))
+	}
+	kek, err := newAead(key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &aesGcmCipher{
+		kek:   kek,
+		keyId: hex.EncodeToString(sum[:keyIdSize]),
+	}, nil
+}
+
+func (c *aesGcmCipher) name() string {
+	return CipherAes256Gcm
+}
+
+func (c *aesGcmCipher) id() string {
+	return c.keyId
+}
+
+func (c *aesGcmCipher) seal(src io.Reader, h *header) (io.ReadCloser, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("cannot generate data key: %w", err)
+	}
+	nonce := make([]byte, c.kek.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cannot generate nonce: %w", err)
+	}
+	aead, err := newAead(dek)
+	if err != nil {
+		return nil, err
+	}
+	// The header is used as additional data, so it cannot be modified without detection
+	wrappedKey := c.kek.Seal(nonce, nonce, dek, h.encode())
+	return io.NopCloser(
+		io.MultiReader(
+			bytes.NewReader(wrappedKey),
+			&segmentEncryptReader{src: src, aead: aead},
+		),
+	), nil
+}
+
+func (c *aesGcmCipher) open(src io.Reader, h *header) (io.Reader, error) {
+	if h.KeyId != c.keyId {
+		return nil, fmt.Errorf(
+			"%w: object is encrypted with key id %s but configured key id is %s", ErrWrongKey, h.KeyId, c.keyId,
+		)
+	}
+	wrappedKey := make([]byte, c.kek.NonceSize()+keySize+c.kek.Overhead())
+	if _, err := io.ReadFull(src, wrappedKey); err != nil {
+		return nil, fmt.Errorf("cannot read data key: %w", err)
+	}
+	nonceSize := c.kek.NonceSize()
+	dek, err := c.kek.Open(nil, wrappedKey[:nonceSize], wrappedKey[nonceSize:], h.encode())
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot unwrap data key: %w", ErrWrongKey, err)
+	}
+	aead, err := newAead(dek)
+	if err != nil {
+		return nil, err
+	}
+	return &segmentDecryptReader{src: src, aead: aead}, nil
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create aes cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// segmentNonce - builds nonce from the segment counter. The last byte marks the final segment that protects from
+// the object truncation
+func segmentNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// readSegment - reads up to size bytes into buf and reports whether the segment is the last one. It reads one byte
+// ahead, and that byte is returned as carry for the next call
+func readSegment(src io.Reader, buf []byte, carry []byte, size int) (data []byte, newCarry []byte, last bool, err error) {
+	if cap(buf) < size+1 {
+		buf = make([]byte, 0, size+1)
+	}
+	buf = append(buf[:0], carry...)
+	buf = buf[:size+1]
+	n, err := io.ReadFull(src, buf[len(carry):])
+	n += len(carry)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, false, err
+	}
+	if n == size+1 {
+		return buf[:size], []byte{buf[size]}, false, nil
+	}
+	return buf[:n], nil, true, nil
+}
+
+type segmentEncryptReader struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	counter uint64
+	plain   []byte
+	carry   []byte
+	outBuf  []byte
+	out     []byte
+	done    bool
+}
+
+func (r *segmentEncryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		data, carry, last, err := readSegment(r.src, r.plain, r.carry, segmentSize)
+		if err != nil {
+			return 0, err
+		}
+		r.plain, r.carry, r.done = data, carry, last
+		r.outBuf = r.aead.Seal(r.outBuf[:0], segmentNonce(r.counter, last), data, nil)
+		r.out = r.outBuf
+		r.counter++
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+type segmentDecryptReader struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	counter uint64
+	sealed  []byte
+	carry   []byte
+	outBuf  []byte
+	out     []byte
+	done    bool
+}
+
+func (r *segmentDecryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		data, carry, last, err := readSegment(r.src, r.sealed, r.carry, segmentSize+r.aead.Overhead())
+		if err != nil {
+			return 0, err
+		}
+		if last && len(data) < r.aead.Overhead() {
+			return 0, fmt.Errorf("encrypted object is truncated")
+		}
+		r.sealed, r.carry, r.done = data, carry, last
+		r.outBuf, err = r.aead.Open(r.outBuf[:0], segmentNonce(r.counter, last), data, nil)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decrypt segment %d: object is corrupted or truncated: %w", r.counter, err)
+		}
+		r.out = r.outBuf
+		r.counter++
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
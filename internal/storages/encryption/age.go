@@ -0,0 +1,134 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/rs/zerolog/log"
+)
+
+// ageCipher - encrypts objects for the list of age recipients. The object can be decrypted by any identity that
+// matches one of the recipients. The age format generates a random file key per object, so it is envelope
+// encryption as well
+type ageCipher struct {
+	recipients []age.Recipient
+	identities []age.Identity
+	keyId      string
+}
+
+func newAgeCipher(cfg *Config) (*ageCipher, error) {
+	c := &ageCipher{}
+	recipientLines := slices.Clone(cfg.Recipients)
+	if cfg.RecipientsFile != "" {
+		data, err := os.ReadFile(cfg.RecipientsFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read recipients file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			recipientLines = append(recipientLines, line)
+		}
+	}
+
+	for _, line := range recipientLines {
+		r, err := age.ParseX25519Recipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse age recipient %q: %w", line, err)
+		}
+		c.recipients = append(c.recipients, r)
+	}
+
+	if cfg.IdentityFile != "" {
+		f, err := os.Open(cfg.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open identity file: %w", err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Warn().Err(err).Msg("error closing identity file")
+			}
+		}()
+		c.identities, err = age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse identity file: %w", err)
+		}
+		if len(recipientLines) == 0 {
+			// Derive recipients from identities. It allows to use the same config for dump and restore
+			for _, identity := range c.identities {
+				if x, ok := identity.(*age.X25519Identity); ok {
+					c.recipients = append(c.recipients, x.Recipient())
+					recipientLines = append(recipientLines, x.Recipient().String())
+				}
+			}
+		}
+	}
+
+	slices.Sort(recipientLines)
+	sum := sha256.Sum256([]byte(strings.Join(recipientLines, "\n")))
+	c.keyId = hex.EncodeToString(sum[:keyIdSize])
+	return c, nil
+}
+
+func (c *ageCipher) name() string {
+	return CipherAge
+}
+
+func (c *ageCipher) id() string {
+	return c.keyId
+}
+
+func (c *ageCipher) seal(src io.Reader, _ *header) (io.ReadCloser, error) {
+	if len(c.recipients) == 0 {
+		return nil, fmt.Errorf("age recipients are not configured: cannot encrypt object")
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := age.Encrypt(pw, c.recipients...)
+		if err != nil {
+			pw.CloseWithError(fmt.Errorf("cannot initialize age encryption: %w", err))
+			return
+		}
+		if _, err = io.Copy(w, src); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(w.Close())
+	}()
+	return pr, nil
+}
+
+func (c *ageCipher) open(src io.Reader, h *header) (io.Reader, error) {
+	if len(c.identities) == 0 {
+		return nil, fmt.Errorf("age identity_file is not configured: cannot decrypt object")
+	}
+	r, err := age.Decrypt(src, c.identities...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%w: object is encrypted for recipients with key id %s: %w", ErrWrongKey, h.KeyId, err,
+		)
+	}
+	return r, nil
+}
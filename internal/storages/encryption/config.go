@@ -0,0 +1,70 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"fmt"
+)
+
+const (
+	CipherAes256Gcm = "aes-256-gcm"
+	CipherAge       = "age"
+)
+
+type Config struct {
+	// Cipher - encryption algorithm. Empty value disables encryption
+	Cipher string `mapstructure:"cipher"`
+	// KeyFile - path to the file with base64 encoded 32 bytes key (aes-256-gcm)
+	KeyFile string `mapstructure:"key_file"`
+	// KeyEnv - name of the environment variable with base64 encoded 32 bytes key (aes-256-gcm)
+	KeyEnv string `mapstructure:"key_env"`
+	// Recipients - list of age public keys used for encryption (age)
+	Recipients []string `mapstructure:"recipients"`
+	// RecipientsFile - path to the file with age public keys - one per line (age)
+	RecipientsFile string `mapstructure:"recipients_file"`
+	// IdentityFile - path to the age identity file used for decryption (age)
+	IdentityFile string `mapstructure:"identity_file"`
+}
+
+func NewConfig() *Config {
+	return &Config{}
+}
+
+func (c *Config) Enabled() bool {
+	return c.Cipher != ""
+}
+
+func (c *Config) Validate() error {
+	switch c.Cipher {
+	case "":
+		return nil
+	case CipherAes256Gcm:
+		if c.KeyFile == "" && c.KeyEnv == "" {
+			return fmt.Errorf("one of key_file or key_env is required for %s cipher", c.Cipher)
+		}
+		if c.KeyFile != "" && c.KeyEnv != "" {
+			return fmt.Errorf("key_file and key_env are mutually exclusive")
+		}
+	case CipherAge:
+		if len(c.Recipients) == 0 && c.RecipientsFile == "" && c.IdentityFile == "" {
+			return fmt.Errorf(
+				"at least one of recipients, recipients_file or identity_file is required for %s cipher", c.Cipher,
+			)
+		}
+	default:
+		return fmt.Errorf("unknown cipher %q: expected %s or %s", c.Cipher, CipherAes256Gcm, CipherAge)
+	}
+	return nil
+}
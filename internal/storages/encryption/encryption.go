@@ -0,0 +1,173 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/greenmaskio/greenmask/internal/storages"
+)
+
+var ErrEncryptionIsNotConfigured = errors.New("storage encryption is not configured")
+
+type objectCipher interface {
+	name() string
+	id() string
+	// seal - returns reader that produces encrypted payload of src. The header is already written by the caller
+	seal(src io.Reader, h *header) (io.ReadCloser, error)
+	// open - returns reader that produces decrypted payload of src. The header is already consumed by the caller
+	open(src io.Reader, h *header) (io.Reader, error)
+}
+
+// Info - describes the encryption of the dump. It is stored in the dump metadata
+type Info struct {
+	Cipher string `json:"cipher" yaml:"cipher"`
+	KeyId  string `json:"keyId" yaml:"keyId"`
+}
+
+// Storage - wraps any storages.Storager and encrypts objects on PutObject and decrypts them on GetObject. The objects
+// that do not have encryption header are returned as is, so the dumps created without encryption can still be read.
+// If encryption is not configured, objects are written as is, but reading of an encrypted object fails with clear
+// error instead of returning ciphertext
+type Storage struct {
+	storages.Storager
+	cipher objectCipher
+}
+
+func NewStorage(st storages.Storager, cfg *Config) (*Storage, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Storage{
+		Storager: st,
+	}
+	var err error
+	switch cfg.Cipher {
+	case CipherAes256Gcm:
+		s.cipher, err = newAesGcmCipher(cfg)
+	case CipherAge:
+		s.cipher, err = newAgeCipher(cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize %s cipher: %w", cfg.Cipher, err)
+	}
+	return s, nil
+}
+
+// Info - returns encryption info or nil if encryption is disabled
+func (s *Storage) Info() *Info {
+	if s.cipher == nil {
+		return nil
+	}
+	return &Info{
+		Cipher: s.cipher.name(),
+		KeyId:  s.cipher.id(),
+	}
+}
+
+func (s *Storage) ListDir(ctx context.Context) (files []string, dirs []storages.Storager, err error) {
+	files, dirs, err = s.Storager.ListDir(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for idx := range dirs {
+		dirs[idx] = s.wrap(dirs[idx])
+	}
+	return files, dirs, nil
+}
+
+func (s *Storage) GetObject(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	r, err := s.Storager.GetObject(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		if errors.Is(err, errNotEncrypted) {
+			return &readCloser{Reader: br, closer: r}, nil
+		}
+		closeReader(r)
+		return nil, fmt.Errorf("object %s: %w", filePath, err)
+	}
+
+	if s.cipher == nil {
+		closeReader(r)
+		return nil, fmt.Errorf(
+			"%w: object %s is encrypted with %s (key id %s)", ErrEncryptionIsNotConfigured, filePath, h.Cipher, h.KeyId,
+		)
+	}
+	if h.Cipher != s.cipher.name() {
+		closeReader(r)
+		return nil, fmt.Errorf(
+			"object %s is encrypted with %s cipher but %s is configured", filePath, h.Cipher, s.cipher.name(),
+		)
+	}
+	plain, err := s.cipher.open(br, h)
+	if err != nil {
+		closeReader(r)
+		return nil, fmt.Errorf("object %s: %w", filePath, err)
+	}
+	return &readCloser{Reader: plain, closer: r}, nil
+}
+
+func (s *Storage) PutObject(ctx context.Context, filePath string, body io.Reader) error {
+	if s.cipher == nil {
+		return s.Storager.PutObject(ctx, filePath, body)
+	}
+	h := &header{
+		Cipher: s.cipher.name(),
+		KeyId:  s.cipher.id(),
+	}
+	sealed, err := s.cipher.seal(body, h)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt object %s: %w", filePath, err)
+	}
+	defer closeReader(sealed)
+	return s.Storager.PutObject(ctx, filePath, io.MultiReader(bytes.NewReader(h.encode()), sealed))
+}
+
+func (s *Storage) SubStorage(subPath string, relative bool) storages.Storager {
+	return s.wrap(s.Storager.SubStorage(subPath, relative))
+}
+
+func (s *Storage) wrap(st storages.Storager) *Storage {
+	return &Storage{
+		Storager: st,
+		cipher:   s.cipher,
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (rc *readCloser) Close() error {
+	return rc.closer.Close()
+}
+
+func closeReader(c io.Closer) {
+	if err := c.Close(); err != nil {
+		log.Warn().Err(err).Msg("error closing object reader")
+	}
+}
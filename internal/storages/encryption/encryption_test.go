@@ -0,0 +1,183 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/greenmaskio/greenmask/internal/storages/validate"
+)
+
+func newTestKey(t *testing.T) string {
+	key := make([]byte, keySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newAesStorage(t *testing.T, backend *validate.Storage, key string) *Storage {
+	t.Setenv("GREENMASK_TEST_ENCRYPTION_KEY", key)
+	st, err := NewStorage(backend, &Config{Cipher: CipherAes256Gcm, KeyEnv: "GREENMASK_TEST_ENCRYPTION_KEY"})
+	require.NoError(t, err)
+	return st
+}
+
+func readAll(t *testing.T, st *Storage, name string) ([]byte, error) {
+	r, err := st.GetObject(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		require.NoError(t, r.Close())
+	}()
+	return io.ReadAll(r)
+}
+
+func TestStorage_AesGcmRoundTrip(t *testing.T) {
+	backend := validate.New("")
+	st := newAesStorage(t, backend, newTestKey(t))
+
+	sizes := []int{0, 1, segmentSize - 1, segmentSize, segmentSize + 1, 3*segmentSize + 17}
+	for _, size := range sizes {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+
+		require.NoError(t, st.PutObject(context.Background(), "1.dat.gz", bytes.NewReader(data)))
+
+		raw, err := backend.GetObject(context.Background(), "1.dat.gz")
+		require.NoError(t, err)
+		ciphertext, err := io.ReadAll(raw)
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(ciphertext, magic))
+		if size > 0 {
+			assert.False(t, bytes.Contains(ciphertext, data))
+		}
+
+		res, err := readAll(t, st, "1.dat.gz")
+		require.NoError(t, err)
+		assert.Equal(t, data, res, "size %d", size)
+	}
+}
+
+func TestStorage_WrongKey(t *testing.T) {
+	backend := validate.New("")
+	st := newAesStorage(t, backend, newTestKey(t))
+	require.NoError(t, st.PutObject(context.Background(), "metadata.json", bytes.NewBufferString("{}")))
+
+	another := newAesStorage(t, backend, newTestKey(t))
+	_, err := another.GetObject(context.Background(), "metadata.json")
+	require.ErrorIs(t, err, ErrWrongKey)
+	assert.Contains(t, err.Error(), st.Info().KeyId)
+}
+
+func TestStorage_NotConfigured(t *testing.T) {
+	backend := validate.New("")
+	st := newAesStorage(t, backend, newTestKey(t))
+	require.NoError(t, st.PutObject(context.Background(), "toc.dat", bytes.NewBufferString("toc")))
+
+	plain, err := NewStorage(backend, NewConfig())
+	require.NoError(t, err)
+	assert.Nil(t, plain.Info())
+	_, err = plain.GetObject(context.Background(), "toc.dat")
+	require.ErrorIs(t, err, ErrEncryptionIsNotConfigured)
+}
+
+func TestStorage_PlaintextObjectIsReadable(t *testing.T) {
+	backend := validate.New("")
+	require.NoError(t, backend.PutObject(context.Background(), "heartbeat", bytes.NewBufferString("done")))
+
+	st := newAesStorage(t, backend, newTestKey(t))
+	res, err := readAll(t, st, "heartbeat")
+	require.NoError(t, err)
+	assert.Equal(t, "done", string(res))
+}
+
+func TestStorage_Truncated(t *testing.T) {
+	backend := validate.New("")
+	st := newAesStorage(t, backend, newTestKey(t))
+	data := make([]byte, 2*segmentSize+10)
+	require.NoError(t, st.PutObject(context.Background(), "1.dat.gz", bytes.NewReader(data)))
+
+	raw, err := backend.GetObject(context.Background(), "1.dat.gz")
+	require.NoError(t, err)
+	ciphertext, err := io.ReadAll(raw)
+	require.NoError(t, err)
+	// Cut the last segment entirely - the previous segment is not marked as the last one
+	truncated := ciphertext[:len(ciphertext)-(10+16)]
+	require.NoError(t, backend.PutObject(context.Background(), "1.dat.gz", bytes.NewReader(truncated)))
+
+	_, err = readAll(t, st, "1.dat.gz")
+	require.Error(t, err)
+}
+
+func TestStorage_SubStorageIsEncrypted(t *testing.T) {
+	backend := validate.New("")
+	st := newAesStorage(t, backend, newTestKey(t))
+	sub := st.SubStorage("123", true)
+	encSub, ok := sub.(*Storage)
+	require.True(t, ok)
+	assert.Equal(t, st.Info(), encSub.Info())
+}
+
+func TestStorage_Age(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	identityFile := path.Join(t.TempDir(), "identity.txt")
+	require.NoError(t, os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600))
+
+	backend := validate.New("")
+	writer, err := NewStorage(backend, &Config{Cipher: CipherAge, Recipients: []string{identity.Recipient().String()}})
+	require.NoError(t, err)
+	require.NoError(t, writer.PutObject(context.Background(), "toc.dat", bytes.NewBufferString("data")))
+
+	_, err = writer.GetObject(context.Background(), "toc.dat")
+	require.Error(t, err)
+
+	reader, err := NewStorage(backend, &Config{Cipher: CipherAge, IdentityFile: identityFile})
+	require.NoError(t, err)
+	assert.Equal(t, writer.Info().KeyId, reader.Info().KeyId)
+	res, err := readAll(t, reader, "toc.dat")
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(res))
+
+	another, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	anotherFile := path.Join(t.TempDir(), "another.txt")
+	require.NoError(t, os.WriteFile(anotherFile, []byte(another.String()+"\n"), 0600))
+	wrongReader, err := NewStorage(backend, &Config{Cipher: CipherAge, IdentityFile: anotherFile})
+	require.NoError(t, err)
+	_, err = wrongReader.GetObject(context.Background(), "toc.dat")
+	require.ErrorIs(t, err, ErrWrongKey)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, NewConfig().Validate())
+	assert.Error(t, (&Config{Cipher: CipherAes256Gcm}).Validate())
+	assert.Error(t, (&Config{Cipher: CipherAes256Gcm, KeyEnv: "A", KeyFile: "B"}).Validate())
+	assert.Error(t, (&Config{Cipher: CipherAge}).Validate())
+	assert.Error(t, (&Config{Cipher: "rot13"}).Validate())
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const headerVersion byte = 1
+
+// magic - prefix of each encrypted object. It is used for detecting encrypted objects on reading
+var magic = []byte("GMENC")
+
+var errNotEncrypted = errors.New("object is not encrypted")
+
+// header - the plaintext prefix of encrypted object. It allows to fail clearly when the object was encrypted
+// with another cipher or key
+type header struct {
+	Cipher string
+	KeyId  string
+}
+
+func (h *header) encode() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(magic)+3+len(h.Cipher)+len(h.KeyId)))
+	buf.Write(magic)
+	buf.WriteByte(headerVersion)
+	buf.WriteByte(byte(len(h.Cipher)))
+	buf.WriteString(h.Cipher)
+	buf.WriteByte(byte(len(h.KeyId)))
+	buf.WriteString(h.KeyId)
+	return buf.Bytes()
+}
+
+// readHeader - reads header from the reader. Returns errNotEncrypted if the object does not start with magic. In this
+// case nothing is consumed from the reader
+func readHeader(r *bufio.Reader) (*header, error) {
+	prefix, err := r.Peek(len(magic))
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errNotEncrypted
+		}
+		return nil, fmt.Errorf("cannot read object header: %w", err)
+	}
+	if !bytes.Equal(prefix, magic) {
+		return nil, errNotEncrypted
+	}
+	if _, err = r.Discard(len(magic)); err != nil {
+		return nil, fmt.Errorf("cannot read object header: %w", err)
+	}
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read object header version: %w", err)
+	}
+	if version != headerVersion {
+		return nil, fmt.Errorf("unsupported encryption header version %d", version)
+	}
+	cipherName, err := readShortString(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cipher name: %w", err)
+	}
+	keyId, err := readShortString(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key id: %w", err)
+	}
+	return &header{
+		Cipher: cipherName,
+		KeyId:  keyId,
+	}, nil
+}
+
+func readShortString(r *bufio.Reader) (string, error) {
+	size, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, size)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
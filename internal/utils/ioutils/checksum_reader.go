@@ -0,0 +1,50 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ioutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// ChecksumReader - calculates SHA-256 checksum of the data read from the underlying reader
+type ChecksumReader struct {
+	r io.ReadCloser
+	h hash.Hash
+}
+
+func NewChecksumReader(r io.ReadCloser) *ChecksumReader {
+	return &ChecksumReader{
+		r: r,
+		h: sha256.New(),
+	}
+}
+
+func (cr *ChecksumReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.h.Write(p[:n])
+	return n, err
+}
+
+func (cr *ChecksumReader) Close() error {
+	return cr.r.Close()
+}
+
+// Checksum - hex encoded checksum of the data read so far
+func (cr *ChecksumReader) Checksum() string {
+	return hex.EncodeToString(cr.h.Sum(nil))
+}
@@ -0,0 +1,319 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ioutils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	GzipCodecName = "gzip"
+	ZstdCodecName = "zstd"
+	Lz4CodecName  = "lz4"
+	NoneCodecName = "none"
+)
+
+const (
+	GzipExtension = ".gz"
+	ZstdExtension = ".zst"
+	Lz4Extension  = ".lz4"
+)
+
+// zstdLongWindowSize - window size of the zstd long distance matching mode. It is the same as the default window
+// of zstd --long and fits the default window limit of the decoder
+const zstdLongWindowSize = 1 << 27
+
+var lz4Levels = []lz4.CompressionLevel{
+	lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4, lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9,
+}
+
+// Codec - compression algorithm of the dump objects (table data and large objects)
+type Codec interface {
+	// Name - name of the codec that is stored in the dump metadata
+	Name() string
+	// Extension - file extension of the compressed object. It is empty for the uncompressed objects
+	Extension() string
+	// NewWriter - wrap the writer into the compressing writer. Close flushes the compressed data and closes w
+	NewWriter(w io.WriteCloser) io.WriteCloser
+	// NewReader - wrap the reader into the decompressing reader. Close closes r as well. r is closed if the
+	// reader cannot be created
+	NewReader(r io.ReadCloser) (io.ReadCloser, error)
+}
+
+// NewCodec - create codec by name. Level 0 means the default level of the codec. The long window mode is supported
+// by zstd only. usePgzip enables parallel gzip implementation for the gzip codec
+func NewCodec(name string, level int, longWindow, usePgzip bool) (Codec, error) {
+	if longWindow && name != ZstdCodecName {
+		return nil, fmt.Errorf("long window mode is supported by %s codec only", ZstdCodecName)
+	}
+	switch name {
+	case GzipCodecName, "":
+		if level < 0 || level > gzip.BestCompression {
+			return nil, fmt.Errorf("%s compression level must be in range 1..%d", GzipCodecName, gzip.BestCompression)
+		}
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return &gzipCodec{level: level, usePgzip: usePgzip}, nil
+	case ZstdCodecName:
+		if level < 0 || level > 22 {
+			return nil, fmt.Errorf("%s compression level must be in range 1..22", ZstdCodecName)
+		}
+		var opts []zstd.EOption
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		if longWindow {
+			opts = append(opts, zstd.WithWindowSize(zstdLongWindowSize))
+		}
+		// Check the options once, so the writer creation cannot fail later
+		enc, err := zstd.NewWriter(nil, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create %s encoder: %w", ZstdCodecName, err)
+		}
+		if err = enc.Close(); err != nil {
+			return nil, fmt.Errorf("cannot close %s encoder: %w", ZstdCodecName, err)
+		}
+		return &zstdCodec{opts: opts}, nil
+	case Lz4CodecName:
+		if level < 0 || level > len(lz4Levels) {
+			return nil, fmt.Errorf("%s compression level must be in range 1..%d", Lz4CodecName, len(lz4Levels))
+		}
+		res := &lz4Codec{level: lz4.Fast}
+		if level != 0 {
+			res.level = lz4Levels[level-1]
+		}
+		return res, nil
+	case NoneCodecName:
+		if level != 0 {
+			return nil, fmt.Errorf("compression level cannot be used with %s codec", NoneCodecName)
+		}
+		return &noneCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown compression codec \"%s\"", name)
+}
+
+// DefaultCodec - gzip codec with the default level
+func DefaultCodec() Codec {
+	return &gzipCodec{level: gzip.DefaultCompression}
+}
+
+// GetCodecByName - get the codec for decompression by its name. Empty name means gzip that was the only codec of
+// the dumps made before the codecs were introduced
+func GetCodecByName(name string, usePgzip bool) (Codec, error) {
+	return NewCodec(name, 0, false, usePgzip)
+}
+
+// GetCodecByFileName - get the codec for decompression by the object file extension. The objects without known
+// extension are considered uncompressed
+func GetCodecByFileName(fileName string, usePgzip bool) Codec {
+	switch {
+	case strings.HasSuffix(fileName, GzipExtension):
+		return &gzipCodec{level: gzip.DefaultCompression, usePgzip: usePgzip}
+	case strings.HasSuffix(fileName, ZstdExtension):
+		return &zstdCodec{}
+	case strings.HasSuffix(fileName, Lz4Extension):
+		return &lz4Codec{level: lz4.Fast}
+	}
+	return &noneCodec{}
+}
+
+// NewCompressionPipe - the same as NewGzipPipe but uses the provided codec
+func NewCompressionPipe(c Codec) (CountWriteCloser, CountReadCloser) {
+	pr, pw := io.Pipe()
+	return NewWriter(c.NewWriter(pw)), NewReader(pr)
+}
+
+type gzipCodec struct {
+	level    int
+	usePgzip bool
+}
+
+func (c *gzipCodec) Name() string {
+	return GzipCodecName
+}
+
+func (c *gzipCodec) Extension() string {
+	return GzipExtension
+}
+
+func (c *gzipCodec) NewWriter(w io.WriteCloser) io.WriteCloser {
+	if c.level == gzip.DefaultCompression {
+		return NewGzipWriter(w, c.usePgzip)
+	}
+	var gz WriteCloseFlusher
+	var err error
+	if c.usePgzip {
+		gz, err = pgzip.NewWriterLevel(w, c.level)
+	} else {
+		gz, err = gzip.NewWriterLevel(w, c.level)
+	}
+	if err != nil {
+		return &errWriter{w: w, err: fmt.Errorf("cannot create gzip writer: %w", err)}
+	}
+	return &GzipWriter{w: w, gz: gz}
+}
+
+func (c *gzipCodec) NewReader(r io.ReadCloser) (io.ReadCloser, error) {
+	return NewGzipReader(r, c.usePgzip)
+}
+
+type zstdCodec struct {
+	opts []zstd.EOption
+}
+
+func (c *zstdCodec) Name() string {
+	return ZstdCodecName
+}
+
+func (c *zstdCodec) Extension() string {
+	return ZstdExtension
+}
+
+func (c *zstdCodec) NewWriter(w io.WriteCloser) io.WriteCloser {
+	enc, err := zstd.NewWriter(w, c.opts...)
+	if err != nil {
+		return &errWriter{w: w, err: fmt.Errorf("cannot create zstd writer: %w", err)}
+	}
+	return &codecWriter{w: w, cw: enc}
+}
+
+func (c *zstdCodec) NewReader(r io.ReadCloser) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r)
+	if err != nil {
+		closeObject(r)
+		return nil, fmt.Errorf("cannot create zstd reader: %w", err)
+	}
+	return &codecReader{r: r, cr: dec.IOReadCloser()}, nil
+}
+
+type lz4Codec struct {
+	level lz4.CompressionLevel
+}
+
+func (c *lz4Codec) Name() string {
+	return Lz4CodecName
+}
+
+func (c *lz4Codec) Extension() string {
+	return Lz4Extension
+}
+
+func (c *lz4Codec) NewWriter(w io.WriteCloser) io.WriteCloser {
+	lw := lz4.NewWriter(w)
+	if err := lw.Apply(lz4.CompressionLevelOption(c.level)); err != nil {
+		return &errWriter{w: w, err: fmt.Errorf("cannot create lz4 writer: %w", err)}
+	}
+	return &codecWriter{w: w, cw: lw}
+}
+
+func (c *lz4Codec) NewReader(r io.ReadCloser) (io.ReadCloser, error) {
+	return &codecReader{r: r, cr: io.NopCloser(lz4.NewReader(r))}, nil
+}
+
+type noneCodec struct{}
+
+func (c *noneCodec) Name() string {
+	return NoneCodecName
+}
+
+func (c *noneCodec) Extension() string {
+	return ""
+}
+
+func (c *noneCodec) NewWriter(w io.WriteCloser) io.WriteCloser {
+	return w
+}
+
+func (c *noneCodec) NewReader(r io.ReadCloser) (io.ReadCloser, error) {
+	return r, nil
+}
+
+// codecWriter - closes the compressing writer with flushing its buffer and then the underlying writer
+type codecWriter struct {
+	w  io.WriteCloser
+	cw io.WriteCloser
+}
+
+func (cw *codecWriter) Write(p []byte) (int, error) {
+	return cw.cw.Write(p)
+}
+
+func (cw *codecWriter) Close() error {
+	var globalErr error
+	if err := cw.cw.Close(); err != nil {
+		globalErr = fmt.Errorf("error closing compression writer: %w", err)
+		log.Warn().Err(err).Msg("error closing compression writer")
+	}
+	if err := cw.w.Close(); err != nil {
+		globalErr = fmt.Errorf("error closing dump file: %w", err)
+		log.Warn().Err(err).Msg("error closing dump file")
+	}
+	return globalErr
+}
+
+// codecReader - closes the decompressing reader and then the underlying reader
+type codecReader struct {
+	r  io.ReadCloser
+	cr io.ReadCloser
+}
+
+func (cr *codecReader) Read(p []byte) (int, error) {
+	return cr.cr.Read(p)
+}
+
+func (cr *codecReader) Close() error {
+	var lastErr error
+	if err := cr.cr.Close(); err != nil {
+		lastErr = fmt.Errorf("error closing decompression reader: %w", err)
+		log.Warn().Err(err).Msg("error closing decompression reader")
+	}
+	if err := cr.r.Close(); err != nil {
+		lastErr = fmt.Errorf("error closing dump file: %w", err)
+		log.Warn().Err(err).Msg("error closing dump file")
+	}
+	return lastErr
+}
+
+// errWriter - the writer that returns the initialization error of the compressing writer on each call
+type errWriter struct {
+	w   io.WriteCloser
+	err error
+}
+
+func (ew *errWriter) Write(_ []byte) (int, error) {
+	return 0, ew.err
+}
+
+func (ew *errWriter) Close() error {
+	if err := ew.w.Close(); err != nil {
+		log.Warn().Err(err).Msg("error closing dump file")
+	}
+	return ew.err
+}
+
+func closeObject(r io.Closer) {
+	if err := r.Close(); err != nil {
+		log.Warn().Err(err).Msg("error closing dump file")
+	}
+}
@@ -0,0 +1,85 @@
+package ioutils
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCodec_RoundTrip(t *testing.T) {
+	data := strings.Repeat("20383\t24ca7574-0adb-4b17-8777-93f5589dbea2\t2017-12-13 13:46:49.39\n", 1000)
+	tests := []struct {
+		name       string
+		codec      string
+		level      int
+		longWindow bool
+		usePgzip   bool
+		ext        string
+	}{
+		{name: "gzip", codec: GzipCodecName, ext: ".gz"},
+		{name: "gzip with level", codec: GzipCodecName, level: 9, ext: ".gz"},
+		{name: "pgzip with level", codec: GzipCodecName, level: 1, usePgzip: true, ext: ".gz"},
+		{name: "zstd", codec: ZstdCodecName, ext: ".zst"},
+		{name: "zstd with level and long window", codec: ZstdCodecName, level: 19, longWindow: true, ext: ".zst"},
+		{name: "lz4", codec: Lz4CodecName, ext: ".lz4"},
+		{name: "lz4 with level", codec: Lz4CodecName, level: 9, ext: ".lz4"},
+		{name: "none", codec: NoneCodecName, ext: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCodec(tt.codec, tt.level, tt.longWindow, tt.usePgzip)
+			require.NoError(t, err)
+			assert.Equal(t, tt.codec, c.Name())
+			assert.Equal(t, tt.ext, c.Extension())
+
+			objSrc := &writeCloserMock{}
+			w := c.NewWriter(objSrc)
+			_, err = w.Write([]byte(data))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			require.Equal(t, 1, objSrc.closeCallCount)
+
+			obj := &readCloserMock{Buffer: bytes.NewBuffer(objSrc.data)}
+			r, err := GetCodecByFileName("1.dat"+c.Extension(), tt.usePgzip).NewReader(obj)
+			require.NoError(t, err)
+			res, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, data, string(res))
+			assert.Equal(t, 1, obj.closeCallCount)
+		})
+	}
+}
+
+func TestNewCodec_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		codec      string
+		level      int
+		longWindow bool
+		err        string
+	}{
+		{name: "unknown codec", codec: "brotli", err: "unknown compression codec"},
+		{name: "gzip level", codec: GzipCodecName, level: 10, err: "must be in range 1..9"},
+		{name: "zstd level", codec: ZstdCodecName, level: 23, err: "must be in range 1..22"},
+		{name: "lz4 level", codec: Lz4CodecName, level: -1, err: "must be in range 1..9"},
+		{name: "none level", codec: NoneCodecName, level: 1, err: "compression level cannot be used"},
+		{name: "long window", codec: GzipCodecName, longWindow: true, err: "long window mode is supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCodec(tt.codec, tt.level, tt.longWindow, false)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestGetCodecByName(t *testing.T) {
+	c, err := GetCodecByName("", false)
+	require.NoError(t, err)
+	assert.Equal(t, GzipCodecName, c.Name())
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+const GlobalSaltEnvVariable = "GREENMASK_GLOBAL_SALT"
+
+// GetGlobalSalt - decode hex encoded global salt provided via GREENMASK_GLOBAL_SALT environment variable
+func GetGlobalSalt() ([]byte, error) {
+	saltHex := os.Getenv(GlobalSaltEnvVariable)
+	if saltHex == "" {
+		return nil, nil
+	}
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding salt from hex: %w", err)
+	}
+	return salt, nil
+}